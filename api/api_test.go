@@ -0,0 +1,240 @@
+// package api contains tests for the shared handler helpers
+package api
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mergePatchTest contains the examples from RFC 7386 Appendix A
+var mergePatchTest = []struct {
+	target   string // original document
+	patch    string // merge patch
+	expected string // expected result
+}{
+	{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+	{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+	{`{"a":"b"}`, `{"a":null}`, `{}`},
+	{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+	{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+	{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+	{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+	{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+	{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+	{`{"a":"b"}`, `["c"]`, `["c"]`},
+	{`{"a":"foo"}`, `null`, `null`},
+	{`{"a":"foo"}`, `"bar"`, `"bar"`},
+	{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+	{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+	{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+}
+
+// TestMergePatch tests whether MergePatch func gives the results from RFC 7386
+func TestMergePatch(t *testing.T) {
+	for _, p := range mergePatchTest {
+		patched, err := MergePatch([]byte(p.target), []byte(p.patch))
+		assert.NoError(t, err)
+		assert.JSONEq(t, p.expected, string(patched), "Patching %s with %s", p.target, p.patch)
+	}
+}
+
+// TestPatchResource tests whether PatchResource func changes only the patched fields of a struct
+// and rejects patches which are not objects
+func TestPatchResource(t *testing.T) {
+	type resource struct {
+		Name        string
+		Description string
+		Price       int
+	}
+	r := resource{Name: "Name", Description: "Description", Price: 10}
+
+	assert.NoError(t, PatchResource(&r, []byte(`{"Price":20,"Description":null}`)))
+	assert.Equal(t, resource{Name: "Name", Price: 20}, r)
+
+	assert.Equal(t, ErrPatchNotObject, PatchResource(&r, []byte(`["Name"]`)))
+	assert.Equal(t, ErrPatchNotObject, PatchResource(&r, []byte(`null`)))
+	assert.Error(t, PatchResource(&r, []byte(`{"Price":`)))
+}
+
+// TestWriteError tests whether WriteError func writes problem details with the right status and content type
+// and hides the details of unexpected errors
+func TestWriteError(t *testing.T) {
+	req := httptest.NewRequest("POST", "/products/new", nil)
+
+	rr := httptest.NewRecorder()
+	WriteError(rr, req, Validation(FieldError{Field: "ProductName", Detail: "Kindly enter the product name"}))
+	assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected")
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	expected := `{"type":"/problems/validation","title":"Unprocessable Entity","status":422,"detail":"The request body contains invalid fields","instance":"/products/new","errors":[{"field":"ProductName","detail":"Kindly enter the product name"}]}`
+	assert.JSONEq(t, expected, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	WriteError(rr, req, errors.New("database is on fire"))
+	assert.Equal(t, 500, rr.Code, "Internal Server Error response is expected")
+	assert.JSONEq(t, `{"type":"/problems/internal","title":"Internal Server Error","status":500,"instance":"/products/new"}`, rr.Body.String())
+}
+
+// TestWriteJSON tests whether WriteJSON func reports a value which can not be encoded as 500 instead of a broken body
+func TestWriteJSON(t *testing.T) {
+	rr := httptest.NewRecorder()
+	WriteJSON(rr, http.StatusCreated, map[string]string{"a": "b"})
+	assert.Equal(t, 201, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"a":"b"}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	WriteJSON(rr, http.StatusOK, func() {})
+	assert.Equal(t, 500, rr.Code, "Internal Server Error response is expected")
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+}
+
+// TestSnakeKeys tests whether SnakeKeys and GoKeys funcs rename the fields both ways, keeping the order
+// of the fields and the keys of the maps
+func TestSnakeKeys(t *testing.T) {
+	for _, p := range []struct {
+		goKeys    string
+		snakeKeys string
+	}{
+		{`{"ProductID":"a","SKU":"b","TTLSeconds":60,"OutOfStock":true,"BasePrice":null}`,
+			`{"product_id":"a","sku":"b","ttl_seconds":60,"out_of_stock":true,"base_price":null}`},
+		{`[{"Attributes":{"Size_EU":"38","ProductID":1.5}},{"Attributes":[{"Name":"size","Values":["38"]}]}]`,
+			`[{"attributes":{"Size_EU":"38","ProductID":1.5}},{"attributes":[{"name":"size","values":["38"]}]}]`},
+		{`{"Base":"EUR","Rates":{"USD":1.08},"Rounding":{"JPY":{"Mode":"up","Increment":10}}}`,
+			`{"base":"EUR","rates":{"USD":1.08},"rounding":{"JPY":{"mode":"up","increment":10}}}`},
+	} {
+		renamed, err := SnakeKeys([]byte(p.goKeys))
+		assert.NoError(t, err)
+		assert.Equal(t, p.snakeKeys, string(renamed), "Expected the snake_case names of %s", p.goKeys)
+		renamed, err = GoKeys([]byte(p.snakeKeys))
+		assert.NoError(t, err)
+		assert.Equal(t, p.goKeys, string(renamed), "Expected the Go names of %s", p.snakeKeys)
+	}
+
+	_, err := SnakeKeys([]byte(`{"a":`))
+	assert.Error(t, err, "Expected an error for invalid JSON")
+}
+
+// TestPrefersCSV tests whether the error report of an import is sent as CSV only when the client prefers it to JSON
+func TestPrefersCSV(t *testing.T) {
+	for accept, expected := range map[string]bool{
+		"":                                 false,
+		"text/csv":                         true,
+		"text/csv, application/json":       false,
+		"text/csv, application/json;q=0.5": true,
+		"application/json, text/csv;q=0.9": false,
+		"*/*;q=0.1, text/csv":              true,
+	} {
+		assert.Equal(t, expected, prefersCSV(accept), "Unexpected preference for %q", accept)
+	}
+	_, ok := negotiate("text/csv")
+	assert.True(t, ok, "Expected the CSV resources to accept text/csv")
+}
+
+// TestEscapeCell tests whether the cells a spreadsheet would run as formulas are escaped and read back as they were
+func TestEscapeCell(t *testing.T) {
+	for value, escaped := range map[string]string{
+		"Pegasus":           "Pegasus",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1":                "'+1",
+		"-1":                "'-1",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"'=1":               "''=1",
+		"'quoted":           "'quoted",
+		"":                  "",
+		"a=b":               "a=b",
+		"\t=1+1":            "'\t=1+1",
+		"\r@SUM(A1)":        "'\r@SUM(A1)",
+	} {
+		assert.Equal(t, escaped, escapeCell(value), "Unexpected escape of %q", value)
+		assert.Equal(t, value, unescapeCell(escaped), "Expected %q back", value)
+	}
+}
+
+// TestIdempotencyKeys tests whether Idempotent func serves a request once for every key and body,
+// replays its response until the key expires and does not keep the errors of the server
+func TestIdempotencyKeys(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	keys := NewIdempotencyKeys(time.Hour)
+	keys.now = func() time.Time { return now }
+	served := 0
+	handler := keys.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		body, _ := ReadBody(r)
+		if string(body) == "fail" {
+			WriteError(w, r, errors.New("database is locked"))
+			return
+		}
+		WriteJSON(w, http.StatusCreated, map[string]interface{}{"served": served, "body": string(body)})
+	}))
+	send := func(key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/products/new", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := send("k1", "shoe")
+	assert.Equal(t, 201, first.Code)
+	replayed := send("k1", "shoe")
+	assert.Equal(t, 201, replayed.Code, "Expected the stored status")
+	assert.Equal(t, first.Body.String(), replayed.Body.String(), "Expected the stored body")
+	assert.Equal(t, "application/json", replayed.Header().Get("Content-Type"))
+	assert.Equal(t, "true", replayed.Header().Get(ReplayedHeader))
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+	assert.Equal(t, 1, served, "Expected the request to be served once")
+
+	reused := send("k1", "boot")
+	assert.Equal(t, 422, reused.Code, "Unprocessable Entity response is expected for another body")
+	assert.Contains(t, reused.Body.String(), TypeKeyReused)
+	assert.Equal(t, 201, send("k2", "boot").Code, "Expected another key to be served")
+	assert.Equal(t, 201, send("", "shoe").Code, "Expected a request without a key to be served")
+	assert.Equal(t, 201, send("", "shoe").Code, "Expected a request without a key to be served")
+	assert.Equal(t, 4, served)
+
+	//a server error is not stored
+	assert.Equal(t, 500, send("k3", "fail").Code)
+	assert.Equal(t, 500, send("k3", "fail").Code)
+	assert.Equal(t, 6, served, "Expected the request failed by the server to be served again")
+
+	assert.Equal(t, 400, send(strings.Repeat("k", 256), "shoe").Code, "Bad Request response is expected for a long key")
+
+	//the key can be used again once it expires
+	now = now.Add(time.Hour)
+	assert.Equal(t, 201, send("k1", "boot").Code, "Expected the expired key to be served with another body")
+	assert.Equal(t, 7, served)
+	assert.Len(t, keys.requests, 1, "Expected the expired keys to be forgotten")
+}
+
+// TestIdempotencyKeysInProgress tests whether a request with the key of a request still being served is rejected
+func TestIdempotencyKeysInProgress(t *testing.T) {
+	keys := NewIdempotencyKeys(time.Hour)
+	entered, release := make(chan bool), make(chan bool)
+	handler := keys.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- true
+		<-release
+		WriteJSON(w, http.StatusCreated, "created")
+	}))
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/categories/new", strings.NewReader("{}"))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send() }()
+	<-entered
+	assert.Equal(t, 409, send().Code, "Conflict response is expected while the first request is served")
+	close(release)
+	assert.Equal(t, 201, (<-done).Code)
+	assert.Equal(t, 201, send().Code, "Expected the stored response once the first request is served")
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"sort"
+	"strings"
+)
+
+// AttributeErrors returns the errors of the product attributes which are missing, unknown
+// or of another type than in the schema of the category of the product
+func AttributeErrors(attributes map[string]interface{}, schema []store.Attribute) []FieldError {
+	var fieldErrors []FieldError
+	known := make(map[string]bool, len(schema))
+	for _, a := range schema {
+		known[a.Name] = true
+		field := "Attributes." + a.Name
+		value, ok := attributes[a.Name]
+		switch {
+		case !ok:
+			if a.Required {
+				fieldErrors = append(fieldErrors, FieldError{Field: field, Detail: "Kindly enter the " + a.Name})
+			}
+		case !a.Accepts(value):
+			fieldErrors = append(fieldErrors, FieldError{Field: field, Detail: "The " + a.Name + " must be " + expectedValue(a)})
+		}
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	return append(fieldErrors, unknownAttributeErrors(names, known)...)
+}
+
+// CheckProducts returns a 409 Problem listing the products which, or whose variants, have attributes
+// not matching the attribute schema of the category of the product. It is called in a store transaction after
+// the products or the schemas are written, so nothing is stored if some products do not match.
+func CheckProducts(s store.CatalogStore, products []store.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ProductID)
+	}
+	variants, err := s.VariantsOf(ids)
+	if err != nil {
+		return err
+	}
+
+	schemas := make(map[string][]store.Attribute)
+	mismatched := make([]string, 0)
+	for _, p := range products {
+		schema, ok := schemas[p.CategoryID]
+		if !ok {
+			if schema, err = store.CategorySchema(s, p.CategoryID); err != nil {
+				return err
+			}
+			schemas[p.CategoryID] = schema
+		}
+		matches := len(AttributeErrors(p.Attributes, schema)) == 0
+		for _, v := range variants[p.ProductID] {
+			matches = matches && len(VariantAttributeErrors(v.Attributes, schema)) == 0
+		}
+		if !matches {
+			mismatched = append(mismatched, p.ProductID)
+		}
+	}
+	if len(mismatched) == 0 {
+		return nil
+	}
+	p := Conflict("%d products do not match the attribute schema of their category, kindly change them first", len(mismatched))
+	p.Extensions = map[string]interface{}{"mismatched_products": mismatched}
+	return p
+}
+
+// VariantAttributeErrors returns the errors of the variant attributes which are unknown or are not values
+// of the attributes in the schema of the category of the product. The variants tell apart only by some
+// of the attributes, so none of them is required.
+func VariantAttributeErrors(attributes map[string]string, schema []store.Attribute) []FieldError {
+	var fieldErrors []FieldError
+	known := make(map[string]bool, len(schema))
+	for _, a := range schema {
+		known[a.Name] = true
+		if value, ok := attributes[a.Name]; ok && !a.AcceptsText(value) {
+			fieldErrors = append(fieldErrors, FieldError{Field: "Attributes." + a.Name, Detail: "The " + a.Name + " must be " + expectedValue(a)})
+		}
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	return append(fieldErrors, unknownAttributeErrors(names, known)...)
+}
+
+// unknownAttributeErrors returns the errors of the attribute names which are not known by the schema
+func unknownAttributeErrors(names []string, known map[string]bool) []FieldError {
+	//the names are sorted, so the errors are reported in the same order every time
+	sort.Strings(names)
+	var fieldErrors []FieldError
+	for _, name := range names {
+		if !known[name] {
+			fieldErrors = append(fieldErrors, FieldError{Field: "Attributes." + name, Detail: "The category of the product has no attribute " + name})
+		}
+	}
+	return fieldErrors
+}
+
+// expectedValue describes the values the attribute accepts
+func expectedValue(a store.Attribute) string {
+	switch a.Type {
+	case store.AttributeNumber:
+		if a.Unit != "" {
+			return "a number of " + a.Unit
+		}
+		return "a number"
+	case store.AttributeEnum:
+		return "one of " + strings.Join(a.Values, ", ")
+	case store.AttributeBool:
+		return "true or false"
+	}
+	return "a text"
+}
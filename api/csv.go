@@ -0,0 +1,320 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CSVContentType is the media type of the CSV import and export
+const CSVContentType = "text/csv"
+
+// columnMapParamPrefix starts the query parameters naming the header of a column in the imported file,
+// e.g. map.product_name=Title
+const columnMapParamPrefix = "map."
+
+// errorsColumn is the column of the error report with the errors of the row
+const errorsColumn = "errors"
+
+// formulaEscape is put before the cells a spreadsheet would run as a formula
+const formulaEscape = "'"
+
+// formulaStarts are the characters a spreadsheet starts a formula with, the tab and the carriage return included
+const formulaStarts = "=+-@\t\r"
+
+// escapeCell returns the value with formulaEscape in front if a spreadsheet would run it as a formula,
+// e.g. '=HYPERLINK(...). A value which already looks escaped gets another formulaEscape, so unescapeCell
+// gives back every value as it was.
+func escapeCell(value string) string {
+	if looksLikeFormula(value) {
+		return formulaEscape + value
+	}
+	return value
+}
+
+// unescapeCell takes back the formulaEscape escapeCell has put in front of the value
+func unescapeCell(value string) string {
+	if strings.HasPrefix(value, formulaEscape) && looksLikeFormula(value) {
+		return strings.TrimPrefix(value, formulaEscape)
+	}
+	return value
+}
+
+// looksLikeFormula reports whether the value starts with a formula character after any formulaEscape
+func looksLikeFormula(value string) bool {
+	value = strings.TrimLeft(value, formulaEscape)
+	return value != "" && strings.ContainsAny(value[:1], formulaStarts)
+}
+
+// WriteCSV writes the rows under the header as the CSV file to download under the given name.
+// The cells starting with =, +, -, @, a tab or a carriage return are escaped with a leading ', so a spreadsheet
+// opening the file shows them as text instead of running them, and ReadCSV takes the ' back.
+func WriteCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", CSVContentType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+	writer := csv.NewWriter(w)
+	writer.Write(escapeRow(header))
+	for _, row := range rows {
+		writer.Write(escapeRow(row))
+	}
+	writer.Flush()
+}
+
+// escapeRow returns the cells of the row escaped by escapeCell
+func escapeRow(row []string) []string {
+	escaped := make([]string, len(row))
+	for i, value := range row {
+		escaped[i] = escapeCell(value)
+	}
+	return escaped
+}
+
+// CSVImport reads the rows of a CSV file uploaded to an import resource.
+// The first row is the header naming the columns, the columns are known by their export names
+// or by the headers given in the map.{column} query parameters.
+type CSVImport struct {
+	// DryRun is set by dry_run=true, the rows are only checked
+	DryRun bool
+	// Header is the first row of the file
+	Header []string
+
+	reader *csv.Reader
+	// columns are the export names of the columns of the file, by their position
+	columns []string
+	// positions are the positions of the columns by their export names
+	positions map[string]int
+}
+
+// ReadCSV starts reading the CSV file in the request body. The known function tells whether a column
+// is imported, e.g. product_name or attr.size. It returns a 400 Problem if the file has no header,
+// a column is unknown or given twice, or the query parameters are invalid.
+// The cells escaped by WriteCSV are read without the escape, so an exported file is imported with the same values.
+func ReadCSV(r *http.Request, known func(column string) bool) (*CSVImport, error) {
+	params := r.URL.Query()
+	in := &CSVImport{reader: csv.NewReader(r.Body), positions: make(map[string]int)}
+	if dryRun := params.Get("dry_run"); dryRun != "" {
+		var err error
+		if in.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			return nil, BadRequest("The dry_run parameter must be true or false")
+		}
+	}
+
+	//map.product_name=Title reads the Title column as product_name
+	mapped := make(map[string]string)
+	for param, values := range params {
+		if !strings.HasPrefix(param, columnMapParamPrefix) {
+			continue
+		}
+		column := strings.TrimPrefix(param, columnMapParamPrefix)
+		if !known(column) {
+			return nil, BadRequest("The column %s of the %s parameter is unknown", column, param)
+		}
+		mapped[values[0]] = column
+	}
+
+	//the rows can have another number of fields than the header, they are reported one by one
+	in.reader.FieldsPerRecord = -1
+	header, err := in.reader.Read()
+	if err == io.EOF {
+		return nil, BadRequest("The CSV file is empty, its first row must name the columns")
+	} else if err != nil {
+		return nil, BadRequest("The CSV file can not be read: %v", err)
+	}
+	in.Header = unescapeRow(header)
+	for i, name := range in.Header {
+		//a file saved by a spreadsheet can start with the byte order mark
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF"))
+		column, ok := mapped[name]
+		if !ok {
+			column = strings.ToLower(name)
+		}
+		if !known(column) {
+			return nil, BadRequest("The column %q is unknown, name it after an exported column or map it with map.{column}=%s", name, name)
+		}
+		if _, ok := in.positions[column]; ok {
+			return nil, BadRequest("The column %s is given more than once", column)
+		}
+		in.columns = append(in.columns, column)
+		in.positions[column] = i
+	}
+	return in, nil
+}
+
+// unescapeRow returns the cells of the row with the escapes of escapeCell taken back
+func unescapeRow(row []string) []string {
+	for i, value := range row {
+		row[i] = unescapeCell(value)
+	}
+	return row
+}
+
+// Columns returns the export names of the columns of the file in their order
+func (in *CSVImport) Columns() []string {
+	return in.columns
+}
+
+// Next returns the next row and the line it starts on, or io.EOF after the last row.
+// A row which can not be parsed is reported as a 400 Problem.
+func (in *CSVImport) Next() (CSVRow, error) {
+	record, err := in.reader.Read()
+	if err == io.EOF {
+		return CSVRow{}, err
+	} else if err != nil {
+		return CSVRow{}, BadRequest("The CSV file can not be read: %v", err)
+	}
+	line, _ := in.reader.FieldPos(0)
+	raw := append([]string(nil), record...)
+	return CSVRow{Line: line, Record: unescapeRow(record), raw: raw, in: in}, nil
+}
+
+// CSVRow is one row of an imported file
+type CSVRow struct {
+	// Line is the line of the file the row starts on
+	Line int
+	// Record are the values of the row, without the escapes of WriteCSV
+	Record []string
+
+	// raw are the values as they are in the file, with the escapes
+	raw []string
+	in  *CSVImport
+}
+
+// Value returns the trimmed value of the column of the row and false if the file has no such column
+func (row CSVRow) Value(column string) (string, bool) {
+	i, ok := row.in.positions[column]
+	if !ok {
+		return "", false
+	}
+	if i >= len(row.raw) {
+		return "", true
+	}
+	//the value is trimmed before the escape is taken back, so an escaped value keeps its leading tab
+	return unescapeCell(strings.TrimSpace(row.raw[i])), true
+}
+
+// header returns the header of the column in the file, or the column itself if the file does not have it
+func (in *CSVImport) header(column string) string {
+	if i, ok := in.positions[column]; ok {
+		return in.Header[i]
+	}
+	return column
+}
+
+// ImportResult is the summary of an import
+type ImportResult struct {
+	DryRun bool `json:"DryRun"`
+	// Rows is the number of rows in the file without the header
+	Rows    int `json:"Rows"`
+	Created int `json:"Created"`
+	Updated int `json:"Updated"`
+	Failed  int `json:"Failed"`
+	// Errors are the errors of the failed rows
+	Errors []RowError `json:"Errors"`
+
+	in *CSVImport
+	// failed are the failed rows for the error report
+	failed []CSVRow
+}
+
+// RowError is why a row of the imported file can not be imported
+type RowError struct {
+	// Row is the line of the file the row starts on
+	Row int `json:"Row"`
+	// ID is the id of the resource of the row, if it has one
+	ID string `json:"ID,omitempty"`
+	// Column is the header of the invalid column in the file, empty if the whole row is invalid
+	Column string `json:"Column,omitempty"`
+	Detail string `json:"Detail"`
+}
+
+// NewImportResult returns the empty summary of the import
+func NewImportResult(in *CSVImport) *ImportResult {
+	return &ImportResult{DryRun: in.DryRun, Errors: []RowError{}, in: in}
+}
+
+// Fail adds the failed row to the summary with the Problem of its import. The field errors of a validation Problem
+// are reported under the columns returned by column, e.g. product_name for ProductName.
+func (result *ImportResult) Fail(row CSVRow, id string, p *Problem, column func(field string) string) {
+	result.Failed++
+	result.failed = append(result.failed, row)
+	if len(p.Errors) == 0 {
+		result.Errors = append(result.Errors, RowError{Row: row.Line, ID: id, Detail: p.Detail})
+		return
+	}
+	for _, fieldError := range p.Errors {
+		result.Errors = append(result.Errors,
+			RowError{Row: row.Line, ID: id, Column: result.in.header(column(fieldError.Field)), Detail: fieldError.Detail})
+	}
+}
+
+// WriteImportResult writes the summary of the import as JSON, or, if the request accepts CSV rather than JSON,
+// the failed rows as they are in the file with their errors in the last column, to be fixed and imported again
+func WriteImportResult(w http.ResponseWriter, r *http.Request, result *ImportResult, filename string) {
+	if !prefersCSV(r.Header.Get("Accept")) {
+		WriteJSON(w, http.StatusOK, result)
+		return
+	}
+
+	//the errors of every row
+	errorsOf := make(map[int][]string)
+	for _, e := range result.Errors {
+		detail := e.Detail
+		if e.Column != "" {
+			detail = e.Column + ": " + detail
+		}
+		errorsOf[e.Row] = append(errorsOf[e.Row], detail)
+	}
+	rows := make([][]string, 0, len(result.failed))
+	for _, row := range result.failed {
+		record := append([]string{}, row.Record...)
+		for len(record) < len(result.in.Header) {
+			record = append(record, "")
+		}
+		rows = append(rows, append(record[:len(result.in.Header)], strings.Join(errorsOf[row.Line], "; ")))
+	}
+	WriteCSV(w, filename, append(append([]string{}, result.in.Header...), errorsColumn), rows)
+}
+
+// prefersCSV reports whether the Accept header asks for CSV with a higher quality than JSON
+func prefersCSV(accept string) bool {
+	quality := map[string]float64{}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if param, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(param, 64); err != nil {
+				continue
+			}
+		}
+		quality[mediaType] = q
+	}
+	jsonQuality := 0.0
+	for _, mediaType := range []string{"application/json", V1MediaType, "*/*"} {
+		if quality[mediaType] > jsonQuality {
+			jsonQuality = quality[mediaType]
+		}
+	}
+	return quality[CSVContentType] > jsonQuality
+}
+
+// CategoryByName returns the id of the category named in an imported row, or the error of the field
+// if no category or more than one category has the name
+func CategoryByName(names store.CategoryNames, name string, field string) (string, *FieldError) {
+	ids := names.IDs(name)
+	switch len(ids) {
+	case 0:
+		return "", &FieldError{Field: field, Detail: fmt.Sprintf("Category named %q not found", name)}
+	case 1:
+		return ids[0], nil
+	}
+	return "", &FieldError{Field: field, Detail: fmt.Sprintf("%d categories are named %q, kindly give the category ID", len(ids), name)}
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header with the key a client gives a create request, so the request
+// can be sent again, e.g. after a timeout, without creating the resource twice
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ReplayedHeader marks the response of a request with an Idempotency-Key which is the stored one
+const ReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength is the length of the longest Idempotency-Key, e.g. a UUID is 36 characters long
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyKeys keeps the responses of the requests with an Idempotency-Key until the keys expire.
+// The keys are kept in memory, so they are forgotten on restart. It is safe for concurrent use.
+type IdempotencyKeys struct {
+	mu  sync.Mutex
+	ttl time.Duration
+	// now returns the current time, it is changed by the tests
+	now func() time.Time
+	//the request of every key, by the method and the link of the request and the key
+	requests map[string]*keyedRequest
+	//the requests in the order they were received, which is the order they expire in
+	order []*keyedRequest
+}
+
+// keyedRequest is a request with an Idempotency-Key and, once it is served, its response
+type keyedRequest struct {
+	scope       string
+	fingerprint [sha256.Size]byte
+	expiresAt   time.Time
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+// NewIdempotencyKeys returns IdempotencyKeys which replay the responses for the given time after the request
+func NewIdempotencyKeys(ttl time.Duration) *IdempotencyKeys {
+	return &IdempotencyKeys{ttl: ttl, now: time.Now, requests: make(map[string]*keyedRequest)}
+}
+
+// Idempotent is the middleware of the create routes which serves a request with an Idempotency-Key once.
+// The response is stored and sent again to every request with the same key, method, link and body until the key
+// expires, with the Idempotent-Replayed header. A key sent with another body is reported as 422 and a key
+// whose request is still being served as 409. A response with a 5xx status is not stored, so the request can be
+// sent again. A request without the header is served as it is.
+func (k *IdempotencyKeys) Idempotent(next http.Handler) http.Handler {
+	return &idempotentHandler{keys: k, next: next}
+}
+
+// idempotentHandler is the handler of a create route served through IdempotencyKeys
+type idempotentHandler struct {
+	keys *IdempotencyKeys
+	next http.Handler
+}
+
+// Unwrap returns the handler of the route, e.g. for the OpenAPI document to find its operation
+func (h *idempotentHandler) Unwrap() http.Handler {
+	return h.next
+}
+
+// ServeHTTP serves the request once for every key
+func (h *idempotentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		WriteError(w, r, BadRequest("The %s can be at most %d characters long", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+		return
+	}
+
+	//the body is compared with the one of the first request with the key
+	//or an error is reported
+	body, err := ReadBody(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	//the key of another route is another key
+	request, first, err := h.keys.begin(r.Method+" "+r.URL.Path+" "+key, sha256.Sum256(body))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if !first {
+		request.replay(w)
+		return
+	}
+
+	response := &keyedResponse{ResponseWriter: w, status: http.StatusOK}
+	served := false
+	defer func() {
+		//a handler which panics leaves the key free, like an error of the server
+		if !served {
+			response.status = http.StatusInternalServerError
+		}
+		h.keys.end(request, response)
+	}()
+	h.next.ServeHTTP(response, r)
+	served = true
+}
+
+// begin returns the stored request with the key in the scope, or stores a new one and returns true,
+// or returns the Problem of a key sent with another body or of a request which is still being served
+func (k *IdempotencyKeys) begin(scope string, fingerprint [sha256.Size]byte) (*keyedRequest, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := k.now()
+	k.expire(now)
+
+	if request, ok := k.requests[scope]; ok {
+		switch {
+		case request.fingerprint != fingerprint:
+			return nil, false, KeyReused("The %s has already been sent with another request body", IdempotencyKeyHeader)
+		case !request.done:
+			return nil, false, Conflict("The request with the %s is still being served, kindly send it again later", IdempotencyKeyHeader)
+		}
+		return request, false, nil
+	}
+	request := &keyedRequest{scope: scope, fingerprint: fingerprint, expiresAt: now.Add(k.ttl)}
+	k.requests[scope] = request
+	k.order = append(k.order, request)
+	return request, true, nil
+}
+
+// end stores the response of the request, or forgets the request if the response is an error of the server
+func (k *IdempotencyKeys) end(request *keyedRequest, response *keyedResponse) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if response.status >= http.StatusInternalServerError {
+		delete(k.requests, request.scope)
+		return
+	}
+	request.done = true
+	request.status = response.status
+	request.header = response.Header().Clone()
+	request.body = response.body.Bytes()
+}
+
+// expire forgets the requests which have expired at the given time while the caller holds the lock
+func (k *IdempotencyKeys) expire(now time.Time) {
+	expired := 0
+	for expired < len(k.order) && !k.order[expired].expiresAt.After(now) {
+		request := k.order[expired]
+		//a request forgotten after a server error may have been received again since
+		if k.requests[request.scope] == request {
+			delete(k.requests, request.scope)
+		}
+		expired++
+	}
+	k.order = k.order[expired:]
+}
+
+// replay writes the stored response of the request
+func (request *keyedRequest) replay(w http.ResponseWriter) {
+	for name, values := range request.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(request.status)
+	w.Write(request.body)
+}
+
+// keyedResponse writes the response of a request with an Idempotency-Key and keeps its status and body
+type keyedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// WriteHeader keeps the status and writes it
+func (response *keyedResponse) WriteHeader(status int) {
+	if !response.wroteHeader {
+		response.status = status
+		response.wroteHeader = true
+	}
+	response.ResponseWriter.WriteHeader(status)
+}
+
+// Write keeps the body and writes it
+func (response *keyedResponse) Write(data []byte) (int, error) {
+	response.wroteHeader = true
+	response.body.Write(data)
+	return response.ResponseWriter.Write(data)
+}
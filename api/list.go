@@ -0,0 +1,132 @@
+package api
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MaxLimit is the biggest page size a client can ask for
+const MaxLimit = 1000
+
+// attributeParamPrefix starts the names of the query parameters filtering the products by an attribute
+const attributeParamPrefix = "attr."
+
+// ParseListQuery reads the list parameters of the request:
+// limit, cursor, sort (one of sorts, prefixed with "-" for the descending order), name_contains,
+// and currency, price_min, price_max and the attr.{name} attribute filters if the list can be sorted by price.
+// Sorting by price needs the currency filter. Invalid parameters are reported as a 400 Problem.
+func ParseListQuery(r *http.Request, sorts ...string) (store.ListQuery, error) {
+	params := r.URL.Query()
+	q := store.ListQuery{Sort: store.SortCreated, NameContains: params.Get("name_contains")}
+
+	//sort=price or sort=-price
+	if sortParam := params.Get("sort"); sortParam != "" {
+		q.Desc = strings.HasPrefix(sortParam, "-")
+		q.Sort = strings.TrimPrefix(sortParam, "-")
+		if !contains(sorts, q.Sort) {
+			return store.ListQuery{}, BadRequest("The sort must be one of %s, prefixed with - for the descending order", strings.Join(sorts, ", "))
+		}
+	}
+
+	limit, err := ParseLimit(r, 0)
+	if err != nil {
+		return store.ListQuery{}, err
+	}
+	q.Limit = limit
+
+	if cursorParam := params.Get("cursor"); cursorParam != "" {
+		cursor, err := store.ParseCursor(cursorParam, q)
+		if err != nil {
+			return store.ListQuery{}, BadRequest("The cursor is invalid or was issued for another sort order")
+		}
+		q.After = cursor
+	}
+
+	//the prices are compared in the minor units of the currency
+	if currency := params.Get("currency"); currency != "" {
+		if !contains(sorts, store.SortPrice) {
+			return store.ListQuery{}, BadRequest("The currency filter is not supported by this list")
+		}
+		if _, ok := money.Exponent(currency); !ok {
+			return store.ListQuery{}, BadRequest("The currency filter must be an ISO 4217 currency code")
+		}
+		q.Currency = currency
+	}
+	//the amounts are in the minor units of different currencies, so they are only sorted within one
+	if q.Sort == store.SortPrice && q.Currency == "" {
+		return store.ListQuery{}, BadRequest("Kindly give the currency filter to sort by price, the prices in different currencies can not be compared")
+	}
+	for name, bound := range map[string]**int64{"price_min": &q.PriceMin, "price_max": &q.PriceMax} {
+		param := params.Get(name)
+		if param == "" {
+			continue
+		}
+		if !contains(sorts, store.SortPrice) {
+			return store.ListQuery{}, BadRequest("The %s filter is not supported by this list", name)
+		}
+		price, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return store.ListQuery{}, BadRequest("The %s filter must be a number of minor currency units", name)
+		}
+		*bound = &price
+	}
+
+	//attr.size=38 keeps the products with the size 38
+	for param, values := range params {
+		if !strings.HasPrefix(param, attributeParamPrefix) {
+			continue
+		}
+		if !contains(sorts, store.SortPrice) {
+			return store.ListQuery{}, BadRequest("The attribute filters are not supported by this list")
+		}
+		if q.Attributes == nil {
+			q.Attributes = make(map[string]string)
+		}
+		q.Attributes[strings.TrimPrefix(param, attributeParamPrefix)] = values[0]
+	}
+	return q, nil
+}
+
+// ParseLimit reads the limit parameter of the request, from 1 to MaxLimit, or returns defaultLimit if it is not given.
+// An invalid limit is reported as a 400 Problem.
+func ParseLimit(r *http.Request, defaultLimit int) (int, error) {
+	limitParam := r.URL.Query().Get("limit")
+	if limitParam == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit < 1 || limit > MaxLimit {
+		return 0, BadRequest("The limit must be a number from 1 to %d", MaxLimit)
+	}
+	return limit, nil
+}
+
+// WritePage writes one page of a list as the JSON array of its items.
+// The number of items on all the pages is sent in the X-Total-Count header
+// and the link to the next page in the Link header.
+func WritePage(w http.ResponseWriter, r *http.Request, items interface{}, total int, next *store.Cursor) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if next != nil {
+		//the same link with the cursor of the next page
+		nextURL := *r.URL
+		params := nextURL.Query()
+		params.Set("cursor", next.String())
+		nextURL.RawQuery = params.Encode()
+		//added, as the deprecated routes also link to their successor
+		w.Header().Add("Link", "<"+nextURL.RequestURI()+`>; rel="next"`)
+	}
+	WriteJSON(w, http.StatusOK, items)
+}
+
+// contains reports whether the value is in the list
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+//package api contains the helpers shared by the category and product handlers
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// MergePatchContentType is the media type of RFC 7386 JSON Merge Patch documents
+const MergePatchContentType = "application/merge-patch+json"
+
+// ErrPatchNotObject is returned when a merge patch for a resource is not a JSON object
+var ErrPatchNotObject = errors.New("merge patch must be a JSON object")
+
+// MergePatch applies the RFC 7386 JSON Merge Patch to the target document and returns the patched document.
+// Members of the patch replace the members of the target, null members remove them,
+// and objects are merged recursively.
+func MergePatch(target []byte, patch []byte) ([]byte, error) {
+	var targetValue, patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+	if len(target) != 0 {
+		if err := json.Unmarshal(target, &targetValue); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(mergeValue(targetValue, patchValue))
+}
+
+// mergeValue is the MergePatch(Target, Patch) function from RFC 7386 section 2
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		//anything but an object replaces the whole target
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergeValue(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// PatchResource applies the merge patch to the JSON form of the resource v and decodes the result back into v,
+// which has to be a pointer.
+// The patch of a resource has to be a JSON object, otherwise ErrPatchNotObject is returned.
+func PatchResource(v interface{}, patch []byte) error {
+	var patchObject map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &patchObject); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return err
+		}
+		return ErrPatchNotObject
+	}
+	if patchObject == nil {
+		return ErrPatchNotObject
+	}
+
+	target, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	patched, err := MergePatch(target, patch)
+	if err != nil {
+		return err
+	}
+	//members removed by the patch should end up as zero values instead of keeping the old ones
+	resource := reflect.ValueOf(v).Elem()
+	resource.Set(reflect.Zero(resource.Type()))
+	return json.Unmarshal(patched, v)
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+)
+
+// NDJSONContentType is the media type of newline-delimited JSON, one JSON document on every line
+const NDJSONContentType = "application/x-ndjson"
+
+// IsNDJSON reports whether the request body is newline-delimited JSON, which is read as a stream
+func IsNDJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == NDJSONContentType
+}
+
+// NDJSONWriter writes the response as newline-delimited JSON, every line is sent to the client as soon as it is written
+type NDJSONWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+// NewNDJSONWriter starts the newline-delimited JSON response with the given status.
+// The request body can still be read while the lines are written, so a stream can be answered line by line.
+func NewNDJSONWriter(w http.ResponseWriter, status int) *NDJSONWriter {
+	controller := http.NewResponseController(w)
+	//an HTTP/1 server reads the rest of the request body before the response unless it is told otherwise,
+	//HTTP/2 is always full duplex
+	controller.EnableFullDuplex()
+	w.Header().Set("Content-Type", NDJSONContentType)
+	w.WriteHeader(status)
+	return &NDJSONWriter{w: w, controller: controller}
+}
+
+// Write writes v as the next line. The status has already been sent, so a value which can not be encoded
+// is only logged and the error returned, and the error of a client which has gone away is returned as it is.
+func (nw *NDJSONWriter) Write(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	if _, err = nw.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	//a writer which can not be flushed, e.g. a recorder, gets the lines all the same
+	if err = nw.controller.Flush(); err != nil && err != http.ErrNotSupported {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// problem types, relative URIs which identify the kind of the error for the API clients
+const (
+	TypeBadRequest    = "/problems/bad-request"
+	TypeNotFound      = "/problems/not-found"
+	TypeNotAcceptable = "/problems/not-acceptable"
+	TypeConflict      = "/problems/conflict"
+	TypeValidation    = "/problems/validation"
+	TypeKeyReused     = "/problems/idempotency-key-reused"
+	TypeInternal      = "/problems/internal"
+)
+
+// FieldError describes why one field of the request body is invalid
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// Problem is the RFC 7807 problem details object every handler reports its errors with
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// Extensions are the additional members sent next to the standard ones, e.g. the number of blocking products
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON writes the Extensions as members of the problem details object
+func (p Problem) MarshalJSON() ([]byte, error) {
+	//standard has the same fields without this method
+	type standard Problem
+	body, err := json.Marshal(standard(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return body, err
+	}
+	extensions, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	//join {"type":...} and {"products":...} into one object
+	return append(append(body[:len(body)-1], ','), extensions[1:]...), nil
+}
+
+// Error makes a Problem usable as an error
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// newProblem returns a Problem with the standard title of the status
+func newProblem(problemType string, status int, detail string) *Problem {
+	return &Problem{
+		Type:   problemType,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// BadRequest returns a 400 Problem for a request body which can not be read or parsed
+func BadRequest(format string, args ...interface{}) *Problem {
+	return newProblem(TypeBadRequest, http.StatusBadRequest, fmt.Sprintf(format, args...))
+}
+
+// NotFound returns a 404 Problem for an unknown id
+func NotFound(format string, args ...interface{}) *Problem {
+	return newProblem(TypeNotFound, http.StatusNotFound, fmt.Sprintf(format, args...))
+}
+
+// NotAcceptable returns a 406 Problem for a request which accepts none of the media types of the response
+func NotAcceptable(format string, args ...interface{}) *Problem {
+	return newProblem(TypeNotAcceptable, http.StatusNotAcceptable, fmt.Sprintf(format, args...))
+}
+
+// Conflict returns a 409 Problem for a request which conflicts with the current state of the catalog
+func Conflict(format string, args ...interface{}) *Problem {
+	return newProblem(TypeConflict, http.StatusConflict, fmt.Sprintf(format, args...))
+}
+
+// Validation returns a 422 Problem listing the invalid fields of the request body
+func Validation(errors ...FieldError) *Problem {
+	p := newProblem(TypeValidation, http.StatusUnprocessableEntity, "The request body contains invalid fields")
+	p.Errors = errors
+	return p
+}
+
+// KeyReused returns a 422 Problem for an Idempotency-Key sent again with another request
+func KeyReused(format string, args ...interface{}) *Problem {
+	return newProblem(TypeKeyReused, http.StatusUnprocessableEntity, fmt.Sprintf(format, args...))
+}
+
+// PriceErrors returns the errors of the price field with the given name
+// if it has no ISO 4217 currency or a negative amount
+func PriceErrors(field string, price money.Money) []FieldError {
+	switch price.Validate() {
+	case money.ErrUnknownCurrency:
+		return []FieldError{{Field: field + ".Currency", Detail: "Kindly enter an ISO 4217 currency code, e.g. EUR"}}
+	case money.ErrNegativeAmount:
+		return []FieldError{{Field: field + ".Amount", Detail: "The price can not be negative"}}
+	}
+	return nil
+}
+
+// Internal returns a 500 Problem, the details of the error are only logged and never sent to the client
+func Internal() *Problem {
+	return newProblem(TypeInternal, http.StatusInternalServerError, "")
+}
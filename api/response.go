@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// AuthorHeader is the request header naming who makes the change, it is recorded e.g. in the price history
+const AuthorHeader = "X-Author"
+
+// anonymousAuthor is the author of the requests without AuthorHeader
+const anonymousAuthor = "anonymous"
+
+// Author returns who makes the request as given in AuthorHeader, or "anonymous"
+func Author(r *http.Request) string {
+	if author := strings.TrimSpace(r.Header.Get(AuthorHeader)); author != "" {
+		return author
+	}
+	return anonymousAuthor
+}
+
+// WriteJSON writes v as the JSON response body with the given status.
+// The body is encoded before anything is written, so an encoding error can still be reported as 500.
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Print(err)
+		writeProblem(w, Internal())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// WriteError reports the error to the client as problem details.
+// A *Problem is written as it is, any other error is logged and reported as 500.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var p *Problem
+	if !errors.As(err, &p) {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		p = Internal()
+	}
+	//the link of the request identifies this occurrence of the problem
+	withInstance := *p
+	if withInstance.Instance == "" {
+		withInstance.Instance = r.URL.Path
+	}
+	writeProblem(w, &withInstance)
+}
+
+// writeProblem writes the problem details with their status
+func writeProblem(w http.ResponseWriter, p *Problem) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		//a Problem contains only strings, numbers and the plain values of its extensions
+		panic(err)
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	w.Write(append(body, '\n'))
+}
+
+// ReadBody returns the request body or a 400 Problem if it can not be read
+func ReadBody(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, BadRequest("The request body can not be read: %v", err)
+	}
+	return body, nil
+}
+
+// ReadJSON decodes the JSON request body into v or returns a 400 Problem
+func ReadJSON(r *http.Request, v interface{}) error {
+	body, err := ReadBody(r)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		return BadRequest("The request body is not valid JSON: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// V1MediaType is the media type a client can ask for in the Accept header to get the /v1 representation
+const V1MediaType = "application/vnd.catalog.v1+json"
+
+// initialisms are the words written in capitals in the Go field names, e.g. ProductID or TTLSeconds
+var initialisms = map[string]bool{"id": true, "sku": true, "ttl": true}
+
+// mapFields are the fields whose objects map data to values, e.g. the attribute names of a product
+// or the currencies of the exchange rates, so the keys of the objects are not renamed
+var mapFields = map[string]bool{"Attributes": true, "Rates": true, "Rounding": true, "Prices": true}
+
+// SnakeCase serves the /v1 resources with the snake_case field names, e.g. product_id instead of ProductID.
+// The field names of the JSON request body are turned into the Go names the handlers read, and the field names
+// of the JSON response body and the field paths of the problem details into snake_case.
+// A request which accepts neither JSON, V1MediaType nor the CSV of the import and export gets 406.
+// The newline-delimited JSON requests are streamed to the handler and back as they are.
+func SnakeCase(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, ok := negotiate(r.Header.Get("Accept"))
+		if !ok {
+			WriteError(w, r, NotAcceptable("The resources are available as application/json or %s, and as %s for the CSV import and export", V1MediaType, CSVContentType))
+			return
+		}
+
+		//a stream can not be buffered, its lines are named the same way in both versions
+		if IsNDJSON(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		//the handlers read the Go field names
+		body, err := ReadBody(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		if renamed, err := GoKeys(body); err == nil {
+			body = renamed
+		}
+		//invalid JSON is passed on as it is and reported by the handler
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+
+		buffered := &bufferedResponse{header: w.Header()}
+		next.ServeHTTP(buffered, r)
+
+		//the JSON documents are written with a newline at the end, as WriteJSON does
+		body = buffered.body.Bytes()
+		switch contentType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type")); contentType {
+		case "application/json":
+			if renamed, err := SnakeKeys(body); err == nil {
+				body = append(renamed, '\n')
+			}
+			w.Header().Set("Content-Type", mediaType)
+		case ProblemContentType:
+			if renamed, err := renameKeys(body, func(name string) string { return name }, snakePath); err == nil {
+				body = append(renamed, '\n')
+			}
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if buffered.status == 0 {
+			buffered.status = http.StatusOK
+		}
+		w.WriteHeader(buffered.status)
+		w.Write(body)
+	})
+}
+
+// Deprecated marks the responses of the unversioned routes as deprecated since deprecatedAt (RFC 9745),
+// tells when they are going to be removed (RFC 8594) and links to the same resource under /v1
+func Deprecated(deprecatedAt time.Time, sunsetAt time.Time) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+			w.Header().Set("Sunset", sunsetAt.UTC().Format(http.TimeFormat))
+			w.Header().Add("Link", "</v1"+r.URL.Path+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// negotiate returns the media type of the /v1 JSON responses for the Accept header,
+// or false if the client accepts neither JSON, V1MediaType, CSV nor NDJSON
+func negotiate(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return "application/json", true
+	}
+	//the CSV and NDJSON resources answer with JSON too, e.g. the problem details
+	acceptsJSON := false
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch mediaType {
+		case V1MediaType:
+			return V1MediaType, true
+		case "application/json", ProblemContentType, "application/*", "*/*", CSVContentType, NDJSONContentType:
+			acceptsJSON = true
+		}
+	}
+	return "application/json", acceptsJSON
+}
+
+// SnakeKeys renames the fields of the JSON document from the Go names to snake_case, keeping their order
+func SnakeKeys(data []byte) ([]byte, error) {
+	return renameKeys(data, SnakeName, snakePath)
+}
+
+// GoKeys renames the fields of the JSON document from snake_case to the Go names, keeping their order
+func GoKeys(data []byte) ([]byte, error) {
+	return renameKeys(data, goName, goPath)
+}
+
+// SnakeName turns the Go field name into its /v1 snake_case name, e.g. TTLSeconds into ttl_seconds
+func SnakeName(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			b.WriteRune(r)
+			continue
+		}
+		//a word starts after a lowercase letter or at the last capital of an initialism followed by a lowercase letter
+		if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// goName turns the snake_case field name into the Go name, e.g. product_id into ProductID
+func goName(name string) string {
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		if initialisms[word] {
+			words[i] = strings.ToUpper(word)
+		} else if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// snakePath renames the fields of a FieldError path, e.g. Attributes[0].Name into attributes[0].name
+func snakePath(path string) string {
+	return renamePath(path, SnakeName)
+}
+
+// goPath renames the fields of a FieldError path back to the Go names
+func goPath(path string) string {
+	return renamePath(path, goName)
+}
+
+// renamePath renames the fields of the dotted path, the keys of the mapFields are kept,
+// e.g. the size of Attributes.size
+func renamePath(path string, rename func(string) string) string {
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		if i > 0 && mapFields[goName(segments[i-1])] {
+			continue
+		}
+		//the index of a list item is kept
+		name, index := segment, ""
+		if bracket := strings.IndexByte(segment, '['); bracket >= 0 {
+			name, index = segment[:bracket], segment[bracket:]
+		}
+		segments[i] = rename(name) + index
+	}
+	return strings.Join(segments, ".")
+}
+
+// renameKeys rewrites the JSON document with the keys of its objects renamed and the "field" paths
+// of the problem details renamed with renameField. The keys of the objects of the mapFields are kept.
+func renameKeys(data []byte, rename func(string) string, renameField func(string) string) ([]byte, error) {
+	//frame is an object or an array the decoder is in
+	type frame struct {
+		object    bool
+		keepKeys  bool
+		expectKey bool
+		// key is the last key of the object
+		key   string
+		count int
+	}
+	var stack []*frame
+	var out bytes.Buffer
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	//write writes the JSON value of the token
+	write := func(v interface{}) error {
+		encoded, err := json.Marshal(v)
+		out.Write(encoded)
+		return err
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF && len(stack) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		} else if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		//the end of an object or an array finishes the value of its parent
+		if token == json.Delim('}') || token == json.Delim(']') {
+			out.WriteString(token.(json.Delim).String())
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.count++
+				parent.expectKey = parent.object
+			}
+			continue
+		}
+
+		if top != nil && top.expectKey {
+			key := token.(string)
+			if top.count > 0 {
+				out.WriteByte(',')
+			}
+			top.key, top.expectKey = key, false
+			if !top.keepKeys {
+				key = rename(key)
+			}
+			if err = write(key); err != nil {
+				return nil, err
+			}
+			out.WriteByte(':')
+			continue
+		}
+		if top != nil && !top.object && top.count > 0 {
+			out.WriteByte(',')
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			out.WriteString(t.String())
+			//an object under a map field keeps the keys, the objects in its values do not
+			keepKeys := t == json.Delim('{') && top != nil && top.object && !top.keepKeys && mapFields[goName(top.key)]
+			stack = append(stack, &frame{object: t == json.Delim('{'), keepKeys: keepKeys, expectKey: t == json.Delim('{')})
+			continue
+		case string:
+			if top != nil && top.object && !top.keepKeys && top.key == "field" {
+				t = renameField(t)
+			}
+			err = write(t)
+		default:
+			err = write(t)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if top != nil {
+			top.count++
+			top.expectKey = top.object
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// bufferedResponse keeps the status and the body the handler writes, so they can be changed before they are sent
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header returns the headers of the real response
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+// WriteHeader keeps the first status written
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// Write keeps the body, with 200 as the status if none has been written
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(data)
+}
@@ -0,0 +1,129 @@
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// maxBatchSize is the number of operations a batch can have, the store is locked while they are applied
+const maxBatchSize = 1000
+
+// errRollback stops the transaction of a batch with a failed operation
+var errRollback = errors.New("batch operation failed")
+
+// BatchHandler serves the batch requests by sending their operations to the routes of the catalog,
+// all of them in one store transaction
+type BatchHandler struct {
+	store store.CatalogStore
+	// routes returns the routes of the catalog working on the store of the transaction
+	routes func(tx store.CatalogStore) http.Handler
+}
+
+// NewBatchHandler returns a BatchHandler which sends the operations to the routes working on a transaction of the store.
+// The routes are the ones the batch route is registered next to, so the operations use the same API version.
+func NewBatchHandler(catalog store.CatalogStore, routes func(tx store.CatalogStore) http.Handler) *BatchHandler {
+	return &BatchHandler{store: catalog, routes: routes}
+}
+
+// Batch applies the operations given as newline-delimited JSON, like the ones of Bulk, all or none of them.
+// The operations are applied in their order, so a later one can use the id of an item created by an earlier one
+// as "${ref}". The results of all of them are written back as newline-delimited JSON if they all succeed.
+// At the first failed operation the changes of all the earlier ones are rolled back, and the problem of the
+// failed operation is reported with its status, the line and the Result of the operation.
+func (h *BatchHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	if !api.IsNDJSON(r) {
+		api.WriteError(w, r, api.BadRequest("The operations must be sent as %s, one JSON object on every line", api.NDJSONContentType))
+		return
+	}
+
+	//read all the operations before anything is changed
+	//or report an error
+	type numberedLine struct {
+		number int
+		line   []byte
+	}
+	var operations []numberedLine
+	lines := newLineScanner(r)
+	for number := 1; lines.Scan(); number++ {
+		line := bytes.TrimSpace(lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(operations) == maxBatchSize {
+			api.WriteError(w, r, api.BadRequest("A batch can have at most %d operations", maxBatchSize))
+			return
+		}
+		operations = append(operations, numberedLine{number: number, line: append([]byte(nil), line...)})
+	}
+	switch err := lines.Err(); {
+	case err == bufio.ErrTooLong:
+		api.WriteError(w, r, api.BadRequest("A line is longer than %d bytes", maxLineSize))
+		return
+	case err != nil:
+		api.WriteError(w, r, api.BadRequest("The request body can not be read: %v", err))
+		return
+	}
+	if len(operations) == 0 {
+		api.WriteError(w, r, api.BadRequest("Kindly enter at least one operation"))
+		return
+	}
+
+	//the links of the operations are under the same prefix as the batch route, e.g. /v1
+	prefix := strings.TrimSuffix(path.Dir(r.URL.Path), "/")
+
+	//apply the operations in one transaction
+	//or report the failed one, or an error of the store
+	results := make([]Result, 0, len(operations))
+	err := h.store.Transaction(func(tx store.CatalogStore) error {
+		routes := h.routes(tx)
+		refs := make(map[string]string)
+		for _, op := range operations {
+			result := apply(routes, r, prefix, op.number, op.line, refs)
+			results = append(results, result)
+			if result.Status >= http.StatusBadRequest {
+				return errRollback
+			}
+		}
+		return nil
+	})
+	if err == errRollback {
+		api.WriteError(w, r, batchProblem(results[len(results)-1]))
+		return
+	} else if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	out := api.NewNDJSONWriter(w, http.StatusOK)
+	for _, result := range results {
+		if err = out.Write(result); err != nil {
+			return
+		}
+	}
+}
+
+// batchProblem returns the problem of the batch with the failed operation: the problem of the operation
+// with its line and its Result
+func batchProblem(failed Result) *api.Problem {
+	var p api.Problem
+	if err := json.Unmarshal(failed.Body, &p); err != nil || p.Type == "" {
+		//a response which is not problem details
+		p = api.Problem{Type: "about:blank", Title: http.StatusText(failed.Status)}
+	}
+	p.Status = failed.Status
+	p.Instance = ""
+	if p.Detail == "" {
+		p.Detail = p.Title
+	}
+	p.Detail = fmt.Sprintf("Line %d: %s. None of the operations are applied", failed.Line, strings.TrimSuffix(p.Detail, "."))
+	p.Extensions = map[string]interface{}{"line": failed.Line, "result": failed}
+	return &p
+}
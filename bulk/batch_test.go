@@ -0,0 +1,153 @@
+//package bulk contains test for batch.go
+package bulk
+
+import (
+	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/categories"
+	"github.com/KseniiaL/AdcashTestAssignment/products"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//catalogRoutes returns the /v1 category and product routes working on the store
+func catalogRoutes(catalog store.CatalogStore) http.Handler {
+	router := mux.NewRouter()
+	v1 := router.PathPrefix("/v1").Subrouter()
+	v1.Use(api.SnakeCase)
+	categoryHandler := categories.NewHandler(catalog)
+	productHandler := products.NewHandler(catalog)
+	v1.HandleFunc("/categories/new", categoryHandler.CreateCategory).Methods("POST")
+	v1.HandleFunc("/categories/{id}", categoryHandler.DeleteCategory).Methods("DELETE")
+	v1.HandleFunc("/products/new", productHandler.CreateProduct).Methods("POST")
+	v1.HandleFunc("/products/{id}", productHandler.UpdateProduct).Methods("PATCH")
+	return router
+}
+
+//batchStores returns a new MemoryStore and SQLiteStore
+func batchStores(t *testing.T) map[string]store.CatalogStore {
+	sqlite, err := store.OpenSQLite(filepath.Join(t.TempDir(), "catalog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	return map[string]store.CatalogStore{"memory": store.NewMemoryStore(), "sqlite": sqlite}
+}
+
+//postBatch sends the lines to the Batch func of a handler working on the store
+func postBatch(catalog store.CatalogStore, lines ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/v1/batch", strings.NewReader(strings.Join(lines, "\n")))
+	req.Header.Set("Content-Type", api.NDJSONContentType)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewBatchHandler(catalog, catalogRoutes).Batch).ServeHTTP(rr, req)
+	return rr
+}
+
+//TestBatch tests whether Batch func applies all the operations, giving the later ones the ids created by the earlier ones
+func TestBatch(t *testing.T) {
+	for name, catalog := range batchStores(t) {
+		rr := postBatch(catalog,
+			`{"op":"create","resource":"category","ref":"boots","body":{"category_name":"Boots"}}`,
+			`{"op":"create","resource":"product","ref":"hiker","body":{"product_name":"Hiker","price":{"amount":12000,"currency":"EUR"},"category_id":"${boots}"}}`,
+			`{"op":"update","resource":"product","id":"${hiker}","body":{"product_description":"Waterproof"}}`,
+			`{"op":"delete","resource":"category","id":"bq4fb3b7jhfi7v7uo39g"}`)
+
+		assert.Equal(t, 200, rr.Code, "%s: OK response is expected, body: %s", name, rr.Body.String())
+		results := readResults(t, rr.Body)
+		if !assert.Len(t, results, 4, "%s: expected a result for every operation", name) {
+			continue
+		}
+		for _, result := range results {
+			assert.Less(t, result.Status, 300, "%s: expected line %d to succeed, body: %s", name, result.Line, result.Body)
+		}
+		categoryID, productID := results[0].ID, results[1].ID
+		assert.Equal(t, productID, results[2].ID, "%s: expected the reference to be replaced with the id", name)
+
+		stored, err := catalog.Product(productID)
+		assert.NoError(t, err, "%s: expected the product to be stored", name)
+		assert.Equal(t, categoryID, stored.CategoryID, "%s: expected the product in the created category", name)
+		assert.Equal(t, "Waterproof", stored.ProductDescription, "%s: expected the product to be updated", name)
+		_, err = catalog.Category("bq4fb3b7jhfi7v7uo39g")
+		assert.Equal(t, store.ErrNotFound, err, "%s: expected the category to be deleted", name)
+	}
+}
+
+//TestBatchRollback tests whether Batch func reports the first failed operation and stores none of them
+func TestBatchRollback(t *testing.T) {
+	for name, catalog := range batchStores(t) {
+		categoriesBefore, _ := catalog.Categories()
+		productsBefore, _ := catalog.Products()
+		for _, c := range []struct {
+			lines  []string
+			status int
+			line   int
+			detail string
+		}{
+			{
+				lines: []string{
+					`{"op":"create","resource":"category","ref":"boots","body":{"category_name":"Boots"}}`,
+					`{"op":"delete","resource":"category","id":"bq4fb3b7jhfi7v7uo39g"}`,
+					`{"op":"create","resource":"product","body":{"product_name":"Hiker","price":{"amount":-1,"currency":"EUR"},"category_id":"${boots}"}}`,
+				},
+				status: 422,
+				line:   3,
+				detail: "Line 3: The request body contains invalid fields. None of the operations are applied",
+			},
+			{
+				lines: []string{
+					`{"op":"create","resource":"category","ref":"boots","body":{"category_name":"Boots"}}`,
+					`{"op":"update","resource":"product","id":"${hiker}","body":{"product_description":"Waterproof"}}`,
+				},
+				status: 400,
+				line:   2,
+				detail: "Line 2: ${hiker} is not the ref of an item created by an earlier operation. None of the operations are applied",
+			},
+			{
+				lines: []string{
+					`{"op":"create","resource":"category","ref":"boots","body":{"category_name":"Boots"}}`,
+					"",
+					`{"op":"create","resource":"category","ref":"boots","body":{"category_name":"Boots"}}`,
+				},
+				status: 400,
+				line:   3,
+				detail: `Line 3: The ref "boots" must name the item of one create operation. None of the operations are applied`,
+			},
+		} {
+			rr := postBatch(catalog, c.lines...)
+
+			assert.Equal(t, c.status, rr.Code, "%s: unexpected status, body: %s", name, rr.Body.String())
+			var problem struct {
+				api.Problem
+				Line   int    `json:"line"`
+				Result Result `json:"result"`
+			}
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, c.detail, problem.Detail, name)
+			assert.Equal(t, "/v1/batch", problem.Instance, name)
+			assert.Equal(t, c.line, problem.Line, "%s: expected the failed line", name)
+			assert.Equal(t, c.line, problem.Result.Line, "%s: expected the result of the failed line", name)
+		}
+
+		categoriesAfter, _ := catalog.Categories()
+		assert.Equal(t, categoriesBefore, categoriesAfter, "%s: expected the categories to be rolled back", name)
+		productsAfter, _ := catalog.Products()
+		assert.Equal(t, productsBefore, productsAfter, "%s: expected the products to be rolled back", name)
+	}
+}
+
+//TestBatchWrongBody tests whether Batch func rejects a body which is not NDJSON or has no operations
+func TestBatchWrongBody(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewBatchHandler(catalog, catalogRoutes).Batch).ServeHTTP(rr, httptest.NewRequest("POST", "/v1/batch", strings.NewReader(`[]`)))
+	assert.Equal(t, 400, rr.Code, "Bad Request response is expected without the NDJSON content type")
+
+	rr = postBatch(catalog, "", " ")
+	assert.Equal(t, 400, rr.Code, "Bad Request response is expected without operations")
+}
@@ -0,0 +1,349 @@
+//package bulk applies many category and product operations sent in one request,
+//every operation is served by the route of its resource, so it is checked and stored like a single request
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// maxLineSize is the size of the longest line of a bulk request, a longer one stops the stream
+const maxLineSize = 1 << 20
+
+// the operations of a bulk request
+const (
+	OpCreate  = "create"
+	OpUpdate  = "update"
+	OpReplace = "replace"
+	OpDelete  = "delete"
+)
+
+// the resources the operations apply to
+const (
+	ResourceCategory = "category"
+	ResourceProduct  = "product"
+)
+
+// Operation is one line of a bulk request
+type Operation struct {
+	// Op is one of OpCreate, OpUpdate (a JSON Merge Patch), OpReplace and OpDelete
+	Op string `json:"op"`
+	// Resource is ResourceCategory or ResourceProduct
+	Resource string `json:"resource"`
+	// ID is the id of the updated, replaced or deleted item
+	ID string `json:"id,omitempty"`
+	// Body is the request body of the operation with the field names of the API version of the bulk request
+	Body json.RawMessage `json:"body,omitempty"`
+	// Ref names the item made by a create operation, the later operations give its id as "${ref}",
+	// in the ID or as a whole string value anywhere in the Body
+	Ref string `json:"ref,omitempty"`
+}
+
+// Result is one line of the response to a bulk request, written as soon as its operation is done
+type Result struct {
+	// Line is the line of the request with the operation
+	Line     int    `json:"line"`
+	Op       string `json:"op,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	// ID is the id of the item, also the one made by a create operation and the one a reference stands for
+	ID string `json:"id,omitempty"`
+	// Status is the status the single request of the operation gets
+	Status int `json:"status"`
+	// Body is the response body of the operation: the stored item or the problem details
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// route is the single request an operation is sent as
+type route struct {
+	method string
+	// path is the link of the resource, with {id} replaced by the id of the operation
+	path string
+}
+
+// routes are the requests of the operations on every resource
+var routes = map[string]map[string]route{
+	ResourceCategory: {
+		OpCreate:  {http.MethodPost, "/categories/new"},
+		OpUpdate:  {http.MethodPatch, "/categories/{id}"},
+		OpReplace: {http.MethodPut, "/categories/{id}"},
+		OpDelete:  {http.MethodDelete, "/categories/{id}"},
+	},
+	ResourceProduct: {
+		OpCreate:  {http.MethodPost, "/products/new"},
+		OpUpdate:  {http.MethodPatch, "/products/{id}"},
+		OpReplace: {http.MethodPut, "/products/{id}"},
+		OpDelete:  {http.MethodDelete, "/products/{id}"},
+	},
+}
+
+// idFields are the fields of the response bodies with the ids of the created items, named as in the Go API
+var idFields = map[string]string{
+	ResourceCategory: "CategoryID",
+	ResourceProduct:  "ProductID",
+}
+
+// Handler serves the bulk requests by sending their operations to the routes of the catalog
+type Handler struct {
+	routes http.Handler
+}
+
+// NewHandler returns a Handler which sends the operations to the given routes,
+// the ones registered next to the bulk route, so the operations use the same API version
+func NewHandler(routes http.Handler) *Handler {
+	return &Handler{routes: routes}
+}
+
+// Bulk applies the operations given as newline-delimited JSON, one Operation on every line, in their order.
+// The body is read as a stream and a Result is written back for every line as soon as it is applied,
+// a failed operation does not stop the following ones. An empty line is skipped.
+func (h *Handler) Bulk(w http.ResponseWriter, r *http.Request) {
+	if !api.IsNDJSON(r) {
+		api.WriteError(w, r, api.BadRequest("The operations must be sent as %s, one JSON object on every line", api.NDJSONContentType))
+		return
+	}
+
+	//the links of the operations are under the same prefix as the bulk route, e.g. /v1
+	prefix := strings.TrimSuffix(path.Dir(r.URL.Path), "/")
+
+	out := api.NewNDJSONWriter(w, http.StatusOK)
+	lines := newLineScanner(r)
+	number := 0
+	refs := make(map[string]string)
+	for lines.Scan() {
+		number++
+		line := bytes.TrimSpace(lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		//stop when the client has gone away
+		if err := out.Write(apply(h.routes, r, prefix, number, line, refs)); err != nil {
+			return
+		}
+	}
+
+	//the rest of the body can not be read, so the stream ends with the error of the next line
+	switch err := lines.Err(); {
+	case err == bufio.ErrTooLong:
+		out.Write(problemResult(r, number+1, api.BadRequest("The line is longer than %d bytes", maxLineSize)))
+	case err != nil:
+		out.Write(problemResult(r, number+1, api.BadRequest("The request body can not be read: %v", err)))
+	}
+}
+
+// newLineScanner returns a scanner of the lines of the request body, up to maxLineSize bytes long
+func newLineScanner(r *http.Request) *bufio.Scanner {
+	lines := bufio.NewScanner(r.Body)
+	lines.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return lines
+}
+
+// apply sends the operation on the line to its route served by the handler and returns its result.
+// The ids of the items created with a ref are added to refs, so the later operations can refer to them.
+func apply(handler http.Handler, r *http.Request, prefix string, number int, line []byte, refs map[string]string) Result {
+	var op Operation
+	if err := json.Unmarshal(line, &op); err != nil {
+		return problemResult(r, number, api.BadRequest("The line is not a valid JSON object: %v", err))
+	}
+	result := Result{Line: number, Op: op.Op, Resource: op.Resource, ID: op.ID}
+
+	//find the request of the operation
+	//or report an error
+	resourceRoutes, ok := routes[op.Resource]
+	if !ok {
+		return withProblem(result, r, api.BadRequest("The resource must be %s or %s", ResourceCategory, ResourceProduct))
+	}
+	opRoute, ok := resourceRoutes[op.Op]
+	if !ok {
+		return withProblem(result, r, api.BadRequest("The op must be one of %s, %s, %s and %s", OpCreate, OpUpdate, OpReplace, OpDelete))
+	}
+	if strings.Contains(opRoute.path, "{id}") && op.ID == "" {
+		return withProblem(result, r, api.BadRequest("Kindly enter the id of the %s to %s", op.Resource, op.Op))
+	}
+	if _, taken := refs[op.Ref]; op.Ref != "" && (op.Op != OpCreate || taken) {
+		return withProblem(result, r, api.BadRequest("The ref %q must name the item of one %s operation", op.Ref, OpCreate))
+	}
+
+	//put the ids of the items created by the earlier operations in place of their references
+	//or report an error
+	if p := resolveRefs(&op, refs); p != nil {
+		return withProblem(result, r, p)
+	}
+	result.ID = op.ID
+
+	//send the single request with the headers of the bulk one
+	link := prefix + strings.Replace(opRoute.path, "{id}", url.PathEscape(op.ID), 1)
+	req, err := http.NewRequestWithContext(r.Context(), opRoute.method, link, bytes.NewReader(op.Body))
+	if err != nil {
+		return withProblem(result, r, api.BadRequest("The id %q can not be put in a link", op.ID))
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if opRoute.method == http.MethodPatch {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	}
+	req.Header.Set(api.AuthorHeader, r.Header.Get(api.AuthorHeader))
+	response := newRecorder()
+	handler.ServeHTTP(response, req)
+
+	result.Status = response.status
+	if result.Status == 0 {
+		result.Status = http.StatusOK
+	}
+	if json.Valid(response.body.Bytes()) {
+		result.Body = json.RawMessage(bytes.TrimSpace(response.body.Bytes()))
+	}
+
+	//the later operations can refer to the created item
+	if op.Op == OpCreate && result.Status < http.StatusBadRequest {
+		result.ID = createdID(op.Resource, result.Body)
+		if op.Ref != "" && result.ID != "" {
+			refs[op.Ref] = result.ID
+		}
+	}
+	return result
+}
+
+// resolveRefs replaces the references in the id and the body of the operation with the ids of the items
+// created by the earlier operations, or returns a 400 Problem for an unknown reference
+func resolveRefs(op *Operation, refs map[string]string) *api.Problem {
+	if name, ok := reference(op.ID); ok {
+		id, found := refs[name]
+		if !found {
+			return unknownRef(name)
+		}
+		op.ID = id
+	}
+	if !bytes.Contains(op.Body, []byte("${")) {
+		return nil
+	}
+
+	//the numbers are kept as they are written
+	decoder := json.NewDecoder(bytes.NewReader(op.Body))
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		//the route reports the body which is not valid JSON
+		return nil
+	}
+	body, p := resolveValue(body, refs)
+	if p != nil {
+		return p
+	}
+	resolved, err := json.Marshal(body)
+	if err != nil {
+		return api.BadRequest("The body can not be encoded again: %v", err)
+	}
+	op.Body = resolved
+	return nil
+}
+
+// resolveValue replaces the references among the decoded JSON value and its members
+func resolveValue(v interface{}, refs map[string]string) (interface{}, *api.Problem) {
+	switch v := v.(type) {
+	case string:
+		name, ok := reference(v)
+		if !ok {
+			return v, nil
+		}
+		id, found := refs[name]
+		if !found {
+			return nil, unknownRef(name)
+		}
+		return id, nil
+	case map[string]interface{}:
+		for key, member := range v {
+			resolved, p := resolveValue(member, refs)
+			if p != nil {
+				return nil, p
+			}
+			v[key] = resolved
+		}
+	case []interface{}:
+		for i, member := range v {
+			resolved, p := resolveValue(member, refs)
+			if p != nil {
+				return nil, p
+			}
+			v[i] = resolved
+		}
+	}
+	return v, nil
+}
+
+// reference returns the ref named by the string if it is a reference, "${ref}"
+func reference(s string) (string, bool) {
+	if len(s) > 3 && strings.HasPrefix(s, "${") && strings.HasSuffix(s, "}") {
+		return s[2 : len(s)-1], true
+	}
+	return "", false
+}
+
+// unknownRef returns the 400 Problem of a reference which is not the ref of an earlier create operation
+func unknownRef(name string) *api.Problem {
+	return api.BadRequest("${%s} is not the ref of an item created by an earlier operation", name)
+}
+
+// createdID returns the id of the item in the response body of a create operation, named as in any API version
+func createdID(resource string, body json.RawMessage) string {
+	var created map[string]interface{}
+	if err := json.Unmarshal(body, &created); err != nil {
+		return ""
+	}
+	field := idFields[resource]
+	for _, name := range []string{field, api.SnakeName(field)} {
+		if id, ok := created[name].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// problemResult returns the result of a line which is not an operation
+func problemResult(r *http.Request, number int, p *api.Problem) Result {
+	return withProblem(Result{Line: number}, r, p)
+}
+
+// withProblem returns the result of the operation failed with the problem, written as the handlers write it
+func withProblem(result Result, r *http.Request, p *api.Problem) Result {
+	response := newRecorder()
+	api.WriteError(response, r, p)
+	result.Status = response.status
+	result.Body = json.RawMessage(bytes.TrimSpace(response.body.Bytes()))
+	return result
+}
+
+// recorder keeps the response of the single request of an operation
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// newRecorder returns an empty recorder
+func newRecorder() *recorder {
+	return &recorder{header: make(http.Header)}
+}
+
+// Header returns the headers of the response
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+// WriteHeader keeps the first status written
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+// Write keeps the body, with 200 as the status if none has been written
+func (rec *recorder) Write(data []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(data)
+}
@@ -0,0 +1,165 @@
+//package bulk contains test for bulk.go
+package bulk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+//echoRoutes returns routes which answer every request with its method, link, headers and body
+func echoRoutes() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodDelete {
+			fmt.Fprintf(w, "deleted %s", r.URL.Path)
+			return
+		}
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			status = http.StatusCreated
+		}
+		api.WriteJSON(w, status, map[string]string{"method": r.Method, "path": r.URL.Path, "author": r.Header.Get(api.AuthorHeader),
+			"content_type": r.Header.Get("Content-Type"), "body": string(body)})
+	})
+}
+
+//readResults reads the NDJSON results from the response body
+func readResults(t *testing.T, body io.Reader) []Result {
+	var results []Result
+	decoder := json.NewDecoder(body)
+	for decoder.More() {
+		var result Result
+		if !assert.NoError(t, decoder.Decode(&result)) {
+			break
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+//TestBulk tests whether Bulk func sends every operation to the route of its resource and writes a result for every line
+func TestBulk(t *testing.T) {
+	body := `{"op":"create","resource":"category","body":{"category_name":"Shoes"}}` + "\n" +
+		"\n" +
+		`{"op":"update","resource":"product","id":"a b","body":{"price":{"amount":1}}}` + "\n" +
+		`{"op":"delete","resource":"product","id":"p1"}` + "\n" +
+		`{"op":"create","resource":"variant"}` + "\n" +
+		`{"op":"rename","resource":"product"}` + "\n" +
+		`{"op":"replace","resource":"category"}` + "\n" +
+		`{"op":` + "\n"
+	req := httptest.NewRequest("POST", "/v1/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", api.NDJSONContentType)
+	req.Header.Set(api.AuthorHeader, "erp")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(echoRoutes()).Bulk).ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, api.NDJSONContentType, rr.Header().Get("Content-Type"))
+	results := readResults(t, rr.Body)
+	if !assert.Len(t, results, 7, "Expected a result for every line but the empty one") {
+		return
+	}
+	assert.Equal(t, Result{Line: 1, Op: "create", Resource: "category", Status: 201,
+		Body: json.RawMessage(`{"author":"erp","body":"{\"category_name\":\"Shoes\"}","content_type":"application/json","method":"POST","path":"/v1/categories/new"}`)}, results[0])
+	assert.Equal(t, Result{Line: 3, Op: "update", Resource: "product", ID: "a b", Status: 200,
+		Body: json.RawMessage(`{"author":"erp","body":"{\"price\":{\"amount\":1}}","content_type":"application/merge-patch+json","method":"PATCH","path":"/v1/products/a b"}`)}, results[1])
+	assert.Equal(t, Result{Line: 4, Op: "delete", Resource: "product", ID: "p1", Status: 200}, results[2],
+		"Expected the plain text response to be left out")
+
+	for i, detail := range map[int]string{
+		3: "The resource must be category or product",
+		4: "The op must be one of create, update, replace and delete",
+		5: "Kindly enter the id of the category to replace",
+		6: "The line is not a valid JSON object: unexpected end of JSON input",
+	} {
+		var problem api.Problem
+		assert.Equal(t, 400, results[i].Status, "Bad Request is expected on line %d", results[i].Line)
+		assert.NoError(t, json.Unmarshal(results[i].Body, &problem))
+		assert.Equal(t, detail, problem.Detail, "Unexpected problem on line %d", results[i].Line)
+		assert.Equal(t, "/v1/bulk", problem.Instance)
+	}
+}
+
+//TestBulkWrongBody tests whether Bulk func rejects a body which is not NDJSON and stops at a line which is too long
+func TestBulkWrongBody(t *testing.T) {
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(echoRoutes()).Bulk).ServeHTTP(rr, httptest.NewRequest("POST", "/bulk", strings.NewReader(`[]`)))
+	assert.Equal(t, 400, rr.Code, "Bad Request response is expected without the NDJSON content type")
+
+	body := `{"op":"delete","resource":"product","id":"p1"}` + "\n" + `{"id":"` + strings.Repeat("x", maxLineSize) + `"}` + "\n" +
+		`{"op":"delete","resource":"product","id":"p2"}` + "\n"
+	req := httptest.NewRequest("POST", "/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", api.NDJSONContentType)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(echoRoutes()).Bulk).ServeHTTP(rr, req)
+
+	results := readResults(t, rr.Body)
+	if assert.Len(t, results, 2, "Expected the stream to stop at the long line") {
+		assert.Equal(t, 200, results[0].Status)
+		assert.Equal(t, 2, results[1].Line)
+		assert.Equal(t, 400, results[1].Status, "Expected the long line to be reported")
+	}
+}
+
+//TestBulkStreaming tests whether the result of a line is sent before the next line is read,
+//also through the /v1 middleware, so the body is never held in memory as a whole
+func TestBulkStreaming(t *testing.T) {
+	router := mux.NewRouter()
+	v1 := router.PathPrefix("/v1").Subrouter()
+	v1.Use(api.SnakeCase)
+	v1.HandleFunc("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "deleted")
+	}).Methods("DELETE")
+	v1.HandleFunc("/bulk", NewHandler(v1).Bulk).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	body, lines := io.Pipe()
+	req, err := http.NewRequest("POST", server.URL+"/v1/bulk", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", api.NDJSONContentType)
+	responses := make(chan *http.Response)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		responses <- resp
+	}()
+
+	//every line is answered while the request is still being sent
+	var results *bufio.Reader
+	for i := 1; i <= 3; i++ {
+		fmt.Fprintf(lines, `{"op":"delete","resource":"product","id":"p%d"}`+"\n", i)
+		if results == nil {
+			select {
+			case resp := <-responses:
+				if !assert.NotNil(t, resp) {
+					return
+				}
+				defer resp.Body.Close()
+				assert.Equal(t, api.NDJSONContentType, resp.Header.Get("Content-Type"))
+				results = bufio.NewReader(resp.Body)
+			case <-time.After(5 * time.Second):
+				t.Fatal("Expected the response to start before the request body ends")
+			}
+		}
+		line, err := results.ReadString('\n')
+		assert.NoError(t, err)
+		assert.JSONEq(t, fmt.Sprintf(`{"line":%d,"op":"delete","resource":"product","id":"p%d","status":200}`, i, i), line)
+	}
+	lines.Close()
+	_, err = results.ReadString('\n')
+	assert.Equal(t, io.EOF, err, "Expected the response to end with the request body")
+}
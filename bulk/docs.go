@@ -0,0 +1,24 @@
+package bulk
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/openapi"
+	"net/http"
+)
+
+// Operations describes the bulk and batch handlers for the OpenAPI document
+func Operations() openapi.Operations {
+	o := openapi.Operations{}
+	o.Add((*Handler).Bulk, openapi.Operation{
+		Summary:  "Applies the category and product operations streamed one on every line, and streams back their results",
+		Request:  openapi.NDJSON{Line: Operation{}},
+		Response: openapi.NDJSON{Line: Result{}},
+		Errors:   []int{http.StatusBadRequest},
+	})
+	o.Add((*BatchHandler).Batch, openapi.Operation{
+		Summary:  "Applies all or none of the category and product operations sent one on every line, and sends back their results",
+		Request:  openapi.NDJSON{Line: Operation{}},
+		Response: openapi.NDJSON{Line: Result{}},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	return o
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/client"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// categoryCommands are the commands of the categories resource
+var categoryCommands = map[string]command{
+	"list":   {"lists the categories", listCategories},
+	"get":    {"shows the category with the id", getCategory},
+	"create": {"creates a category", createCategory},
+	"update": {"changes the fields of the category given in the flags", updateCategory},
+	"delete": {"deletes the category", deleteCategory},
+}
+
+// productCommands are the commands of the products resource
+var productCommands = map[string]command{
+	"list":   {"lists the products", listProducts},
+	"get":    {"shows the product with the id", getProduct},
+	"create": {"creates a product", createProduct},
+	"update": {"changes the fields of the product given in the flags", updateProduct},
+	"delete": {"deletes the product", deleteProduct},
+}
+
+// newFlags returns the flags of the command, printing their usage to the standard error
+func newFlags(e *env, name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: catalogctl %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// listFlags adds the flags of the list commands to the options
+func listFlags(flags *flag.FlagSet, opts *client.ListOptions, sorts string) {
+	flags.IntVar(&opts.Limit, "limit", 0, "the number of items to list, all of them if 0")
+	flags.StringVar(&opts.Sort, "sort", "", "the order of the items: "+sorts)
+	flags.BoolVar(&opts.Desc, "desc", false, "sorts in the descending order")
+	flags.StringVar(&opts.NameContains, "name-contains", "", "lists the items with the text in their name")
+}
+
+// fileFlag adds the flag reading the body of a create command from a JSON file
+func fileFlag(flags *flag.FlagSet) *string {
+	return flags.String("f", "", "the JSON file with the fields in their /v1 names, - for the standard input; the other flags override them")
+}
+
+// readFile decodes the JSON file, or the standard input for -, into v
+func readFile(e *env, path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(e.stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s is not a valid JSON object: %v", path, err)
+	}
+	return nil
+}
+
+// isSet reports whether the flag is given on the command line
+func isSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// attributeFlag collects the repeated -attr name=value flags
+type attributeFlag map[string]string
+
+// String returns the attributes as they are given on the command line
+func (a attributeFlag) String() string {
+	pairs := make([]string, 0, len(a))
+	for name, value := range a {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set adds one name=value attribute
+func (a attributeFlag) Set(pair string) error {
+	name, value, ok := strings.Cut(pair, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("the attribute must be given as name=value")
+	}
+	a[name] = value
+	return nil
+}
+
+// listCategories lists the categories, all of them page by page unless -limit is given
+func listCategories(ctx context.Context, e *env, args []string) error {
+	var opts client.ListOptions
+	flags := newFlags(e, "categories list", "")
+	listFlags(flags, &opts, "created (default) or name")
+	if _, err := parseArgs(flags, args, 0, "no arguments"); err != nil {
+		return err
+	}
+
+	categories := []client.Category{}
+	if opts.Limit > 0 {
+		page, err := e.client.ListCategories(ctx, opts)
+		if err != nil {
+			return err
+		}
+		categories = page.Items
+	} else {
+		it := e.client.Categories(opts)
+		for it.Next(ctx) {
+			categories = append(categories, it.Category())
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+	}
+	return e.out(e.stdout, categoryView(categories, false))
+}
+
+// getCategory shows the category with the id
+func getCategory(ctx context.Context, e *env, args []string) error {
+	flags := newFlags(e, "categories get", "<id>")
+	ids, err := parseArgs(flags, args, 1, "the id of the category")
+	if err != nil {
+		return err
+	}
+	category, err := e.client.GetCategory(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	return e.out(e.stdout, categoryView([]client.Category{*category}, true))
+}
+
+// createCategory creates the category given in the flags or in the JSON file
+func createCategory(ctx context.Context, e *env, args []string) error {
+	flags := newFlags(e, "categories create", "")
+	file := fileFlag(flags)
+	name := flags.String("name", "", "the name of the category")
+	description := flags.String("description", "", "the description of the category")
+	parent := flags.String("parent", "", "the id of the category it is nested in")
+	if _, err := parseArgs(flags, args, 0, "no arguments"); err != nil {
+		return err
+	}
+
+	var category client.Category
+	if err := readFile(e, *file, &category); err != nil {
+		return err
+	}
+	setString(flags, "name", &category.CategoryName, *name)
+	setString(flags, "description", &category.CategoryDescription, *description)
+	setString(flags, "parent", &category.ParentID, *parent)
+
+	created, err := e.client.CreateCategory(ctx, category)
+	if err != nil {
+		return err
+	}
+	return e.out(e.stdout, categoryView([]client.Category{*created}, true))
+}
+
+// updateCategory changes the fields of the category given in the flags, the other fields are kept
+func updateCategory(ctx context.Context, e *env, args []string) error {
+	flags := newFlags(e, "categories update", "<id>")
+	name := flags.String("name", "", "the new name of the category")
+	description := flags.String("description", "", "the new description of the category")
+	parent := flags.String("parent", "", "the id of the category it is moved under, empty for the top level")
+	ids, err := parseArgs(flags, args, 1, "the id of the category")
+	if err != nil {
+		return err
+	}
+
+	patch := make(map[string]interface{})
+	addPatch(flags, patch, "name", "category_name", *name)
+	addPatch(flags, patch, "description", "category_description", *description)
+	if isSet(flags, "parent") {
+		//an empty parent moves the category to the top level
+		patch["parent_id"] = nilIfEmpty(*parent)
+	}
+	if len(patch) == 0 {
+		fmt.Fprintln(e.stderr, "catalogctl: give at least one field to change")
+		flags.Usage()
+		return errUsage
+	}
+
+	updated, err := e.client.UpdateCategory(ctx, ids[0], patch)
+	if err != nil {
+		return err
+	}
+	return e.out(e.stdout, categoryView([]client.Category{*updated}, true))
+}
+
+// deleteCategory deletes the category with the id
+func deleteCategory(ctx context.Context, e *env, args []string) error {
+	flags := newFlags(e, "categories delete", "<id>")
+	policy := flags.String("policy", "", "what happens to the products: restrict (default), cascade or reassign")
+	target := flags.String("target", "", "the category the products are moved to with -policy reassign")
+	ids, err := parseArgs(flags, args, 1, "the id of the category")
+	if err != nil {
+		return err
+	}
+	if err = e.client.DeleteCategory(ctx, ids[0], *policy, *target); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stderr, "Category %s deleted\n", ids[0])
+	return nil
+}
+
+// listProducts lists the products, all of them page by page unless -limit is given
+func listProducts(ctx context.Context, e *env, args []string) error {
+	opts := client.ProductListOptions{Attributes: make(attributeFlag)}
+	flags := newFlags(e, "products list", "")
+	listFlags(flags, &opts.ListOptions, "created (default), name or price, which needs -currency")
+	flags.StringVar(&opts.CategoryID, "category", "", "lists the products of the category")
+	flags.BoolVar(&opts.Descendants, "descendants", false, "also lists the products of the subcategories of -category")
+	flags.StringVar(&opts.Currency, "currency", "", "lists the products priced in the currency, e.g. EUR")
+	priceMin := flags.String("price-min", "", "lists the products priced from the amount, e.g. 10.50")
+	priceMax := flags.String("price-max", "", "lists the products priced up to the amount, e.g. 99.99")
+	flags.Var(attributeFlag(opts.Attributes), "attr", "lists the products with the attribute value, e.g. -attr size=38, can be repeated")
+	if _, err := parseArgs(flags, args, 0, "no arguments"); err != nil {
+		return err
+	}
+
+	//the price bounds are written in the major units of the currency
+	bounds := []struct {
+		flag  string
+		text  string
+		bound **int64
+	}{{"price-min", *priceMin, &opts.PriceMin}, {"price-max", *priceMax, &opts.PriceMax}}
+	for _, b := range bounds {
+		if b.text == "" {
+			continue
+		}
+		if opts.Currency == "" {
+			return fmt.Errorf("-%s needs the -currency of the amount", b.flag)
+		}
+		price, err := money.Parse(b.text, opts.Currency)
+		if err != nil {
+			return fmt.Errorf("-%s %s: %v", b.flag, b.text, err)
+		}
+		*b.bound = &price.Amount
+	}
+
+	products := []client.ListedProduct{}
+	if opts.Limit > 0 {
+		page, err := e.client.ListProducts(ctx, opts)
+		if err != nil {
+			return err
+		}
+		products = page.Items
+	} else {
+		it := e.client.Products(opts)
+		for it.Next(ctx) {
+			products = append(products, it.Product())
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+	}
+	return e.out(e.stdout, listedProductView(products))
+}
+
+// getProduct shows the product with the id, with its price resolved in the currency and the price list if they are given
+func getProduct(ctx context.Context, e *env, args []string) error {
+	flags := newFlags(e, "products get", "<id>")
+	currency := flags.String("currency", "", "the currency the price is converted to")
+	priceList := flags.String("pricelist", "", "the price list the price is taken from")
+	ids, err := parseArgs(flags, args, 1, "the id of the product")
+	if err != nil {
+		return err
+	}
+	product, err := e.client.GetProduct(ctx, ids[0], *currency, *priceList)
+	if err != nil {
+		return err
+	}
+	//the resolved price is printed with the base price in the json and yaml formats
+	v := productView(product.Product)
+	v.value = product
+	return e.out(e.stdout, v)
+}
+
+// productFlags are the flags of the product fields
+type productFlags struct {
+	name, description, price, currency, category *string
+	attributes                                   attributeFlag
+}
+
+// newProductFlags adds the flags of the product fields
+func newProductFlags(flags *flag.FlagSet, verb string) *productFlags {
+	p := &productFlags{
+		name:        flags.String("name", "", "the "+verb+" name of the product"),
+		description: flags.String("description", "", "the "+verb+" description of the product"),
+		price:       flags.String("price", "", "the "+verb+" price in the major units of the currency, e.g. 10.50"),
+		currency:    flags.String("currency", "", "the ISO 4217 currency of the price, e.g. EUR"),
+		category:    flags.String("category", "", "the id of the "+verb+" category of the product"),
+		attributes:  make(attributeFlag),
+	}
+	flags.Var(p.attributes, "attr", "the attribute value, e.g. -attr size=38, can be repeated; it is typed by the schema of the category")
+	return p
+}
+
+// createProduct creates the product given in the flags or in the JSON file
+func createProduct(ctx context.Context, e *env, args []string) error {
+	flags := newFlags(e, "products create", "")
+	file := fileFlag(flags)
+	fields := newProductFlags(flags, "")
+	if _, err := parseArgs(flags, args, 0, "no arguments"); err != nil {
+		return err
+	}
+
+	var product client.Product
+	if err := readFile(e, *file, &product); err != nil {
+		return err
+	}
+	setString(flags, "name", &product.ProductName, *fields.name)
+	setString(flags, "description", &product.ProductDescription, *fields.description)
+	setString(flags, "category", &product.CategoryID, *fields.category)
+	setString(flags, "currency", &product.Price.Currency, *fields.currency)
+	if *fields.price != "" {
+		price, err := money.Parse(*fields.price, product.Price.Currency)
+		if err != nil {
+			return fmt.Errorf("-price %s %s: %v", *fields.price, product.Price.Currency, err)
+		}
+		product.Price = client.Money(price)
+	}
+	if len(fields.attributes) > 0 {
+		values, err := attributeValues(ctx, e.client, product.CategoryID, fields.attributes)
+		if err != nil {
+			return err
+		}
+		if product.Attributes == nil {
+			product.Attributes = make(map[string]interface{})
+		}
+		for name, value := range values {
+			product.Attributes[name] = value
+		}
+	}
+
+	created, err := e.client.CreateProduct(ctx, product)
+	if err != nil {
+		return err
+	}
+	return e.out(e.stdout, productView(*created))
+}
+
+// updateProduct changes the fields of the product given in the flags, the other fields are kept
+func updateProduct(ctx context.Context, e *env, args []string) error {
+	flags := newFlags(e, "products update", "<id>")
+	fields := newProductFlags(flags, "new")
+	ids, err := parseArgs(flags, args, 1, "the id of the product")
+	if err != nil {
+		return err
+	}
+
+	patch := make(map[string]interface{})
+	addPatch(flags, patch, "name", "product_name", *fields.name)
+	addPatch(flags, patch, "description", "product_description", *fields.description)
+	addPatch(flags, patch, "category", "category_id", *fields.category)
+	if isSet(flags, "price") || isSet(flags, "currency") || len(fields.attributes) > 0 {
+		//the price is read in the currency of the product and the attributes are typed by its category
+		current, err := e.client.GetProduct(ctx, ids[0], "", "")
+		if err != nil {
+			return err
+		}
+		price := current.Price
+		setString(flags, "currency", &price.Currency, *fields.currency)
+		if isSet(flags, "price") {
+			parsed, err := money.Parse(*fields.price, price.Currency)
+			if err != nil {
+				return fmt.Errorf("-price %s %s: %v", *fields.price, price.Currency, err)
+			}
+			price = client.Money(parsed)
+		}
+		patch["price"] = map[string]interface{}{"amount": price.Amount, "currency": price.Currency}
+
+		if len(fields.attributes) > 0 {
+			categoryID := current.CategoryID
+			setString(flags, "category", &categoryID, *fields.category)
+			values, err := attributeValues(ctx, e.client, categoryID, fields.attributes)
+			if err != nil {
+				return err
+			}
+			patch["attributes"] = values
+		}
+	}
+	if len(patch) == 0 {
+		fmt.Fprintln(e.stderr, "catalogctl: give at least one field to change")
+		flags.Usage()
+		return errUsage
+	}
+
+	updated, err := e.client.UpdateProduct(ctx, ids[0], patch)
+	if err != nil {
+		return err
+	}
+	return e.out(e.stdout, productView(*updated))
+}
+
+// deleteProduct deletes the product with the id
+func deleteProduct(ctx context.Context, e *env, args []string) error {
+	flags := newFlags(e, "products delete", "<id>")
+	ids, err := parseArgs(flags, args, 1, "the id of the product")
+	if err != nil {
+		return err
+	}
+	if err = e.client.DeleteProduct(ctx, ids[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stderr, "Product %s deleted\n", ids[0])
+	return nil
+}
+
+// attributeValues types the attribute values given as text by the attribute schema of the category and its ancestors:
+// the numbers and the booleans are sent as JSON numbers and booleans, the rest as strings
+func attributeValues(ctx context.Context, c *client.Client, categoryID string, texts map[string]string) (map[string]interface{}, error) {
+	types := make(map[string]string)
+	if categoryID != "" {
+		category, err := c.GetCategory(ctx, categoryID)
+		if err != nil {
+			return nil, err
+		}
+		ancestors, err := c.CategoryAncestors(ctx, categoryID)
+		if err != nil {
+			return nil, err
+		}
+		for _, owner := range append(ancestors, *category) {
+			for _, a := range owner.Attributes {
+				types[a.Name] = a.Type
+			}
+		}
+	}
+
+	values := make(map[string]interface{}, len(texts))
+	for name, text := range texts {
+		var err error
+		switch types[name] {
+		case "number":
+			values[name], err = strconv.ParseFloat(text, 64)
+		case "bool":
+			values[name], err = strconv.ParseBool(text)
+		default:
+			values[name] = text
+		}
+		if err != nil {
+			return nil, fmt.Errorf("-attr %s=%s: the attribute is a %s", name, text, types[name])
+		}
+	}
+	return values, nil
+}
+
+// setString sets the field to the value of the flag if the flag is given
+func setString(flags *flag.FlagSet, name string, field *string, value string) {
+	if isSet(flags, name) {
+		*field = value
+	}
+}
+
+// addPatch adds the value of the flag to the merge patch under the /v1 field name if the flag is given
+func addPatch(flags *flag.FlagSet, patch map[string]interface{}, name string, field string, value string) {
+	if isSet(flags, name) {
+		patch[field] = value
+	}
+}
+
+// nilIfEmpty returns nil for an empty value, which removes the field in a merge patch
+func nilIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,173 @@
+//package main is catalogctl, the command-line tool managing the categories and products of a running catalog server
+//through the /v1 API:
+//
+//	catalogctl [-server URL] [-o table|json|yaml] categories|products list|get|create|update|delete [flags] [id]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/client"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultServer is the catalog the commands are sent to without the -server flag and $CATALOG_URL
+const defaultServer = "http://localhost:8080"
+
+// the exit codes of the command
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage is returned for a command which can not be run as it is given, the usage is printed for it
+var errUsage = errors.New("usage")
+
+// command is a subcommand of a resource, e.g. products list
+type command struct {
+	summary string
+	// run runs the command with its arguments after the resource and the command name
+	run func(ctx context.Context, env *env, args []string) error
+}
+
+// resources are the commands of every resource
+var resources = map[string]map[string]command{
+	"categories": categoryCommands,
+	"products":   productCommands,
+}
+
+// env is what the commands work with
+type env struct {
+	client *client.Client
+	out    output
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command line and returns the exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("catalogctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", envOr("CATALOG_URL", defaultServer), "the URL of the catalog server (defaults to $CATALOG_URL)")
+	format := flags.String("o", "table", "the output format: table, json or yaml")
+	author := flags.String("author", os.Getenv("CATALOG_AUTHOR"), "who makes the changes, recorded e.g. in the price history (defaults to $CATALOG_AUTHOR)")
+	timeout := flags.Duration("timeout", 30*time.Second, "how long the command can take")
+	flags.Usage = func() { usage(flags) }
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	//find the command of the resource
+	//or print the usage
+	out, ok := outputs[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "catalogctl: unknown output format %q, use table, json or yaml\n", *format)
+		return exitUsage
+	}
+	if flags.NArg() < 2 {
+		usage(flags)
+		return exitUsage
+	}
+	commands, ok := resources[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "catalogctl: unknown resource %q, use categories or products\n", flags.Arg(0))
+		return exitUsage
+	}
+	cmd, ok := commands[flags.Arg(1)]
+	if !ok {
+		fmt.Fprintf(stderr, "catalogctl: unknown command %q of %s\n", flags.Arg(1), flags.Arg(0))
+		return exitUsage
+	}
+
+	c, err := client.New(*server)
+	if err != nil {
+		fmt.Fprintf(stderr, "catalogctl: %v\n", err)
+		return exitUsage
+	}
+	c.Author = *author
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	err = cmd.run(ctx, &env{client: c, out: out, stdin: stdin, stdout: stdout, stderr: stderr}, flags.Args()[2:])
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	}
+	printError(stderr, err)
+	return exitError
+}
+
+// usage prints how to run the commands
+func usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintln(w, "Usage: catalogctl [flags] <resource> <command> [command flags] [id]")
+	fmt.Fprintln(w, "\nFlags:")
+	flags.PrintDefaults()
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "\n%s commands:\n", strings.ToUpper(name[:1])+name[1:])
+		for _, cmdName := range []string{"list", "get", "create", "update", "delete"} {
+			fmt.Fprintf(w, "  %-7s %s\n", cmdName, resources[name][cmdName].summary)
+		}
+	}
+	fmt.Fprintln(w, "\nRun catalogctl <resource> <command> -h for the flags of the command.")
+}
+
+// printError prints the error, with the invalid fields of a validation problem
+func printError(w io.Writer, err error) {
+	fmt.Fprintf(w, "catalogctl: %v\n", err)
+	var problem *client.Error
+	if errors.As(err, &problem) {
+		for _, fieldError := range problem.Errors {
+			fmt.Fprintf(w, "  %s: %s\n", fieldError.Field, fieldError.Detail)
+		}
+	}
+}
+
+// envOr returns the environment variable or the default value if it is empty
+func envOr(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// parseArgs parses the flags of the command, which can be given before and after its arguments,
+// and returns the arguments. With n >= 0 exactly n arguments are expected.
+func parseArgs(flags *flag.FlagSet, args []string, n int, names string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if n >= 0 && len(positional) != n {
+		fmt.Fprintf(flags.Output(), "catalogctl: expected %s\n", names)
+		flags.Usage()
+		return nil, errUsage
+	}
+	return positional, nil
+}
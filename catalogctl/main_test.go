@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/categories"
+	"github.com/KseniiaL/AdcashTestAssignment/products"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//newServer returns the catalog server with the /v1 category and product routes the commands call
+func newServer(t *testing.T) *httptest.Server {
+	catalog := store.NewMemoryStore()
+	categoryHandler := categories.NewHandler(catalog)
+	productHandler := products.NewHandler(catalog)
+	router := mux.NewRouter()
+	v1 := router.PathPrefix("/v1").Subrouter()
+	v1.Use(api.SnakeCase)
+	v1.HandleFunc("/categories", categoryHandler.GetAllCategories).Methods("GET")
+	v1.HandleFunc("/categories/new", categoryHandler.CreateCategory).Methods("POST")
+	v1.HandleFunc("/categories/{id}", categoryHandler.GetCategoryById).Methods("GET")
+	v1.HandleFunc("/categories/{id}", categoryHandler.UpdateCategory).Methods("PATCH")
+	v1.HandleFunc("/categories/{id}", categoryHandler.DeleteCategory).Methods("DELETE")
+	v1.HandleFunc("/categories/{id}/ancestors", categoryHandler.GetCategoryAncestors).Methods("GET")
+	v1.HandleFunc("/products", productHandler.GetAllProducts).Methods("GET")
+	v1.HandleFunc("/products/new", productHandler.CreateProduct).Methods("POST")
+	v1.HandleFunc("/products/{id}", productHandler.GetProductById).Methods("GET")
+	v1.HandleFunc("/products/{id}", productHandler.UpdateProduct).Methods("PATCH")
+	v1.HandleFunc("/products/{id}", productHandler.DeleteProduct).Methods("DELETE")
+	v1.HandleFunc("/products/category/{id}", productHandler.GetProductsOfCategory).Methods("GET")
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+//runCommand runs the command line against the server and returns the exit code, the output and the errors
+func runCommand(server *httptest.Server, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(append([]string{"-server", server.URL}, args...), strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+//TestCategoryCommands tests whether the categories can be created, listed, changed and deleted
+func TestCategoryCommands(t *testing.T) {
+	server := newServer(t)
+
+	code, out, errs := runCommand(server, "", "-o", "json", "categories", "create", "-name", "Shoes", "-description", "All the shoes")
+	if !assert.Equal(t, exitOK, code, errs) {
+		return
+	}
+	assert.Contains(t, out, `"category_name": "Shoes"`, "Expected the created category in JSON")
+	id := between(out, `"category_id": "`, `"`)
+
+	code, out, errs = runCommand(server, "", "categories", "list", "-sort", "name")
+	assert.Equal(t, exitOK, code, errs)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if assert.Len(t, lines, 4, "Expected the header and three categories") {
+		assert.Equal(t, []string{"ID", "NAME", "PARENT", "ATTRIBUTES", "DESCRIPTION"}, strings.Fields(lines[0]))
+		assert.True(t, strings.HasPrefix(lines[1], id+"  Shoes"), "Expected the new category first: %s", lines[1])
+	}
+
+	code, out, errs = runCommand(server, "", "categories", "update", id, "-description", "Running shoes", "-o", "yaml")
+	assert.Equal(t, exitUsage, code, "Expected the global flags before the resource")
+	code, out, errs = runCommand(server, "", "-o", "yaml", "categories", "update", id, "-description", "Running shoes")
+	assert.Equal(t, exitOK, code, errs)
+	assert.Equal(t, "category_id: "+id+"\ncategory_name: Shoes\ncategory_description: Running shoes\n", out, "Expected the category in YAML")
+
+	code, _, errs = runCommand(server, "", "categories", "create", "-description", "No name")
+	assert.Equal(t, exitError, code, "Expected the validation error")
+	assert.Equal(t, "catalogctl: catalog: Unprocessable Entity: The request body contains invalid fields\n"+
+		"  category_name: Kindly enter the category name\n", errs, "Expected the invalid fields")
+
+	code, _, errs = runCommand(server, "", "categories", "delete", id)
+	assert.Equal(t, exitOK, code, errs)
+	assert.Equal(t, "Category "+id+" deleted\n", errs)
+	code, _, errs = runCommand(server, "", "categories", "get", id)
+	assert.Equal(t, exitError, code, "Expected the deleted category not to be found")
+	assert.Contains(t, errs, "Not Found")
+}
+
+//TestProductCommands tests whether the products are created with their prices in the major units
+//and their attributes typed by the schema of the category
+func TestProductCommands(t *testing.T) {
+	server := newServer(t)
+
+	categoryJSON := `{"category_name":"Lamps","attributes":[{"name":"watts","type":"number"},{"name":"dimmable","type":"bool"},{"name":"colour","type":"string"}]}`
+	code, out, errs := runCommand(server, categoryJSON, "-o", "json", "categories", "create", "-f", "-")
+	if !assert.Equal(t, exitOK, code, errs) {
+		return
+	}
+	categoryID := between(out, `"category_id": "`, `"`)
+
+	code, out, errs = runCommand(server, "", "-o", "yaml", "products", "create", "-name", "Desk lamp", "-price", "19.90", "-currency", "EUR",
+		"-category", categoryID, "-attr", "watts=40", "-attr", "dimmable=true", "-attr", "colour=01")
+	if !assert.Equal(t, exitOK, code, errs) {
+		return
+	}
+	productID := between(out, "product_id: ", "\n")
+	assert.Equal(t, "product_id: "+productID+"\nproduct_name: Desk lamp\nproduct_description: \"\"\n"+
+		"price:\n  amount: 1990\n  currency: EUR\ncategory_id: "+categoryID+"\n"+
+		"attributes:\n  colour: \"01\"\n  dimmable: true\n  watts: 40\n", out, "Expected the typed attributes in YAML")
+
+	code, _, errs = runCommand(server, "", "products", "create", "-name", "Lamp", "-price", "19.999", "-currency", "EUR", "-category", categoryID)
+	assert.Equal(t, exitError, code, "Expected the price with too many decimal places to be rejected")
+	assert.Contains(t, errs, "invalid amount")
+
+	code, out, errs = runCommand(server, "", "products", "update", productID, "-price", "24.50", "-attr", "watts=60")
+	assert.Equal(t, exitOK, code, errs)
+	assert.Contains(t, out, "24.50 EUR", "Expected the new price")
+	assert.Contains(t, out, "colour=01,dimmable=true,watts=60", "Expected the merged attributes")
+
+	code, out, errs = runCommand(server, "", "products", "list", "-category", categoryID, "-currency", "EUR", "-price-min", "20")
+	assert.Equal(t, exitOK, code, errs)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if assert.Len(t, lines, 2, "Expected the header and the lamp") {
+		assert.Equal(t, []string{"ID", "NAME", "PRICE", "AVAILABLE", "CATEGORY", "ATTRIBUTES"}, strings.Fields(lines[0]))
+		assert.Contains(t, lines[1], "out of stock", "Expected the availability")
+	}
+
+	code, _, errs = runCommand(server, "", "products", "delete", productID)
+	assert.Equal(t, exitOK, code, errs)
+	code, out, _ = runCommand(server, "", "-o", "json", "products", "list", "-category", categoryID)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "[]\n", out, "Expected no products")
+}
+
+//TestUsage tests whether the wrong command lines are reported with the usage
+func TestUsage(t *testing.T) {
+	server := newServer(t)
+	for _, args := range [][]string{
+		{},
+		{"categories"},
+		{"variants", "list"},
+		{"categories", "rename"},
+		{"categories", "get"},
+		{"categories", "update", "bq4fasj7jhfi127rimlg"},
+		{"-o", "xml", "categories", "list"},
+	} {
+		code, out, errs := runCommand(server, "", args...)
+		assert.Equal(t, exitUsage, code, "Expected %v to be a usage error", args)
+		assert.Empty(t, out, "Expected nothing printed for %v", args)
+		assert.NotEmpty(t, errs, "Expected the usage for %v", args)
+	}
+}
+
+//between returns the text between the first start and the end after it
+func between(text string, start string, end string) string {
+	text = text[strings.Index(text, start)+len(start):]
+	return text[:strings.Index(text, end)]
+}
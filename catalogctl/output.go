@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/client"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// view is what a command prints: the value in the json and yaml formats and the rows in the table format
+type view struct {
+	value  interface{}
+	header []string
+	rows   [][]string
+}
+
+// output prints the view in one of the formats
+type output func(w io.Writer, v view) error
+
+// outputs are the formats of the -o flag
+var outputs = map[string]output{
+	"table": writeTable,
+	"json":  writeJSON,
+	"yaml":  writeYAML,
+}
+
+// categoryView shows the categories, one category is printed as an object rather than a list
+func categoryView(categories []client.Category, one bool) view {
+	v := view{value: categories, header: []string{"ID", "NAME", "PARENT", "ATTRIBUTES", "DESCRIPTION"}}
+	if one {
+		v.value = categories[0]
+	}
+	for _, c := range categories {
+		names := make([]string, 0, len(c.Attributes))
+		for _, a := range c.Attributes {
+			names = append(names, a.Name+":"+a.Type)
+		}
+		v.rows = append(v.rows, []string{c.CategoryID, c.CategoryName, c.ParentID, strings.Join(names, ","), c.CategoryDescription})
+	}
+	return v
+}
+
+// productView shows one product
+func productView(p client.Product) view {
+	return view{
+		value:  p,
+		header: []string{"ID", "NAME", "PRICE", "CATEGORY", "ATTRIBUTES", "DESCRIPTION"},
+		rows:   [][]string{{p.ProductID, p.ProductName, p.Price.String(), p.CategoryID, attributeText(p.Attributes), p.ProductDescription}},
+	}
+}
+
+// listedProductView shows the products of a list with their availability
+func listedProductView(products []client.ListedProduct) view {
+	v := view{value: products, header: []string{"ID", "NAME", "PRICE", "AVAILABLE", "CATEGORY", "ATTRIBUTES"}}
+	for _, p := range products {
+		available := strconv.FormatInt(p.Available, 10)
+		if p.OutOfStock {
+			available = "out of stock"
+		}
+		v.rows = append(v.rows, []string{p.ProductID, p.ProductName, p.Price.String(), available, p.CategoryID, attributeText(p.Attributes)})
+	}
+	return v
+}
+
+// attributeText writes the attributes as name=value pairs ordered by name
+func attributeText(attributes map[string]interface{}) string {
+	pairs := make([]string, 0, len(attributes))
+	for name, value := range attributes {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// writeTable prints the rows in aligned columns under the header
+func writeTable(w io.Writer, v view) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(v.header, "\t"))
+	for _, row := range v.rows {
+		//a tab or a newline in a description would break the columns
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.Join(strings.Fields(cell), " ")
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeJSON prints the value as indented JSON with the /v1 field names
+func writeJSON(w io.Writer, v view) error {
+	data, err := json.MarshalIndent(v.value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// writeYAML prints the value as a YAML document with the /v1 field names in the order of the JSON fields
+func writeYAML(w io.Writer, v view) error {
+	data, err := json.Marshal(v.value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	writeYAMLValue(&b, value, 0, "")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// member is a member of a JSON object, the members are kept in the order they are written
+type member struct {
+	key   string
+	value interface{}
+}
+
+// decodeOrdered decodes the next JSON value, the objects into []member and the arrays into []interface{}
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		members := []member{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, member{key.(string), value})
+		}
+		_, err = decoder.Token()
+		return members, err
+	case json.Delim('['):
+		items := []interface{}{}
+		for decoder.More() {
+			item, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err = decoder.Token()
+		return items, err
+	}
+	return token, nil
+}
+
+// writeYAMLValue writes the value in the block style, the nested values indented by two more spaces.
+// The first line starts with first, e.g. nothing after the dash of a list item.
+func writeYAMLValue(b *strings.Builder, value interface{}, indent int, first string) {
+	prefix := strings.Repeat("  ", indent)
+	switch v := value.(type) {
+	case []member:
+		for i, m := range v {
+			if i == 0 {
+				b.WriteString(first + yamlScalar(m.key) + ":")
+			} else {
+				b.WriteString(prefix + yamlScalar(m.key) + ":")
+			}
+			if isBlock(m.value) {
+				b.WriteString("\n")
+				writeYAMLValue(b, m.value, indent+1, prefix+"  ")
+			} else {
+				b.WriteString(" " + yamlFlow(m.value) + "\n")
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if i == 0 {
+				b.WriteString(first + "- ")
+			} else {
+				b.WriteString(prefix + "- ")
+			}
+			switch {
+			case !isBlock(item):
+				b.WriteString(yamlFlow(item) + "\n")
+			case isObject(item):
+				//the first member of an object starts on the line of the dash
+				writeYAMLValue(b, item, indent+1, "")
+			default:
+				b.WriteString("\n")
+				writeYAMLValue(b, item, indent+1, prefix+"  ")
+			}
+		}
+	default:
+		b.WriteString(first + yamlFlow(v) + "\n")
+	}
+}
+
+// isBlock reports whether the value is a non-empty object or list, which is written on the next lines
+func isBlock(value interface{}) bool {
+	switch v := value.(type) {
+	case []member:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+// isObject reports whether the value is a JSON object
+func isObject(value interface{}) bool {
+	_, ok := value.([]member)
+	return ok
+}
+
+// yamlFlow writes the scalar or the empty object or list on one line
+func yamlFlow(value interface{}) string {
+	switch value.(type) {
+	case []member:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	return yamlScalar(value)
+}
+
+// plainScalar matches the strings which can be written without quotes unless they look like a number or a date
+var plainScalar = regexp.MustCompile(`^[A-Za-z0-9_/][A-Za-z0-9 _./()'-]*$`)
+
+// yamlDate matches the strings YAML would read as a timestamp
+var yamlDate = regexp.MustCompile(`^\d{4}-\d\d?-\d\d?`)
+
+// yamlScalar writes the JSON scalar, quoting the strings which YAML would read as another type or can not read plain
+func yamlScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+			return strconv.Quote(v)
+		}
+		_, numberErr := strconv.ParseFloat(v, 64)
+		if plainScalar.MatchString(v) && !strings.HasSuffix(v, " ") && numberErr != nil && !yamlDate.MatchString(v) {
+			return v
+		}
+		//a JSON string is a double-quoted YAML scalar
+		quoted, _ := json.Marshal(v)
+		return string(quoted)
+	}
+	return fmt.Sprint(value)
+}
@@ -2,178 +2,376 @@
 package categories
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
 	"github.com/gorilla/mux"
 	"github.com/rs/xid"
-	"io/ioutil"
-	"log"
 	"net/http"
+	"strings"
 )
 
 // Category stores information about category fields
-type Category struct {
-	CategoryID  		string `json:"CategoryID"`
-	CategoryName       	string `json:"CategoryName"`
-	CategoryDescription string `json:"CategoryDescription"`
-}
-
-// allCategories is the slice of Category structs
-type allCategories []Category
-
-// Categories is the simple imitation of the DB (categories table)
-var Categories = allCategories {
-	{
-		CategoryID:  		 "bq4fasj7jhfi127rimlg",
-		CategoryName:        "Shopping Products",
-		CategoryDescription: "Products consumers purchase and consume on a less frequent schedule compared to convenience products.",
-	},
-	{
-		CategoryID:  		 "bq4fb3b7jhfi7v7uo39g",
-		CategoryName:        "Specialty Products",
-		CategoryDescription: "Products that are more expensive relative to convenience and shopping products.",
-	},
-}
-
-// GetAllCategories returns Categories slice in JSON format as a response
-func GetAllCategories (w http.ResponseWriter, r *http.Request) {
-	//define new encoder that writes to the w
+type Category = store.Category
+
+// Handler contains all the category handlers and the store they work with
+type Handler struct {
+	store store.CatalogStore
+}
+
+// NewHandler returns a Handler which reads and writes categories through the given store
+func NewHandler(s store.CatalogStore) *Handler {
+	return &Handler{store: s}
+}
+
+// GetAllCategories returns the categories in JSON format as a response.
+// The list can be paged, sorted and filtered with the query parameters read by api.ParseListQuery.
+func (h *Handler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	//read the paging, sorting and filtering parameters
+	//or report an error
+	q, err := api.ParseListQuery(r, store.SortCreated, store.SortName)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//get the page from the store
 	//or report an error
-	if err := json.NewEncoder(w).Encode(Categories); err != nil {
-		log.Printf(err.Error())
-		w.WriteHeader(500)
+	page, err := h.store.ListCategories(q)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
 	}
+
+	api.WritePage(w, r, page.Items, page.Total, page.Next)
 }
 
-// GetCategoryById gets a category id from the request link and looks for the corresponding item in []Categories
-func GetCategoryById (w http.ResponseWriter, r *http.Request) {
+// GetCategoryById gets a category id from the request link and looks for the corresponding item in the store
+func (h *Handler) GetCategoryById(w http.ResponseWriter, r *http.Request) {
 	//get category id from the link
 	categoryID := mux.Vars(r)["id"]
 
-	//find the category with the given id in the slice
-	var givenCategory Category
-	for _, singleCategory := range Categories {
-		if singleCategory.CategoryID == categoryID {
-			givenCategory = singleCategory
-		}
+	//find the category with the given id in the store
+	//or report an error
+	givenCategory, err := h.store.Category(categoryID)
+	if err != nil {
+		api.WriteError(w, r, categoryError(err, Category{CategoryID: categoryID}))
+		return
 	}
 
 	//return the Category information to ResponseWriter
-	//or log the encoding error
-	if givenCategory.CategoryID == categoryID {
-		if err := json.NewEncoder(w).Encode(givenCategory); err != nil {
-			log.Printf(err.Error())
-			w.WriteHeader(500)
-			return
-		}
-	} else {
-		w.WriteHeader(412)
-		fmt.Fprintf(w, "Category with ID %s not found", categoryID)
+	api.WriteJSON(w, http.StatusOK, givenCategory)
+}
+
+// GetCategoryTree returns all the categories nested under their parents in the Children field
+func (h *Handler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	//get the tree from the store
+	//or report an error
+	tree, err := h.store.CategoryTree()
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
 	}
+
+	api.WriteJSON(w, http.StatusOK, tree)
 }
 
-// CreateCategory creates a new sample of Category, fills it with the information from the request body,
-// and appends to the []Categories slice
-func CreateCategory (w http.ResponseWriter, r *http.Request) {
-	var newCategory Category
+// GetChildCategories gets a category id from the request link and returns the categories directly under it
+func (h *Handler) GetChildCategories(w http.ResponseWriter, r *http.Request) {
+	//get category id from the link
+	categoryID := mux.Vars(r)["id"]
 
-	//get the information containing in request's body
+	//find the subcategories in the store
 	//or report an error
-	reqBody, err := ioutil.ReadAll(r.Body)
+	children, err := h.store.ChildCategories(categoryID)
 	if err != nil {
-		fmt.Fprintf(w, "Kindly enter data with the category name and description only in order to update")
-		log.Fatal(err.Error())
+		api.WriteError(w, r, categoryError(err, Category{CategoryID: categoryID}))
+		return
 	}
 
-	//generate unique categoryID
-	newCategory.CategoryID = xid.New().String()
-	//unmarshal the information from JSON into the Category instance
+	api.WriteJSON(w, http.StatusOK, children)
+}
+
+// GetCategoryAncestors gets a category id from the request link and returns its breadcrumb:
+// the categories it is nested in, starting from the top-level one
+func (h *Handler) GetCategoryAncestors(w http.ResponseWriter, r *http.Request) {
+	//get category id from the link
+	categoryID := mux.Vars(r)["id"]
+
+	//find the ancestors in the store
 	//or report an error
-	if err = json.Unmarshal(reqBody, &newCategory); err != nil {
-		log.Printf("Body parse error, %v", err.Error())
-		w.WriteHeader(400)
+	ancestors, err := h.store.CategoryAncestors(categoryID)
+	if err != nil {
+		api.WriteError(w, r, categoryError(err, Category{CategoryID: categoryID}))
 		return
 	}
 
-	//CategoryName is required field
-	if len(newCategory.CategoryName) == 0 {
-		w.WriteHeader(422)
-		fmt.Fprintf(w, "Kindly enter data with the category name in order to create new category")
+	api.WriteJSON(w, http.StatusOK, ancestors)
+}
+
+// CreateCategory creates a new sample of Category, fills it with the information from the request body,
+// and adds it to the store
+func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var newCategory Category
+
+	//unmarshal the information from JSON into the Category instance
+	//or report an error
+	if err := api.ReadJSON(r, &newCategory); err != nil {
+		api.WriteError(w, r, err)
 		return
 	}
+	//generate unique categoryID, an id in the body is ignored, so the categories are sorted by the time they are created
+	newCategory.CategoryID = xid.New().String()
 
-	//append the new category to the slice
-	Categories = append(Categories, newCategory)
-	w.WriteHeader(http.StatusCreated)
+	//check the required fields
+	if err := validate(newCategory); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
 
-	//return the category in response
+	//add the new category to the store
 	//or report an error
-	if err = json.NewEncoder(w).Encode(newCategory); err != nil {
-		log.Printf(err.Error())
-		w.WriteHeader(500)
+	if err := h.store.CreateCategory(newCategory); err != nil {
+		api.WriteError(w, r, categoryError(err, newCategory))
 		return
 	}
+
+	//return the category in response
+	api.WriteJSON(w, http.StatusCreated, newCategory)
 }
 
-// DeleteCategory gets a category id from the request link and removes corresponding item from the slice
-func DeleteCategory (w http.ResponseWriter, r *http.Request) {
+// DeleteCategory gets a category id from the request link and removes corresponding item from the store.
+// The policy query parameter says what happens to the products of the category:
+// restrict (the default) keeps the category while it has products, cascade deletes the products with it
+// and reassign moves them to the category given in the target query parameter, unless some of them
+// do not match the attribute schema of the target.
+func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	//get category id from the link
 	categoryID := mux.Vars(r)["id"]
-	//to define if some category was found in slice and deleted
-	categoriesLength := len(Categories)
-
-	//find the category with the given id and remove from the slice
-	for i, singleCategory := range Categories {
-		if singleCategory.CategoryID == categoryID {
-			Categories = append(Categories[:i], Categories[i+1:]...)
-			fmt.Fprintf(w, "The category with ID %v has been deleted successfully", categoryID)
+
+	//read the policy from the link
+	//or report an error
+	policy, err := deletePolicy(r, categoryID)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//remove the category with the given id from the store in one transaction with checking the moved products
+	//against the attribute schema of the target
+	//or report an error
+	err = h.store.Transaction(func(tx store.CatalogStore) error {
+		var moved []store.Product
+		if policy.Mode == store.DeleteReassign {
+			products, err := tx.ProductsOfCategory(categoryID)
+			if err != nil && err != store.ErrNotFound {
+				return err
+			}
+			moved = products
 		}
+		if err := tx.DeleteCategory(categoryID, policy); err == store.ErrCategoryNotFound {
+			return api.BadRequest("Category with ID %s given in target not found", policy.TargetID)
+		} else if err != nil {
+			return categoryError(err, Category{CategoryID: categoryID})
+		}
+		for i := range moved {
+			moved[i].CategoryID = policy.TargetID
+		}
+		return api.CheckProducts(tx, moved)
+	})
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
 	}
+	fmt.Fprintf(w, "The category with ID %v has been deleted successfully", categoryID)
+}
 
-	//report category with the given id not exists
-	if categoriesLength == len(Categories) {
-		w.WriteHeader(412)
-		fmt.Fprintf(w, "Category with ID %s not found", categoryID)
+// deletePolicy reads the policy and target query parameters of the delete request
+// or returns a 400 Problem if the policy can not be applied to the category with the given id
+func deletePolicy(r *http.Request, categoryID string) (store.DeletePolicy, error) {
+	policy := store.DeletePolicy{Mode: r.URL.Query().Get("policy"), TargetID: r.URL.Query().Get("target")}
+	switch policy.Mode {
+	case "", store.DeleteRestrict, store.DeleteCascade:
+		return policy, nil
+	case store.DeleteReassign:
+		if policy.TargetID == "" {
+			return store.DeletePolicy{}, api.BadRequest("Kindly enter the category the products are moved to in the target parameter")
+		}
+		if policy.TargetID == categoryID {
+			return store.DeletePolicy{}, api.BadRequest("The products can not be moved to the deleted category")
+		}
+		return policy, nil
 	}
+	return store.DeletePolicy{}, api.BadRequest("The policy must be one of %s, %s and %s", store.DeleteRestrict, store.DeleteCascade, store.DeleteReassign)
 }
 
-// UpdateCategory gets a Category id from the request link and replaces the fields in the corresponding Category
-// with the given ones in the request body
-func UpdateCategory (w http.ResponseWriter, r *http.Request) {
+// UpdateCategory gets a Category id from the request link and applies the JSON Merge Patch (RFC 7386)
+// from the request body to the corresponding Category, so only the fields given in the body are changed
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	//get category id from the link
 	categoryID := mux.Vars(r)["id"]
-	var updateCategory Category
 
 	//get the information containing in request's body
 	//or report an error
-	reqBody, err := ioutil.ReadAll(r.Body)
+	reqBody, err := api.ReadBody(r)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//find the Category, apply the patch to it and write it in one store transaction,
+	//so a change made by another request in between is not lost
+	//or report an error
+	var singleCategory Category
+	err = h.store.Transaction(func(tx store.CatalogStore) error {
+		stored, err := tx.Category(categoryID)
+		if err != nil {
+			return categoryError(err, Category{CategoryID: categoryID})
+		}
+		if err = api.PatchResource(&stored, reqBody); err != nil {
+			return api.BadRequest("The request body is not a valid merge patch: %v", err)
+		}
+		//the id is taken from the link only
+		stored.CategoryID = categoryID
+		singleCategory = stored
+		return writeCategory(tx, stored)
+	})
 	if err != nil {
-		fmt.Fprintf(w, "Kindly enter data with the category name and description only in order to update")
+		api.WriteError(w, r, err)
+		return
 	}
 
+	//return the Category in response
+	api.WriteJSON(w, http.StatusOK, singleCategory)
+}
+
+// ReplaceCategory gets a Category id from the request link and replaces the whole corresponding Category
+// with the one in the request body, the fields missing in the body are cleared
+func (h *Handler) ReplaceCategory(w http.ResponseWriter, r *http.Request) {
+	//get category id from the link
+	categoryID := mux.Vars(r)["id"]
+	var replaceCategory Category
+
 	//unmarshal the information from JSON into the Category instance
 	//or report an error
-	if err = json.Unmarshal(reqBody, &updateCategory); err != nil {
-		log.Printf("Body parse error, %v", err.Error())
-		w.WriteHeader(400)
+	if err := api.ReadJSON(r, &replaceCategory); err != nil {
+		api.WriteError(w, r, err)
 		return
 	}
+	//the id is taken from the link only
+	replaceCategory.CategoryID = categoryID
 
-	//find the given Category in the slice by id
-	for i, singleCategory := range Categories {
-		if singleCategory.CategoryID == categoryID {
-			//change the fields
-			singleCategory.CategoryName = updateCategory.CategoryName
-			singleCategory.CategoryDescription = updateCategory.CategoryDescription
-
-			Categories = append(Categories[:i], singleCategory)
-			//return the Category in response
-			//or report an error
-			if err = json.NewEncoder(w).Encode(singleCategory); err != nil {
-				log.Printf(err.Error())
-				w.WriteHeader(500)
-				return
+	h.saveCategory(w, r, replaceCategory)
+}
+
+// saveCategory validates the updated Category, writes it to the store and returns it in response
+func (h *Handler) saveCategory(w http.ResponseWriter, r *http.Request, updatedCategory Category) {
+	err := h.store.Transaction(func(tx store.CatalogStore) error {
+		return writeCategory(tx, updatedCategory)
+	})
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//return the Category in response
+	api.WriteJSON(w, http.StatusOK, updatedCategory)
+}
+
+// writeCategory checks the required fields of the updated Category and writes it to the store with updateCategory,
+// or returns the Problem of the invalid fields or of the failed write
+func writeCategory(s store.CatalogStore, updatedCategory Category) error {
+	if err := validate(updatedCategory); err != nil {
+		return err
+	}
+	return updateCategory(s, updatedCategory)
+}
+
+// updateCategory writes the updated Category to the store and checks whether the products of the category
+// and of its subcategories still match their attribute schema, which changes with the attributes and the parent
+// of the category. It is called in a store transaction, so nothing is stored if some products do not match.
+func updateCategory(s store.CatalogStore, updatedCategory Category) error {
+	if err := s.UpdateCategory(updatedCategory); err != nil {
+		return categoryError(err, updatedCategory)
+	}
+	page, err := s.ListProducts(store.ListQuery{CategoryID: updatedCategory.CategoryID, IncludeDescendants: true})
+	if err != nil {
+		return err
+	}
+	return api.CheckProducts(s, page.Items)
+}
+
+// validate returns a validation Problem if some of the Category fields are invalid
+func validate(c Category) error {
+	var fieldErrors []api.FieldError
+	//CategoryName is required field
+	if len(c.CategoryName) == 0 {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "CategoryName", Detail: "Kindly enter the category name"})
+	}
+	fieldErrors = append(fieldErrors, schemaErrors(c.Attributes)...)
+
+	if len(fieldErrors) != 0 {
+		return api.Validation(fieldErrors...)
+	}
+	return nil
+}
+
+// schemaErrors returns the errors of the attributes in the schema of a category
+func schemaErrors(schema []store.Attribute) []api.FieldError {
+	var fieldErrors []api.FieldError
+	names := make(map[string]bool, len(schema))
+	for i, a := range schema {
+		field := fmt.Sprintf("Attributes[%d]", i)
+		//the products refer to the attributes by name
+		switch {
+		case strings.TrimSpace(a.Name) == "":
+			fieldErrors = append(fieldErrors, api.FieldError{Field: field + ".Name", Detail: "Kindly enter the attribute name"})
+		case names[a.Name]:
+			fieldErrors = append(fieldErrors, api.FieldError{Field: field + ".Name", Detail: fmt.Sprintf("The category already has attribute %s", a.Name)})
+		}
+		names[a.Name] = true
+
+		switch a.Type {
+		case store.AttributeEnum:
+			if len(a.Values) == 0 {
+				fieldErrors = append(fieldErrors, api.FieldError{Field: field + ".Values", Detail: "Kindly enter the values of the enum attribute"})
+			}
+		case store.AttributeString, store.AttributeNumber, store.AttributeBool:
+			if len(a.Values) != 0 {
+				fieldErrors = append(fieldErrors, api.FieldError{Field: field + ".Values", Detail: "Only the enum attributes have values"})
 			}
+		default:
+			fieldErrors = append(fieldErrors, api.FieldError{Field: field + ".Type", Detail: "The type must be one of " + strings.Join(store.AttributeTypes, ", ")})
 		}
+		if a.Unit != "" && a.Type != store.AttributeNumber {
+			fieldErrors = append(fieldErrors, api.FieldError{Field: field + ".Unit", Detail: "Only the number attributes have units"})
+		}
+	}
+	return fieldErrors
+}
+
+// categoryError turns the store errors about the given category into Problems
+func categoryError(err error, c Category) error {
+	var inUse *store.CategoryInUseError
+	if errors.As(err, &inUse) {
+		p := api.Conflict("Category with ID %s still has %d products and %d subcategories",
+			c.CategoryID, inUse.Products, inUse.Subcategories)
+		p.Extensions = map[string]interface{}{"products": inUse.Products, "subcategories": inUse.Subcategories}
+		return p
+	}
+
+	switch err {
+	case store.ErrNotFound:
+		return api.NotFound("Category with ID %s not found", c.CategoryID)
+	case store.ErrAlreadyExists:
+		return api.Conflict("Category with ID %s already exists", c.CategoryID)
+	case store.ErrParentNotFound:
+		return api.Validation(api.FieldError{Field: "ParentID", Detail: fmt.Sprintf("Category with ID %q not found", c.ParentID)})
+	case store.ErrInvalidPolicy:
+		return api.BadRequest("The delete policy can not be applied to the category with ID %s", c.CategoryID)
+	case store.ErrCategoryCycle:
+		return api.Validation(api.FieldError{Field: "ParentID", Detail: "A category can not be put under itself or its subcategories"})
 	}
+	return err
 }
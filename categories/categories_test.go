@@ -4,7 +4,10 @@ package categories
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/products"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
 	"github.com/gorilla/mux"
+	"github.com/rs/xid"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
@@ -25,13 +28,23 @@ var categoryByIdTest = []struct {
 	},
 	{
 		"randomID",
-		`Category with ID randomID not found`,
-		412,
+		`{"type":"/problems/not-found","title":"Not Found","status":404,"detail":"Category with ID randomID not found","instance":"/categories/randomID"}`,
+		404,
 	},
 }
 
+//countCategories returns the number of categories in the given store
+func countCategories(t *testing.T, catalog store.CatalogStore) int {
+	allCategories, err := catalog.Categories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(allCategories)
+}
+
 //TestGetAllCategories tests whether GetAllCategories func returns the right response body and status
 func TestGetAllCategories(t *testing.T) {
+	catalog := store.NewMemoryStore()
 	//Create a request to pass to the handler
 	req, err := http.NewRequest("GET", "/categories", nil)
 	if err != nil {
@@ -39,7 +52,7 @@ func TestGetAllCategories(t *testing.T) {
 	}
 	//Create a ResponseRecorder to record the response
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(GetAllCategories)
+	handler := http.HandlerFunc(NewHandler(catalog).GetAllCategories)
 
 	handler.ServeHTTP(rr, req)
 
@@ -53,6 +66,7 @@ func TestGetAllCategories(t *testing.T) {
 //TestGetCategoryById tests whether GetCategoryById func returns the right response bodies and statuses
 //while iterating over categoryByIdTest
 func TestGetCategoryById(t *testing.T) {
+	catalog := store.NewMemoryStore()
 	for _, p := range categoryByIdTest {
 		//request url
 		path := "/categories/" + p.categoryId
@@ -64,19 +78,20 @@ func TestGetCategoryById(t *testing.T) {
 		}
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(GetCategoryById)
+		handler := http.HandlerFunc(NewHandler(catalog).GetCategoryById)
 
 		handler.ServeHTTP(rr, req)
 
 		assert.Equal(t, p.expectedCode, rr.Code, "OK response is expected")
-		assert.Equal(t, p.expected, strings.TrimSuffix(rr.Body.String(),"\n"), "Response body is expected to be equal to expected value")
+		assert.JSONEq(t, p.expected, rr.Body.String(), "Response body is expected to be equal to expected value")
 	}
 }
 
-//TestCreateCategory tests whether CreateCategory func returns the right status and actually appends a Category to []Categories
+//TestCreateCategory tests whether CreateCategory func returns the right status and actually appends a Category to the store
 func TestCreateCategory(t *testing.T) {
-	//initial length of []Categories
-	initialLen := len(Categories)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countCategories(t, catalog)
 	//parameters passed to request body
 	requestBody := &Category{
 		CategoryName: 		"Super Cool Category",
@@ -89,20 +104,21 @@ func TestCreateCategory(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateCategory)
+	handler := http.HandlerFunc(NewHandler(catalog).CreateCategory)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 201, rr.Code, "Created response is expected")
-	//the length of []Categories should increase after creating new category
-	assert.NotEqual(t, initialLen, len(Categories), "Expected length to increase after creating new Category")
+	//the length of the store should increase after creating new category
+	assert.NotEqual(t, initialLen, countCategories(t, catalog), "Expected length to increase after creating new Category")
 }
 
-//TestCreateCategoryEmptyBody tests whether CreateCategory func returns the right status and does not append a Category to []Categories
+//TestCreateCategoryEmptyBody tests whether CreateCategory func returns the right status and does not append a Category to the store
 //because of the empty request body
 func TestCreateCategoryEmptyBody (t *testing.T) {
-	//initial length of []Categories
-	initialLen := len(Categories)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countCategories(t, catalog)
 	//empty body
 	requestBody := &Category{}
 	jsonCategory, _ := json.Marshal(requestBody)
@@ -111,20 +127,21 @@ func TestCreateCategoryEmptyBody (t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateCategory)
+	handler := http.HandlerFunc(NewHandler(catalog).CreateCategory)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected")
-	//the length of []Categories should not change after trying to create new empty category
-	assert.Equal(t, initialLen, len(Categories), "Expected length to stay the same after adding empty category name")
+	//the length of the store should not change after trying to create new empty category
+	assert.Equal(t, initialLen, countCategories(t, catalog), "Expected length to stay the same after adding empty category name")
 }
 
-//TestCreateCategoryWrongJSONSyntax tests whether CreateCategory func returns the right status and does not append a Category to []Categories
+//TestCreateCategoryWrongJSONSyntax tests whether CreateCategory func returns the right status and does not append a Category to the store
 //because of the wrong syntax in JSON request body
 func TestCreateCategoryWrongJSONSyntax(t *testing.T) {
-	//initial length of []Categories
-	initialLen := len(Categories)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countCategories(t, catalog)
 	//parameters passed to request body
 	requestBody := `{{"CategoryID":"bq4fasj7jhfi127rimlg","CategoryName":"Name",,,}}`
 	req, err := http.NewRequest("POST", "/categories/new", bytes.NewBufferString(requestBody))
@@ -132,42 +149,45 @@ func TestCreateCategoryWrongJSONSyntax(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateCategory)
+	handler := http.HandlerFunc(NewHandler(catalog).CreateCategory)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 400, rr.Code, "Bad request response is expected")
-	assert.Equal(t, initialLen, len(Categories), "Expected length to stay the same after wrong syntax json")
+	assert.Equal(t, initialLen, countCategories(t, catalog), "Expected length to stay the same after wrong syntax json")
 
 }
 
-//TestDeleteCategory tests whether DeleteCategory func returns the right status and actually deletes a Category from []Categories
+//TestDeleteCategory tests whether DeleteCategory func returns the right status and actually deletes a Category from the store
 func TestDeleteCategory(t *testing.T) {
-	//initial length of []products
-	initialLen := len(Categories)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countCategories(t, catalog)
 
-	req, err := http.NewRequest("DELETE", "/categories/bq4fasj7jhfi127rimlg", nil)
-	req = mux.SetURLVars(req, map[string]string{"id": "bq4fasj7jhfi127rimlg"})
+	//the category without products
+	req, err := http.NewRequest("DELETE", "/categories/bq4fb3b7jhfi7v7uo39g", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4fb3b7jhfi7v7uo39g"})
 
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(DeleteCategory)
+	handler := http.HandlerFunc(NewHandler(catalog).DeleteCategory)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 200, rr.Code, "OK response is expected")
-	//the length of []Categories should decrease after deleting category
-	assert.NotEqual(t, initialLen, len(Categories), "Expected length to decrease after deleting new Category")
+	//the length of the store should decrease after deleting category
+	assert.NotEqual(t, initialLen, countCategories(t, catalog), "Expected length to decrease after deleting new Category")
 }
 
-//TestDeleteCategoryWrongID tests whether DeleteCategory func returns the right status and does not delete a Category from []Categories
+//TestDeleteCategoryWrongID tests whether DeleteCategory func returns the right status and does not delete a Category from the store
 //because the ID is wrong
 func TestDeleteCategoryWrongID(t *testing.T) {
-	//initial length of []products
-	initialLen := len(Categories)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countCategories(t, catalog)
 
 	req, err := http.NewRequest("DELETE", "/categories/randomID", nil)
 	req = mux.SetURLVars(req, map[string]string{"id": "randomID"})
@@ -177,19 +197,21 @@ func TestDeleteCategoryWrongID(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(DeleteCategory)
+	handler := http.HandlerFunc(NewHandler(catalog).DeleteCategory)
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, 412, rr.Code, "Precondition Failed response is expected")
-	//the length of []Categories should not change after trying to delete non-existing category
-	assert.Equal(t, initialLen, len(Categories), "Expected length to stay the same after creating new Category")
+	assert.Equal(t, 404, rr.Code, "Not Found response is expected")
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"), "Problem details are expected")
+	//the length of the store should not change after trying to delete non-existing category
+	assert.Equal(t, initialLen, countCategories(t, catalog), "Expected length to stay the same after creating new Category")
 }
 
-//TestUpdateCategory tests whether UpdateCategory func returns the right status and does not change []Categories, but updates fields
+//TestUpdateCategory tests whether UpdateCategory func returns the right status and does not change the store, but updates fields
 func TestUpdateCategory(t *testing.T) {
-	//initial length of []products
-	initialLen := len(Categories)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countCategories(t, catalog)
 	//parameters passed to request body
 	requestBody := &Category{
 		CategoryName: 		"Super Cool Category",
@@ -204,19 +226,20 @@ func TestUpdateCategory(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(UpdateCategory)
+	handler := http.HandlerFunc(NewHandler(catalog).UpdateCategory)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 200, rr.Code, "OK response is expected")
-	assert.Equal(t, initialLen, len(Categories), "Expected length to stay the same after creating new product")
+	assert.Equal(t, initialLen, countCategories(t, catalog), "Expected length to stay the same after creating new product")
 }
 
 //TestUpdateCategoryWrongJSONSyntax tests whether UpdateCategory func returns the right status and does update fields
 //because of the wrong syntax in JSON request body
 func TestUpdateCategoryWrongJSONSyntax(t *testing.T) {
-	//initial length of []products
-	initialLen := len(Categories)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countCategories(t, catalog)
 	//parameters passed to request body
 	requestBody := `{{"CategoryID":"bq4fasj7jhfi127rimlg","CategoryName":"Name",,,}}`
 	req, err := http.NewRequest("PATCH", "/categories/bq4fasj7jhfi127rimlg", bytes.NewBufferString(requestBody))
@@ -227,10 +250,455 @@ func TestUpdateCategoryWrongJSONSyntax(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(UpdateCategory)
+	handler := http.HandlerFunc(NewHandler(catalog).UpdateCategory)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 400, rr.Code, "Bad request response is expected")
-	assert.Equal(t, initialLen, len(Categories), "Expected length to stay the same after updating product")
-}
\ No newline at end of file
+	assert.Equal(t, initialLen, countCategories(t, catalog), "Expected length to stay the same after updating product")
+}
+//TestUpdateCategoryPartial tests whether UpdateCategory func changes only the fields given in the merge patch
+//and keeps all the other categories in the store
+func TestUpdateCategoryPartial(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	requestBody := `{"CategoryDescription":"Only the description is changed"}`
+	req, err := http.NewRequest("PATCH", "/categories/bq4fasj7jhfi127rimlg", bytes.NewBufferString(requestBody))
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4fasj7jhfi127rimlg"})
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).UpdateCategory)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	expected := `{"CategoryID":"bq4fasj7jhfi127rimlg","CategoryName":"Shopping Products","CategoryDescription":"Only the description is changed"}`
+	assert.JSONEq(t, expected, rr.Body.String(), "Expected only the description to change")
+	//the category after the updated one should still be there
+	_, err = catalog.Category("bq4fb3b7jhfi7v7uo39g")
+	assert.NoError(t, err, "Expected the other categories to stay in the store")
+}
+
+//TestUpdateCategoryNullName tests whether UpdateCategory func does not allow to remove the required name
+func TestUpdateCategoryNullName(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	requestBody := `{"CategoryName":null}`
+	req, err := http.NewRequest("PATCH", "/categories/bq4fasj7jhfi127rimlg", bytes.NewBufferString(requestBody))
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4fasj7jhfi127rimlg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).UpdateCategory)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected")
+	stored, _ := catalog.Category("bq4fasj7jhfi127rimlg")
+	assert.Equal(t, "Shopping Products", stored.CategoryName, "Expected the name to stay the same")
+}
+
+//TestUpdateCategoryWrongID tests whether UpdateCategory and ReplaceCategory funcs report an unknown id
+func TestUpdateCategoryWrongID(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, method := range []string{"PATCH", "PUT"} {
+		requestBody := `{"CategoryName":"Name"}`
+		req, err := http.NewRequest(method, "/categories/randomID", bytes.NewBufferString(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": "randomID"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).UpdateCategory)
+		if method == "PUT" {
+			handler = NewHandler(catalog).ReplaceCategory
+		}
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, 404, rr.Code, "Not Found response is expected for %s", method)
+		assert.Equal(t, 2, countCategories(t, catalog), "Expected length to stay the same after updating unknown category")
+	}
+}
+
+//TestReplaceCategory tests whether ReplaceCategory func replaces the whole category and clears the missing fields
+func TestReplaceCategory(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	requestBody := `{"CategoryID":"ignoredID","CategoryName":"Replaced Category"}`
+	req, err := http.NewRequest("PUT", "/categories/bq4fasj7jhfi127rimlg", bytes.NewBufferString(requestBody))
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4fasj7jhfi127rimlg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).ReplaceCategory)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	expected := Category{CategoryID: "bq4fasj7jhfi127rimlg", CategoryName: "Replaced Category"}
+	stored, _ := catalog.Category("bq4fasj7jhfi127rimlg")
+	assert.Equal(t, expected, stored, "Expected the description to be cleared and the id to stay the same")
+	assert.Equal(t, 2, countCategories(t, catalog), "Expected length to stay the same after replacing category")
+}
+
+//TestCreateCategoryExistingID tests whether CreateCategory func creates the category under a generated id
+//instead of the id in the body, so it neither overwrites a category nor breaks the created order
+func TestCreateCategoryExistingID(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	requestBody := `{"CategoryID":"bq4fasj7jhfi127rimlg","CategoryName":"Name"}`
+	req, err := http.NewRequest("POST", "/categories/new", bytes.NewBufferString(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).CreateCategory)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 201, rr.Code, "Created response is expected")
+	var created Category
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	_, err = xid.FromString(created.CategoryID)
+	assert.NoError(t, err, "Expected a generated id")
+	stored, _ := catalog.Category("bq4fasj7jhfi127rimlg")
+	assert.Equal(t, "Shopping Products", stored.CategoryName, "Expected the category to stay the same")
+}
+
+//TestGetAllCategoriesPaged tests whether GetAllCategories func sorts by name and links the next page
+func TestGetAllCategoriesPaged(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	req, err := http.NewRequest("GET", "/categories?sort=-name&limit=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).GetAllCategories)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, "2", rr.Header().Get("X-Total-Count"), "Expected the total number of categories")
+	assert.Contains(t, rr.Header().Get("Link"), `rel="next"`, "Expected the link to the next page")
+	var page []Category
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	if assert.Len(t, page, 1, "Expected one category on the page") {
+		assert.Equal(t, "Specialty Products", page[0].CategoryName, "Expected the last category by name")
+	}
+}
+
+//TestGetAllCategoriesWrongQuery tests whether GetAllCategories func rejects the product only parameters
+func TestGetAllCategoriesWrongQuery(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, query := range []string{"sort=price", "price_min=10", "attr.size=38"} {
+		req, err := http.NewRequest("GET", "/categories?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).GetAllCategories)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, 400, rr.Code, "Bad Request response is expected for %s", query)
+	}
+}
+
+//TestCategoryParent tests whether UpdateCategory func reports an unknown parent and a cycle in the ParentID field
+func TestCategoryParent(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	assert.NoError(t, catalog.CreateCategory(Category{CategoryID: "child", CategoryName: "Child", ParentID: "bq4fasj7jhfi127rimlg"}))
+	for _, p := range []struct {
+		requestBody string
+		expected    string
+	}{
+		{
+			`{"ParentID":"randomID"}`,
+			`[{"field":"ParentID","detail":"Category with ID \"randomID\" not found"}]`,
+		},
+		{
+			`{"ParentID":"child"}`,
+			`[{"field":"ParentID","detail":"A category can not be put under itself or its subcategories"}]`,
+		},
+	} {
+		req, err := http.NewRequest("PATCH", "/categories/bq4fasj7jhfi127rimlg", bytes.NewBufferString(p.requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": "bq4fasj7jhfi127rimlg"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).UpdateCategory)
+
+		handler.ServeHTTP(rr, req)
+
+		var problem struct {
+			Errors json.RawMessage `json:"errors"`
+		}
+		assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected for %s", p.requestBody)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.JSONEq(t, p.expected, string(problem.Errors), "Expected field errors for %s", p.requestBody)
+	}
+
+	//the parent is cleared with null
+	req, err := http.NewRequest("PATCH", "/categories/child", bytes.NewBufferString(`{"ParentID":null}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "child"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).UpdateCategory).ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.JSONEq(t, `{"CategoryID":"child","CategoryName":"Child","CategoryDescription":""}`, rr.Body.String(), "Expected a top-level category")
+}
+
+//TestCategoryTreeRoutes tests whether GetChildCategories, GetCategoryAncestors and GetCategoryTree funcs
+//return the nested categories
+func TestCategoryTreeRoutes(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	assert.NoError(t, catalog.CreateCategory(Category{CategoryID: "women", CategoryName: "Women", ParentID: "bq4fasj7jhfi127rimlg"}))
+	assert.NoError(t, catalog.CreateCategory(Category{CategoryID: "shoes", CategoryName: "Shoes", ParentID: "women"}))
+	handler := NewHandler(catalog)
+
+	for _, p := range []struct {
+		path         string
+		handler      http.HandlerFunc
+		expected     string
+		expectedCode int
+	}{
+		{
+			"/categories/women/children",
+			handler.GetChildCategories,
+			`[{"CategoryID":"shoes","CategoryName":"Shoes","CategoryDescription":"","ParentID":"women"}]`,
+			200,
+		},
+		{
+			"/categories/shoes/ancestors",
+			handler.GetCategoryAncestors,
+			`[{"CategoryID":"bq4fasj7jhfi127rimlg","CategoryName":"Shopping Products","CategoryDescription":"Products consumers purchase and consume on a less frequent schedule compared to convenience products."},` +
+				`{"CategoryID":"women","CategoryName":"Women","CategoryDescription":"","ParentID":"bq4fasj7jhfi127rimlg"}]`,
+			200,
+		},
+		{
+			"/categories/randomID/children",
+			handler.GetChildCategories,
+			`{"type":"/problems/not-found","title":"Not Found","status":404,"detail":"Category with ID randomID not found","instance":"/categories/randomID/children"}`,
+			404,
+		},
+		{
+			"/categories/tree",
+			handler.GetCategoryTree,
+			`[{"CategoryID":"bq4fasj7jhfi127rimlg","CategoryName":"Shopping Products","CategoryDescription":"Products consumers purchase and consume on a less frequent schedule compared to convenience products.","Children":[` +
+				`{"CategoryID":"women","CategoryName":"Women","CategoryDescription":"","ParentID":"bq4fasj7jhfi127rimlg","Children":[` +
+				`{"CategoryID":"shoes","CategoryName":"Shoes","CategoryDescription":"","ParentID":"women","Children":[]}]}]},` +
+				`{"CategoryID":"bq4fb3b7jhfi7v7uo39g","CategoryName":"Specialty Products","CategoryDescription":"Products that are more expensive relative to convenience and shopping products.","Children":[]}]`,
+			200,
+		},
+	} {
+		req, err := http.NewRequest("GET", p.path, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strings.Split(p.path, "/")[2]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+
+		p.handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.path)
+		assert.JSONEq(t, p.expected, rr.Body.String(), "Expected another body for %s", p.path)
+	}
+}
+
+//deleteCategory sends the DELETE request with the given query to DeleteCategory func
+func deleteCategory(t *testing.T, catalog store.CatalogStore, categoryID string, query string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("DELETE", "/categories/"+categoryID+query, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": categoryID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).DeleteCategory)
+
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+//productsOfCategory returns the body the products handler sends for GET /products/category/{id}
+func productsOfCategory(t *testing.T, catalog store.CatalogStore, categoryID string) string {
+	req, err := http.NewRequest("GET", "/products/category/"+categoryID, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": categoryID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(products.NewHandler(catalog).GetProductsOfCategory)
+
+	handler.ServeHTTP(rr, req)
+	return rr.Body.String()
+}
+
+//TestDeleteCategoryRestrict tests whether DeleteCategory func keeps a category with products and counts them
+func TestDeleteCategoryRestrict(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, query := range []string{"", "?policy=restrict"} {
+		rr := deleteCategory(t, catalog, "bq4fasj7jhfi127rimlg", query)
+
+		assert.Equal(t, 409, rr.Code, "Conflict response is expected for %q", query)
+		expected := `{"type":"/problems/conflict","title":"Conflict","status":409,` +
+			`"detail":"Category with ID bq4fasj7jhfi127rimlg still has 2 products and 0 subcategories",` +
+			`"instance":"/categories/bq4fasj7jhfi127rimlg","products":2,"subcategories":0}`
+		assert.JSONEq(t, expected, rr.Body.String(), "Expected the number of products in the problem details")
+		assert.Equal(t, 2, countCategories(t, catalog), "Expected the category to stay")
+	}
+}
+
+//TestDeleteCategoryCascade tests whether DeleteCategory func deletes the products of the category with it,
+//so the products handler does not find them any more
+func TestDeleteCategoryCascade(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	rr := deleteCategory(t, catalog, "bq4fasj7jhfi127rimlg", "?policy=cascade")
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, 1, countCategories(t, catalog), "Expected the category to be deleted")
+	assert.JSONEq(t, `[]`, productsOfCategory(t, catalog, "bq4fasj7jhfi127rimlg"), "Expected the products to be deleted")
+	allProducts, _ := catalog.Products()
+	assert.Empty(t, allProducts, "Expected the products to be deleted")
+}
+
+//TestDeleteCategoryReassign tests whether DeleteCategory func moves the products to the target category
+func TestDeleteCategoryReassign(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	rr := deleteCategory(t, catalog, "bq4fasj7jhfi127rimlg", "?policy=reassign&target=bq4fb3b7jhfi7v7uo39g")
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, 1, countCategories(t, catalog), "Expected the category to be deleted")
+	expected := `[{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Nike SuperRep Go","ProductDescription":"Women's Training Shoe","Price":{"Amount":10000,"Currency":"EUR","Formatted":"100.00 EUR"},"CategoryID":"bq4fb3b7jhfi7v7uo39g","Available":0,"OutOfStock":true},` +
+		`{"ProductID":"bq5457j7jhfi2s58o030","ProductName":"Nike Icon Clash","ProductDescription":"Women's Seamless Light-Support Sports Bra","Price":{"Amount":5000,"Currency":"EUR","Formatted":"50.00 EUR"},"CategoryID":"bq4fb3b7jhfi7v7uo39g","Available":0,"OutOfStock":true}]`
+	assert.JSONEq(t, expected, productsOfCategory(t, catalog, "bq4fb3b7jhfi7v7uo39g"), "Expected the products in the target category")
+}
+
+//TestDeleteCategoryWrongPolicy tests whether DeleteCategory func reports a policy which can not be applied
+//and leaves the category and its products as they were
+func TestDeleteCategoryWrongPolicy(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, query := range []string{
+		"?policy=orphan",
+		"?policy=reassign",
+		"?policy=reassign&target=bq4fasj7jhfi127rimlg",
+		"?policy=reassign&target=randomID",
+	} {
+		rr := deleteCategory(t, catalog, "bq4fasj7jhfi127rimlg", query)
+
+		assert.Equal(t, 400, rr.Code, "Bad Request response is expected for %s", query)
+		assert.Equal(t, 2, countCategories(t, catalog), "Expected the category to stay")
+		assert.Equal(t, 2, countProductsOfCategory(t, catalog, "bq4fasj7jhfi127rimlg"), "Expected the products to stay")
+	}
+}
+
+//countProductsOfCategory returns the number of products the products handler finds in the category
+func countProductsOfCategory(t *testing.T, catalog store.CatalogStore, categoryID string) int {
+	var found []store.Product
+	if err := json.Unmarshal([]byte(productsOfCategory(t, catalog, categoryID)), &found); err != nil {
+		t.Fatal(err)
+	}
+	return len(found)
+}
+
+//TestCategorySchemaErrors tests whether CreateCategory func reports the invalid attributes of the schema
+func TestCategorySchemaErrors(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, p := range []struct {
+		requestBody string
+		expected    string
+	}{
+		{
+			`{"CategoryName":"Footwear","Attributes":[{"Name":"size","Type":"enum"},{"Name":"size","Type":"string","Values":["38"]}]}`,
+			`[{"field":"Attributes[0].Values","detail":"Kindly enter the values of the enum attribute"},` +
+				`{"field":"Attributes[1].Name","detail":"The category already has attribute size"},` +
+				`{"field":"Attributes[1].Values","detail":"Only the enum attributes have values"}]`,
+		},
+		{
+			`{"CategoryName":"Electronics","Attributes":[{"Type":"number","Unit":"W"},{"Name":"colour","Type":"color","Unit":"RGB"}]}`,
+			`[{"field":"Attributes[0].Name","detail":"Kindly enter the attribute name"},` +
+				`{"field":"Attributes[1].Type","detail":"The type must be one of string, number, enum, bool"},` +
+				`{"field":"Attributes[1].Unit","detail":"Only the number attributes have units"}]`,
+		},
+	} {
+		req, err := http.NewRequest("POST", "/categories/new", bytes.NewBufferString(p.requestBody))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).CreateCategory)
+
+		handler.ServeHTTP(rr, req)
+
+		var problem struct {
+			Errors json.RawMessage `json:"errors"`
+		}
+		assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected for %s", p.requestBody)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.JSONEq(t, p.expected, string(problem.Errors), "Expected field errors for %s", p.requestBody)
+	}
+
+	allCategories, _ := catalog.Categories()
+	assert.Len(t, allCategories, 2, "Expected no category with an invalid schema to be created")
+}
+
+//TestCategorySchemaMismatch tests whether UpdateCategory, ReplaceCategory and DeleteCategory funcs keep the category
+//as it was and list the products which would not match the attribute schema of their category any more
+func TestCategorySchemaMismatch(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	c, _ := catalog.Category("bq4fasj7jhfi127rimlg")
+	c.Attributes = []store.Attribute{{Name: "size", Type: store.AttributeString}, {Name: "colour", Type: store.AttributeString}}
+	if err := catalog.UpdateCategory(c); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := catalog.Product("bq4foj37jhfipc5nqri0")
+	p.Attributes = map[string]interface{}{"size": "M"}
+	if err := catalog.UpdateProduct(p, "test"); err != nil {
+		t.Fatal(err)
+	}
+	err := catalog.CreateVariant(store.Variant{VariantID: "v1", ProductID: "bq5457j7jhfi2s58o030", SKU: "ICON-BLK", Attributes: map[string]string{"colour": "black"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		method     string
+		handler    func(*Handler) http.HandlerFunc
+		body       string
+		mismatched string
+	}{
+		{"PATCH", func(h *Handler) http.HandlerFunc { return h.UpdateCategory },
+			`{"Attributes":[{"Name":"size","Type":"number"},{"Name":"colour","Type":"string"}]}`, `["bq4foj37jhfipc5nqri0"]`},
+		{"PUT", func(h *Handler) http.HandlerFunc { return h.ReplaceCategory },
+			`{"CategoryName":"Shopping Products","Attributes":[{"Name":"size","Type":"string"}]}`, `["bq5457j7jhfi2s58o030"]`},
+		{"DELETE", func(h *Handler) http.HandlerFunc { return h.DeleteCategory },
+			"", `["bq4foj37jhfipc5nqri0","bq5457j7jhfi2s58o030"]`},
+	} {
+		req, err := http.NewRequest(test.method, "/categories/bq4fasj7jhfi127rimlg?policy=reassign&target=bq4fb3b7jhfi7v7uo39g", strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": "bq4fasj7jhfi127rimlg"})
+		rr := httptest.NewRecorder()
+		test.handler(NewHandler(catalog)).ServeHTTP(rr, req)
+
+		var problem struct {
+			Mismatched json.RawMessage `json:"mismatched_products"`
+		}
+		assert.Equal(t, 409, rr.Code, "Conflict response is expected for %s, body: %s", test.method, rr.Body.String())
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.JSONEq(t, test.mismatched, string(problem.Mismatched), "Expected the mismatched products for %s", test.method)
+		stored, err := catalog.Category("bq4fasj7jhfi127rimlg")
+		assert.NoError(t, err, "Expected the category to stay after %s", test.method)
+		assert.Equal(t, c, stored, "Expected the schema to stay after %s", test.method)
+		assert.Equal(t, 2, countProductsOfCategory(t, catalog, "bq4fasj7jhfi127rimlg"), "Expected the products to stay after %s", test.method)
+	}
+}
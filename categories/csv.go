@@ -0,0 +1,200 @@
+package categories
+
+import (
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/rs/xid"
+	"io"
+	"net/http"
+)
+
+// categoryColumns are the columns of the exported categories
+var categoryColumns = []string{"category_id", "category_name", "category_description", "parent_id", "parent_name"}
+
+// ExportCategories returns the categories as a CSV file with one row per category and the name of its parent.
+// The categories are sorted and filtered with the parameters read by api.ParseListQuery.
+func (h *Handler) ExportCategories(w http.ResponseWriter, r *http.Request) {
+	//read the sorting and filtering parameters
+	//or report an error
+	q, err := api.ParseListQuery(r, store.SortCreated, store.SortName)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//get the categories and the names of their parents
+	//or report an error
+	page, err := h.store.ListCategories(q)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	categories, err := h.store.Categories()
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	names := make(map[string]string, len(categories))
+	for _, c := range categories {
+		names[c.CategoryID] = c.CategoryName
+	}
+
+	rows := make([][]string, 0, len(page.Items))
+	for _, c := range page.Items {
+		rows = append(rows, []string{c.CategoryID, c.CategoryName, c.CategoryDescription, c.ParentID, names[c.ParentID]})
+	}
+
+	api.WriteCSV(w, "categories.csv", categoryColumns, rows)
+}
+
+// ImportCategories creates and updates the categories from the CSV file in the request body, in the columns
+// ExportCategories writes. A row with the category_id of a stored category updates the columns of the file only
+// and keeps the attribute schema, a row without category_id creates a category and a row with an unknown
+// category_id is reported.
+// The parent can be given by parent_id or by parent_name, also of a category created by an earlier row.
+// Every row is checked with the same rules as CreateCategory, and the invalid ones are reported without stopping
+// the import. Nothing is stored with dry_run=true. The summary is returned as JSON, or the failed rows
+// with their errors as a CSV file for a request accepting text/csv.
+func (h *Handler) ImportCategories(w http.ResponseWriter, r *http.Request) {
+	//read the header of the file
+	//or report an error
+	in, err := api.ReadCSV(r, isCategoryColumn)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//get the categories the parent columns refer to
+	//or report an error
+	categories, err := h.store.Categories()
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	rows := &categoryRows{h: h, names: store.NewCategoryNames(categories), ids: make(map[string]bool, len(categories))}
+	for _, c := range categories {
+		rows.ids[c.CategoryID] = true
+	}
+
+	result := api.NewImportResult(in)
+	for {
+		//read the next row
+		//or report an error
+		row, err := in.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			api.WriteError(w, r, err)
+			return
+		}
+		result.Rows++
+
+		//store the category of the row
+		//or report the failed row, or an error of the store
+		c, exists, err := rows.category(row)
+		if err == nil && !in.DryRun {
+			if exists {
+				err = h.store.Transaction(func(tx store.CatalogStore) error {
+					return updateCategory(tx, c)
+				})
+			} else {
+				err = categoryError(h.store.CreateCategory(c), c)
+			}
+		}
+		if problem, ok := err.(*api.Problem); ok {
+			result.Fail(row, c.CategoryID, problem, api.SnakeName)
+			continue
+		} else if err != nil {
+			api.WriteError(w, r, err)
+			return
+		}
+		//the later rows can name it as their parent
+		if exists {
+			result.Updated++
+			rows.names.Rename(c)
+		} else {
+			result.Created++
+			rows.names.Add(c)
+			rows.ids[c.CategoryID] = true
+		}
+	}
+
+	api.WriteImportResult(w, r, result, "categories-import-errors.csv")
+}
+
+// categoryRows turns the rows of an imported file into categories
+type categoryRows struct {
+	h     *Handler
+	names store.CategoryNames
+	// ids are the ids of the stored categories and of the ones created by the rows read so far
+	ids map[string]bool
+}
+
+// category returns the category of the row: the stored one with the columns of the row, or a new one,
+// and whether it is stored. A row which can not be imported is reported as a Problem.
+func (rows *categoryRows) category(row api.CSVRow) (Category, bool, error) {
+	//the category with the id of the row is updated
+	c, exists := Category{}, false
+	if id, _ := row.Value("category_id"); id != "" {
+		stored, err := rows.h.store.Category(id)
+		if err == store.ErrNotFound {
+			//an id which is not stored is most likely mistyped, the ids of the new categories are generated
+			return Category{CategoryID: id}, false, api.Validation(api.FieldError{Field: "CategoryID",
+				Detail: fmt.Sprintf("Category with ID %q not found, leave the category_id empty to create a category", id)})
+		} else if err != nil {
+			return Category{CategoryID: id}, false, err
+		}
+		c, exists = stored, true
+	} else {
+		//generate unique categoryID
+		c.CategoryID = xid.New().String()
+	}
+
+	var fieldErrors []api.FieldError
+	if name, ok := row.Value("category_name"); ok {
+		c.CategoryName = name
+	}
+	if description, ok := row.Value("category_description"); ok {
+		c.CategoryDescription = description
+	}
+
+	//a row with empty parent columns puts the category at the top level
+	parentID, hasID := row.Value("parent_id")
+	parentName, hasName := row.Value("parent_name")
+	switch {
+	case parentID != "":
+		c.ParentID = parentID
+		if !rows.ids[parentID] {
+			//the store reports it too, but not on a dry run
+			fieldErrors = append(fieldErrors, api.FieldError{Field: "ParentID", Detail: fmt.Sprintf("Category with ID %q not found", parentID)})
+		}
+	case parentName != "":
+		id, err := api.CategoryByName(rows.names, parentName, "ParentName")
+		if err != nil {
+			fieldErrors = append(fieldErrors, *err)
+		}
+		c.ParentID = id
+	case hasID || hasName:
+		c.ParentID = ""
+	}
+
+	//check the required fields
+	if err := validate(c); err != nil {
+		fieldErrors = append(fieldErrors, err.(*api.Problem).Errors...)
+	}
+	if len(fieldErrors) != 0 {
+		return c, exists, api.Validation(fieldErrors...)
+	}
+	return c, exists, nil
+}
+
+// isCategoryColumn reports whether the column is one of the columns of the categories CSV
+func isCategoryColumn(column string) bool {
+	for _, c := range categoryColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
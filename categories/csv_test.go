@@ -0,0 +1,91 @@
+//package categories contains test for csv.go
+package categories
+
+import (
+	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//importCategories sends the CSV file to ImportCategories and returns the summary of the import
+func importCategories(t *testing.T, catalog store.CatalogStore, query string, file string) api.ImportResult {
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).ImportCategories).ServeHTTP(rr, httptest.NewRequest("POST", "/categories/import"+query, strings.NewReader(file)))
+
+	var result api.ImportResult
+	assert.Equal(t, 200, rr.Code, "OK response is expected, body: %s", rr.Body.String())
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	return result
+}
+
+//TestExportCategories tests whether ExportCategories func writes the categories with the names of their parents as CSV
+func TestExportCategories(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	if err := catalog.CreateCategory(store.Category{CategoryID: "boots", CategoryName: "Boots", ParentID: "bq4fasj7jhfi127rimlg"}); err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).ExportCategories).ServeHTTP(rr, httptest.NewRequest("GET", "/categories/export?sort=-name", nil))
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, "attachment; filename=categories.csv", rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, "category_id,category_name,category_description,parent_id,parent_name\n"+
+		"bq4fb3b7jhfi7v7uo39g,Specialty Products,Products that are more expensive relative to convenience and shopping products.,,\n"+
+		"bq4fasj7jhfi127rimlg,Shopping Products,Products consumers purchase and consume on a less frequent schedule compared to convenience products.,,\n"+
+		"boots,Boots,,bq4fasj7jhfi127rimlg,Shopping Products\n", rr.Body.String())
+}
+
+//TestImportCategories tests whether ImportCategories func creates the categories under the parents named in the file,
+//also the ones created by the earlier rows, and updates the stored ones keeping their attributes
+func TestImportCategories(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	schema := []store.Attribute{{Name: "size", Type: store.AttributeString}}
+	if err := catalog.CreateCategory(store.Category{CategoryID: "footwear", CategoryName: "Footwear", Attributes: schema}); err != nil {
+		t.Fatal(err)
+	}
+	file := "category_id,category_name,category_description,parent_name\n" +
+		"footwear,Shoes,All the shoes,\n" +
+		",Boots,,shoes\n" +
+		",Hiking Boots,,Boots\n" +
+		",Sandals,,Summer\n" +
+		",,,\n" +
+		"garden,Garden,,\n"
+	result := importCategories(t, catalog, "", file)
+
+	assert.Equal(t, 6, result.Rows)
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, 1, result.Updated)
+	for i := range result.Errors {
+		result.Errors[i].ID = ""
+	}
+	assert.Equal(t, []api.RowError{
+		{Row: 5, Column: "parent_name", Detail: `Category named "Summer" not found`},
+		{Row: 6, Column: "category_name", Detail: "Kindly enter the category name"},
+		{Row: 7, Column: "category_id", Detail: `Category with ID "garden" not found, leave the category_id empty to create a category`},
+	}, result.Errors, "Expected the errors of the failed rows")
+
+	updated, err := catalog.Category("footwear")
+	assert.NoError(t, err)
+	assert.Equal(t, store.Category{CategoryID: "footwear", CategoryName: "Shoes", CategoryDescription: "All the shoes", Attributes: schema}, updated)
+	boots, err := catalog.ListCategories(store.ListQuery{NameContains: "Boots"})
+	assert.NoError(t, err)
+	if assert.Len(t, boots.Items, 2, "Expected the created categories") {
+		assert.Equal(t, "footwear", boots.Items[0].ParentID, "Expected the parent found by its new name")
+		assert.Equal(t, boots.Items[0].CategoryID, boots.Items[1].ParentID, "Expected the parent created by an earlier row")
+		assert.NotEqual(t, "garden", boots.Items[0].CategoryID, "Expected a generated id")
+	}
+	_, err = catalog.Category("garden")
+	assert.Equal(t, store.ErrNotFound, err, "Expected no category under the unknown id")
+
+	//nothing is stored on a dry run
+	count := countCategories(t, catalog)
+	result = importCategories(t, catalog, "?dry_run=true", "category_name,parent_id\nSandals,footwear\nClogs,garden\n")
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, []api.RowError{{Row: 3, ID: result.Errors[0].ID, Column: "parent_id", Detail: `Category with ID "garden" not found`}}, result.Errors)
+	assert.Equal(t, count, countCategories(t, catalog), "Expected no categories to be stored")
+}
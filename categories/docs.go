@@ -0,0 +1,84 @@
+package categories
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/openapi"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"net/http"
+)
+
+// Operations describes the category handlers for the OpenAPI document
+func Operations() openapi.Operations {
+	o := openapi.Operations{}
+	o.Add((*Handler).GetAllCategories, openapi.Operation{
+		Summary:    "Returns the page of the categories",
+		Parameters: openapi.ListParameters,
+		Response:   []Category{},
+		Paged:      true,
+		Errors:     []int{http.StatusBadRequest},
+	})
+	o.Add((*Handler).GetCategoryTree, openapi.Operation{
+		Summary:  "Returns all the categories with their subcategories nested in Children",
+		Response: []store.CategoryNode{},
+	})
+	o.Add((*Handler).ExportCategories, openapi.Operation{
+		Summary:    "Returns the categories as a CSV file with the names of their parents",
+		Parameters: openapi.ListParameters[2:],
+		Response:   openapi.CSV{},
+		Errors:     []int{http.StatusBadRequest},
+	})
+	o.Add((*Handler).ImportCategories, openapi.Operation{
+		Summary:    "Creates and updates the categories from the rows of a CSV file in the columns of the export",
+		Parameters: openapi.ImportParameters,
+		Request:    openapi.CSV{},
+		Response:   api.ImportResult{},
+		CSVReport:  true,
+		Errors:     []int{http.StatusBadRequest},
+	})
+	o.Add((*Handler).GetCategoryById, openapi.Operation{
+		Summary:  "Returns the category",
+		Response: Category{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).GetChildCategories, openapi.Operation{
+		Summary:  "Returns the categories directly under the category",
+		Response: []Category{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).GetCategoryAncestors, openapi.Operation{
+		Summary:  "Returns the categories the category is nested in, from the top-level one down",
+		Response: []Category{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).CreateCategory, openapi.Operation{
+		Summary:    "Creates the category",
+		Parameters: openapi.IdempotencyParameters,
+		Request:    Category{},
+		Status:     http.StatusCreated,
+		Response:   Category{},
+		Errors:     []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).DeleteCategory, openapi.Operation{
+		Summary: "Deletes the category, the policy says what happens to its products",
+		Parameters: []openapi.Parameter{
+			openapi.Query("policy", "string", "restrict (default) keeps the category while it has products, "+
+				"cascade deletes them and reassign moves them to the target category"),
+			openapi.Query("target", "string", "The category the products are moved to with the reassign policy"),
+		},
+		Response: "",
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+	})
+	o.Add((*Handler).UpdateCategory, openapi.Operation{
+		Summary:  "Changes the fields of the category given in the JSON Merge Patch",
+		Request:  Category{},
+		Response: Category{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).ReplaceCategory, openapi.Operation{
+		Summary:  "Replaces the whole category",
+		Request:  Category{},
+		Response: Category{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	return o
+}
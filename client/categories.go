@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// the policies of DeleteCategory for the products of the category
+const (
+	// DeleteRestrict refuses to delete a category which still has products, the default
+	DeleteRestrict = "restrict"
+	// DeleteCascade deletes the products together with the category
+	DeleteCascade = "cascade"
+	// DeleteReassign moves the products to the target category before deleting the category
+	DeleteReassign = "reassign"
+)
+
+// ListCategories returns one page of the categories
+func (c *Client) ListCategories(ctx context.Context, opts ListOptions) (*CategoryPage, error) {
+	page := &CategoryPage{}
+	resp, err := c.do(ctx, http.MethodGet, "/categories", opts.values(), nil, &page.Items)
+	if err != nil {
+		return nil, err
+	}
+	page.Total, page.Next = pageOf(resp.header)
+	return page, nil
+}
+
+// Categories returns the iterator of all the categories of the list, fetched a page at a time
+func (c *Client) Categories(opts ListOptions) *CategoryIterator {
+	if opts.Limit == 0 {
+		opts.Limit = iteratorPageSize
+	}
+	it := &CategoryIterator{}
+	it.cursor = opts.Cursor
+	it.next = func(ctx context.Context, cursor string) (string, error) {
+		opts.Cursor = cursor
+		page, err := c.ListCategories(ctx, opts)
+		if err != nil {
+			return "", err
+		}
+		it.items = page.Items
+		return page.Next, nil
+	}
+	return it
+}
+
+// CategoryTree returns the top-level categories with their subcategories nested in them
+func (c *Client) CategoryTree(ctx context.Context) ([]CategoryNode, error) {
+	var tree []CategoryNode
+	_, err := c.do(ctx, http.MethodGet, "/categories/tree", nil, nil, &tree)
+	return tree, err
+}
+
+// GetCategory returns the category with the id
+func (c *Client) GetCategory(ctx context.Context, id string) (*Category, error) {
+	category := &Category{}
+	if _, err := c.do(ctx, http.MethodGet, pathOf("categories", id), nil, nil, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// ChildCategories returns the categories directly under the category with the id
+func (c *Client) ChildCategories(ctx context.Context, id string) ([]Category, error) {
+	var children []Category
+	_, err := c.do(ctx, http.MethodGet, pathOf("categories", id, "children"), nil, nil, &children)
+	return children, err
+}
+
+// CategoryAncestors returns the categories the category with the id is nested in, from its parent to the top
+func (c *Client) CategoryAncestors(ctx context.Context, id string) ([]Category, error) {
+	var ancestors []Category
+	_, err := c.do(ctx, http.MethodGet, pathOf("categories", id, "ancestors"), nil, nil, &ancestors)
+	return ancestors, err
+}
+
+// CreateCategory creates the category and returns it with its new id
+func (c *Client) CreateCategory(ctx context.Context, category Category) (*Category, error) {
+	created := &Category{}
+	if _, err := c.do(ctx, http.MethodPost, "/categories/new", nil, category, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateCategory changes the fields of the category given in the JSON Merge Patch by their /v1 names,
+// e.g. map[string]interface{}{"category_description": "Running shoes"}, a nil value removes the field
+func (c *Client) UpdateCategory(ctx context.Context, id string, patch map[string]interface{}) (*Category, error) {
+	updated := &Category{}
+	if _, err := c.do(ctx, http.MethodPatch, pathOf("categories", id), nil, patch, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// ReplaceCategory replaces the whole category with the id of the given one
+func (c *Client) ReplaceCategory(ctx context.Context, category Category) (*Category, error) {
+	replaced := &Category{}
+	if _, err := c.do(ctx, http.MethodPut, pathOf("categories", category.CategoryID), nil, category, replaced); err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
+
+// DeleteCategory deletes the category with the id. The policy is DeleteRestrict if it is empty,
+// the target is the category the products are moved to with DeleteReassign.
+func (c *Client) DeleteCategory(ctx context.Context, id string, policy string, target string) error {
+	query := url.Values{}
+	if policy != "" {
+		query.Set("policy", policy)
+	}
+	if target != "" {
+		query.Set("target", target)
+	}
+	_, err := c.do(ctx, http.MethodDelete, pathOf("categories", id), query, nil, nil)
+	return err
+}
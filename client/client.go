@@ -0,0 +1,167 @@
+//package client is the typed Go client of the /v1 catalog API.
+//It only depends on the money package, so the services using it do not pull in the store and its SQLite driver.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// the media types of the catalog API
+const (
+	jsonContentType       = "application/json"
+	problemContentType    = "application/problem+json"
+	mergePatchContentType = "application/merge-patch+json"
+)
+
+// defaults of a new Client
+const (
+	defaultRetries = 3
+	defaultBackoff = 100 * time.Millisecond
+	defaultTimeout = 30 * time.Second
+)
+
+// Client calls the /v1 catalog API of one server. The fields can be changed before the first request.
+type Client struct {
+	// HTTPClient sends the requests
+	HTTPClient *http.Client
+	// Author is sent in the X-Author header, it is recorded by the catalog e.g. in the price history
+	Author string
+	// Retries is the number of times a request failing with 5xx or a network error is sent again.
+	// The POST requests are not retried, as they can create a resource more than once.
+	Retries int
+	// Backoff is the wait before the first retry, it doubles before every next one
+	Backoff time.Duration
+
+	baseURL *url.URL
+}
+
+// New returns the Client of the catalog served at the base URL, e.g. http://localhost:8080
+func New(baseURL string) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("client: the base URL must be absolute, e.g. http://localhost:8080")
+	}
+	return &Client{
+		HTTPClient: &http.Client{Timeout: defaultTimeout},
+		Retries:    defaultRetries,
+		Backoff:    defaultBackoff,
+		baseURL:    u,
+	}, nil
+}
+
+// response is a successful response with its body read
+type response struct {
+	header http.Header
+	body   []byte
+}
+
+// do sends the request to the /v1 path with the JSON of in as the body if it is not nil,
+// decodes the JSON response body into out if it is not nil and returns the response.
+// A response which has not succeeded is returned as an *Error.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, in interface{}, out interface{}) (*response, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+
+	link := c.baseURL.String() + "/v1" + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, link, body)
+		retry := attempt < c.Retries && method != http.MethodPost
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return nil, ctx.Err()
+		case err != nil && !retry:
+			return nil, err
+		case err == nil && resp.status >= http.StatusInternalServerError && retry:
+		case err == nil && resp.status >= http.StatusBadRequest:
+			return nil, responseError(resp.status, resp.contentType, resp.body)
+		case err == nil:
+			if out != nil && len(resp.body) > 0 && resp.contentType != "text/plain" {
+				if err = json.Unmarshal(resp.body, out); err != nil {
+					return nil, err
+				}
+			}
+			return &response{header: resp.header, body: resp.body}, nil
+		}
+
+		//wait before sending the request again
+		//or give up when the context is done
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// sentResponse is the response of one attempt
+type sentResponse struct {
+	status      int
+	contentType string
+	header      http.Header
+	body        []byte
+}
+
+// send sends the request once and reads the whole response
+func (c *Client) send(ctx context.Context, method string, link string, body []byte) (*sentResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", jsonContentType)
+	if body != nil {
+		contentType := jsonContentType
+		if method == http.MethodPatch {
+			contentType = mergePatchContentType
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Author != "" {
+		req.Header.Set("X-Author", c.Author)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return &sentResponse{status: resp.StatusCode, contentType: contentType, header: resp.Header, body: data}, nil
+}
+
+// pathOf joins the segments into a link, escaping every one of them, e.g. /products/{id}/prices
+func pathOf(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return "/" + strings.Join(escaped, "/")
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//newTestClient returns the Client of the test server with a short backoff
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c, err := New(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Backoff = time.Millisecond
+	return c
+}
+
+//TestRetries tests whether the requests failing with 5xx are sent again, except the POST ones
+func TestRetries(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"category_id":"c1","category_name":"Shoes"}`)
+	})
+
+	category, err := c.GetCategory(context.Background(), "c1")
+	assert.NoError(t, err)
+	assert.Equal(t, &Category{CategoryID: "c1", CategoryName: "Shoes"}, category, "Expected the category of the third attempt")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls), "Expected two retries")
+
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.CreateCategory(context.Background(), Category{CategoryName: "Shoes"})
+	assert.True(t, errors.Is(err, ErrServer), "Expected the server error, got %v", err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "Expected a POST request not to be sent again")
+
+	atomic.StoreInt32(&calls, -10)
+	c.Retries = 2
+	err = c.DeleteProduct(context.Background(), "p1")
+	assert.True(t, errors.Is(err, ErrServer), "Expected the server error, got %v", err)
+	assert.Equal(t, int32(-7), atomic.LoadInt32(&calls), "Expected the request to be sent 1+Retries times")
+}
+
+//TestRetryCanceled tests whether the wait before a retry stops when the context is done
+func TestRetryCanceled(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	c.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.CategoryTree(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err, "Expected the context error")
+}
+
+//TestErrors tests whether the problem details are returned as an *Error of the kind of the problem
+func TestErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/categories/c1":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"type":"/problems/conflict","title":"Conflict","status":409,"detail":"Category with ID c1 still has 2 products and 0 subcategories",`+
+				`"instance":"/v1/categories/c1","products":2,"subcategories":0}`)
+		case "/v1/products/new":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"type":"/problems/validation","title":"Unprocessable Entity","status":422,"detail":"The request body contains invalid fields",`+
+				`"errors":[{"field":"price.amount","detail":"The price can not be negative"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	err := c.DeleteCategory(context.Background(), "c1", "", "")
+	var e *Error
+	if assert.True(t, errors.As(err, &e), "Expected an *Error, got %v", err) {
+		assert.True(t, errors.Is(err, ErrConflict), "Expected a conflict")
+		assert.False(t, errors.Is(err, ErrNotFound), "Expected only a conflict")
+		assert.Equal(t, map[string]interface{}{"products": 2.0, "subcategories": 0.0}, e.Extensions, "Expected the extensions")
+		assert.Equal(t, "catalog: Conflict: Category with ID c1 still has 2 products and 0 subcategories", err.Error())
+	}
+
+	_, err = c.CreateProduct(context.Background(), Product{ProductName: "Boot"})
+	if assert.True(t, errors.As(err, &e), "Expected an *Error, got %v", err) {
+		assert.True(t, errors.Is(err, ErrValidation), "Expected a validation error")
+		assert.Equal(t, []FieldError{{Field: "price.amount", Detail: "The price can not be negative"}}, e.Errors)
+	}
+
+	//a response without problem details is mapped by its status
+	_, err = c.GetProduct(context.Background(), "p1", "", "")
+	assert.True(t, errors.Is(err, ErrNotFound), "Expected not found, got %v", err)
+}
+
+//TestListOptions tests whether the options are sent as the query parameters and the page is read from the headers
+func TestListOptions(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/products/category/c%201", r.URL.EscapedPath(), "Expected the escaped category id")
+		assert.Equal(t, "attr.size=38&cursor=abc&descendants=true&limit=2&price_min=100&sort=-price", r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total-Count", "5")
+		w.Header().Add("Link", `</v1/products/c%201>; rel="successor-version"`)
+		w.Header().Add("Link", `</v1/products/category/c%201?cursor=def&limit=2>; rel="next"`)
+		fmt.Fprint(w, `[{"product_id":"p1","price":{"amount":100,"currency":"EUR","formatted":"1.00 EUR"},"available":3}]`)
+	})
+
+	priceMin := int64(100)
+	page, err := c.ListProducts(context.Background(), ProductListOptions{
+		ListOptions: ListOptions{Limit: 2, Cursor: "abc", Sort: "price", Desc: true},
+		CategoryID:  "c 1", Descendants: true, PriceMin: &priceMin, Attributes: map[string]string{"size": "38"},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 5, page.Total, "Expected the total count")
+		assert.Equal(t, "def", page.Next, "Expected the cursor of the next page")
+		assert.Equal(t, int64(3), page.Items[0].Available, "Expected the listed product")
+		assert.Equal(t, "EUR", page.Items[0].Price.Currency, "Expected the price")
+	}
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// the kinds of the errors the catalog reports, check them with errors.Is, e.g. errors.Is(err, client.ErrNotFound)
+var (
+	// ErrBadRequest is a request the catalog can not read, e.g. invalid JSON or an unknown sort order
+	ErrBadRequest = errors.New("bad request")
+	// ErrNotFound is an unknown id
+	ErrNotFound = errors.New("not found")
+	// ErrNotAcceptable is a request which accepts none of the media types of the response
+	ErrNotAcceptable = errors.New("not acceptable")
+	// ErrConflict is a request conflicting with the current state of the catalog, e.g. a duplicate name
+	ErrConflict = errors.New("conflict")
+	// ErrValidation is a request body with invalid fields, they are listed in Error.Errors
+	ErrValidation = errors.New("validation failed")
+	// ErrServer is an error of the server, reported after the retries have run out
+	ErrServer = errors.New("server error")
+)
+
+// problemKinds are the errors of the problem types sent by the catalog
+var problemKinds = map[string]error{
+	"/problems/bad-request":    ErrBadRequest,
+	"/problems/not-found":      ErrNotFound,
+	"/problems/not-acceptable": ErrNotAcceptable,
+	"/problems/conflict":       ErrConflict,
+	"/problems/validation":     ErrValidation,
+	"/problems/internal":       ErrServer,
+}
+
+// statusKinds are the errors of the responses without problem details, by their status
+var statusKinds = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusNotAcceptable:       ErrNotAcceptable,
+	http.StatusConflict:            ErrConflict,
+	http.StatusUnprocessableEntity: ErrValidation,
+}
+
+// FieldError describes why one field of the request body is invalid, the field is given by its /v1 path, e.g. price.amount
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// Error is the RFC 7807 problem details the catalog reports an error with
+type Error struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// Extensions are the additional members of the problem details, e.g. the products blocking a category
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// Error describes the problem
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return "catalog: " + e.Title
+	}
+	return "catalog: " + e.Title + ": " + e.Detail
+}
+
+// Is reports whether the problem is of the kind of the target, e.g. ErrNotFound
+func (e *Error) Is(target error) bool {
+	kind, ok := problemKinds[e.Type]
+	if !ok {
+		kind, ok = statusKinds[e.Status]
+	}
+	if !ok && e.Status >= http.StatusInternalServerError {
+		kind, ok = ErrServer, true
+	}
+	return ok && kind == target
+}
+
+// UnmarshalJSON reads the problem details with the members which are not standard into Extensions
+func (e *Error) UnmarshalJSON(data []byte) error {
+	//standard has the same fields without this method
+	type standard Error
+	if err := json.Unmarshal(data, (*standard)(e)); err != nil {
+		return err
+	}
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, name := range []string{"type", "title", "status", "detail", "instance", "errors"} {
+		delete(members, name)
+	}
+	if len(members) > 0 {
+		e.Extensions = members
+	}
+	return nil
+}
+
+// responseError returns the error of the response which has not succeeded, read from its problem details if it has them
+func responseError(status int, contentType string, body []byte) error {
+	e := &Error{}
+	if contentType != problemContentType || json.Unmarshal(body, e) != nil || e.Status == 0 {
+		e = &Error{Title: http.StatusText(status), Status: status, Detail: strings.TrimSpace(string(body))}
+	}
+	return e
+}
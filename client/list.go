@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// iteratorPageSize is the number of items an iterator asks for at once when the options do not give a limit
+const iteratorPageSize = 100
+
+// nextLink finds the link to the next page in the Link header
+var nextLink = regexp.MustCompile(`<([^>]*)>;\s*rel="next"`)
+
+// ListOptions page, sort and filter a list
+type ListOptions struct {
+	// Limit is the page size, all the items are returned on one page if it is 0
+	Limit int
+	// Cursor is the position to continue from, taken from the Next of the previous page
+	Cursor string
+	// Sort is created (the default), name or, for the products, price, which needs the Currency of ProductListOptions
+	Sort string
+	// Desc sorts in the descending order
+	Desc bool
+	// NameContains keeps the items with the text in their name, ignoring case
+	NameContains string
+}
+
+// values returns the query parameters of the options
+func (o ListOptions) values() url.Values {
+	query := url.Values{}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		query.Set("cursor", o.Cursor)
+	}
+	if o.Sort != "" || o.Desc {
+		sort := o.Sort
+		if sort == "" {
+			sort = "created"
+		}
+		if o.Desc {
+			sort = "-" + sort
+		}
+		query.Set("sort", sort)
+	}
+	if o.NameContains != "" {
+		query.Set("name_contains", o.NameContains)
+	}
+	return query
+}
+
+// ProductListOptions page, sort and filter a list of products
+type ProductListOptions struct {
+	ListOptions
+	// CategoryID keeps the products of the category
+	CategoryID string
+	// Descendants also keeps the products of all the subcategories of CategoryID
+	Descendants bool
+	// Currency keeps the products priced in the currency, e.g. EUR
+	Currency string
+	// PriceMin and PriceMax keep the products priced from and up to the amounts in minor units
+	PriceMin *int64
+	PriceMax *int64
+	// Attributes keep the products with the attribute values, e.g. {"size":"38"}
+	Attributes map[string]string
+}
+
+// path returns the link of the list
+func (o ProductListOptions) path() string {
+	if o.CategoryID == "" {
+		return "/products"
+	}
+	return pathOf("products", "category", o.CategoryID)
+}
+
+// values returns the query parameters of the options
+func (o ProductListOptions) values() url.Values {
+	query := o.ListOptions.values()
+	if o.CategoryID != "" && o.Descendants {
+		query.Set("descendants", "true")
+	}
+	o.filters(query)
+	return query
+}
+
+// filters adds the parameters filtering the products, the ones which are read by GetFacets too
+func (o ProductListOptions) filters(query url.Values) {
+	if o.NameContains != "" {
+		query.Set("name_contains", o.NameContains)
+	}
+	if o.Currency != "" {
+		query.Set("currency", o.Currency)
+	}
+	if o.PriceMin != nil {
+		query.Set("price_min", strconv.FormatInt(*o.PriceMin, 10))
+	}
+	if o.PriceMax != nil {
+		query.Set("price_max", strconv.FormatInt(*o.PriceMax, 10))
+	}
+	for name, value := range o.Attributes {
+		query.Set("attr."+name, value)
+	}
+}
+
+// pageOf returns the total number of items and the cursor of the next page of the list response
+func pageOf(header http.Header) (total int, next string) {
+	total, _ = strconv.Atoi(header.Get("X-Total-Count"))
+	for _, link := range header.Values("Link") {
+		if match := nextLink.FindStringSubmatch(link); match != nil {
+			if u, err := url.Parse(match[1]); err == nil {
+				return total, u.Query().Get("cursor")
+			}
+		}
+	}
+	return total, ""
+}
+
+// CategoryPage is one page of a list of categories
+type CategoryPage struct {
+	Items []Category
+	// Total is the number of the matching categories on all the pages
+	Total int
+	// Next is the cursor of the next page, empty on the last page
+	Next string
+}
+
+// ProductPage is one page of a list of products
+type ProductPage struct {
+	Items []ListedProduct
+	// Total is the number of the matching products on all the pages
+	Total int
+	// Next is the cursor of the next page, empty on the last page
+	Next string
+}
+
+// pager fetches the pages of a list one after another
+type pager struct {
+	next func(ctx context.Context, cursor string) (next string, err error)
+	// cursor is the position of the next page, started is set after the first page
+	cursor  string
+	started bool
+	err     error
+}
+
+// fetch gets the next page, it returns false after the last page or an error
+func (p *pager) fetch(ctx context.Context) bool {
+	if p.err != nil || p.started && p.cursor == "" {
+		return false
+	}
+	p.started = true
+	p.cursor, p.err = p.next(ctx, p.cursor)
+	return p.err == nil
+}
+
+// CategoryIterator goes through all the categories of a list, fetching the pages when they are needed:
+//
+//	it := c.Categories(client.ListOptions{Sort: "name"})
+//	for it.Next(ctx) {
+//		fmt.Println(it.Category().CategoryName)
+//	}
+//	if err := it.Err(); err != nil {
+type CategoryIterator struct {
+	pager
+	items   []Category
+	current Category
+}
+
+// Next moves to the next category, it returns false at the end of the list or on an error
+func (it *CategoryIterator) Next(ctx context.Context) bool {
+	for len(it.items) == 0 {
+		if !it.fetch(ctx) {
+			return false
+		}
+	}
+	it.current, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+// Category returns the category Next has moved to
+func (it *CategoryIterator) Category() Category {
+	return it.current
+}
+
+// Err returns the error which has stopped the iterator
+func (it *CategoryIterator) Err() error {
+	return it.err
+}
+
+// ProductIterator goes through all the products of a list, fetching the pages when they are needed
+type ProductIterator struct {
+	pager
+	items   []ListedProduct
+	current ListedProduct
+}
+
+// Next moves to the next product, it returns false at the end of the list or on an error
+func (it *ProductIterator) Next(ctx context.Context) bool {
+	for len(it.items) == 0 {
+		if !it.fetch(ctx) {
+			return false
+		}
+	}
+	it.current, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+// Product returns the product Next has moved to
+func (it *ProductIterator) Product() ListedProduct {
+	return it.current
+}
+
+// Err returns the error which has stopped the iterator
+func (it *ProductIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListProducts returns one page of the products, of the whole catalog or of the category of the options
+func (c *Client) ListProducts(ctx context.Context, opts ProductListOptions) (*ProductPage, error) {
+	page := &ProductPage{}
+	resp, err := c.do(ctx, http.MethodGet, opts.path(), opts.values(), nil, &page.Items)
+	if err != nil {
+		return nil, err
+	}
+	page.Total, page.Next = pageOf(resp.header)
+	return page, nil
+}
+
+// Products returns the iterator of all the products of the list, fetched a page at a time
+func (c *Client) Products(opts ProductListOptions) *ProductIterator {
+	if opts.Limit == 0 {
+		opts.Limit = iteratorPageSize
+	}
+	it := &ProductIterator{}
+	it.cursor = opts.Cursor
+	it.next = func(ctx context.Context, cursor string) (string, error) {
+		opts.Cursor = cursor
+		page, err := c.ListProducts(ctx, opts)
+		if err != nil {
+			return "", err
+		}
+		it.items = page.Items
+		return page.Next, nil
+	}
+	return it
+}
+
+// SearchProducts returns up to limit products matching the text, the most relevant first, 20 if limit is 0
+func (c *Client) SearchProducts(ctx context.Context, text string, limit int) ([]ProductMatch, error) {
+	query := url.Values{"q": {text}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var matches []ProductMatch
+	_, err := c.do(ctx, http.MethodGet, "/products/search", query, nil, &matches)
+	return matches, err
+}
+
+// ProductFacets counts the products matching the filters of the options by their attribute values,
+// price buckets of priceStep minor units (50 major units of the currency if it is 0) and subcategories.
+// The products are taken from the category of the options and all its subcategories, or from the whole catalog.
+func (c *Client) ProductFacets(ctx context.Context, opts ProductListOptions, priceStep int64) (*Facets, error) {
+	query := url.Values{}
+	if opts.CategoryID != "" {
+		query.Set("category", opts.CategoryID)
+	}
+	if priceStep > 0 {
+		query.Set("price_step", strconv.FormatInt(priceStep, 10))
+	}
+	opts.filters(query)
+	facets := &Facets{}
+	if _, err := c.do(ctx, http.MethodGet, "/products/facets", query, nil, facets); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// GetProduct returns the product with the id. With a currency or a price list the price is resolved in them
+// and the stored price is returned in BasePrice.
+func (c *Client) GetProduct(ctx context.Context, id string, currency string, priceList string) (*PricedProduct, error) {
+	query := url.Values{}
+	if currency != "" {
+		query.Set("currency", currency)
+	}
+	if priceList != "" {
+		query.Set("pricelist", priceList)
+	}
+	product := &PricedProduct{}
+	if _, err := c.do(ctx, http.MethodGet, pathOf("products", id), query, nil, product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// CreateProduct creates the product and returns it with its new id
+func (c *Client) CreateProduct(ctx context.Context, product Product) (*Product, error) {
+	created := &Product{}
+	if _, err := c.do(ctx, http.MethodPost, "/products/new", nil, product, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateProduct changes the fields of the product given in the JSON Merge Patch by their /v1 names,
+// e.g. map[string]interface{}{"price": map[string]interface{}{"amount": 7500}}, a nil value removes the field
+func (c *Client) UpdateProduct(ctx context.Context, id string, patch map[string]interface{}) (*Product, error) {
+	updated := &Product{}
+	if _, err := c.do(ctx, http.MethodPatch, pathOf("products", id), nil, patch, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// ReplaceProduct replaces the whole product with the id of the given one
+func (c *Client) ReplaceProduct(ctx context.Context, product Product) (*Product, error) {
+	replaced := &Product{}
+	if _, err := c.do(ctx, http.MethodPut, pathOf("products", product.ProductID), nil, product, replaced); err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
+
+// DeleteProduct deletes the product with the id
+func (c *Client) DeleteProduct(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, pathOf("products", id), nil, nil, nil)
+	return err
+}
+
+// ProductPrices returns the prices the product with the id has had and the scheduled ones, ordered by EffectiveAt
+func (c *Client) ProductPrices(ctx context.Context, id string) ([]PriceChange, error) {
+	var timeline []PriceChange
+	_, err := c.do(ctx, http.MethodGet, pathOf("products", id, "prices"), nil, nil, &timeline)
+	return timeline, err
+}
+
+// SchedulePriceChange schedules the future price of the product with the id and returns the scheduled changes:
+// the price and, with Until, the price the product has before it coming back
+func (c *Client) SchedulePriceChange(ctx context.Context, id string, schedule PriceSchedule) ([]PriceChange, error) {
+	var changes []PriceChange
+	_, err := c.do(ctx, http.MethodPost, pathOf("products", id, "prices"), nil, schedule, &changes)
+	return changes, err
+}
+
+// CancelPriceChange cancels the scheduled price change of the product
+func (c *Client) CancelPriceChange(ctx context.Context, id string, changeID string) error {
+	_, err := c.do(ctx, http.MethodDelete, pathOf("products", id, "prices", changeID), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"time"
+)
+
+// Money is an amount in the minor units of its currency, e.g. {1050 EUR} is 10.50 EUR.
+// A Money can be converted to it, e.g. client.Money(money.New(1050, "EUR")).
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// String formats the amount with the decimal places of its currency, e.g. "10.50 EUR"
+func (m Money) String() string {
+	return money.New(m.Amount, m.Currency).String()
+}
+
+// Category is a category of the catalog as it is served under /v1
+type Category struct {
+	CategoryID          string `json:"category_id,omitempty"`
+	CategoryName        string `json:"category_name"`
+	CategoryDescription string `json:"category_description"`
+	// ParentID is the id of the category this one is nested in, empty for a top-level category
+	ParentID string `json:"parent_id,omitempty"`
+	// Attributes is the schema of the attributes of the products, the subcategories inherit it
+	Attributes []Attribute `json:"attributes,omitempty"`
+}
+
+// Attribute describes an attribute the products of a category have
+type Attribute struct {
+	Name string `json:"name"`
+	// Type is one of string, number, enum and bool
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// Unit is what the number is measured in, e.g. "W"
+	Unit string `json:"unit,omitempty"`
+	// Values are the allowed values of an enum attribute
+	Values []string `json:"values,omitempty"`
+}
+
+// CategoryNode is a category with its subcategories
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
+}
+
+// Product is a product of the catalog as it is served under /v1
+type Product struct {
+	ProductID          string `json:"product_id,omitempty"`
+	ProductName        string `json:"product_name"`
+	ProductDescription string `json:"product_description"`
+	Price              Money  `json:"price"`
+	CategoryID         string `json:"category_id"`
+	// Attributes are the values of the attributes in the schema of the category, e.g. {"size":"38","waterproof":true}
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// ListedProduct is a product on a product list with its availability
+type ListedProduct struct {
+	Product
+	// Available is the number of pieces of the product and its variants which can still be reserved
+	Available  int64 `json:"available"`
+	OutOfStock bool  `json:"out_of_stock"`
+	// PriceRange is the lowest and the highest price of the variants, nil for a product without variants
+	PriceRange *PriceRange `json:"price_range,omitempty"`
+}
+
+// PriceRange is the lowest and the highest price of the variants of a product
+type PriceRange struct {
+	Min Money `json:"min"`
+	Max Money `json:"max"`
+}
+
+// PricedProduct is a product with its price resolved in the requested currency and price list
+type PricedProduct struct {
+	Product
+	// BasePrice is the stored price of the product, it is only set when the price has been resolved
+	BasePrice Money  `json:"base_price"`
+	PriceList string `json:"price_list,omitempty"`
+}
+
+// ProductMatch is a product found by SearchProducts with its relevance
+type ProductMatch struct {
+	Product
+	Score float64 `json:"score"`
+}
+
+// PriceChange is a price a product has had or is scheduled to get
+type PriceChange struct {
+	ChangeID    string    `json:"change_id"`
+	ProductID   string    `json:"product_id"`
+	Price       Money     `json:"price"`
+	EffectiveAt time.Time `json:"effective_at"`
+	// Author is who changed or scheduled the price
+	Author     string    `json:"author"`
+	RecordedAt time.Time `json:"recorded_at"`
+	// Status is applied or scheduled
+	Status string `json:"status"`
+	// Reverts is the ChangeID of the scheduled change whose price this change takes back
+	Reverts string `json:"reverts,omitempty"`
+}
+
+// PriceSchedule is a future price of a product
+type PriceSchedule struct {
+	Price Money `json:"price"`
+	// EffectiveAt is when the product gets the price
+	EffectiveAt time.Time `json:"effective_at"`
+	// Until is when the product gets back the price it had before EffectiveAt, never if it is nil
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// Facets are the counts of the products matching the filters, broken down to refine the filters further
+type Facets struct {
+	Total      int              `json:"total"`
+	Attributes []AttributeFacet `json:"attributes"`
+	Prices     []PriceBucket    `json:"prices"`
+	Categories []CategoryCount  `json:"categories"`
+}
+
+// AttributeFacet counts the products with every value of the attribute
+type AttributeFacet struct {
+	Name   string       `json:"name"`
+	Type   string       `json:"type,omitempty"`
+	Unit   string       `json:"unit,omitempty"`
+	Values []ValueCount `json:"values"`
+}
+
+// ValueCount is the number of products with the attribute value
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// PriceBucket is the number of products priced from Min to Max, both ends included
+type PriceBucket struct {
+	Min   Money `json:"min"`
+	Max   Money `json:"max"`
+	Count int   `json:"count"`
+}
+
+// CategoryCount is the number of products in the subcategory and all its own subcategories
+type CategoryCount struct {
+	CategoryID   string `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	Count        int    `json:"count"`
+}
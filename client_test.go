@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/client"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/pricing"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+//TestClient tests whether the client package can call every category and product operation of the routes in main.go
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(newRouter(store.NewMemoryStore(), pricing.NewBook(), api.NewIdempotencyKeys(time.Hour)))
+	defer server.Close()
+	c, err := client.New(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Author = "erp-sync"
+	ctx := context.Background()
+
+	//categories
+	shoes, err := c.CreateCategory(ctx, client.Category{CategoryName: "Shoes", CategoryDescription: "All the shoes",
+		Attributes: []client.Attribute{{Name: "size", Type: "enum", Values: []string{"38", "39"}}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotEmpty(t, shoes.CategoryID, "Expected the new category id")
+	boots, err := c.CreateCategory(ctx, client.Category{CategoryName: "Boots", ParentID: shoes.CategoryID})
+	assert.NoError(t, err)
+	_, err = c.CreateCategory(ctx, client.Category{})
+	var problem *client.Error
+	if assert.True(t, errors.As(err, &problem), "Expected the problem details, got %v", err) {
+		assert.True(t, errors.Is(err, client.ErrValidation), "Expected a validation error")
+		assert.Equal(t, "category_name", problem.Errors[0].Field, "Expected the /v1 field path")
+	}
+
+	got, err := c.GetCategory(ctx, boots.CategoryID)
+	assert.NoError(t, err)
+	assert.Equal(t, boots, got, "Expected the created category")
+	_, err = c.GetCategory(ctx, "unknown")
+	assert.True(t, errors.Is(err, client.ErrNotFound), "Expected not found, got %v", err)
+
+	children, err := c.ChildCategories(ctx, shoes.CategoryID)
+	assert.NoError(t, err)
+	assert.Equal(t, []client.Category{*boots}, children, "Expected the subcategory")
+	ancestors, err := c.CategoryAncestors(ctx, boots.CategoryID)
+	assert.NoError(t, err)
+	assert.Equal(t, []client.Category{*shoes}, ancestors, "Expected the parent")
+	tree, err := c.CategoryTree(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, tree, 3, "Expected the seed categories and the new one at the top")
+
+	updated, err := c.UpdateCategory(ctx, boots.CategoryID, map[string]interface{}{"category_description": "Winter boots"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Winter boots", updated.CategoryDescription, "Expected the patched description")
+	updated.CategoryName = "Tall boots"
+	replaced, err := c.ReplaceCategory(ctx, *updated)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, replaced, "Expected the replaced category")
+
+	var names []string
+	categories := c.Categories(client.ListOptions{Limit: 1, Sort: "name"})
+	for categories.Next(ctx) {
+		names = append(names, categories.Category().CategoryName)
+	}
+	assert.NoError(t, categories.Err())
+	assert.Equal(t, []string{"Shoes", "Shopping Products", "Specialty Products", "Tall boots"}, names, "Expected all the pages")
+
+	//products
+	var created []*client.Product
+	for i, size := range []string{"38", "39", "38"} {
+		p, err := c.CreateProduct(ctx, client.Product{ProductName: "Runner " + size, Price: client.Money(money.New(int64(5000+i*1000), "EUR")),
+			CategoryID: shoes.CategoryID, Attributes: map[string]interface{}{"size": size}})
+		if !assert.NoError(t, err) {
+			return
+		}
+		created = append(created, p)
+	}
+	_, err = c.CreateProduct(ctx, client.Product{ProductName: "Runner", Price: client.Money{Amount: -1, Currency: "EUR"}, CategoryID: shoes.CategoryID})
+	if assert.True(t, errors.As(err, &problem), "Expected the problem details, got %v", err) {
+		assert.Equal(t, "price.amount", problem.Errors[0].Field, "Expected the /v1 field path")
+	}
+
+	product, err := c.GetProduct(ctx, created[0].ProductID, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, *created[0], product.Product, "Expected the created product")
+
+	priceMin := int64(6000)
+	page, err := c.ListProducts(ctx, client.ProductListOptions{ListOptions: client.ListOptions{Limit: 1},
+		CategoryID: shoes.CategoryID, PriceMin: &priceMin})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, page.Total, "Expected the products from 60 EUR")
+		assert.NotEmpty(t, page.Next, "Expected the cursor of the next page")
+	}
+	var ids []string
+	products := c.Products(client.ProductListOptions{ListOptions: client.ListOptions{Limit: 2},
+		Attributes: map[string]string{"size": "38"}})
+	for products.Next(ctx) {
+		ids = append(ids, products.Product().ProductID)
+	}
+	assert.NoError(t, products.Err())
+	assert.Equal(t, []string{created[0].ProductID, created[2].ProductID}, ids, "Expected the products of size 38")
+
+	matches, err := c.SearchProducts(ctx, "runer", 0)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 3, "Expected the products with the typo")
+	facets, err := c.ProductFacets(ctx, client.ProductListOptions{CategoryID: shoes.CategoryID}, 10000)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, facets.Total, "Expected the products of the category")
+		assert.Equal(t, []client.ValueCount{{Value: "38", Count: 2}, {Value: "39", Count: 1}}, facets.Attributes[0].Values, "Expected the sizes")
+	}
+
+	patched, err := c.UpdateProduct(ctx, created[1].ProductID, map[string]interface{}{"product_description": "Light"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Light", patched.ProductDescription, "Expected the patched description")
+	patched.ProductName = "Racer"
+	replacedProduct, err := c.ReplaceProduct(ctx, *patched)
+	assert.NoError(t, err)
+	assert.Equal(t, patched, replacedProduct, "Expected the replaced product")
+
+	//prices
+	effectiveAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	changes, err := c.SchedulePriceChange(ctx, created[0].ProductID, client.PriceSchedule{Price: client.Money{Amount: 4000, Currency: "EUR"}, EffectiveAt: effectiveAt})
+	if assert.NoError(t, err) && assert.Len(t, changes, 1) {
+		assert.Equal(t, "erp-sync", changes[0].Author, "Expected the author of the client")
+		assert.True(t, effectiveAt.Equal(changes[0].EffectiveAt), "Expected the scheduled time")
+		timeline, err := c.ProductPrices(ctx, created[0].ProductID)
+		assert.NoError(t, err)
+		assert.Len(t, timeline, 2, "Expected the applied and the scheduled price")
+		assert.NoError(t, c.CancelPriceChange(ctx, created[0].ProductID, changes[0].ChangeID))
+		err = c.CancelPriceChange(ctx, created[0].ProductID, changes[0].ChangeID)
+		assert.True(t, errors.Is(err, client.ErrNotFound), "Expected the canceled change not to be found, got %v", err)
+	}
+
+	//deletes
+	err = c.DeleteCategory(ctx, shoes.CategoryID, "", "")
+	if assert.True(t, errors.As(err, &problem), "Expected the problem details, got %v", err) {
+		assert.True(t, errors.Is(err, client.ErrConflict), "Expected a conflict")
+		assert.Equal(t, 3.0, problem.Extensions["products"], "Expected the number of the blocking products")
+	}
+	assert.NoError(t, c.DeleteProduct(ctx, created[0].ProductID))
+	assert.NoError(t, c.DeleteCategory(ctx, boots.CategoryID, "", ""))
+	assert.NoError(t, c.DeleteCategory(ctx, shoes.CategoryID, client.DeleteCascade, ""))
+	_, err = c.GetProduct(ctx, created[1].ProductID, "", "")
+	assert.True(t, errors.Is(err, client.ErrNotFound), "Expected the product to be deleted with its category, got %v", err)
+}
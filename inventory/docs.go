@@ -0,0 +1,46 @@
+package inventory
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/openapi"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"net/http"
+)
+
+// Operations describes the stock handlers for the OpenAPI document
+func Operations() openapi.Operations {
+	o := openapi.Operations{}
+	o.Add((*Handler).GetStock, openapi.Operation{
+		Summary:  "Returns the pieces in every warehouse and in total",
+		Response: stock{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).GetStockAdjustments, openapi.Operation{
+		Summary:  "Returns the changes of the quantity with their authors",
+		Response: []store.StockAdjustment{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).AdjustStock, openapi.Operation{
+		Summary:  "Changes the quantity in the warehouse and returns the new stock level",
+		Request:  adjustmentRequest{},
+		Response: store.StockLevel{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).ReserveStock, openapi.Operation{
+		Summary:  "Holds the pieces for checkout until the reservation expires",
+		Request:  reservationRequest{},
+		Status:   http.StatusCreated,
+		Response: store.Reservation{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).ReleaseReservation, openapi.Operation{
+		Summary:  "Releases the reserved pieces",
+		Response: "",
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).CommitReservation, openapi.Operation{
+		Summary:  "Sells the reserved pieces and returns the adjustment",
+		Response: store.StockAdjustment{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	return o
+}
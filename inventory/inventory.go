@@ -0,0 +1,301 @@
+//package inventory contains the methods for tracking the stock of the products in the warehouses
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/gorilla/mux"
+	"github.com/rs/xid"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultTTL is how long a reservation holds its pieces when the request does not give TTLSeconds
+const defaultTTL = 15 * time.Minute
+
+// maxTTL is the longest a reservation can hold its pieces
+const maxTTL = 24 * time.Hour
+
+// stock is the response body of GetStock
+type stock struct {
+	ProductID string `json:"ProductID"`
+	VariantID string `json:"VariantID,omitempty"`
+	// Total is the stock summed over all the warehouses
+	Total      store.StockLevel   `json:"Total"`
+	Warehouses []store.StockLevel `json:"Warehouses"`
+}
+
+// adjustmentRequest is the request body of AdjustStock
+type adjustmentRequest struct {
+	WarehouseID string `json:"WarehouseID"`
+	// Delta is the number of pieces added, negative for the removed ones
+	Delta  int64  `json:"Delta"`
+	Reason string `json:"Reason"`
+	Note   string `json:"Note"`
+}
+
+// reservationRequest is the request body of ReserveStock
+type reservationRequest struct {
+	// WarehouseID is where the pieces are taken from, any warehouse with enough of them if it is empty
+	WarehouseID string `json:"WarehouseID"`
+	Quantity    int64  `json:"Quantity"`
+	// TTLSeconds is how long the reservation holds the pieces, defaultTTL if it is 0
+	TTLSeconds int64 `json:"TTLSeconds"`
+}
+
+// Handler contains all the stock handlers and the store they work with
+type Handler struct {
+	store store.CatalogStore
+}
+
+// NewHandler returns a Handler which reads and writes the stock through the given store
+func NewHandler(s store.CatalogStore) *Handler {
+	return &Handler{store: s}
+}
+
+// GetStock gets a product id and, on the variant links, a variant id from the request link
+// and returns the stock of the product or the variant in every warehouse and in total
+func (h *Handler) GetStock(w http.ResponseWriter, r *http.Request) {
+	//get product id and variant id from the link
+	productID, variantID := mux.Vars(r)["id"], mux.Vars(r)["variantID"]
+
+	//find the stock of the product in the store
+	//or report an error
+	levels, err := h.store.Stock(productID, variantID)
+	if err != nil {
+		api.WriteError(w, r, stockError(err, mux.Vars(r)))
+		return
+	}
+
+	//sum the stock of all the warehouses
+	total := store.StockLevel{}
+	for _, level := range levels {
+		total.OnHand += level.OnHand
+		total.Reserved += level.Reserved
+		total.Available += level.Available
+	}
+	api.WriteJSON(w, http.StatusOK, stock{ProductID: productID, VariantID: variantID, Total: total, Warehouses: levels})
+}
+
+// GetStockAdjustments gets a product id and, on the variant links, a variant id from the request link
+// and returns the stock adjustments of the product or the variant in the order they were made
+func (h *Handler) GetStockAdjustments(w http.ResponseWriter, r *http.Request) {
+	//get product id and variant id from the link
+	productID, variantID := mux.Vars(r)["id"], mux.Vars(r)["variantID"]
+
+	//find the adjustments of the product in the store
+	//or report an error
+	adjustments, err := h.store.StockAdjustments(productID, variantID)
+	if err != nil {
+		api.WriteError(w, r, stockError(err, mux.Vars(r)))
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, adjustments)
+}
+
+// AdjustStock gets a product id and, on the variant links, a variant id from the request link and changes
+// the quantity of the product or the variant in the warehouse by the Delta in the request body. The Reason tells why, the received and returned pieces are added,
+// the sold, damaged and lost ones are removed and a count corrects the quantity either way.
+// The new stock level of the warehouse is returned in response.
+func (h *Handler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	//get product id and variant id from the link
+	productID, variantID := mux.Vars(r)["id"], mux.Vars(r)["variantID"]
+	var request adjustmentRequest
+
+	//unmarshal the information from JSON into the adjustment instance
+	//or report an error
+	if err := api.ReadJSON(r, &request); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//check the warehouse, the delta and the reason
+	if err := validateAdjustment(request); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//change the quantity in the store
+	//or report an error
+	level, err := h.store.AdjustStock(store.StockAdjustment{
+		AdjustmentID: xid.New().String(),
+		ProductID:    productID,
+		VariantID:    variantID,
+		WarehouseID:  request.WarehouseID,
+		Delta:        request.Delta,
+		Reason:       request.Reason,
+		Note:         request.Note,
+		Author:       api.Author(r),
+		At:           time.Now().UTC(),
+	})
+	if err != nil {
+		api.WriteError(w, r, stockError(err, mux.Vars(r)))
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, level)
+}
+
+// ReserveStock gets a product id and, on the variant links, a variant id from the request link and holds
+// the Quantity of the product or the variant in the request body for TTLSeconds, so nobody else can reserve the pieces. The reservation is released automatically
+// when it expires, and is returned in response.
+func (h *Handler) ReserveStock(w http.ResponseWriter, r *http.Request) {
+	//get product id and variant id from the link
+	productID, variantID := mux.Vars(r)["id"], mux.Vars(r)["variantID"]
+	var request reservationRequest
+
+	//unmarshal the information from JSON into the reservation instance
+	//or report an error
+	if err := api.ReadJSON(r, &request); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//check the quantity and the time to live
+	if err := validateReservation(request); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	ttl := defaultTTL
+	if request.TTLSeconds != 0 {
+		ttl = time.Duration(request.TTLSeconds) * time.Second
+	}
+
+	//hold the pieces in the store
+	//or report an error
+	now := time.Now().UTC()
+	reservation, err := h.store.ReserveStock(store.Reservation{
+		ReservationID: xid.New().String(),
+		ProductID:     productID,
+		VariantID:     variantID,
+		WarehouseID:   request.WarehouseID,
+		Quantity:      request.Quantity,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(ttl),
+	})
+	if err != nil {
+		api.WriteError(w, r, stockError(err, mux.Vars(r)))
+		return
+	}
+
+	//return the reservation in response
+	api.WriteJSON(w, http.StatusCreated, reservation)
+}
+
+// ReleaseReservation gets a product id and a reservation id from the request link
+// and releases the pieces held by the reservation
+func (h *Handler) ReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	//get product id and reservation id from the link
+	productID, reservationID := mux.Vars(r)["id"], mux.Vars(r)["reservationID"]
+
+	//remove the reservation from the store
+	//or report an error
+	if err := h.store.ReleaseReservation(productID, reservationID); err != nil {
+		api.WriteError(w, r, stockError(err, mux.Vars(r)))
+		return
+	}
+	fmt.Fprintf(w, "The reservation with ID %v has been released successfully", reservationID)
+}
+
+// CommitReservation gets a product id and a reservation id from the request link and takes the pieces
+// held by the reservation out of the warehouse as sold. The adjustment is returned in response.
+func (h *Handler) CommitReservation(w http.ResponseWriter, r *http.Request) {
+	//get product id and reservation id from the link
+	productID, reservationID := mux.Vars(r)["id"], mux.Vars(r)["reservationID"]
+
+	//sell the reserved pieces
+	//or report an error
+	adjustment, err := h.store.CommitReservation(productID, reservationID, api.Author(r))
+	if err != nil {
+		api.WriteError(w, r, stockError(err, mux.Vars(r)))
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, adjustment)
+}
+
+// validateAdjustment returns a validation Problem if some of the adjustment fields are invalid
+func validateAdjustment(a adjustmentRequest) error {
+	var fieldErrors []api.FieldError
+	//every quantity is kept in some warehouse
+	if strings.TrimSpace(a.WarehouseID) == "" {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "WarehouseID", Detail: "Kindly enter the warehouse ID"})
+	}
+	direction, ok := store.ReasonDirections[a.Reason]
+	switch {
+	case !ok:
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "Reason", Detail: "The reason must be one of " + reasons()})
+	case a.Delta == 0:
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "Delta", Detail: "Kindly enter the number of pieces added or removed"})
+	case direction > 0 && a.Delta < 0:
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "Delta", Detail: fmt.Sprintf("The %s pieces can only be added", a.Reason)})
+	case direction < 0 && a.Delta > 0:
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "Delta", Detail: fmt.Sprintf("The %s pieces can only be removed", a.Reason)})
+	}
+
+	if len(fieldErrors) != 0 {
+		return api.Validation(fieldErrors...)
+	}
+	return nil
+}
+
+// validateReservation returns a validation Problem if the quantity or the time to live are invalid
+func validateReservation(r reservationRequest) error {
+	var fieldErrors []api.FieldError
+	if r.Quantity <= 0 {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "Quantity", Detail: "Kindly enter the number of pieces to reserve"})
+	}
+	if r.TTLSeconds < 0 || r.TTLSeconds > int64(maxTTL/time.Second) {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "TTLSeconds", Detail: fmt.Sprintf("The reservation can be held for up to %d seconds", int64(maxTTL/time.Second))})
+	}
+
+	if len(fieldErrors) != 0 {
+		return api.Validation(fieldErrors...)
+	}
+	return nil
+}
+
+// reasons returns the reason codes of the adjustments sorted and separated by commas
+func reasons() string {
+	codes := make([]string, 0, len(store.ReasonDirections))
+	for code := range store.ReasonDirections {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return strings.Join(codes, ", ")
+}
+
+// stockError turns the store errors about the stock of the product, the variant and the reservation
+// with the ids in the link vars into Problems, ErrNotFound is about the reservation if its id is given
+func stockError(err error, vars map[string]string) error {
+	productID, variantID, reservationID := vars["id"], vars["variantID"], vars["reservationID"]
+	item := "product " + productID
+	if variantID != "" {
+		item = "variant " + variantID + " of product " + productID
+	}
+
+	var insufficient *store.InsufficientStockError
+	if errors.As(err, &insufficient) {
+		p := api.Conflict("Only %d pieces of %s are available in warehouse %s",
+			insufficient.Available, item, insufficient.WarehouseID)
+		if insufficient.WarehouseID == "" {
+			p = api.Conflict("Not enough pieces of %s are available in any warehouse", item)
+		}
+		p.Extensions = map[string]interface{}{"available": insufficient.Available}
+		return p
+	}
+
+	if err == store.ErrNotFound && reservationID != "" {
+		return api.NotFound("Active reservation with ID %s of product %s not found", reservationID, productID)
+	} else if err == store.ErrNotFound {
+		return api.NotFound("Product with ID %s not found", productID)
+	} else if err == store.ErrVariantNotFound {
+		return api.NotFound("Variant with ID %s of product %s not found", variantID, productID)
+	}
+	return err
+}
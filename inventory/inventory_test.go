@@ -0,0 +1,134 @@
+//package inventory contains test for inventory.go
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//serve sends the request with the product id and the reservation id in the link vars to the handler func
+func serve(t *testing.T, handler http.HandlerFunc, method string, vars map[string]string, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, "/products/"+vars["id"]+"/stock", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, vars)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+//TestAdjustStock tests whether AdjustStock func changes the quantity and reports the missing pieces
+func TestAdjustStock(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	adjust := NewHandler(catalog).AdjustStock
+	for _, p := range []struct {
+		productID    string
+		requestBody  string
+		expected     string
+		expectedCode int
+	}{
+		{"bq4foj37jhfipc5nqri0", `{"WarehouseID":"tallinn","Delta":5,"Reason":"received"}`, `{"WarehouseID":"tallinn","OnHand":5,"Reserved":0,"Available":5}`, 200},
+		{"bq4foj37jhfipc5nqri0", `{"WarehouseID":"tallinn","Delta":-2,"Reason":"damaged","Note":"wet box"}`, `{"WarehouseID":"tallinn","OnHand":3,"Reserved":0,"Available":3}`, 200},
+		{"bq4foj37jhfipc5nqri0", `{"WarehouseID":"tallinn","Delta":-4,"Reason":"count"}`,
+			`{"type":"/problems/conflict","title":"Conflict","status":409,"detail":"Only 3 pieces of product bq4foj37jhfipc5nqri0 are available in warehouse tallinn","instance":"/products/bq4foj37jhfipc5nqri0/stock","available":3}`, 409},
+		{"randomID", `{"WarehouseID":"tallinn","Delta":1,"Reason":"received"}`,
+			`{"type":"/problems/not-found","title":"Not Found","status":404,"detail":"Product with ID randomID not found","instance":"/products/randomID/stock"}`, 404},
+	} {
+		rr := serve(t, adjust, "POST", map[string]string{"id": p.productID}, p.requestBody)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.requestBody)
+		assert.JSONEq(t, p.expected, rr.Body.String(), "Expected another body for %s", p.requestBody)
+	}
+
+	rr := serve(t, NewHandler(catalog).GetStockAdjustments, "GET", map[string]string{"id": "bq4foj37jhfipc5nqri0"}, "")
+	var adjustments []store.StockAdjustment
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &adjustments))
+	if assert.Len(t, adjustments, 2, "Expected only the applied adjustments") {
+		assert.Equal(t, "anonymous", adjustments[1].Author)
+		assert.Equal(t, "wet box", adjustments[1].Note)
+	}
+}
+
+//TestAdjustStockFieldErrors tests whether AdjustStock func reports the invalid fields of the adjustment
+func TestAdjustStockFieldErrors(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, p := range []struct {
+		requestBody string
+		expected    string
+	}{
+		{`{"Delta":1,"Reason":"received"}`, `[{"field":"WarehouseID","detail":"Kindly enter the warehouse ID"}]`},
+		{`{"WarehouseID":"tallinn","Delta":1,"Reason":"found"}`, `[{"field":"Reason","detail":"The reason must be one of count, damaged, lost, received, returned, sold"}]`},
+		{`{"WarehouseID":"tallinn","Reason":"count"}`, `[{"field":"Delta","detail":"Kindly enter the number of pieces added or removed"}]`},
+		{`{"WarehouseID":"tallinn","Delta":-1,"Reason":"returned"}`, `[{"field":"Delta","detail":"The returned pieces can only be added"}]`},
+		{`{"WarehouseID":"tallinn","Delta":1,"Reason":"lost"}`, `[{"field":"Delta","detail":"The lost pieces can only be removed"}]`},
+	} {
+		rr := serve(t, NewHandler(catalog).AdjustStock, "POST", map[string]string{"id": "bq4foj37jhfipc5nqri0"}, p.requestBody)
+
+		var problem struct {
+			Errors json.RawMessage `json:"errors"`
+		}
+		assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected for %s", p.requestBody)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.JSONEq(t, p.expected, string(problem.Errors), "Expected field errors for %s", p.requestBody)
+	}
+	adjustments, _ := catalog.StockAdjustments("bq4foj37jhfipc5nqri0", "")
+	assert.Empty(t, adjustments, "Expected nothing to be adjusted")
+}
+
+//TestReservations tests whether ReserveStock, ReleaseReservation and CommitReservation funcs
+//hold, release and sell the pieces
+func TestReservations(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	if _, err := catalog.AdjustStock(store.StockAdjustment{AdjustmentID: "a1", ProductID: "bq4foj37jhfipc5nqri0", WarehouseID: "tallinn", Delta: 5, Reason: store.ReasonReceived}); err != nil {
+		t.Fatal(err)
+	}
+	reserve := NewHandler(catalog).ReserveStock
+	product := map[string]string{"id": "bq4foj37jhfipc5nqri0"}
+
+	for _, p := range []struct {
+		requestBody  string
+		expectedCode int
+	}{
+		{`{"Quantity":0}`, 422},
+		{`{"Quantity":1,"TTLSeconds":86401}`, 422},
+		{`{"Quantity":6}`, 409},
+		{`{"Quantity":1,"WarehouseID":"riga"}`, 409},
+	} {
+		rr := serve(t, reserve, "POST", product, p.requestBody)
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.requestBody)
+	}
+
+	var released, committed store.Reservation
+	rr := serve(t, reserve, "POST", product, `{"Quantity":2,"TTLSeconds":60}`)
+	assert.Equal(t, 201, rr.Code, "Created response is expected")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &released))
+	assert.Equal(t, "tallinn", released.WarehouseID, "Expected the warehouse with the pieces")
+	assert.Equal(t, int64(60), int64(released.ExpiresAt.Sub(released.CreatedAt).Seconds()), "Expected the time to live")
+	rr = serve(t, reserve, "POST", product, `{"Quantity":3,"WarehouseID":"tallinn"}`)
+	assert.Equal(t, 201, rr.Code, "Created response is expected")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &committed))
+
+	release := NewHandler(catalog).ReleaseReservation
+	for _, expectedCode := range []int{200, 404} {
+		rr = serve(t, release, "DELETE", map[string]string{"id": "bq4foj37jhfipc5nqri0", "reservationID": released.ReservationID}, "")
+		assert.Equal(t, expectedCode, rr.Code, "Expected another status")
+	}
+
+	commit := NewHandler(catalog).CommitReservation
+	rr = serve(t, commit, "POST", map[string]string{"id": "bq4foj37jhfipc5nqri0", "reservationID": committed.ReservationID}, "")
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	rr = serve(t, commit, "POST", map[string]string{"id": "bq4foj37jhfipc5nqri0", "reservationID": committed.ReservationID}, "")
+	assert.Equal(t, 404, rr.Code, "Expected the reservation to be committed once")
+
+	rr = serve(t, NewHandler(catalog).GetStock, "GET", product, "")
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.JSONEq(t, `{"ProductID":"bq4foj37jhfipc5nqri0","Total":{"OnHand":2,"Reserved":0,"Available":2},"Warehouses":[{"WarehouseID":"tallinn","OnHand":2,"Reserved":0,"Available":2}]}`,
+		rr.Body.String(), "Expected the sold pieces to be gone")
+}
@@ -2,33 +2,188 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/bulk"
 	"github.com/KseniiaL/AdcashTestAssignment/categories"
+	"github.com/KseniiaL/AdcashTestAssignment/inventory"
+	"github.com/KseniiaL/AdcashTestAssignment/openapi"
+	"github.com/KseniiaL/AdcashTestAssignment/pricelists"
+	"github.com/KseniiaL/AdcashTestAssignment/pricing"
 	"github.com/KseniiaL/AdcashTestAssignment/products"
+	"github.com/KseniiaL/AdcashTestAssignment/scheduler"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/KseniiaL/AdcashTestAssignment/variants"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
+	"os"
+	"time"
 )
 
 func homeLink(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Welcome home!")
 }
 
-func main() {
+// the unversioned routes are deprecated in favour of the /v1 ones and are going to be removed at the sunset
+var (
+	legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	legacySunsetAt     = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// newRouter registers all the routes with the handlers working on the given store and price book,
+// the create routes replay their responses to the requests with the idempotency keys they have served:
+// under /v1 with the snake_case field names, and without the prefix, deprecated, with the Go field names
+func newRouter(catalog store.CatalogStore, book *pricing.Book, keys *api.IdempotencyKeys) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
+	//unknown links are reported with the same problem details as the handler errors
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.WriteError(w, r, api.NotFound("No resource at %s", r.URL.Path))
+	})
 	router.HandleFunc("/", homeLink)
-	router.HandleFunc("/categories", categories.GetAllCategories).Methods("GET")
-	router.HandleFunc("/categories/{id}", categories.GetCategoryById).Methods("GET")
-	router.HandleFunc("/categories/new", categories.CreateCategory).Methods("POST")
-	router.HandleFunc("/categories/{id}", categories.DeleteCategory).Methods("DELETE")
-	router.HandleFunc("/categories/{id}", categories.UpdateCategory).Methods("PATCH")
-	router.HandleFunc("/products", products.GetAllProducts).Methods("GET")
-	router.HandleFunc("/products/{id}", products.GetProductById).Methods("GET")
-	router.HandleFunc("/products/new", products.CreateProduct).Methods("POST")
-	router.HandleFunc("/products/{id}", products.UpdateProduct).Methods("PATCH")
-	router.HandleFunc("/products/{id}", products.DeleteProduct).Methods("DELETE")
-	router.HandleFunc("/products/category/{id}", products.GetProductsOfCategory).Methods("GET")
+	addVersions(router, catalog, book, keys)
+
+	//the document describes the routes above, so it is generated on the first request
+	router.Handle("/openapi.json", openapi.NewSpec(router, apiOperations()...)).Methods("GET")
+	return router
+}
+
+// apiOperations describe the handlers of all the routes for the OpenAPI document
+func apiOperations() []openapi.Operations {
+	home := openapi.Operations{}
+	home.Add(homeLink, openapi.Operation{Summary: "Greets the client", Response: ""})
+	return []openapi.Operations{
+		home,
+		categories.Operations(),
+		products.Operations(),
+		inventory.Operations(),
+		variants.Operations(),
+		pricelists.Operations(),
+		bulk.Operations(),
+	}
+}
+
+// addVersions registers the catalog resources under /v1 with the snake_case field names,
+// and without the prefix, deprecated, with the Go field names
+func addVersions(router *mux.Router, catalog store.CatalogStore, book *pricing.Book, keys *api.IdempotencyKeys) {
+	v1 := router.PathPrefix("/v1").Subrouter()
+	v1.Use(api.SnakeCase)
+	addRoutes(v1, catalog, book, keys)
+
+	legacy := router.NewRoute().Subrouter()
+	legacy.Use(api.Deprecated(legacyDeprecatedAt, legacySunsetAt))
+	addRoutes(legacy, catalog, book, keys)
+}
+
+// addRoutes registers the catalog resources with the handlers working on the given store and price book
+// and the idempotency keys of the create routes
+func addRoutes(router *mux.Router, catalog store.CatalogStore, book *pricing.Book, keys *api.IdempotencyKeys) {
+	categoryHandler := categories.NewHandler(catalog)
+	productHandler := products.NewPricingHandler(catalog, book)
+	priceListHandler := pricelists.NewHandler(book)
+	stockHandler := inventory.NewHandler(catalog)
+	variantHandler := variants.NewHandler(catalog)
+	//the operations of a bulk request are sent to the routes registered here
+	bulkHandler := bulk.NewHandler(router)
+	//the operations of a batch are sent to the same routes working on the transaction of the batch
+	batchHandler := bulk.NewBatchHandler(catalog, func(tx store.CatalogStore) http.Handler {
+		routes := mux.NewRouter()
+		addVersions(routes, tx, book, keys)
+		return routes
+	})
+
+	router.HandleFunc("/categories", categoryHandler.GetAllCategories).Methods("GET")
+	//registered before /categories/{id}, which would take "tree" and "export" for an id
+	router.HandleFunc("/categories/tree", categoryHandler.GetCategoryTree).Methods("GET")
+	router.HandleFunc("/categories/export", categoryHandler.ExportCategories).Methods("GET")
+	router.HandleFunc("/categories/import", categoryHandler.ImportCategories).Methods("POST")
+	router.HandleFunc("/categories/{id}", categoryHandler.GetCategoryById).Methods("GET")
+	router.HandleFunc("/categories/{id}/children", categoryHandler.GetChildCategories).Methods("GET")
+	router.HandleFunc("/categories/{id}/ancestors", categoryHandler.GetCategoryAncestors).Methods("GET")
+	router.Handle("/categories/new", keys.Idempotent(http.HandlerFunc(categoryHandler.CreateCategory))).Methods("POST")
+	router.HandleFunc("/categories/{id}", categoryHandler.DeleteCategory).Methods("DELETE")
+	router.HandleFunc("/categories/{id}", categoryHandler.UpdateCategory).Methods("PATCH")
+	router.HandleFunc("/categories/{id}", categoryHandler.ReplaceCategory).Methods("PUT")
+	router.HandleFunc("/products", productHandler.GetAllProducts).Methods("GET")
+	//registered before /products/{id}, which would take "search", "facets" and "export" for an id
+	router.HandleFunc("/products/search", productHandler.SearchProducts).Methods("GET")
+	router.HandleFunc("/products/facets", productHandler.GetFacets).Methods("GET")
+	router.HandleFunc("/products/export", productHandler.ExportProducts).Methods("GET")
+	router.HandleFunc("/products/import", productHandler.ImportProducts).Methods("POST")
+	router.HandleFunc("/products/{id}", productHandler.GetProductById).Methods("GET")
+	router.Handle("/products/new", keys.Idempotent(http.HandlerFunc(productHandler.CreateProduct))).Methods("POST")
+	router.HandleFunc("/products/{id}", productHandler.UpdateProduct).Methods("PATCH")
+	router.HandleFunc("/products/{id}", productHandler.ReplaceProduct).Methods("PUT")
+	router.HandleFunc("/products/{id}", productHandler.DeleteProduct).Methods("DELETE")
+	router.HandleFunc("/products/{id}/prices", productHandler.GetProductPrices).Methods("GET")
+	router.HandleFunc("/products/{id}/prices", productHandler.SchedulePriceChange).Methods("POST")
+	router.HandleFunc("/products/{id}/prices/{changeID}", productHandler.CancelPriceChange).Methods("DELETE")
+	router.HandleFunc("/products/{id}/stock", stockHandler.GetStock).Methods("GET")
+	router.HandleFunc("/products/{id}/stock/adjustments", stockHandler.GetStockAdjustments).Methods("GET")
+	router.HandleFunc("/products/{id}/stock/adjustments", stockHandler.AdjustStock).Methods("POST")
+	router.HandleFunc("/products/{id}/reservations", stockHandler.ReserveStock).Methods("POST")
+	router.HandleFunc("/products/{id}/reservations/{reservationID}", stockHandler.ReleaseReservation).Methods("DELETE")
+	router.HandleFunc("/products/{id}/reservations/{reservationID}/commit", stockHandler.CommitReservation).Methods("POST")
+	router.HandleFunc("/products/{id}/variants", variantHandler.GetVariants).Methods("GET")
+	router.HandleFunc("/products/{id}/variants", variantHandler.CreateVariant).Methods("POST")
+	router.HandleFunc("/products/{id}/variants/{variantID}", variantHandler.GetVariant).Methods("GET")
+	router.HandleFunc("/products/{id}/variants/{variantID}", variantHandler.UpdateVariant).Methods("PATCH")
+	router.HandleFunc("/products/{id}/variants/{variantID}", variantHandler.ReplaceVariant).Methods("PUT")
+	router.HandleFunc("/products/{id}/variants/{variantID}", variantHandler.DeleteVariant).Methods("DELETE")
+	router.HandleFunc("/products/{id}/variants/{variantID}/stock", stockHandler.GetStock).Methods("GET")
+	router.HandleFunc("/products/{id}/variants/{variantID}/stock/adjustments", stockHandler.GetStockAdjustments).Methods("GET")
+	router.HandleFunc("/products/{id}/variants/{variantID}/stock/adjustments", stockHandler.AdjustStock).Methods("POST")
+	router.HandleFunc("/products/{id}/variants/{variantID}/reservations", stockHandler.ReserveStock).Methods("POST")
+	router.HandleFunc("/products/category/{id}", productHandler.GetProductsOfCategory).Methods("GET")
+	router.HandleFunc("/pricelists", priceListHandler.GetAllPriceLists).Methods("GET")
+	router.HandleFunc("/pricelists/{name}", priceListHandler.GetPriceList).Methods("GET")
+	router.HandleFunc("/pricelists/{name}", priceListHandler.PutPriceList).Methods("PUT")
+	router.HandleFunc("/pricelists/{name}", priceListHandler.DeletePriceList).Methods("DELETE")
+	router.HandleFunc("/rates", priceListHandler.GetRates).Methods("GET")
+	router.HandleFunc("/rates", priceListHandler.PutRates).Methods("PUT")
+	router.HandleFunc("/bulk", bulkHandler.Bulk).Methods("POST")
+	router.HandleFunc("/batch", batchHandler.Batch).Methods("POST")
+}
+
+func main() {
+	//the catalog is kept in memory unless a database file is given
+	dbPath := flag.String("db", os.Getenv("CATALOG_DB"), "path to the SQLite database file (defaults to $CATALOG_DB, in-memory catalog if empty)")
+	pricingPath := flag.String("pricing", os.Getenv("CATALOG_PRICING"), "path to the JSON file with the exchange rates and price lists (defaults to $CATALOG_PRICING)")
+	priceInterval := flag.Duration("price-interval", 30*time.Second, "how often the scheduled price changes and the expired reservations are checked")
+	idempotencyTTL := flag.Duration("idempotency-ttl", 24*time.Hour, "how long the responses of the create requests with an Idempotency-Key are replayed")
+	flag.Parse()
+
+	//the exchange rates and price lists, empty unless a pricing file is given
+	book := pricing.NewBook()
+	if *pricingPath != "" {
+		var err error
+		if book, err = pricing.LoadFile(*pricingPath); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Prices loaded from:", *pricingPath)
+	}
+
+	//the store shared by the category and product handlers
+	var catalog store.CatalogStore
+	if *dbPath == "" {
+		catalog = store.NewMemoryStore()
+	} else {
+		sqliteStore, err := store.OpenSQLite(*dbPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer sqliteStore.Close()
+		catalog = sqliteStore
+		fmt.Println("Catalog stored in:", *dbPath)
+	}
+
+	//apply the scheduled price changes and release the expired reservations in the background
+	background := scheduler.New(catalog, *priceInterval)
+	background.Start()
+	defer background.Stop()
+
 	fmt.Println("Server running on: 8080")
 	//run the server
-	log.Fatal(http.ListenAndServe(":8080", router))
-}
\ No newline at end of file
+	log.Fatal(http.ListenAndServe(":8080", newRouter(catalog, book, api.NewIdempotencyKeys(*idempotencyTTL))))
+}
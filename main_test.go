@@ -0,0 +1,520 @@
+//package main contains the stress test for the routes registered in main.go, run it with go test -race
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/bulk"
+	"github.com/KseniiaL/AdcashTestAssignment/openapi"
+	"github.com/KseniiaL/AdcashTestAssignment/pricing"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	//stressWorkers is the number of clients sending requests at the same time
+	stressWorkers = 16
+	//stressRounds is the number of times every client goes through all the routes
+	stressRounds = 10
+	//sharedProductID is the seed product all the clients update at the same time
+	sharedProductID = "bq4foj37jhfipc5nqri0"
+	//specFile is the committed OpenAPI document the generated one is compared with
+	specFile = "openapi.json"
+)
+
+//update rewrites the committed OpenAPI document, run go test -run TestOpenAPISpec -update after changing the API
+var update = flag.Bool("update", false, "rewrite "+specFile+" with the generated OpenAPI document")
+
+//serve sends the request to the router and returns the recorded response
+func serve(router http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+//serveV1 sends the request with the snake_case field names to the /v1 route
+//and returns the recorded response with the Go field names, so it can be read like the unversioned one
+func serveV1(t *testing.T, router http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	snakeBody, err := api.SnakeKeys([]byte(body))
+	if !assert.NoError(t, err, "%s %s: request body is not valid JSON", method, path) {
+		snakeBody = []byte(body)
+	}
+	rr := serve(router, method, "/v1"+path, string(snakeBody))
+	assert.Empty(t, rr.Header().Get("Deprecation"), "%s /v1%s: expected the route not to be deprecated", method, path)
+	if rr.Header().Get("Content-Type") == "application/json" {
+		goBody, err := api.GoKeys(rr.Body.Bytes())
+		assert.NoError(t, err, "%s /v1%s: response body is not valid JSON", method, path)
+		rr.Body = bytes.NewBuffer(goBody)
+	}
+	return rr
+}
+
+//bulkResults reads the NDJSON results of the bulk or batch request from the response
+func bulkResults(t *testing.T, rr *httptest.ResponseRecorder) []bulk.Result {
+	assert.Equal(t, api.NDJSONContentType, rr.Header().Get("Content-Type"))
+	var results []bulk.Result
+	decoder := json.NewDecoder(rr.Body)
+	for decoder.More() {
+		var result bulk.Result
+		if !assert.NoError(t, decoder.Decode(&result)) {
+			break
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+//stressRoutes makes every worker create, read, update and delete its own category and product
+//while all of them also read and update the same shared product.
+//It returns the "METHOD /path/template" of every route which has served a request.
+func stressRoutes(t *testing.T, catalog store.CatalogStore) map[string]bool {
+	router := newRouter(catalog, pricing.NewBook(), api.NewIdempotencyKeys(time.Hour))
+
+	//remember which routes have been called
+	var visitedMu sync.Mutex
+	visited := make(map[string]bool)
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template, _ := mux.CurrentRoute(r).GetPathTemplate()
+			visitedMu.Lock()
+			visited[r.Method+" "+template] = true
+			visitedMu.Unlock()
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	//expect checks the response status and decodes the JSON body into v if it is given
+	expect := func(rr *httptest.ResponseRecorder, code int, request string, v interface{}) bool {
+		if !assert.Equal(t, code, rr.Code, "%s: unexpected status, body: %s", request, rr.Body.String()) {
+			return false
+		}
+		if v != nil {
+			return assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), v), "%s: body is not valid JSON", request)
+		}
+		return true
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < stressWorkers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			//half of the workers go through the /v1 routes
+			call := func(method string, path string, body string) *httptest.ResponseRecorder {
+				if worker%2 == 0 {
+					return serve(router, method, path, body)
+				}
+				return serveV1(t, router, method, path, body)
+			}
+			//the CSV files are sent and returned as they are on both routes
+			callCSV := func(method string, path string, body string) *httptest.ResponseRecorder {
+				if worker%2 == 0 {
+					return serve(router, method, path, body)
+				}
+				rr := serve(router, method, "/v1"+path, body)
+				if rr.Header().Get("Content-Type") == "application/json" {
+					goBody, err := api.GoKeys(rr.Body.Bytes())
+					assert.NoError(t, err, "%s /v1%s: response body is not valid JSON", method, path)
+					rr.Body = bytes.NewBuffer(goBody)
+				}
+				return rr
+			}
+			//the operations are sent to the bulk and batch routes as newline-delimited JSON
+			callStream := func(route string, body string) *httptest.ResponseRecorder {
+				path := route
+				if worker%2 == 1 {
+					path = "/v1" + route
+				}
+				req := httptest.NewRequest("POST", path, strings.NewReader(body))
+				req.Header.Set("Content-Type", api.NDJSONContentType)
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, req)
+				return rr
+			}
+			//keys names the fields of the JSON document as the route of the worker does
+			keys := func(body string) string {
+				if worker%2 == 0 {
+					return body
+				}
+				snakeBody, err := api.SnakeKeys([]byte(body))
+				assert.NoError(t, err)
+				return string(snakeBody)
+			}
+			for round := 0; round < stressRounds; round++ {
+				name := fmt.Sprintf("stress %d-%d", worker, round)
+
+				expect(serve(router, "GET", "/", ""), 200, "GET /", nil)
+				expect(serve(router, "GET", "/openapi.json", ""), 200, "GET /openapi.json", &map[string]interface{}{})
+
+				var newCategory store.Category
+				body := fmt.Sprintf(`{"CategoryName":%q,"CategoryDescription":"created by the stress test","Attributes":[{"Name":"size","Type":"string"},{"Name":"colour","Type":"string"}]}`, name)
+				if !expect(call("POST", "/categories/new", body), 201, "POST /categories/new", &newCategory) {
+					return
+				}
+				categoryPath := "/categories/" + newCategory.CategoryID
+				expect(call("GET", "/categories", ""), 200, "GET /categories", &[]store.Category{})
+				expect(call("GET", categoryPath, ""), 200, "GET "+categoryPath, &store.Category{})
+				body = fmt.Sprintf(`{"CategoryName":%q,"CategoryDescription":"updated by the stress test"}`, name)
+				expect(call("PATCH", categoryPath, body), 200, "PATCH "+categoryPath, &store.Category{})
+				body = fmt.Sprintf(`{"CategoryName":%q,"Attributes":[{"Name":"size","Type":"string"},{"Name":"colour","Type":"string"}]}`, name)
+				expect(call("PUT", categoryPath, body), 200, "PUT "+categoryPath, &store.Category{})
+
+				//a subcategory of the worker category
+				var child store.Category
+				body = fmt.Sprintf(`{"CategoryName":"%s child","ParentID":%q}`, name, newCategory.CategoryID)
+				if !expect(call("POST", "/categories/new", body), 201, "POST /categories/new child", &child) {
+					return
+				}
+				var children, ancestors []store.Category
+				expect(call("GET", categoryPath+"/children", ""), 200, "GET "+categoryPath+"/children", &children)
+				assert.Equal(t, []store.Category{child}, children, "Expected the subcategory of the worker")
+				expect(call("GET", "/categories/"+child.CategoryID+"/ancestors", ""), 200, "GET ancestors", &ancestors)
+				assert.Len(t, ancestors, 1, "Expected the worker category to be the only ancestor")
+				expect(call("GET", "/categories/tree", ""), 200, "GET /categories/tree", &[]store.CategoryNode{})
+
+				//the worker category is exported and its description imported back
+				rr := callCSV("GET", "/categories/export?name_contains="+url.QueryEscape(name), "")
+				if expect(rr, 200, "GET /categories/export", nil) {
+					assert.Contains(t, rr.Body.String(), newCategory.CategoryID, "Expected the worker category in the export")
+				}
+				var imported api.ImportResult
+				body = fmt.Sprintf("category_id,category_description\n%s,imported by the stress test\n", newCategory.CategoryID)
+				expect(callCSV("POST", "/categories/import", body), 200, "POST /categories/import", &imported)
+				assert.Equal(t, 1, imported.Updated, "Expected the worker category to be updated, errors: %v", imported.Errors)
+
+				var newProduct store.Product
+				body = fmt.Sprintf(`{"ProductName":%q,"Price":{"Amount":%d,"Currency":"EUR"},"CategoryID":%q}`, name, round, newCategory.CategoryID)
+				if !expect(call("POST", "/products/new", body), 201, "POST /products/new", &newProduct) {
+					return
+				}
+				productPath := "/products/" + newProduct.ProductID
+				expect(call("GET", "/products", ""), 200, "GET /products", &[]store.Product{})
+				expect(call("GET", productPath, ""), 200, "GET "+productPath, &store.Product{})
+				var found []store.ProductMatch
+				expect(call("GET", "/products/search?q="+url.QueryEscape(name), ""), 200, "GET /products/search", &found)
+				assert.NotEmpty(t, found, "Expected the new product to be found by its name")
+				var ofCategory []store.Product
+				expect(call("GET", "/products/category/"+newCategory.CategoryID, ""), 200, "GET /products/category", &ofCategory)
+				assert.Len(t, ofCategory, 1, "Expected only the product of the worker in its category")
+				path := "/products/category/" + newCategory.CategoryID + "?descendants=true"
+				expect(call("GET", path, ""), 200, "GET "+path, &ofCategory)
+				assert.Len(t, ofCategory, 1, "Expected no products in the subcategory")
+				var facets struct{ Total int }
+				expect(call("GET", "/products/facets?category="+newCategory.CategoryID, ""), 200, "GET /products/facets", &facets)
+				assert.Equal(t, 1, facets.Total, "Expected only the product of the worker in its facets")
+				rr = callCSV("GET", "/products/export?category="+newCategory.CategoryID, "")
+				if expect(rr, 200, "GET /products/export", nil) {
+					assert.Contains(t, rr.Body.String(), newProduct.ProductID, "Expected the product of the worker in the export")
+				}
+				body = fmt.Sprintf("product_name,price,currency,category_name\n%s copy,1.50,EUR,%s\n", name, name)
+				expect(callCSV("POST", "/products/import?dry_run=true", body), 200, "POST /products/import", &imported)
+				assert.Equal(t, 1, imported.Created, "Expected the copy of the product to be checked, errors: %v", imported.Errors)
+
+				//the price of the product in the own price list of the worker
+				expect(call("PUT", "/rates", `{"Base":"EUR","Rates":{"JPY":160,"USD":1.1}}`), 200, "PUT /rates", &pricing.Rates{})
+				expect(call("GET", "/rates", ""), 200, "GET /rates", &pricing.Rates{})
+				listPath := fmt.Sprintf("/pricelists/stress-%d-%d", worker, round)
+				expect(call("PUT", listPath, `{"Currency":"JPY","Adjustment":-10}`), 201, "PUT "+listPath, &pricing.PriceList{})
+				expect(call("GET", "/pricelists", ""), 200, "GET /pricelists", &[]pricing.PriceList{})
+				expect(call("GET", listPath, ""), 200, "GET "+listPath, &pricing.PriceList{})
+				var priced store.Product
+				path = productPath + "?pricelist=" + strings.TrimPrefix(listPath, "/pricelists/")
+				expect(call("GET", path, ""), 200, "GET "+path, &priced)
+				assert.Equal(t, "JPY", priced.Price.Currency, "Expected the price in the currency of the price list")
+				expect(call("DELETE", listPath, ""), 200, "DELETE "+listPath, nil)
+
+				body = fmt.Sprintf(`{"ProductName":%q,"Price":{"Amount":%d,"Currency":"EUR"},"CategoryID":%q}`, name, round+1, newCategory.CategoryID)
+				expect(call("PUT", productPath, body), 200, "PUT "+productPath, &store.Product{})
+				expect(call("PATCH", productPath, `{"Price":{"Amount":0}}`), 200, "PATCH "+productPath, &store.Product{})
+
+				//a sale which is canceled before it starts
+				var timeline, scheduled []store.PriceChange
+				expect(call("GET", productPath+"/prices", ""), 200, "GET "+productPath+"/prices", &timeline)
+				assert.Len(t, timeline, 3, "Expected the created, replaced and patched prices")
+				start := time.Now().Add(time.Hour)
+				body = fmt.Sprintf(`{"Price":{"Amount":1,"Currency":"EUR"},"EffectiveAt":%q,"Until":%q}`,
+					start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+				if expect(call("POST", productPath+"/prices", body), 201, "POST "+productPath+"/prices", &scheduled) {
+					changePath := productPath + "/prices/" + scheduled[0].ChangeID
+					expect(call("DELETE", changePath, ""), 200, "DELETE "+changePath, nil)
+				}
+
+				//pieces of the product are received, one reservation is sold and another one is released
+				var reservation store.Reservation
+				var stock struct{ Total store.StockLevel }
+				expect(call("POST", productPath+"/stock/adjustments", `{"WarehouseID":"tallinn","Delta":3,"Reason":"received"}`),
+					200, "POST "+productPath+"/stock/adjustments", &store.StockLevel{})
+				if expect(call("POST", productPath+"/reservations", `{"Quantity":2}`), 201, "POST "+productPath+"/reservations", &reservation) {
+					reservationPath := productPath + "/reservations/" + reservation.ReservationID
+					expect(call("POST", reservationPath+"/commit", ""), 200, "POST "+reservationPath+"/commit", &store.StockAdjustment{})
+				}
+				if expect(call("POST", productPath+"/reservations", `{"Quantity":1,"TTLSeconds":60}`), 201, "POST "+productPath+"/reservations", &reservation) {
+					reservationPath := productPath + "/reservations/" + reservation.ReservationID
+					expect(call("DELETE", reservationPath, ""), 200, "DELETE "+reservationPath, nil)
+				}
+				expect(call("GET", productPath+"/stock", ""), 200, "GET "+productPath+"/stock", &stock)
+				assert.Equal(t, store.StockLevel{OnHand: 1, Available: 1}, stock.Total, "Expected the sold pieces to be gone")
+				var adjustments []store.StockAdjustment
+				expect(call("GET", productPath+"/stock/adjustments", ""), 200, "GET "+productPath+"/stock/adjustments", &adjustments)
+				assert.Len(t, adjustments, 2, "Expected the received and the sold pieces")
+
+				//a variant of the product gets its own price and stock
+				var variant store.Variant
+				body = fmt.Sprintf(`{"SKU":"%s-38","Attributes":{"size":"38"},"Price":{"Amount":%d,"Currency":"EUR"}}`, name, round+5)
+				if expect(call("POST", productPath+"/variants", body), 201, "POST "+productPath+"/variants", &variant) {
+					variantPath := productPath + "/variants/" + variant.VariantID
+					var variants []store.Variant
+					expect(call("GET", productPath+"/variants", ""), 200, "GET "+productPath+"/variants", &variants)
+					assert.Len(t, variants, 1, "Expected only the variant of the worker")
+					expect(call("GET", variantPath, ""), 200, "GET "+variantPath, &store.Variant{})
+					expect(call("PATCH", variantPath, `{"Attributes":{"colour":"black"}}`), 200, "PATCH "+variantPath, &store.Variant{})
+					body = fmt.Sprintf(`{"SKU":"%s-39","Attributes":{"size":"39"}}`, name)
+					expect(call("PUT", variantPath, body), 200, "PUT "+variantPath, &store.Variant{})
+					expect(call("POST", variantPath+"/stock/adjustments", `{"WarehouseID":"riga","Delta":2,"Reason":"received"}`),
+						200, "POST "+variantPath+"/stock/adjustments", &store.StockLevel{})
+					if expect(call("POST", variantPath+"/reservations", `{"Quantity":1}`), 201, "POST "+variantPath+"/reservations", &reservation) {
+						reservationPath := productPath + "/reservations/" + reservation.ReservationID
+						expect(call("POST", reservationPath+"/commit", ""), 200, "POST variant "+reservationPath+"/commit", &store.StockAdjustment{})
+					}
+					expect(call("GET", variantPath+"/stock", ""), 200, "GET "+variantPath+"/stock", &stock)
+					assert.Equal(t, store.StockLevel{OnHand: 1, Available: 1}, stock.Total, "Expected the sold piece of the variant to be gone")
+					expect(call("GET", variantPath+"/stock/adjustments", ""), 200, "GET "+variantPath+"/stock/adjustments", &adjustments)
+					assert.Len(t, adjustments, 2, "Expected the received and the sold pieces of the variant")
+					expect(call("DELETE", variantPath, ""), 200, "DELETE "+variantPath, nil)
+					expect(call("GET", variantPath, ""), 404, "GET deleted "+variantPath, nil)
+				}
+
+				//all the workers sell a piece of the same product
+				expect(call("POST", "/products/"+sharedProductID+"/stock/adjustments", `{"WarehouseID":"tallinn","Delta":1,"Reason":"received"}`),
+					200, "POST shared stock adjustment", &store.StockLevel{})
+				if expect(call("POST", "/products/"+sharedProductID+"/reservations", `{"Quantity":1}`), 201, "POST shared reservation", &reservation) {
+					reservationPath := "/products/" + sharedProductID + "/reservations/" + reservation.ReservationID
+					expect(call("POST", reservationPath+"/commit", ""), 200, "POST shared reservation commit", &store.StockAdjustment{})
+				}
+
+				//all the workers change the same product
+				body =fmt.Sprintf(`{"ProductName":%q,"Price":{"Amount":%d,"Currency":"EUR"},"CategoryID":"bq4fasj7jhfi127rimlg"}`, name, round)
+				expect(call("PATCH", "/products/"+sharedProductID, body), 200, "PATCH shared product", &store.Product{})
+				expect(call("GET", "/products/"+sharedProductID, ""), 200, "GET shared product", &store.Product{})
+
+				expect(call("DELETE", productPath, ""), 200, "DELETE "+productPath, nil)
+				expect(call("GET", productPath, ""), 404, "GET deleted "+productPath, nil)
+				expect(call("DELETE", "/categories/"+child.CategoryID, ""), 200, "DELETE child category", nil)
+
+				//a category created, renamed and deleted again in one stream, with a line which is not an operation
+				body = fmt.Sprintf(`{"op":"create","resource":"category","body":%s}`, keys(fmt.Sprintf(`{"CategoryName":"%s bulk"}`, name))) + "\n{\n"
+				rr = callStream("/bulk", body)
+				var results []bulk.Result
+				if expect(rr, 200, "POST /bulk", nil) {
+					results = bulkResults(t, rr)
+				}
+				var bulkCategory store.Category
+				if assert.Len(t, results, 2, "Expected a result for every line") {
+					assert.Equal(t, []int{201, 400}, []int{results[0].Status, results[1].Status}, "Expected the invalid line to fail alone")
+					created, err := api.GoKeys(results[0].Body)
+					assert.NoError(t, err)
+					assert.NoError(t, json.Unmarshal(created, &bulkCategory))
+					body = fmt.Sprintf(`{"op":"update","resource":"category","id":%q,"body":%s}`, bulkCategory.CategoryID, keys(`{"CategoryDescription":"bulk"}`)) + "\n" +
+						fmt.Sprintf(`{"op":"delete","resource":"category","id":%q}`, bulkCategory.CategoryID)
+					rr = callStream("/bulk", body)
+					if expect(rr, 200, "POST /bulk", nil) {
+						results = bulkResults(t, rr)
+						assert.Equal(t, 2, len(results), "Expected a result for every line")
+						for _, result := range results {
+							assert.Equal(t, 200, result.Status, "Expected line %d to be applied, body: %s", result.Line, result.Body)
+						}
+					}
+				}
+
+				//a batch failing at its last line changes nothing, the one which succeeds leaves nothing behind
+				//as it deletes the category and the product it creates
+				createLines := fmt.Sprintf(`{"op":"create","resource":"category","ref":"c","body":%s}`, keys(fmt.Sprintf(`{"CategoryName":"%s batch"}`, name))) + "\n" +
+					fmt.Sprintf(`{"op":"create","resource":"product","ref":"p","body":%s}`, keys(fmt.Sprintf(`{"ProductName":"%s batch","Price":{"Amount":100,"Currency":"EUR"},"CategoryID":"${c}"}`, name))) + "\n"
+				rr = callStream("/batch", createLines+`{"op":"delete","resource":"product","id":"missing"}`)
+				if expect(rr, 404, "POST failed /batch", nil) {
+					assert.Contains(t, rr.Body.String(), `"line":3`, "Expected the failed line to be reported")
+				}
+				rr = callStream("/batch", createLines+`{"op":"delete","resource":"product","id":"${p}"}`+"\n"+`{"op":"delete","resource":"category","id":"${c}"}`)
+				if expect(rr, 200, "POST /batch", nil) {
+					results = bulkResults(t, rr)
+					if assert.Len(t, results, 4, "Expected a result for every line") {
+						assert.Equal(t, []int{201, 201, 200, 200}, []int{results[0].Status, results[1].Status, results[2].Status, results[3].Status})
+						assert.Equal(t, results[0].ID, results[3].ID, "Expected the reference to the created category")
+					}
+				}
+
+				expect(call("DELETE", categoryPath, ""), 200, "DELETE "+categoryPath, nil)
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	//everything the workers created has been deleted again
+	allCategories, err := catalog.Categories()
+	assert.NoError(t, err)
+	assert.Len(t, allCategories, 2, "Expected only the seed categories to be left")
+	allProducts, err := catalog.Products()
+	assert.NoError(t, err)
+	assert.Len(t, allProducts, 2, "Expected only the seed products to be left")
+	levels, err := catalog.Stock(sharedProductID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []store.StockLevel{{WarehouseID: "tallinn"}}, levels, "Expected every received piece of the shared product to be sold")
+
+	return visited
+}
+
+//TestConcurrentRequests tests whether all the routes can serve many clients at the same time
+//without data races or lost products, with both the in-memory and the SQLite store
+func TestConcurrentRequests(t *testing.T) {
+	stores := map[string]func(t *testing.T) store.CatalogStore{
+		"memory": func(t *testing.T) store.CatalogStore {
+			return store.NewMemoryStore()
+		},
+		"sqlite": func(t *testing.T) store.CatalogStore {
+			catalog, err := store.OpenSQLite(filepath.Join(t.TempDir(), "catalog.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { catalog.Close() })
+			return catalog
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			visited := stressRoutes(t, open(t))
+
+			//every registered route should be covered by the stress test
+			err := newRouter(store.NewMemoryStore(), pricing.NewBook(), api.NewIdempotencyKeys(time.Hour)).Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+				//the /v1 prefix and the deprecated routes are grouped in subrouters without handlers
+				if route.GetHandler() == nil {
+					return nil
+				}
+				template, _ := route.GetPathTemplate()
+				methods, err := route.GetMethods()
+				if err != nil {
+					methods = []string{"GET"}
+				}
+				for _, method := range methods {
+					assert.True(t, visited[method+" "+template], "Route %s %s is not covered by the stress test", method, template)
+				}
+				return nil
+			})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+//TestVersionedRoutes tests whether the /v1 routes use the snake_case field names
+//and the unversioned routes are marked as deprecated
+func TestVersionedRoutes(t *testing.T) {
+	router := newRouter(store.NewMemoryStore(), pricing.NewBook(), api.NewIdempotencyKeys(time.Hour))
+
+	rr := serve(router, "GET", "/products/"+sharedProductID, "")
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, "@1792368000", rr.Header().Get("Deprecation"), "Expected the deprecation date")
+	assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", rr.Header().Get("Sunset"), "Expected the sunset date")
+	assert.Equal(t, `</v1/products/`+sharedProductID+`>; rel="successor-version"`, rr.Header().Get("Link"), "Expected the link to the /v1 route")
+	assert.Contains(t, rr.Body.String(), `"ProductID":"`+sharedProductID+`"`, "Expected the Go field names")
+
+	rr = serve(router, "GET", "/v1/products/"+sharedProductID, "")
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Empty(t, rr.Header().Get("Deprecation"), "Expected the /v1 route not to be deprecated")
+	assert.JSONEq(t, `{"product_id":"bq4foj37jhfipc5nqri0","product_name":"Nike SuperRep Go","product_description":"Women's Training Shoe",`+
+		`"price":{"amount":10000,"currency":"EUR","formatted":"100.00 EUR"},"category_id":"bq4fasj7jhfi127rimlg"}`, rr.Body.String(), "Expected the snake_case field names")
+
+	body := `{"product_name":"Boot","price":{"amount":-1,"currency":"EUR"},"category_id":"bq4fasj7jhfi127rimlg","attributes":{"Colour":"black"}}`
+	rr = serve(router, "POST", "/v1/products/new", body)
+	assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected")
+	assert.JSONEq(t, `{"type":"/problems/validation","title":"Unprocessable Entity","status":422,"detail":"The request body contains invalid fields","instance":"/v1/products/new",`+
+		`"errors":[{"field":"price.amount","detail":"The price can not be negative"},{"field":"attributes.Colour","detail":"The category of the product has no attribute Colour"}]}`,
+		rr.Body.String(), "Expected the snake_case field paths")
+
+	req := httptest.NewRequest("GET", "/v1/categories", nil)
+	req.Header.Set("Accept", "text/html")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, 406, rr.Code, "Not Acceptable response is expected")
+	req.Header.Set("Accept", "application/vnd.catalog.v1+json, */*;q=0.5")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, "application/vnd.catalog.v1+json", rr.Header().Get("Content-Type"), "Expected the requested media type")
+}
+
+//TestIdempotentCreate tests whether a create request sent again with its Idempotency-Key gets the response
+//of the first one without creating the product twice, in the field names of its version
+func TestIdempotentCreate(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	router := newRouter(catalog, pricing.NewBook(), api.NewIdempotencyKeys(time.Hour))
+	create := func(path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set(api.IdempotencyKeyHeader, "erp-4711")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	before, _ := catalog.Products()
+
+	body := `{"product_name":"Boot","price":{"amount":9000,"currency":"EUR"},"category_id":"bq4fasj7jhfi127rimlg"}`
+	first := create("/v1/products/new", body)
+	assert.Equal(t, 201, first.Code, "Created response is expected, body: %s", first.Body.String())
+	retried := create("/v1/products/new", body)
+	assert.Equal(t, 201, retried.Code, "Expected the stored status")
+	assert.Equal(t, "true", retried.Header().Get(api.ReplayedHeader))
+	assert.JSONEq(t, first.Body.String(), retried.Body.String(), "Expected the stored product")
+	assert.Contains(t, retried.Body.String(), `"product_id":`, "Expected the snake_case field names")
+
+	rr := create("/v1/products/new", `{"product_name":"Sandal","price":{"amount":9000,"currency":"EUR"},"category_id":"bq4fasj7jhfi127rimlg"}`)
+	assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected for another body")
+	rr = create("/categories/new", `{"CategoryName":"Boots"}`)
+	assert.Equal(t, 201, rr.Code, "Expected the key of another route to be another key")
+
+	after, _ := catalog.Products()
+	assert.Len(t, after, len(before)+1, "Expected the product to be created once")
+}
+
+//TestOpenAPISpec tests whether every route is described in the OpenAPI document
+//and the document is the same as the committed one, so a new route or field can not be added without updating it
+func TestOpenAPISpec(t *testing.T) {
+	document, err := openapi.NewSpec(newRouter(store.NewMemoryStore(), pricing.NewBook(), api.NewIdempotencyKeys(time.Hour)), apiOperations()...).Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated = append(generated, '\n')
+
+	if *update {
+		if err = ioutil.WriteFile(specFile, generated, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	committed, err := ioutil.ReadFile(specFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, string(committed), string(generated), "The API has changed, run go test -run TestOpenAPISpec -update and review %s", specFile)
+
+	//the served document is the generated one
+	rr := serve(newRouter(store.NewMemoryStore(), pricing.NewBook(), api.NewIdempotencyKeys(time.Hour)), "GET", "/openapi.json", "")
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Empty(t, rr.Header().Get("Deprecation"), "Expected the document not to be deprecated")
+	assert.JSONEq(t, string(committed), rr.Body.String(), "Expected the committed document")
+}
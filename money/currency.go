@@ -0,0 +1,40 @@
+package money
+
+// the ISO 4217 currency codes grouped by the number of decimal places of their minor unit
+var (
+	noDecimals = []string{
+		"BIF", "CLP", "DJF", "GNF", "ISK", "JPY", "KMF", "KRW", "PYG", "RWF", "UGX", "UYI", "VND", "VUV", "XAF", "XOF", "XPF",
+	}
+	threeDecimals = []string{"BHD", "IQD", "JOD", "KWD", "LYD", "OMR", "TND"}
+	fourDecimals  = []string{"CLF", "UYW"}
+	twoDecimals   = []string{
+		"AED", "AFN", "ALL", "AMD", "ANG", "AOA", "ARS", "AUD", "AWG", "AZN", "BAM", "BBD", "BDT", "BGN", "BMD", "BND",
+		"BOB", "BOV", "BRL", "BSD", "BTN", "BWP", "BYN", "BZD", "CAD", "CDF", "CHE", "CHF", "CHW", "CNY", "COP", "COU",
+		"CRC", "CUC", "CUP", "CVE", "CZK", "DKK", "DOP", "DZD", "EGP", "ERN", "ETB", "EUR", "FJD", "FKP", "GBP", "GEL",
+		"GHS", "GIP", "GMD", "GTQ", "GYD", "HKD", "HNL", "HTG", "HUF", "IDR", "ILS", "INR", "IRR", "JMD", "KES", "KGS",
+		"KHR", "KPW", "KYD", "KZT", "LAK", "LBP", "LKR", "LRD", "LSL", "MAD", "MDL", "MGA", "MKD", "MMK", "MNT", "MOP",
+		"MRU", "MUR", "MVR", "MWK", "MXN", "MXV", "MYR", "MZN", "NAD", "NGN", "NIO", "NOK", "NPR", "NZD", "PAB", "PEN",
+		"PGK", "PHP", "PKR", "PLN", "QAR", "RON", "RSD", "RUB", "SAR", "SBD", "SCR", "SDG", "SEK", "SGD", "SHP", "SLE",
+		"SOS", "SRD", "SSP", "STN", "SVC", "SYP", "SZL", "THB", "TJS", "TMT", "TOP", "TRY", "TTD", "TWD", "TZS", "UAH",
+		"USD", "USN", "UYU", "UZS", "VED", "VES", "WST", "XCD", "YER", "ZAR", "ZMW", "ZWL",
+	}
+)
+
+// exponents maps every ISO 4217 currency code to the number of decimal places of its minor unit
+var exponents = make(map[string]int)
+
+func init() {
+	//the index is the number of decimal places, no currency has one
+	for exponent, codes := range [][]string{noDecimals, nil, twoDecimals, threeDecimals, fourDecimals} {
+		for _, code := range codes {
+			exponents[code] = exponent
+		}
+	}
+}
+
+// Exponent returns the number of decimal places of the currency, e.g. 2 for EUR, 0 for JPY and 3 for KWD,
+// and false if the code is not an ISO 4217 currency
+func Exponent(currency string) (int, bool) {
+	exponent, ok := exponents[currency]
+	return exponent, ok
+}
@@ -0,0 +1,112 @@
+//package money contains the amount of money in the minor units of its currency
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrNegativeAmount is returned by Validate for an amount below zero
+var ErrNegativeAmount = errors.New("negative amount")
+
+// ErrUnknownCurrency is returned by Validate for a code which is not an ISO 4217 currency
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// Money is an amount in the minor units of the currency, e.g. 1050 EUR is 10.50 EUR and 1050 JPY is 1050 JPY
+type Money struct {
+	Amount   int64  `json:"Amount"`
+	Currency string `json:"Currency"`
+}
+
+// New returns the amount of the currency
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Validate returns ErrUnknownCurrency if the currency is not an ISO 4217 code
+// and ErrNegativeAmount if the amount is below zero
+func (m Money) Validate() error {
+	if _, ok := Exponent(m.Currency); !ok {
+		return ErrUnknownCurrency
+	}
+	if m.Amount < 0 {
+		return ErrNegativeAmount
+	}
+	return nil
+}
+
+// String formats the amount with the decimal places of its currency, e.g. "10.50 EUR", "1050 JPY" or "1.050 KWD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Decimal formats the amount in the major units of its currency without the code, e.g. "10.50" for 1050 EUR,
+// the amount of an unknown currency is written as it is
+func (m Money) Decimal() string {
+	exponent, ok := Exponent(m.Currency)
+	if !ok {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	//pad with zeros, so there is at least one digit before the point
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	point := len(digits) - exponent
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// ErrInvalidAmount is returned by Parse for a text which is not a decimal number with the decimal places of the currency
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// decimal matches a decimal number, e.g. -10.50, 10 or .5
+var decimal = regexp.MustCompile(`^[-+]?(\d+(\.\d*)?|\.\d+)$`)
+
+// Parse reads the amount written in the major units of the currency, e.g. "10.50" EUR is 1050 EUR,
+// the inverse of Decimal. It returns ErrUnknownCurrency if the currency is not
+// an ISO 4217 code and ErrInvalidAmount if the text is not a number with at most the decimal places of the currency.
+func Parse(text string, currency string) (Money, error) {
+	exponent, ok := Exponent(currency)
+	if !ok {
+		return Money{}, ErrUnknownCurrency
+	}
+	text = strings.TrimSpace(text)
+	if !decimal.MatchString(text) {
+		return Money{}, ErrInvalidAmount
+	}
+	whole, fraction := text, ""
+	if point := strings.IndexByte(text, '.'); point >= 0 {
+		whole, fraction = text[:point], text[point+1:]
+	}
+	if len(fraction) > exponent {
+		return Money{}, ErrInvalidAmount
+	}
+	//10.5 EUR is 1050 cents
+	amount, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	return New(amount, currency), nil
+}
+
+// MarshalJSON adds the formatted amount to the JSON object, it is ignored when the object is read back
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount    int64  `json:"Amount"`
+		Currency  string `json:"Currency"`
+		Formatted string `json:"Formatted"`
+	}{m.Amount, m.Currency, m.String()})
+}
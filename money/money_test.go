@@ -0,0 +1,94 @@
+package money
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//TestString tests whether String formats the amount with the decimal places of its currency
+func TestString(t *testing.T) {
+	for _, m := range []struct {
+		money    Money
+		expected string
+	}{
+		{New(1050, "EUR"), "10.50 EUR"},
+		{New(5, "EUR"), "0.05 EUR"},
+		{New(0, "EUR"), "0.00 EUR"},
+		{New(-1050, "USD"), "-10.50 USD"},
+		{New(1050, "JPY"), "1050 JPY"},
+		{New(0, "JPY"), "0 JPY"},
+		{New(1050, "KWD"), "1.050 KWD"},
+		{New(7, "BHD"), "0.007 BHD"},
+		{New(12345, "CLF"), "1.2345 CLF"},
+		{New(1050, "XYZ"), "1050 XYZ"},
+	} {
+		assert.Equal(t, m.expected, m.money.String(), "Expected other formatting for %d %s", m.money.Amount, m.money.Currency)
+	}
+}
+
+//TestParse tests whether Parse reads the amount in the major units of its currency
+func TestParse(t *testing.T) {
+	for _, m := range []struct {
+		text     string
+		currency string
+		expected Money
+	}{
+		{"10.50", "EUR", New(1050, "EUR")},
+		{"10.5", "EUR", New(1050, "EUR")},
+		{"10", "EUR", New(1000, "EUR")},
+		{".05", "EUR", New(5, "EUR")},
+		{" -10.50 ", "USD", New(-1050, "USD")},
+		{"1050", "JPY", New(1050, "JPY")},
+		{"1.050", "KWD", New(1050, "KWD")},
+	} {
+		parsed, err := Parse(m.text, m.currency)
+		assert.NoError(t, err, "Expected %q %s to be parsed", m.text, m.currency)
+		assert.Equal(t, m.expected, parsed, "Expected other amount for %q %s", m.text, m.currency)
+	}
+	for _, text := range []string{"", ".", "-", "10.505", "10,50", "1.-5", "ten"} {
+		_, err := Parse(text, "EUR")
+		assert.Equal(t, ErrInvalidAmount, err, "Expected %q to be invalid", text)
+	}
+	for _, m := range []Money{New(1050, "EUR"), New(-5, "USD"), New(1050, "JPY"), New(1050, "KWD")} {
+		parsed, err := Parse(m.Decimal(), m.Currency)
+		assert.NoError(t, err)
+		assert.Equal(t, m, parsed, "Expected Parse to read back the Decimal of %s", m)
+	}
+	_, err := Parse("10.5", "JPY")
+	assert.Equal(t, ErrInvalidAmount, err, "Expected JPY to have no decimal places")
+	_, err = Parse("10", "XYZ")
+	assert.Equal(t, ErrUnknownCurrency, err)
+}
+
+//TestValidate tests whether Validate rejects unknown currencies and negative amounts
+func TestValidate(t *testing.T) {
+	assert.NoError(t, New(0, "EUR").Validate())
+	assert.NoError(t, New(1000, "JPY").Validate())
+	assert.Equal(t, ErrUnknownCurrency, New(1000, "").Validate())
+	assert.Equal(t, ErrUnknownCurrency, New(1000, "eur").Validate())
+	assert.Equal(t, ErrUnknownCurrency, New(-1000, "XYZ").Validate(), "Expected the currency to be checked first")
+	assert.Equal(t, ErrNegativeAmount, New(-1, "EUR").Validate())
+}
+
+//TestExponent tests whether Exponent knows the decimal places of the currencies
+func TestExponent(t *testing.T) {
+	for currency, expected := range map[string]int{"EUR": 2, "USD": 2, "JPY": 0, "KRW": 0, "KWD": 3, "CLF": 4} {
+		exponent, ok := Exponent(currency)
+		assert.True(t, ok, "Expected %s to be a known currency", currency)
+		assert.Equal(t, expected, exponent, "Expected other decimal places for %s", currency)
+	}
+	_, ok := Exponent("XYZ")
+	assert.False(t, ok, "Expected XYZ to be unknown")
+}
+
+//TestJSON tests whether the money is written with its formatted amount and read back without it
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(New(1050, "EUR"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Amount":1050,"Currency":"EUR","Formatted":"10.50 EUR"}`, string(data))
+
+	var m Money
+	assert.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, New(1050, "EUR"), m)
+}
@@ -0,0 +1,422 @@
+// package openapi describes the routes of the catalog and the bodies of their requests and responses as an OpenAPI 3 document
+package openapi
+
+import (
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/gorilla/mux"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the OpenAPI version of the generated document
+const Version = "3.0.3"
+
+// v1Prefix starts the links of the current version of the API, the other links are deprecated
+const v1Prefix = "/v1/"
+
+// pathParameter matches the variables of a route template, e.g. {id}
+var pathParameter = regexp.MustCompile(`{(\w+)}`)
+
+// pathParameters describe the variables of the route templates, {id} is described by the resource before it
+var pathParameters = map[string]string{
+	"variantID":     "The ID of the variant",
+	"reservationID": "The ID of the reservation",
+	"changeID":      "The ID of the scheduled price change",
+	"name":          "The name of the price list",
+}
+
+// resourceNames are the resources the {id} after the link segment belongs to, the segment itself if it is not listed
+var resourceNames = map[string]string{"categories": "category", "category": "category", "products": "product"}
+
+// Operation describes what a handler does and what it reads and writes
+type Operation struct {
+	// ID is the id of the operation, the name of the handler method if it is empty
+	ID      string
+	Summary string
+	// Parameters are the query and header parameters the handler reads
+	Parameters []Parameter
+	// Request is a value of the type the request body is decoded into, nil if the handler reads no body
+	Request interface{}
+	// Status is the status of a successful response, http.StatusOK if it is 0
+	Status int
+	// Upsert operations answer 201 instead of Status when they create the resource
+	Upsert bool
+	// Response is a value of the type of the successful response body, a string is sent as plain text
+	Response interface{}
+	// Paged lists are sent with the X-Total-Count and Link headers of api.WritePage
+	Paged bool
+	// CSVReport operations send the Response as JSON or, to a request accepting CSV rather than JSON,
+	// as a CSV file, e.g. the error report of an import
+	CSVReport bool
+	// Errors are the statuses of the problem details the handler can report
+	Errors []int
+}
+
+// CSV is the Request or Response of an operation reading or writing a CSV file
+type CSV struct{}
+
+// NDJSON is the Request or Response of an operation streaming newline-delimited JSON,
+// Line is a value of the type of every line
+type NDJSON struct {
+	Line interface{}
+}
+
+// csvSchema is the schema of a CSV file
+var csvSchema = &Schema{Type: "string", Description: "A CSV file with a header row naming the columns"}
+
+// Parameter is a query or header parameter of an operation
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Query returns the query parameter of the given type: string, integer or boolean
+func Query(name string, schemaType string, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: schemaType}}
+}
+
+// Header returns the request header parameter of the given type
+func Header(name string, schemaType string, description string) Parameter {
+	return Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: schemaType}}
+}
+
+// the query parameters read by api.ParseListQuery
+var (
+	// ListParameters page, sort and filter the lists of categories
+	ListParameters = []Parameter{
+		Query("limit", "integer", fmt.Sprintf("The page size from 1 to %d, all the items are returned without it", api.MaxLimit)),
+		Query("cursor", "string", "The position to continue from, taken from the Link header of the previous page"),
+		Query("sort", "string", "created (default) or name, prefixed with - for the descending order"),
+		Query("name_contains", "string", "Keeps the items with the text in their name, ignoring case"),
+	}
+	// ProductListParameters page, sort and filter the lists of products
+	ProductListParameters = append(append([]Parameter{}, ListParameters[:2]...),
+		Query("sort", "string", "created (default), name or price, which needs the currency filter, prefixed with - for the descending order"),
+		ListParameters[3],
+		Query("currency", "string", "Keeps the products priced in the currency, e.g. EUR"),
+		Query("price_min", "integer", "Keeps the products priced from the amount in minor units"),
+		Query("price_max", "integer", "Keeps the products priced up to the amount in minor units"),
+	)
+)
+
+// ImportParameters are the query parameters read by api.ReadCSV,
+// without the map.{column} parameters naming the header of a column
+var ImportParameters = []Parameter{
+	Query("dry_run", "boolean", "Only checks the rows, nothing is stored"),
+}
+
+// IdempotencyParameters are the parameters read by api.IdempotencyKeys
+var IdempotencyParameters = []Parameter{
+	Header(api.IdempotencyKeyHeader, "string", "A key of the request, unique for the client, e.g. a UUID. "+
+		"The request sent again with the same key and body gets the stored response, with the "+api.ReplayedHeader+" header"),
+}
+
+// Operations are the operations of the handlers, keyed by the names of the handler funcs
+type Operations map[string]Operation
+
+// Add describes the handler, a func or a method expression such as (*Handler).GetAllProducts
+func (o Operations) Add(handler interface{}, operation Operation) {
+	o[funcName(handler)] = operation
+}
+
+// funcName returns the name of the func the route calls, the method values are named like their method expressions
+func funcName(handler interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// handlerName returns the name the operation of the handler is added under,
+// a handler wrapped by a middleware such as api.IdempotencyKeys is found by its Unwrap method
+func handlerName(handler http.Handler) string {
+	if wrapper, ok := handler.(interface{ Unwrap() http.Handler }); ok {
+		return handlerName(wrapper.Unwrap())
+	}
+	if f, ok := handler.(http.HandlerFunc); ok {
+		return funcName(f)
+	}
+	return reflect.TypeOf(handler).String()
+}
+
+// Document is the OpenAPI document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+// Info is the title and the version of the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// components are the schemas the operations refer to
+type components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// operation is an OpenAPI operation object
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *body                `json:"requestBody,omitempty"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+// body is an OpenAPI request body object
+type body struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+// response is an OpenAPI response object
+type response struct {
+	Description string               `json:"description"`
+	Headers     map[string]header    `json:"headers,omitempty"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+// header is an OpenAPI header object
+type header struct {
+	Description string  `json:"description"`
+	Schema      *Schema `json:"schema"`
+}
+
+// mediaType is an OpenAPI media type object
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Spec serves the OpenAPI document of all the routes of the router
+type Spec struct {
+	router     *mux.Router
+	operations Operations
+
+	once     sync.Once
+	document *Document
+	err      error
+}
+
+// NewSpec returns the Spec of the routes of the router, the handlers of the routes are described by the operations.
+// The document is generated when it is asked for the first time, after all the routes have been registered.
+func NewSpec(router *mux.Router, operations ...Operations) *Spec {
+	all := Operations{}
+	for _, o := range operations {
+		for name, operation := range o {
+			all[name] = operation
+		}
+	}
+	all[reflect.TypeOf(&Spec{}).String()] = Operation{ID: "getOpenAPI", Summary: "Returns this OpenAPI document",
+		Response: map[string]interface{}{}}
+	return &Spec{router: router, operations: all}
+}
+
+// Document returns the OpenAPI document or an error if some route has no operation
+func (s *Spec) Document() (*Document, error) {
+	s.once.Do(func() {
+		s.document, s.err = s.generate()
+	})
+	return s.document, s.err
+}
+
+// ServeHTTP writes the OpenAPI document as the JSON response
+func (s *Spec) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	document, err := s.Document()
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, document)
+}
+
+// generate describes every route of the router with the operation of its handler
+func (s *Spec) generate() (*Document, error) {
+	document := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title: "Catalog API",
+			Description: "The categories and products of the catalog. The links under /v1 use the snake_case field names, " +
+				"the deprecated links without the prefix use the Go field names.",
+			Version: "1",
+		},
+		Paths: make(map[string]map[string]*operation),
+	}
+
+	//the routes in the order they were registered
+	type route struct {
+		template string
+		methods  []string
+		handler  string
+	}
+	var routes []route
+	templates := make(map[string]bool)
+	err := s.router.Walk(func(r *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		//the /v1 prefix and the deprecated routes are grouped in subrouters without handlers
+		if r.GetHandler() == nil {
+			return nil
+		}
+		template, err := r.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := r.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		routes = append(routes, route{template, methods, handlerName(r.GetHandler())})
+		templates[template] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	types := newSchemas()
+	operationIDs := make(map[string]bool)
+	for _, r := range routes {
+		o, ok := s.operations[r.handler]
+		if !ok {
+			return nil, fmt.Errorf("openapi: no operation describes %s of %s %s", r.handler, r.methods[0], r.template)
+		}
+		//a link is deprecated if the same link is served under /v1
+		v1 := strings.HasPrefix(r.template, v1Prefix)
+		deprecated := !v1 && templates[strings.TrimSuffix(v1Prefix, "/")+r.template]
+		for _, method := range r.methods {
+			described := describe(o, r.template, method, v1, types)
+			described.Deprecated = deprecated
+			described.OperationID = operationID(o, r.handler, r.template, deprecated, operationIDs)
+			if document.Paths[r.template] == nil {
+				document.Paths[r.template] = make(map[string]*operation)
+			}
+			document.Paths[r.template][strings.ToLower(method)] = described
+		}
+	}
+	document.Components.Schemas = types.components
+	return document, nil
+}
+
+// describe returns the OpenAPI operation of the route for the given method
+func describe(o Operation, template string, method string, v1 bool, types *schemas) *operation {
+	described := &operation{Summary: o.Summary, Responses: make(map[string]*response)}
+
+	//the variables of the link
+	for _, match := range pathParameter.FindAllStringSubmatchIndex(template, -1) {
+		name := template[match[2]:match[3]]
+		description, ok := pathParameters[name]
+		if !ok {
+			segments := strings.Split(strings.Trim(template[:match[0]], "/"), "/")
+			resource, ok := resourceNames[segments[len(segments)-1]]
+			if !ok {
+				resource = segments[len(segments)-1]
+			}
+			description = "The ID of the " + resource
+		}
+		described.Parameters = append(described.Parameters,
+			Parameter{Name: name, In: "path", Description: description, Required: true, Schema: &Schema{Type: "string"}})
+	}
+	described.Parameters = append(described.Parameters, o.Parameters...)
+
+	if o.Request != nil {
+		contentType := "application/json"
+		if method == http.MethodPatch {
+			contentType = "application/merge-patch+json"
+		}
+		var schema *Schema
+		switch request := o.Request.(type) {
+		case CSV:
+			contentType, schema = api.CSVContentType, csvSchema
+		case NDJSON:
+			contentType, schema = api.NDJSONContentType, types.of(request.Line, v1)
+		default:
+			schema = types.of(o.Request, v1)
+		}
+		described.RequestBody = &body{Required: true, Content: map[string]mediaType{contentType: {schema}}}
+	}
+
+	status := o.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &response{Description: http.StatusText(status)}
+	switch response := o.Response.(type) {
+	case nil:
+	case string:
+		success.Content = map[string]mediaType{"text/plain": {&Schema{Type: "string"}}}
+	case CSV:
+		success.Content = map[string]mediaType{api.CSVContentType: {csvSchema}}
+		success.Headers = map[string]header{
+			"Content-Disposition": {"Tells the browser to download the file under its name", &Schema{Type: "string"}},
+		}
+	case NDJSON:
+		success.Content = map[string]mediaType{api.NDJSONContentType: {types.of(response.Line, v1)}}
+	default:
+		success.Content = map[string]mediaType{"application/json": {types.of(o.Response, v1)}}
+		if v1 {
+			success.Content[api.V1MediaType] = success.Content["application/json"]
+		}
+		if o.CSVReport {
+			success.Content[api.CSVContentType] = mediaType{csvSchema}
+		}
+	}
+	if o.Paged {
+		success.Headers = map[string]header{
+			"X-Total-Count": {"The number of the matching items on all the pages", &Schema{Type: "integer"}},
+			"Link":          {`The link to the next page with rel="next", missing on the last page`, &Schema{Type: "string"}},
+		}
+	}
+	described.Responses[strconv.Itoa(status)] = success
+	if o.Upsert {
+		created := *success
+		created.Description = http.StatusText(http.StatusCreated)
+		described.Responses[strconv.Itoa(http.StatusCreated)] = &created
+	}
+
+	errors := append([]int{}, o.Errors...)
+	if v1 {
+		errors = append(errors, http.StatusNotAcceptable)
+	}
+	sort.Ints(errors)
+	for _, status := range errors {
+		described.Responses[strconv.Itoa(status)] = &response{
+			Description: http.StatusText(status),
+			Content:     map[string]mediaType{api.ProblemContentType: {types.of(api.Problem{}, v1)}},
+		}
+	}
+	return described
+}
+
+// operationID returns the unique id of the operation, made of the name of its handler method unless it has its own,
+// prefixed with legacy for a deprecated route. A handler serving several routes gets the last variable
+// of the link added, e.g. getStockForVariantID.
+func operationID(o Operation, handler string, template string, deprecated bool, taken map[string]bool) string {
+	id := o.ID
+	if id == "" {
+		id = handler[strings.LastIndex(handler, ".")+1:]
+	}
+	if deprecated {
+		id = "legacy" + upperFirst(id)
+	} else {
+		id = strings.ToLower(id[:1]) + id[1:]
+	}
+	if taken[id] {
+		if matches := pathParameter.FindAllStringSubmatch(template, -1); len(matches) > 0 {
+			id += "For" + upperFirst(matches[len(matches)-1][1])
+		}
+	}
+	taken[id] = true
+	return id
+}
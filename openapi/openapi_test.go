@@ -0,0 +1,62 @@
+package openapi
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// priced is a resource with a Go field name which is renamed in /v1
+type priced struct {
+	ProductID string
+	Price     money.Money
+}
+
+// getPriced writes the priced resource
+func getPriced(w http.ResponseWriter, r *http.Request) {}
+
+// TestDocument tests whether the routes are described with the schemas of both versions
+// and the deprecated routes are marked
+func TestDocument(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/priced/{id}", getPriced).Methods("GET")
+	router.HandleFunc("/priced/{id}", getPriced).Methods("GET")
+	operations := Operations{}
+	operations.Add(getPriced, Operation{Summary: "Returns the priced resource", Response: priced{}, Errors: []int{http.StatusNotFound}})
+
+	document, err := NewSpec(router, operations).Document()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	v1 := document.Paths["/v1/priced/{id}"]["get"]
+	assert.Equal(t, "getPriced", v1.OperationID, "Expected the id made of the handler name")
+	assert.False(t, v1.Deprecated, "Expected the /v1 route not to be deprecated")
+	assert.Equal(t, "#/components/schemas/Priced", v1.Responses["200"].Content["application/json"].Schema.Ref, "Expected the /v1 schema")
+	assert.Equal(t, "The ID of the priced", v1.Parameters[0].Description, "Expected the path parameter")
+	assert.Contains(t, v1.Responses, "406", "Expected the /v1 route to report Not Acceptable")
+
+	legacy := document.Paths["/priced/{id}"]["get"]
+	assert.Equal(t, "legacyGetPriced", legacy.OperationID, "Expected the legacy id")
+	assert.True(t, legacy.Deprecated, "Expected the route without /v1 to be deprecated")
+	assert.Equal(t, "#/components/schemas/LegacyPriced", legacy.Responses["200"].Content["application/json"].Schema.Ref, "Expected the legacy schema")
+	assert.NotContains(t, legacy.Responses, "406", "Expected the legacy route not to negotiate the media type")
+
+	schemas := document.Components.Schemas
+	assert.Contains(t, schemas["Priced"].Properties, "product_id", "Expected the snake_case field name")
+	assert.Contains(t, schemas["LegacyPriced"].Properties, "ProductID", "Expected the Go field name")
+	assert.True(t, schemas["Money"].Properties["formatted"].ReadOnly, "Expected the formatted amount written by MarshalJSON")
+	assert.Equal(t, true, schemas["Problem"].AdditionalProperties, "Expected the problem details to allow extensions")
+	assert.NotContains(t, schemas, "LegacyProblem", "Expected one schema of the problem details")
+}
+
+// TestUndescribedRoute tests whether a route without an operation is reported
+func TestUndescribedRoute(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/priced/{id}", getPriced).Methods("GET")
+
+	_, err := NewSpec(router).Document()
+	assert.EqualError(t, err, "openapi: no operation describes github.com/KseniiaL/AdcashTestAssignment/openapi.getPriced of GET /priced/{id}")
+}
@@ -0,0 +1,186 @@
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// the types encoded by their own MarshalJSON, which need more than their fields to be described
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	moneyType   = reflect.TypeOf(money.Money{})
+	problemType = reflect.TypeOf(api.Problem{})
+	// rawType is a JSON document kept as it is, e.g. the body of an operation of a bulk request
+	rawType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schema is an OpenAPI schema object, the subset of it the catalog types need
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	ReadOnly    bool               `json:"readOnly,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	// AdditionalProperties is the schema of the values of a map, or true for any additional members
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+}
+
+// schemas describes the Go types as they are encoded by encoding/json.
+// The named structs become component schemas, under the /v1 field names if v1 is set
+// and under the Go field names with the Legacy prefix otherwise.
+type schemas struct {
+	components map[string]*Schema
+	// names are the component names of the types without the Legacy prefix
+	names map[reflect.Type]string
+}
+
+// newSchemas returns an empty set of component schemas
+func newSchemas() *schemas {
+	return &schemas{components: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// of returns the schema of the type of the value, a reference for a named struct
+func (s *schemas) of(v interface{}, v1 bool) *Schema {
+	return s.schema(reflect.TypeOf(v), v1)
+}
+
+// schema returns the schema of the type, a reference for a named struct
+func (s *schemas) schema(t reflect.Type, v1 bool) *Schema {
+	if t == rawType {
+		//any JSON value
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.schema(t.Elem(), v1)
+	case reflect.Interface:
+		//any JSON value, e.g. a product attribute
+		return &Schema{}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.schema(t.Elem(), v1)}
+	case reflect.Map:
+		//the keys of a map are data, so they are not renamed
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem(), v1)}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return s.object(t, v1)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t, v1)}
+	}
+	return &Schema{}
+}
+
+// component adds the schema of the named struct to the components and returns its name
+func (s *schemas) component(t reflect.Type, v1 bool) string {
+	name, ok := s.names[t]
+	if !ok {
+		name = upperFirst(t.Name())
+		//the types of two packages can have the same name
+		for _, taken := range s.names {
+			if taken == name {
+				name = upperFirst(path.Base(t.PkgPath())) + name
+				break
+			}
+		}
+		s.names[t] = name
+	}
+	//a type whose fields are named the same way in both versions has one schema, e.g. the problem details
+	if !v1 && !sameNames(t) {
+		name = "Legacy" + name
+	}
+	if _, ok := s.components[name]; !ok {
+		//the placeholder stops the recursion of a type containing itself, e.g. a category node
+		s.components[name] = &Schema{}
+		*s.components[name] = *s.object(t, v1)
+	}
+	return name
+}
+
+// object returns the schema of the struct with its fields as properties
+func (s *schemas) object(t reflect.Type, v1 bool) *Schema {
+	object := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.addFields(object, t, v1)
+	switch t {
+	case moneyType:
+		object.Properties[fieldName("Formatted", v1)] = &Schema{Type: "string", ReadOnly: true,
+			Description: "The amount in the major units with the currency code, e.g. 10.50 EUR"}
+	case problemType:
+		//the extensions, e.g. the number of the products blocking a category
+		object.AdditionalProperties = true
+	}
+	return object
+}
+
+// addFields adds the JSON fields of the struct to the properties of the object,
+// the fields of the embedded structs without a name are added as they are promoted by encoding/json
+func (s *schemas) addFields(object *Schema, t reflect.Type, v1 bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(object, field.Type, v1)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		object.Properties[fieldName(name, v1)] = s.schema(field.Type, v1)
+	}
+}
+
+// jsonName returns the name given in the json tag of the field, empty if the tag does not give one
+func jsonName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+// upperFirst returns the name starting with a capital letter, e.g. Stock for the stock type
+func upperFirst(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// fieldName returns the name of the field in the given version of the API
+func fieldName(name string, v1 bool) string {
+	if v1 {
+		return api.SnakeName(name)
+	}
+	return name
+}
+
+// sameNames reports whether all the fields of the struct have the same names in both versions of the API
+func sameNames(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		if name != "-" && api.SnakeName(name) != name {
+			return false
+		}
+	}
+	return true
+}
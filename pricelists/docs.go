@@ -0,0 +1,44 @@
+package pricelists
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/openapi"
+	"github.com/KseniiaL/AdcashTestAssignment/pricing"
+	"net/http"
+)
+
+// Operations describes the price list and exchange-rate handlers for the OpenAPI document
+func Operations() openapi.Operations {
+	o := openapi.Operations{}
+	o.Add((*Handler).GetAllPriceLists, openapi.Operation{
+		Summary:  "Returns all the price lists sorted by name",
+		Response: []pricing.PriceList{},
+	})
+	o.Add((*Handler).GetPriceList, openapi.Operation{
+		Summary:  "Returns the price list",
+		Response: pricing.PriceList{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).PutPriceList, openapi.Operation{
+		Summary:  "Creates or replaces the price list, the name is taken from the link",
+		Request:  pricing.PriceList{},
+		Upsert:   true,
+		Response: pricing.PriceList{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).DeletePriceList, openapi.Operation{
+		Summary:  "Deletes the price list",
+		Response: "",
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).GetRates, openapi.Operation{
+		Summary:  "Returns the exchange-rate table",
+		Response: pricing.Rates{},
+	})
+	o.Add((*Handler).PutRates, openapi.Operation{
+		Summary:  "Replaces the exchange-rate table",
+		Request:  pricing.Rates{},
+		Response: pricing.Rates{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	})
+	return o
+}
@@ -0,0 +1,127 @@
+//package pricelists contains the methods for managing the price lists and the exchange-rate table
+package pricelists
+
+import (
+	"errors"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/pricing"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// Handler contains all the price list and exchange-rate handlers and the book they work with
+type Handler struct {
+	book *pricing.Book
+}
+
+// NewHandler returns a Handler which reads and writes the price lists and rates of the given book
+func NewHandler(b *pricing.Book) *Handler {
+	return &Handler{book: b}
+}
+
+// GetAllPriceLists returns all the price lists sorted by name
+func (h *Handler) GetAllPriceLists(w http.ResponseWriter, r *http.Request) {
+	api.WriteJSON(w, http.StatusOK, h.book.PriceLists())
+}
+
+// GetPriceList gets a price list name from the request link and returns the corresponding price list
+func (h *Handler) GetPriceList(w http.ResponseWriter, r *http.Request) {
+	//get price list name from the link
+	name := mux.Vars(r)["name"]
+
+	//find the price list with the given name
+	//or report an error
+	list, err := h.book.PriceList(name)
+	if err != nil {
+		api.WriteError(w, r, priceListError(err, name))
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, list)
+}
+
+// PutPriceList gets a price list name from the request link and creates or replaces the price list
+// with the one in the request body
+func (h *Handler) PutPriceList(w http.ResponseWriter, r *http.Request) {
+	//get price list name from the link
+	name := mux.Vars(r)["name"]
+	var list pricing.PriceList
+
+	//unmarshal the information from JSON into the price list instance
+	//or report an error
+	if err := api.ReadJSON(r, &list); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	//the name is taken from the link only
+	list.Name = name
+
+	//store the price list
+	//or report an error
+	created, err := h.book.PutPriceList(list)
+	if err != nil {
+		api.WriteError(w, r, priceListError(err, name))
+		return
+	}
+
+	//return the price list in response
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	api.WriteJSON(w, status, list)
+}
+
+// DeletePriceList gets a price list name from the request link and removes the corresponding price list
+func (h *Handler) DeletePriceList(w http.ResponseWriter, r *http.Request) {
+	//get price list name from the link
+	name := mux.Vars(r)["name"]
+
+	//remove the price list with the given name
+	//or report an error
+	if err := h.book.DeletePriceList(name); err != nil {
+		api.WriteError(w, r, priceListError(err, name))
+		return
+	}
+	fmt.Fprintf(w, "The price list %v has been deleted successfully", name)
+}
+
+// GetRates returns the exchange-rate table
+func (h *Handler) GetRates(w http.ResponseWriter, r *http.Request) {
+	api.WriteJSON(w, http.StatusOK, h.book.Rates())
+}
+
+// PutRates replaces the exchange-rate table with the one in the request body
+func (h *Handler) PutRates(w http.ResponseWriter, r *http.Request) {
+	var rates pricing.Rates
+
+	//unmarshal the information from JSON into the rates instance
+	//or report an error
+	if err := api.ReadJSON(r, &rates); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//replace the table
+	//or report an error
+	if err := h.book.SetRates(rates); err != nil {
+		api.WriteError(w, r, priceListError(err, ""))
+		return
+	}
+
+	//return the table in response
+	api.WriteJSON(w, http.StatusOK, h.book.Rates())
+}
+
+// priceListError turns the pricing errors about the price list with the given name into Problems
+func priceListError(err error, name string) error {
+	var invalid *pricing.InvalidFieldError
+	if errors.As(err, &invalid) {
+		return api.Validation(api.FieldError{Field: invalid.Field, Detail: invalid.Detail})
+	}
+	if err == pricing.ErrPriceListNotFound {
+		return api.NotFound("Price list %s not found", name)
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+//package pricelists contains test for pricelists.go
+package pricelists
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/pricing"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//TestPutPriceList tests whether PutPriceList func creates a price list and then replaces it
+func TestPutPriceList(t *testing.T) {
+	book := pricing.NewBook()
+	for _, p := range []struct {
+		requestBody  string
+		expectedCode int
+	}{
+		{`{"Name":"ignored","Currency":"USD","Adjustment":-20}`, 201},
+		{`{"Adjustment":-10,"Prices":{"bq4foj37jhfipc5nqri0":{"Amount":9000,"Currency":"EUR"}}}`, 200},
+	} {
+		req, err := http.NewRequest("PUT", "/pricelists/wholesale", bytes.NewBufferString(p.requestBody))
+		req = mux.SetURLVars(req, map[string]string{"name": "wholesale"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(book).PutPriceList)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.requestBody)
+	}
+
+	list, err := book.PriceList("wholesale")
+	assert.NoError(t, err)
+	assert.Equal(t, "wholesale", list.Name, "Expected the name to be taken from the link")
+	assert.Equal(t, "", list.Currency, "Expected the whole price list to be replaced")
+	assert.Equal(t, -10.0, list.Adjustment)
+	assert.Len(t, list.Prices, 1)
+}
+
+//TestPutPriceListFieldErrors tests whether PutPriceList func reports the invalid fields of the price list
+func TestPutPriceListFieldErrors(t *testing.T) {
+	book := pricing.NewBook()
+	for _, p := range []struct {
+		requestBody string
+		expected    string
+	}{
+		{`{"Currency":"EURO"}`, `[{"field":"Currency","detail":"Kindly enter an ISO 4217 currency code, e.g. EUR"}]`},
+		{`{"Adjustment":-100}`, `[{"field":"Adjustment","detail":"The adjustment must be above -100 percent"}]`},
+		{`{"Prices":{"p":{"Amount":-1,"Currency":"EUR"}}}`, `[{"field":"Prices.p.Amount","detail":"The price can not be negative"}]`},
+		{`{"Rounding":{"Increment":-5}}`, `[{"field":"Rounding.Increment","detail":"The rounding increment can not be negative"}]`},
+	} {
+		req, err := http.NewRequest("PUT", "/pricelists/retail", bytes.NewBufferString(p.requestBody))
+		req = mux.SetURLVars(req, map[string]string{"name": "retail"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(book).PutPriceList)
+
+		handler.ServeHTTP(rr, req)
+
+		var problem struct {
+			Errors json.RawMessage `json:"errors"`
+		}
+		assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected for %s", p.requestBody)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.JSONEq(t, p.expected, string(problem.Errors), "Expected field errors for %s", p.requestBody)
+	}
+	assert.Empty(t, book.PriceLists(), "Expected no price list to be stored")
+}
+
+//TestGetPriceList tests whether GetPriceList and GetAllPriceLists funcs return the stored price lists
+func TestGetPriceList(t *testing.T) {
+	book := pricing.NewBook()
+	if _, err := book.PutPriceList(pricing.PriceList{Name: "retail", Currency: "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []struct {
+		name         string
+		expected     string
+		expectedCode int
+	}{
+		{"retail", `{"Name":"retail","Currency":"EUR","Adjustment":0}`, 200},
+		{"wholesale", `{"type":"/problems/not-found","title":"Not Found","status":404,"detail":"Price list wholesale not found","instance":"/pricelists/wholesale"}`, 404},
+	} {
+		req, err := http.NewRequest("GET", "/pricelists/"+p.name, nil)
+		req = mux.SetURLVars(req, map[string]string{"name": p.name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(book).GetPriceList)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.name)
+		assert.JSONEq(t, p.expected, rr.Body.String(), "Expected another body for %s", p.name)
+	}
+
+	req, err := http.NewRequest("GET", "/pricelists", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(book).GetAllPriceLists).ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.JSONEq(t, `[{"Name":"retail","Currency":"EUR","Adjustment":0}]`, rr.Body.String())
+}
+
+//TestDeletePriceList tests whether DeletePriceList func removes the price list and reports an unknown one
+func TestDeletePriceList(t *testing.T) {
+	book := pricing.NewBook()
+	if _, err := book.PutPriceList(pricing.PriceList{Name: "retail"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, expectedCode := range []int{200, 404} {
+		req, err := http.NewRequest("DELETE", "/pricelists/retail", nil)
+		req = mux.SetURLVars(req, map[string]string{"name": "retail"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(book).DeletePriceList)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, expectedCode, rr.Code, "Expected another status")
+	}
+	assert.Empty(t, book.PriceLists(), "Expected the price list to be deleted")
+}
+
+//TestPutRates tests whether PutRates func replaces the exchange-rate table and rejects invalid rates
+func TestPutRates(t *testing.T) {
+	book := pricing.NewBook()
+	for _, p := range []struct {
+		requestBody  string
+		expectedCode int
+	}{
+		{`{"Base":"USD","Rates":{"EUR":0.9,"JPY":150},"Rounding":{"JPY":{"Mode":"half_even","Increment":10}}}`, 200},
+		{`{"Base":"USD","Rates":{"EUR":-1}}`, 422},
+		{`{"Base":"USD","Rates":{"EUR":0.9},"Rounding":{"JPY":{"Mode":"nearest"}}}`, 422},
+		{`{"Base":"USD",,}`, 400},
+	} {
+		req, err := http.NewRequest("PUT", "/rates", bytes.NewBufferString(p.requestBody))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(book).PutRates)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.requestBody)
+	}
+
+	req, err := http.NewRequest("GET", "/rates", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(book).GetRates).ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.JSONEq(t, `{"Base":"USD","Rates":{"EUR":0.9,"JPY":150},"Rounding":{"JPY":{"Mode":"half_even","Increment":10}}}`,
+		rr.Body.String(), "Expected the first table to be kept")
+}
@@ -0,0 +1,242 @@
+//package pricing resolves the product prices in other currencies and price lists using the exchange-rate table
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+)
+
+// DefaultBase is the base currency of the exchange-rate table of a new Book
+const DefaultBase = "EUR"
+
+// ErrPriceListNotFound is returned when the requested price list does not exist
+var ErrPriceListNotFound = errors.New("price list not found")
+
+// ErrRateNotFound is matched by the *RateNotFoundError returned when a price can not be converted
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// ErrOutOfRange is returned when a resolved price does not fit into the amount of money.Money
+var ErrOutOfRange = errors.New("price out of range")
+
+// RateNotFoundError is returned when there is no exchange rate for one of the currencies of a conversion
+type RateNotFoundError struct {
+	From string
+	To   string
+}
+
+// Error tells which conversion is missing
+func (e *RateNotFoundError) Error() string {
+	return fmt.Sprintf("no exchange rate from %s to %s", e.From, e.To)
+}
+
+// Is makes errors.Is(err, ErrRateNotFound) true for a *RateNotFoundError
+func (e *RateNotFoundError) Is(target error) bool {
+	return target == ErrRateNotFound
+}
+
+// InvalidFieldError is returned for the field of the exchange-rate table or of a price list which can not be used
+type InvalidFieldError struct {
+	Field  string
+	Detail string
+}
+
+// Error tells which field is invalid and why
+func (e *InvalidFieldError) Error() string {
+	return e.Field + ": " + e.Detail
+}
+
+// Config is the content of the pricing file: the exchange-rate table and the price lists
+type Config struct {
+	Rates      Rates       `json:"Rates"`
+	PriceLists []PriceList `json:"PriceLists"`
+}
+
+// Book holds the exchange-rate table and the price lists, it is safe for concurrent use
+type Book struct {
+	mu    sync.RWMutex
+	rates Rates
+	lists map[string]PriceList
+}
+
+// NewBook returns a Book without price lists and exchange rates, its base currency is DefaultBase
+func NewBook() *Book {
+	return &Book{rates: Rates{Base: DefaultBase, Rates: make(map[string]float64)}, lists: make(map[string]PriceList)}
+}
+
+// NewBookFromConfig returns a Book with the rates and price lists of the config
+// or an InvalidFieldError if some of them can not be used
+func NewBookFromConfig(config Config) (*Book, error) {
+	if err := config.Rates.check(); err != nil {
+		return nil, prefixField("Rates.", err)
+	}
+	b := NewBook()
+	b.rates = config.Rates.copy()
+	for _, list := range config.PriceLists {
+		if err := list.check(); err != nil {
+			return nil, prefixField("PriceLists."+list.Name+".", err)
+		}
+		if _, ok := b.lists[list.Name]; ok {
+			return nil, &InvalidFieldError{Field: "PriceLists." + list.Name, Detail: "The price list is given twice"}
+		}
+		b.lists[list.Name] = list.copy()
+	}
+	return b, nil
+}
+
+// LoadFile reads the Config from the JSON file and returns the Book with its rates and price lists
+func LoadFile(path string) (*Book, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("pricing file %s: %v", path, err)
+	}
+	b, err := NewBookFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("pricing file %s: %v", path, err)
+	}
+	return b, nil
+}
+
+// Rates returns the exchange-rate table
+func (b *Book) Rates() Rates {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.rates.copy()
+}
+
+// SetRates replaces the exchange-rate table or returns an InvalidFieldError if it can not be used
+func (b *Book) SetRates(rates Rates) error {
+	if err := rates.check(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rates = rates.copy()
+	return nil
+}
+
+// PriceLists returns all the price lists sorted by name
+func (b *Book) PriceLists() []PriceList {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	lists := make([]PriceList, 0, len(b.lists))
+	for _, list := range b.lists {
+		lists = append(lists, list.copy())
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].Name < lists[j].Name })
+	return lists
+}
+
+// PriceList returns the price list with the given name or ErrPriceListNotFound
+func (b *Book) PriceList(name string) (PriceList, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	list, ok := b.lists[name]
+	if !ok {
+		return PriceList{}, ErrPriceListNotFound
+	}
+	return list.copy(), nil
+}
+
+// PutPriceList adds the price list or replaces the one with the same name.
+// It returns true if the list is new and an InvalidFieldError if it can not be used.
+func (b *Book) PutPriceList(list PriceList) (bool, error) {
+	if err := list.check(); err != nil {
+		return false, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, replaced := b.lists[list.Name]
+	b.lists[list.Name] = list.copy()
+	return !replaced, nil
+}
+
+// DeletePriceList removes the price list with the given name or returns ErrPriceListNotFound
+func (b *Book) DeletePriceList(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.lists[name]; !ok {
+		return ErrPriceListNotFound
+	}
+	delete(b.lists, name)
+	return nil
+}
+
+// Resolve returns the price of the product in the currency and price list, both optional.
+// Without a currency the price is given in the currency of the list, or of the product if the list has none.
+// The prices which are converted or adjusted are rounded with the rule of the list or of the currency,
+// the fixed and unchanged prices are returned as they are.
+// It returns ErrPriceListNotFound for an unknown list and a *RateNotFoundError if the price can not be converted.
+func (b *Book) Resolve(productID string, price money.Money, currency string, listName string) (money.Money, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	amount := new(big.Rat).SetInt64(price.Amount)
+	computed := false
+	var rounding *Rounding
+
+	//take the fixed price of the list or adjust the product price
+	if listName != "" {
+		list, ok := b.lists[listName]
+		if !ok {
+			return money.Money{}, ErrPriceListNotFound
+		}
+		if fixed, ok := list.Prices[productID]; ok {
+			price = fixed
+			amount.SetInt64(fixed.Amount)
+		} else if list.Adjustment != 0 {
+			factor := new(big.Rat).Add(big.NewRat(100, 1), decimal(list.Adjustment))
+			amount.Mul(amount, factor.Quo(factor, big.NewRat(100, 1)))
+			computed = true
+		}
+		if currency == "" {
+			currency = list.Currency
+		}
+		rounding = list.Rounding
+	}
+
+	//convert the price to the currency
+	//or report an error
+	if currency == "" {
+		currency = price.Currency
+	}
+	if currency != price.Currency {
+		var err error
+		if amount, err = b.rates.convert(amount, price.Currency, currency); err != nil {
+			return money.Money{}, err
+		}
+		computed = true
+	}
+	if !computed {
+		return price, nil
+	}
+
+	//round to the minor units of the currency
+	if rounding == nil {
+		currencyRounding := b.rates.Rounding[currency]
+		rounding = &currencyRounding
+	}
+	rounded := rounding.round(amount)
+	if !rounded.IsInt64() {
+		return money.Money{}, ErrOutOfRange
+	}
+	return money.New(rounded.Int64(), currency), nil
+}
+
+// prefixField adds the prefix to the field of an InvalidFieldError
+func prefixField(prefix string, err error) error {
+	var invalid *InvalidFieldError
+	if errors.As(err, &invalid) {
+		return &InvalidFieldError{Field: prefix + invalid.Field, Detail: invalid.Detail}
+	}
+	return err
+}
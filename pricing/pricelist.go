@@ -0,0 +1,60 @@
+package pricing
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+)
+
+// PriceList is a named set of prices, e.g. retail, wholesale or the prices of a country.
+// A product gets its price from Prices if the list has one for it, otherwise its own price changed by Adjustment.
+type PriceList struct {
+	Name string `json:"Name"`
+	// Currency is the currency the list quotes in unless another one is asked for, the product currency if empty
+	Currency string `json:"Currency,omitempty"`
+	// Adjustment is the percentage added to the product prices, e.g. -20 for a 20% discount
+	Adjustment float64 `json:"Adjustment"`
+	// Prices maps the product ids to their fixed prices in the list
+	Prices map[string]money.Money `json:"Prices,omitempty"`
+	// Rounding replaces the rounding rule of the currency for the prices of the list
+	Rounding *Rounding `json:"Rounding,omitempty"`
+}
+
+// check returns an InvalidFieldError if some of the price list fields can not be used
+func (list PriceList) check() error {
+	if list.Name == "" {
+		return &InvalidFieldError{Field: "Name", Detail: "Kindly enter the price list name"}
+	}
+	if _, ok := money.Exponent(list.Currency); list.Currency != "" && !ok {
+		return &InvalidFieldError{Field: "Currency", Detail: "Kindly enter an ISO 4217 currency code, e.g. EUR"}
+	}
+	if list.Adjustment <= -100 {
+		return &InvalidFieldError{Field: "Adjustment", Detail: "The adjustment must be above -100 percent"}
+	}
+	for productID, price := range list.Prices {
+		switch price.Validate() {
+		case money.ErrUnknownCurrency:
+			return &InvalidFieldError{Field: "Prices." + productID + ".Currency", Detail: "Kindly enter an ISO 4217 currency code, e.g. EUR"}
+		case money.ErrNegativeAmount:
+			return &InvalidFieldError{Field: "Prices." + productID + ".Amount", Detail: "The price can not be negative"}
+		}
+	}
+	if list.Rounding != nil {
+		return list.Rounding.check("Rounding")
+	}
+	return nil
+}
+
+// copy returns the price list with its own map and rounding
+func (list PriceList) copy() PriceList {
+	copied := list
+	if list.Prices != nil {
+		copied.Prices = make(map[string]money.Money, len(list.Prices))
+		for productID, price := range list.Prices {
+			copied.Prices[productID] = price
+		}
+	}
+	if list.Rounding != nil {
+		rounding := *list.Rounding
+		copied.Rounding = &rounding
+	}
+	return copied
+}
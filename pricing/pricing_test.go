@@ -0,0 +1,188 @@
+package pricing
+
+import (
+	"errors"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//testBook returns a Book with the rates of EUR, USD, JPY and KWD, and a wholesale and a Japanese price list
+func testBook(t *testing.T) *Book {
+	b, err := NewBookFromConfig(Config{
+		Rates: Rates{
+			Base:     "EUR",
+			Rates:    map[string]float64{"USD": 1.1, "JPY": 160.25, "KWD": 0.335},
+			Rounding: map[string]Rounding{"JPY": {Mode: RoundHalfUp, Increment: 10}},
+		},
+		PriceLists: []PriceList{
+			{Name: "wholesale", Adjustment: -20, Prices: map[string]money.Money{"fixed": money.New(999, "USD")}},
+			{Name: "japan", Currency: "JPY", Rounding: &Rounding{Mode: RoundUp, Increment: 100}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+//TestRound tests whether every rounding mode rounds to the increment
+func TestRound(t *testing.T) {
+	for _, r := range []struct {
+		amount   *big.Rat
+		rounding Rounding
+		expected int64
+	}{
+		{big.NewRat(1005, 10), Rounding{}, 101},
+		{big.NewRat(1004, 10), Rounding{}, 100},
+		{big.NewRat(1005, 10), Rounding{Mode: RoundHalfEven}, 100},
+		{big.NewRat(1015, 10), Rounding{Mode: RoundHalfEven}, 102},
+		{big.NewRat(1001, 10), Rounding{Mode: RoundUp}, 101},
+		{big.NewRat(1009, 10), Rounding{Mode: RoundDown}, 100},
+		{big.NewRat(1234, 1), Rounding{Increment: 5}, 1235},
+		{big.NewRat(1232, 1), Rounding{Increment: 5}, 1230},
+		{big.NewRat(1250, 1), Rounding{Mode: RoundHalfEven, Increment: 100}, 1200},
+		{big.NewRat(1201, 1), Rounding{Mode: RoundUp, Increment: 100}, 1300},
+		{big.NewRat(1200, 1), Rounding{Mode: RoundUp, Increment: 100}, 1200},
+	} {
+		assert.Equal(t, r.expected, r.rounding.round(r.amount).Int64(), "Expected other rounding of %s with %+v", r.amount, r.rounding)
+	}
+}
+
+//TestResolve tests whether the prices are converted, adjusted and rounded for the currency and price list
+func TestResolve(t *testing.T) {
+	b := testBook(t)
+	for _, r := range []struct {
+		productID string
+		price     money.Money
+		currency  string
+		list      string
+		expected  money.Money
+	}{
+		//nothing to change
+		{"p", money.New(1055, "EUR"), "", "", money.New(1055, "EUR")},
+		{"p", money.New(1055, "EUR"), "EUR", "", money.New(1055, "EUR")},
+		//10.55 EUR * 1.1 = 11.605 USD
+		{"p", money.New(1055, "EUR"), "USD", "", money.New(1161, "USD")},
+		//11.61 USD / 1.1 = 10.5545... EUR
+		{"p", money.New(1161, "USD"), "EUR", "", money.New(1055, "EUR")},
+		//10.55 EUR * 160.25 = 1690.6375 JPY, rounded to tens
+		{"p", money.New(1055, "EUR"), "JPY", "", money.New(1690, "JPY")},
+		//10.55 EUR * 0.335 = 3.534250 KWD
+		{"p", money.New(1055, "EUR"), "KWD", "", money.New(3534, "KWD")},
+		//1000 JPY / 160.25 = 6.2402... EUR
+		{"p", money.New(1000, "JPY"), "EUR", "", money.New(624, "EUR")},
+		//20% off 10.55 EUR = 8.44 EUR
+		{"p", money.New(1055, "EUR"), "", "wholesale", money.New(844, "EUR")},
+		{"p", money.New(1055, "EUR"), "USD", "wholesale", money.New(928, "USD")},
+		//the fixed price is not adjusted
+		{"fixed", money.New(1055, "EUR"), "", "wholesale", money.New(999, "USD")},
+		//9.99 USD / 1.1 = 9.0818... EUR
+		{"fixed", money.New(1055, "EUR"), "EUR", "wholesale", money.New(908, "EUR")},
+		//the list currency and rounding, 1690.6375 JPY up to hundreds
+		{"p", money.New(1055, "EUR"), "", "japan", money.New(1700, "JPY")},
+		//the list rounding is kept in another currency
+		{"p", money.New(1055, "EUR"), "USD", "japan", money.New(1200, "USD")},
+	} {
+		price, err := b.Resolve(r.productID, r.price, r.currency, r.list)
+		assert.NoError(t, err)
+		assert.Equal(t, r.expected, price, "Expected other price of %s %s in %q and list %q", r.productID, r.price, r.currency, r.list)
+	}
+}
+
+//TestResolveErrors tests whether Resolve reports unknown price lists and missing rates
+func TestResolveErrors(t *testing.T) {
+	b := testBook(t)
+	_, err := b.Resolve("p", money.New(100, "EUR"), "", "retail")
+	assert.Equal(t, ErrPriceListNotFound, err)
+
+	_, err = b.Resolve("p", money.New(100, "EUR"), "GBP", "")
+	assert.True(t, errors.Is(err, ErrRateNotFound), "Expected a missing rate, got %v", err)
+	assert.EqualError(t, err, "no exchange rate from EUR to GBP")
+
+	_, err = b.Resolve("p", money.New(100, "GBP"), "EUR", "")
+	assert.EqualError(t, err, "no exchange rate from GBP to EUR")
+
+	_, err = b.Resolve("p", money.New(math.MaxInt64, "EUR"), "JPY", "")
+	assert.Equal(t, ErrOutOfRange, err)
+}
+
+//TestInvalidConfig tests whether the rates and price lists which can not be used are rejected with the field
+func TestInvalidConfig(t *testing.T) {
+	for _, c := range []struct {
+		config Config
+		field  string
+	}{
+		{Config{Rates: Rates{Base: "XYZ"}}, "Rates.Base"},
+		{Config{Rates: Rates{Base: "EUR", Rates: map[string]float64{"USD": 0}}}, "Rates.Rates.USD"},
+		{Config{Rates: Rates{Base: "EUR", Rates: map[string]float64{"EUR": 2}}}, "Rates.Rates.EUR"},
+		{Config{Rates: Rates{Base: "EUR", Rounding: map[string]Rounding{"JPY": {Mode: "nearest"}}}}, "Rates.Rounding.JPY.Mode"},
+		{Config{Rates: Rates{Base: "EUR"}, PriceLists: []PriceList{{Name: "retail", Adjustment: -100}}}, "PriceLists.retail.Adjustment"},
+		{Config{Rates: Rates{Base: "EUR"}, PriceLists: []PriceList{{Name: "retail", Currency: "xyz"}}}, "PriceLists.retail.Currency"},
+		{Config{Rates: Rates{Base: "EUR"}, PriceLists: []PriceList{{Name: "retail"}, {Name: "retail"}}}, "PriceLists.retail"},
+		{Config{Rates: Rates{Base: "EUR"}, PriceLists: []PriceList{{}}}, "PriceLists..Name"},
+	} {
+		_, err := NewBookFromConfig(c.config)
+		var invalid *InvalidFieldError
+		if assert.True(t, errors.As(err, &invalid), "Expected an invalid field for %+v, got %v", c.config, err) {
+			assert.Equal(t, c.field, invalid.Field)
+		}
+	}
+}
+
+//TestPriceLists tests whether the price lists are added, replaced, listed and deleted
+func TestPriceLists(t *testing.T) {
+	b := NewBook()
+	created, err := b.PutPriceList(PriceList{Name: "retail", Currency: "EUR"})
+	assert.NoError(t, err)
+	assert.True(t, created, "Expected a new price list")
+	created, err = b.PutPriceList(PriceList{Name: "retail", Adjustment: 5})
+	assert.NoError(t, err)
+	assert.False(t, created, "Expected the price list to be replaced")
+	_, err = b.PutPriceList(PriceList{Name: "b2b", Prices: map[string]money.Money{"p": money.New(-1, "EUR")}})
+	assert.Error(t, err)
+	_, err = b.PutPriceList(PriceList{Name: "b2b"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []PriceList{{Name: "b2b"}, {Name: "retail", Adjustment: 5}}, b.PriceLists())
+	assert.NoError(t, b.DeletePriceList("b2b"))
+	assert.Equal(t, ErrPriceListNotFound, b.DeletePriceList("b2b"))
+	_, err = b.PriceList("b2b")
+	assert.Equal(t, ErrPriceListNotFound, err)
+
+	//the returned lists are copies
+	list, err := b.PriceList("retail")
+	assert.NoError(t, err)
+	list.Prices = map[string]money.Money{"p": money.New(1, "EUR")}
+	stored, _ := b.PriceList("retail")
+	assert.Nil(t, stored.Prices, "Expected the stored list to stay the same")
+}
+
+//TestLoadFile tests whether the rates and price lists are read from the pricing file
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	content := `{
+		"Rates": {"Base": "EUR", "Rates": {"USD": 1.1}},
+		"PriceLists": [{"Name": "wholesale", "Adjustment": -20}]
+	}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadFile(path)
+	assert.NoError(t, err)
+	price, err := b.Resolve("p", money.New(1000, "EUR"), "USD", "wholesale")
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(880, "USD"), price)
+
+	if err = os.WriteFile(path, []byte(`{"Rates": {"Base": "EURO"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadFile(path)
+	assert.Error(t, err, "Expected the unknown base currency to be reported")
+	_, err = LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err, "Expected the missing file to be reported")
+}
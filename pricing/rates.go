@@ -0,0 +1,112 @@
+package pricing
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"math/big"
+	"strconv"
+)
+
+// Rates is the exchange-rate table: how much of every currency one unit of the base currency buys,
+// together with the rounding rules of the currencies
+type Rates struct {
+	// Base is the currency all the rates are given against
+	Base string `json:"Base"`
+	// Rates maps the currency codes to the amount of the currency one unit of Base buys, e.g. "USD": 1.08 for EUR
+	Rates map[string]float64 `json:"Rates"`
+	// Rounding maps the currency codes to the rounding of the prices computed in the currency,
+	// the prices are rounded half up to whole minor units in the other currencies
+	Rounding map[string]Rounding `json:"Rounding,omitempty"`
+}
+
+// check returns an InvalidFieldError if the base currency, a rate or a rounding rule can not be used
+func (rates Rates) check() error {
+	if _, ok := money.Exponent(rates.Base); !ok {
+		return &InvalidFieldError{Field: "Base", Detail: "Kindly enter an ISO 4217 currency code, e.g. EUR"}
+	}
+	for currency, rate := range rates.Rates {
+		if _, ok := money.Exponent(currency); !ok {
+			return &InvalidFieldError{Field: "Rates." + currency, Detail: "Unknown ISO 4217 currency code"}
+		}
+		if rate <= 0 {
+			return &InvalidFieldError{Field: "Rates." + currency, Detail: "The exchange rate must be above zero"}
+		}
+		if currency == rates.Base && rate != 1 {
+			return &InvalidFieldError{Field: "Rates." + currency, Detail: "The exchange rate of the base currency must be 1"}
+		}
+	}
+	for currency, rounding := range rates.Rounding {
+		if _, ok := money.Exponent(currency); !ok {
+			return &InvalidFieldError{Field: "Rounding." + currency, Detail: "Unknown ISO 4217 currency code"}
+		}
+		if err := rounding.check("Rounding." + currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rate returns the amount of the currency one unit of the base currency buys
+// or false if there is no rate for the currency
+func (rates Rates) rate(currency string) (*big.Rat, bool) {
+	if currency == rates.Base {
+		return big.NewRat(1, 1), true
+	}
+	rate, ok := rates.Rates[currency]
+	if !ok {
+		return nil, false
+	}
+	return decimal(rate), true
+}
+
+// convert returns the amount of minor units of from converted to the minor units of to
+// or a *RateNotFoundError if one of the currencies has no rate
+func (rates Rates) convert(amount *big.Rat, from string, to string) (*big.Rat, error) {
+	if from == to {
+		return amount, nil
+	}
+	fromRate, ok := rates.rate(from)
+	if !ok {
+		return nil, &RateNotFoundError{From: from, To: to}
+	}
+	toRate, ok := rates.rate(to)
+	if !ok {
+		return nil, &RateNotFoundError{From: from, To: to}
+	}
+
+	//minor units of from -> units of from -> units of base -> units of to -> minor units of to
+	fromExponent, _ := money.Exponent(from)
+	toExponent, _ := money.Exponent(to)
+	converted := new(big.Rat).Quo(amount, fromRate)
+	converted.Mul(converted, toRate)
+	return converted.Mul(converted, pow10(toExponent-fromExponent)), nil
+}
+
+// copy returns the rates with their own maps
+func (rates Rates) copy() Rates {
+	copied := Rates{Base: rates.Base, Rates: make(map[string]float64, len(rates.Rates))}
+	for currency, rate := range rates.Rates {
+		copied.Rates[currency] = rate
+	}
+	if rates.Rounding != nil {
+		copied.Rounding = make(map[string]Rounding, len(rates.Rounding))
+		for currency, rounding := range rates.Rounding {
+			copied.Rounding[currency] = rounding
+		}
+	}
+	return copied
+}
+
+// decimal returns the exact value of the shortest decimal representation of the number,
+// so a rate of 1.1 is 11/10 and not the binary fraction closest to it
+func decimal(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return r
+}
+
+// pow10 returns 10 to the power of the exponent, which may be negative
+func pow10(exponent int) *big.Rat {
+	if exponent < 0 {
+		return new(big.Rat).Inv(pow10(-exponent))
+	}
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil))
+}
@@ -0,0 +1,70 @@
+package pricing
+
+import (
+	"math/big"
+)
+
+// the rounding modes of the converted and adjusted prices
+const (
+	// RoundHalfUp rounds to the nearest step, the halves away from zero
+	RoundHalfUp = "half_up"
+	// RoundHalfEven rounds to the nearest step, the halves to the even step (banker's rounding)
+	RoundHalfEven = "half_even"
+	// RoundUp always rounds up to the next step
+	RoundUp = "up"
+	// RoundDown always rounds down to the previous step
+	RoundDown = "down"
+)
+
+// Rounding says how a computed price is rounded to whole minor units of its currency
+type Rounding struct {
+	// Mode is one of RoundHalfUp (the default), RoundHalfEven, RoundUp and RoundDown
+	Mode string `json:"Mode"`
+	// Increment is the step in minor units the price is rounded to, e.g. 5 for 0.05 CHF or 100 for whole euros,
+	// 1 if it is not set
+	Increment int64 `json:"Increment"`
+}
+
+// check returns an InvalidFieldError for an unknown mode or a negative increment
+func (rounding Rounding) check(field string) error {
+	switch rounding.Mode {
+	case "", RoundHalfUp, RoundHalfEven, RoundUp, RoundDown:
+	default:
+		return &InvalidFieldError{Field: field + ".Mode",
+			Detail: "The rounding mode must be one of " + RoundHalfUp + ", " + RoundHalfEven + ", " + RoundUp + " and " + RoundDown}
+	}
+	if rounding.Increment < 0 {
+		return &InvalidFieldError{Field: field + ".Increment", Detail: "The rounding increment can not be negative"}
+	}
+	return nil
+}
+
+// round rounds the non-negative amount of minor units to a multiple of the increment
+func (rounding Rounding) round(amount *big.Rat) *big.Int {
+	increment := big.NewInt(rounding.Increment)
+	if rounding.Increment <= 0 {
+		increment.SetInt64(1)
+	}
+
+	//the number of increments, split into the whole steps and the remainder
+	steps := new(big.Rat).Quo(amount, new(big.Rat).SetInt(increment))
+	quotient, remainder := new(big.Int).QuoRem(steps.Num(), steps.Denom(), new(big.Int))
+	if remainder.Sign() != 0 {
+		//compare the remainder with the half of the step
+		half := new(big.Int).Lsh(remainder, 1).Cmp(steps.Denom())
+		switch rounding.Mode {
+		case RoundUp:
+			quotient.Add(quotient, big.NewInt(1))
+		case RoundDown:
+		case RoundHalfEven:
+			if half > 0 || half == 0 && quotient.Bit(0) == 1 {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		default:
+			if half >= 0 {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		}
+	}
+	return quotient.Mul(quotient, increment)
+}
@@ -0,0 +1,309 @@
+package products
+
+import (
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/rs/xid"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// productColumns are the columns of the exported products, followed by an attr.{name} column for every attribute
+var productColumns = []string{"product_id", "product_name", "product_description", "price", "currency", "category_id", "category_name"}
+
+// attributeColumnPrefix starts the names of the columns with the attribute values, e.g. attr.size
+const attributeColumnPrefix = "attr."
+
+// ExportProducts returns the products as a CSV file with one row per product, the price in the major units
+// of its currency and one attr.{name} column for every attribute the products have.
+// The products are taken from the category given in the category parameter and all its subcategories,
+// or from the whole catalog without it, and sorted and filtered with the parameters read by api.ParseListQuery.
+func (h *Handler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	//read the sorting and filtering parameters
+	//or report an error
+	q, err := api.ParseListQuery(r, store.SortCreated, store.SortName, store.SortPrice)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	q.CategoryID, q.IncludeDescendants = r.URL.Query().Get("category"), true
+
+	//get the products and the names of their categories
+	//or report an error
+	page, err := h.store.ListProducts(q)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	categories, err := h.store.Categories()
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	names := make(map[string]string, len(categories))
+	for _, c := range categories {
+		names[c.CategoryID] = c.CategoryName
+	}
+
+	//the attribute columns are sorted, so the file has the same columns every time
+	attributes := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, p := range page.Items {
+		for name := range p.Attributes {
+			if !seen[name] {
+				seen[name] = true
+				attributes = append(attributes, name)
+			}
+		}
+	}
+	sort.Strings(attributes)
+
+	header := append([]string{}, productColumns...)
+	for _, name := range attributes {
+		header = append(header, attributeColumnPrefix+name)
+	}
+	rows := make([][]string, 0, len(page.Items))
+	for _, p := range page.Items {
+		row := []string{p.ProductID, p.ProductName, p.ProductDescription, p.Price.Decimal(), p.Price.Currency,
+			p.CategoryID, names[p.CategoryID]}
+		for _, name := range attributes {
+			row = append(row, store.AttributeText(p.Attributes[name]))
+		}
+		rows = append(rows, row)
+	}
+
+	api.WriteCSV(w, "products.csv", header, rows)
+}
+
+// ImportProducts creates and updates the products from the CSV file in the request body, in the columns
+// ExportProducts writes. A row with the product_id of a stored product updates the columns of the file only,
+// a row without product_id creates a product and a row with an unknown product_id is reported.
+// The category can be given by category_id or by category_name, an empty category or currency keeps the stored one
+// and an empty attr.{name} removes the attribute.
+// Every row is checked with the same rules as CreateProduct, and the invalid ones are reported without stopping
+// the import. Nothing is stored with dry_run=true. The summary is returned as JSON, or the failed rows
+// with their errors as a CSV file for a request accepting text/csv.
+func (h *Handler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	//read the header of the file
+	//or report an error
+	in, err := api.ReadCSV(r, isProductColumn)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//get the categories the category_name column refers to
+	//or report an error
+	categories, err := h.store.Categories()
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	rows := &productRows{in: in, categories: store.NewCategoryNames(categories)}
+
+	result := api.NewImportResult(in)
+	for {
+		//read the next row
+		//or report an error
+		row, err := in.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			api.WriteError(w, r, err)
+			return
+		}
+		result.Rows++
+
+		//read the product of the row and store it in one transaction, so the schema of its category
+		//can not change in between
+		//or report the failed row, or an error of the store
+		var p product
+		var exists bool
+		err = h.store.Transaction(func(tx store.CatalogStore) error {
+			var err error
+			if p, exists, err = rows.product(&Handler{store: tx, book: h.book}, row); err != nil || in.DryRun {
+				return err
+			}
+			if !exists {
+				return productError(tx.CreateProduct(p, api.Author(r)), p)
+			}
+			if err = tx.UpdateProduct(p, api.Author(r)); err != nil {
+				return productError(err, p)
+			}
+			//the variants are checked as the product can be moved to another category
+			return api.CheckProducts(tx, []store.Product{p})
+		})
+		if problem, ok := err.(*api.Problem); ok {
+			result.Fail(row, p.ProductID, problem, productColumn)
+			continue
+		} else if err != nil {
+			api.WriteError(w, r, err)
+			return
+		}
+		if exists {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+	}
+
+	api.WriteImportResult(w, r, result, "products-import-errors.csv")
+}
+
+// productRows turns the rows of an imported file into products
+type productRows struct {
+	in         *api.CSVImport
+	categories store.CategoryNames
+}
+
+// product returns the product of the row: the stored one with the columns of the row, or a new one,
+// and whether it is stored, read through the given Handler. A row which can not be imported is reported as a Problem.
+func (rows *productRows) product(h *Handler, row api.CSVRow) (product, bool, error) {
+	//the product with the id of the row is updated
+	p, exists := product{}, false
+	if id, _ := row.Value("product_id"); id != "" {
+		stored, err := h.store.Product(id)
+		if err == store.ErrNotFound {
+			//an id which is not stored is most likely mistyped, the ids of the new products are generated
+			return product{ProductID: id}, false, api.Validation(api.FieldError{Field: "ProductID",
+				Detail: fmt.Sprintf("Product with ID %q not found, leave the product_id empty to create a product", id)})
+		} else if err != nil {
+			return product{ProductID: id}, false, err
+		}
+		p, exists = stored, true
+	} else {
+		//generate unique productID
+		p.ProductID = xid.New().String()
+	}
+
+	var fieldErrors []api.FieldError
+	if name, ok := row.Value("product_name"); ok {
+		p.ProductName = name
+	}
+	if description, ok := row.Value("product_description"); ok {
+		p.ProductDescription = description
+	}
+	if categoryID, ok := row.Value("category_id"); ok && categoryID != "" {
+		p.CategoryID = categoryID
+	} else if name, ok := row.Value("category_name"); ok && name != "" {
+		categoryID, err := api.CategoryByName(rows.categories, name, "CategoryName")
+		if err != nil {
+			fieldErrors = append(fieldErrors, *err)
+		}
+		p.CategoryID = categoryID
+	}
+
+	//the price is read in the major units of the currency of the row, or of the stored one
+	if currency, _ := row.Value("currency"); currency != "" {
+		p.Price.Currency = currency
+	}
+	if text, ok := row.Value("price"); ok {
+		price, err := money.Parse(text, p.Price.Currency)
+		switch {
+		case err == money.ErrUnknownCurrency:
+			fieldErrors = append(fieldErrors, api.FieldError{Field: "Price.Currency", Detail: "Kindly enter an ISO 4217 currency code, e.g. EUR"})
+		case err != nil:
+			exponent, _ := money.Exponent(p.Price.Currency)
+			fieldErrors = append(fieldErrors, api.FieldError{Field: "Price.Amount",
+				Detail: fmt.Sprintf("The price must be a number with at most %d decimal places", exponent)})
+		default:
+			p.Price = price
+		}
+	}
+
+	//check the required fields and the attributes
+	//or report an error
+	schema, err := h.schema(p.CategoryID)
+	if err != nil {
+		return p, exists, err
+	}
+	if schema == nil && p.CategoryID != "" {
+		//the store reports it too, but not on a dry run
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "CategoryID", Detail: fmt.Sprintf("Category with ID %q not found", p.CategoryID)})
+	}
+	p.Attributes = attributeValues(p.Attributes, row, rows.in.Columns(), schema)
+	if err := validate(p, schema); err != nil {
+		//a field which could not be read is reported once, e.g. an unknown currency
+		reported := make(map[string]bool, len(fieldErrors))
+		for _, e := range fieldErrors {
+			reported[e.Field] = true
+		}
+		for _, e := range err.(*api.Problem).Errors {
+			if !reported[e.Field] && !(e.Field == "CategoryID" && reported["CategoryName"]) {
+				fieldErrors = append(fieldErrors, e)
+			}
+		}
+	}
+	if len(fieldErrors) != 0 {
+		return p, exists, api.Validation(fieldErrors...)
+	}
+	return p, exists, nil
+}
+
+// attributeValues returns the attributes with the values of the attr.{name} columns of the row,
+// read as the type of the attribute in the schema. An empty value removes the attribute.
+func attributeValues(attributes map[string]interface{}, row api.CSVRow, columns []string, schema []store.Attribute) map[string]interface{} {
+	types := make(map[string]string, len(schema))
+	for _, a := range schema {
+		types[a.Name] = a.Type
+	}
+	values := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		values[name] = value
+	}
+	for _, column := range columns {
+		if !strings.HasPrefix(column, attributeColumnPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(column, attributeColumnPrefix)
+		text, _ := row.Value(column)
+		if text == "" {
+			delete(values, name)
+			continue
+		}
+		//a value which is not of the type of the attribute is kept as a text and reported by validate
+		values[name] = text
+		switch types[name] {
+		case store.AttributeNumber:
+			if number, err := strconv.ParseFloat(text, 64); err == nil {
+				values[name] = number
+			}
+		case store.AttributeBool:
+			if b, err := strconv.ParseBool(text); err == nil {
+				values[name] = b
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// isProductColumn reports whether the column is one of the columns of the products CSV
+func isProductColumn(column string) bool {
+	for _, c := range productColumns {
+		if c == column {
+			return true
+		}
+	}
+	return strings.HasPrefix(column, attributeColumnPrefix) && len(column) > len(attributeColumnPrefix)
+}
+
+// productColumn returns the column of the products CSV with the product field, e.g. price for Price.Amount
+func productColumn(field string) string {
+	switch {
+	case field == "Price" || field == "Price.Amount":
+		return "price"
+	case field == "Price.Currency":
+		return "currency"
+	case strings.HasPrefix(field, "Attributes."):
+		return attributeColumnPrefix + strings.TrimPrefix(field, "Attributes.")
+	}
+	return api.SnakeName(field)
+}
@@ -0,0 +1,222 @@
+//package products contains test for csv.go
+package products
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//csvCatalog returns a store with the footwear categories and a product with attributes
+func csvCatalog(t *testing.T) store.CatalogStore {
+	catalog := store.NewMemoryStore()
+	for _, c := range []store.Category{
+		{CategoryID: "footwear", CategoryName: "Footwear", Attributes: []store.Attribute{
+			{Name: "size", Type: store.AttributeEnum, Required: true, Values: []string{"38", "39", "40"}},
+			{Name: "waterproof", Type: store.AttributeBool},
+		}},
+		{CategoryID: "running", CategoryName: "Running Shoes", ParentID: "footwear", Attributes: []store.Attribute{
+			{Name: "drop", Type: store.AttributeNumber, Unit: "mm"},
+		}},
+		{CategoryID: "trail-footwear", CategoryName: "Trail", ParentID: "footwear"},
+		{CategoryID: "trail-bikes", CategoryName: "Trail"},
+	} {
+		if err := catalog.CreateCategory(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := product{ProductID: "pegasus", ProductName: "Pegasus", ProductDescription: "Road, running", Price: money.New(9050, "EUR"),
+		CategoryID: "running", Attributes: map[string]interface{}{"size": "38", "drop": 10.0}}
+	if err := catalog.CreateProduct(p, "test"); err != nil {
+		t.Fatal(err)
+	}
+	return catalog
+}
+
+//importProducts sends the CSV file to ImportProducts and returns the recorded response
+func importProducts(catalog store.CatalogStore, query string, accept string, file string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/products/import"+query, strings.NewReader(file))
+	req.Header.Set("Accept", accept)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).ImportProducts).ServeHTTP(rr, req)
+	return rr
+}
+
+//TestExportProducts tests whether ExportProducts func writes the products of the category with their attributes as CSV
+func TestExportProducts(t *testing.T) {
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(csvCatalog(t)).ExportProducts).ServeHTTP(rr, httptest.NewRequest("GET", "/products/export?category=footwear", nil))
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=products.csv", rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, "product_id,product_name,product_description,price,currency,category_id,category_name,attr.drop,attr.size\n"+
+		"pegasus,Pegasus,\"Road, running\",90.50,EUR,running,Running Shoes,10,38\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(csvCatalog(t)).ExportProducts).ServeHTTP(rr, httptest.NewRequest("GET", "/products/export?sort=size", nil))
+	assert.Equal(t, 400, rr.Code, "Bad Request response is expected for an unknown sort")
+}
+
+//TestExportProductsFormulas tests whether ExportProducts func escapes the values a spreadsheet would run as formulas
+//and ImportProducts func reads them back as they were
+func TestExportProductsFormulas(t *testing.T) {
+	catalog := csvCatalog(t)
+	p, _ := catalog.Product("pegasus")
+	p.ProductName, p.ProductDescription = `=HYPERLINK("http://example.com","Pegasus")`, "-10% off"
+	if err := catalog.UpdateProduct(p, "test"); err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).ExportProducts).ServeHTTP(rr, httptest.NewRequest("GET", "/products/export?category=running", nil))
+	records, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, `'=HYPERLINK("http://example.com","Pegasus")`, records[1][1], "Expected the formula to be escaped")
+		assert.Equal(t, "'-10% off", records[1][2], "Expected the leading minus to be escaped")
+	}
+
+	//the exported file is imported with the same values
+	p.ProductName, p.ProductDescription = "Pegasus", "Road"
+	if err := catalog.UpdateProduct(p, "test"); err != nil {
+		t.Fatal(err)
+	}
+	result := importProducts(catalog, "", "", rr.Body.String())
+	assert.Equal(t, 200, result.Code, "OK response is expected, body: %s", result.Body.String())
+	imported, _ := catalog.Product("pegasus")
+	assert.Equal(t, `=HYPERLINK("http://example.com","Pegasus")`, imported.ProductName)
+	assert.Equal(t, "-10% off", imported.ProductDescription)
+}
+
+//TestExportProductsControlFormulas tests whether ExportProducts func escapes the values starting with a tab
+//or a carriage return, which a spreadsheet runs as formulas too, and ImportProducts func reads them back as they were
+func TestExportProductsControlFormulas(t *testing.T) {
+	catalog := csvCatalog(t)
+	p, _ := catalog.Product("pegasus")
+	p.ProductName, p.ProductDescription = "\t=1+1", "\r@SUM(A1)"
+	if err := catalog.UpdateProduct(p, "test"); err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).ExportProducts).ServeHTTP(rr, httptest.NewRequest("GET", "/products/export?category=running", nil))
+	records, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "'\t=1+1", records[1][1], "Expected the leading tab to be escaped")
+		assert.Equal(t, "'\r@SUM(A1)", records[1][2], "Expected the leading carriage return to be escaped")
+	}
+
+	//the exported file is imported with the same values
+	p.ProductName, p.ProductDescription = "Pegasus", "Road"
+	if err := catalog.UpdateProduct(p, "test"); err != nil {
+		t.Fatal(err)
+	}
+	result := importProducts(catalog, "", "", rr.Body.String())
+	assert.Equal(t, 200, result.Code, "OK response is expected, body: %s", result.Body.String())
+	imported, _ := catalog.Product("pegasus")
+	assert.Equal(t, "\t=1+1", imported.ProductName)
+	assert.Equal(t, "\r@SUM(A1)", imported.ProductDescription)
+}
+
+//TestImportProducts tests whether ImportProducts func creates and updates the products of the valid rows
+//and reports the invalid ones under their columns
+func TestImportProducts(t *testing.T) {
+	catalog := csvCatalog(t)
+	file := "Title,price,currency,category_name,attr.size,attr.drop,product_id\n" +
+		"Vomero,120,EUR,running shoes,39,8,\n" +
+		"Pegasus 41,95.5,,,40,,pegasus\n" +
+		"Terra,1.234,EUR,Trail,40,,\n" +
+		",10,XYZ,Hiking,,,\n" +
+		"Pegasus Trail,100,EUR,running shoes,39,,pegasus-trail\n"
+	rr := importProducts(catalog, "?map.product_name=Title", "", file)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected, body: %s", rr.Body.String())
+	var result api.ImportResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, 5, result.Rows)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 3, result.Failed)
+	for i := range result.Errors {
+		result.Errors[i].ID = ""
+	}
+	assert.Equal(t, []api.RowError{
+		{Row: 4, Column: "category_name", Detail: `2 categories are named "Trail", kindly give the category ID`},
+		{Row: 4, Column: "price", Detail: "The price must be a number with at most 2 decimal places"},
+		{Row: 5, Column: "category_name", Detail: `Category named "Hiking" not found`},
+		{Row: 5, Column: "currency", Detail: "Kindly enter an ISO 4217 currency code, e.g. EUR"},
+		{Row: 5, Column: "Title", Detail: "Kindly enter the product name"},
+		{Row: 6, Column: "product_id", Detail: `Product with ID "pegasus-trail" not found, leave the product_id empty to create a product`},
+	}, result.Errors, "Expected the errors under the headers of the file")
+
+	//the updated product keeps the columns missing in the file and loses the empty attributes
+	updated, err := catalog.Product("pegasus")
+	assert.NoError(t, err)
+	assert.Equal(t, product{ProductID: "pegasus", ProductName: "Pegasus 41", ProductDescription: "Road, running", Price: money.New(9550, "EUR"),
+		CategoryID: "running", Attributes: map[string]interface{}{"size": "40"}}, updated)
+	page, err := catalog.ListProducts(store.ListQuery{NameContains: "Vomero"})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1, "Expected the created product") {
+		assert.Equal(t, "running", page.Items[0].CategoryID, "Expected the category found by its name")
+		assert.Equal(t, map[string]interface{}{"size": "39", "drop": 8.0}, page.Items[0].Attributes)
+	}
+}
+
+//TestImportProductsDryRun tests whether ImportProducts func only checks the rows with dry_run=true
+func TestImportProductsDryRun(t *testing.T) {
+	catalog := csvCatalog(t)
+	count := countProducts(t, catalog)
+	rr := importProducts(catalog, "?dry_run=true", "", "product_name,price,currency,category_id,attr.size\nVomero,120,EUR,running,39\nBoot,10,EUR,boots,\n")
+
+	var result api.ImportResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, api.ImportResult{DryRun: true, Rows: 2, Created: 1, Failed: 1, Errors: []api.RowError{
+		{Row: 3, ID: result.Errors[0].ID, Column: "category_id", Detail: `Category with ID "boots" not found`},
+	}}, result, "Expected the unknown category to be reported without storing")
+	assert.Equal(t, count, countProducts(t, catalog), "Expected no products to be stored")
+}
+
+//TestImportProductsErrorReport tests whether ImportProducts func returns the failed rows with their errors
+//as CSV to a request accepting text/csv
+func TestImportProductsErrorReport(t *testing.T) {
+	file := "product_name,price,currency,category_id,attr.size\nVomero,120,EUR,running,39\nBoot,-1,EUR,running,45\n"
+	rr := importProducts(csvCatalog(t), "", "text/csv, application/json;q=0.5", file)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, "attachment; filename=products-import-errors.csv", rr.Header().Get("Content-Disposition"))
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"product_name", "price", "currency", "category_id", "attr.size", "errors"},
+		{"Boot", "'-1", "EUR", "running", "45", "price: The price can not be negative; attr.size: The size must be one of 38, 39, 40"},
+	}, records, "Expected only the failed row with its errors")
+}
+
+//TestImportProductsWrongFile tests whether ImportProducts func rejects a file it can not read
+func TestImportProductsWrongFile(t *testing.T) {
+	for _, test := range []struct {
+		query  string
+		file   string
+		detail string
+	}{
+		{"", "", "The CSV file is empty, its first row must name the columns"},
+		{"", "product_name,weight\n", `The column "weight" is unknown, name it after an exported column or map it with map.{column}=weight`},
+		{"", "product_name,Product_Name\n", "The column product_name is given more than once"},
+		{"?map.title=Name", "Name\n", "The column title of the map.title parameter is unknown"},
+		{"?dry_run=maybe", "product_name\n", "The dry_run parameter must be true or false"},
+		{"", "product_name\n\"Vomero\n", "The CSV file can not be read: parse error on line 2, column 9: extraneous or missing \" in quoted-field"},
+	} {
+		rr := importProducts(store.NewMemoryStore(), test.query, "", test.file)
+		var problem api.Problem
+		assert.Equal(t, 400, rr.Code, "Bad Request response is expected for %q", test.file)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, test.detail, problem.Detail)
+	}
+}
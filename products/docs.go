@@ -0,0 +1,115 @@
+package products
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/openapi"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"net/http"
+)
+
+// Operations describes the product handlers for the OpenAPI document
+func Operations() openapi.Operations {
+	o := openapi.Operations{}
+	o.Add((*Handler).GetAllProducts, openapi.Operation{
+		Summary:    "Returns the page of the products with their availability and the price range of their variants",
+		Parameters: openapi.ProductListParameters,
+		Response:   []listedProduct{},
+		Paged:      true,
+		Errors:     []int{http.StatusBadRequest},
+	})
+	o.Add((*Handler).GetProductsOfCategory, openapi.Operation{
+		Summary: "Returns the page of the products of the category",
+		Parameters: append([]openapi.Parameter{
+			openapi.Query("descendants", "boolean", "Also returns the products of all the subcategories"),
+		}, openapi.ProductListParameters...),
+		Response: []listedProduct{},
+		Paged:    true,
+		Errors:   []int{http.StatusBadRequest},
+	})
+	o.Add((*Handler).SearchProducts, openapi.Operation{
+		Summary: "Returns the products matching the text, the most relevant first",
+		Parameters: []openapi.Parameter{
+			{Name: "q", In: "query", Description: "The search text", Required: true, Schema: &openapi.Schema{Type: "string"}},
+			openapi.Query("limit", "integer", "The number of results, 20 by default"),
+		},
+		Response: []store.ProductMatch{},
+		Errors:   []int{http.StatusBadRequest},
+	})
+	o.Add((*Handler).GetFacets, openapi.Operation{
+		Summary: "Counts the matching products by their attribute values, price buckets and subcategories",
+		Parameters: append([]openapi.Parameter{
+			openapi.Query("category", "string", "Counts the products of the category and all its subcategories"),
+			openapi.Query("price_step", "integer", "The width of the price buckets in minor units, 50 major units of the currency by default"),
+		}, openapi.ProductListParameters[3:]...),
+		Response: facets{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+	o.Add((*Handler).ExportProducts, openapi.Operation{
+		Summary: "Returns the matching products as a CSV file, the attributes in the attr.{name} columns",
+		Parameters: append([]openapi.Parameter{
+			openapi.Query("category", "string", "Exports the products of the category and all its subcategories"),
+		}, openapi.ProductListParameters[2:]...),
+		Response: openapi.CSV{},
+		Errors:   []int{http.StatusBadRequest},
+	})
+	o.Add((*Handler).ImportProducts, openapi.Operation{
+		Summary:    "Creates and updates the products from the rows of a CSV file in the columns of the export",
+		Parameters: openapi.ImportParameters,
+		Request:    openapi.CSV{},
+		Response:   api.ImportResult{},
+		CSVReport:  true,
+		Errors:     []int{http.StatusBadRequest},
+	})
+	o.Add((*Handler).GetProductById, openapi.Operation{
+		Summary: "Returns the product, with its price resolved in the currency and price list if they are given",
+		Parameters: []openapi.Parameter{
+			openapi.Query("currency", "string", "The currency the price is converted to, e.g. USD"),
+			openapi.Query("pricelist", "string", "The price list the price is taken from"),
+		},
+		Response: pricedProduct{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+	o.Add((*Handler).CreateProduct, openapi.Operation{
+		Summary:    "Creates the product",
+		Parameters: openapi.IdempotencyParameters,
+		Request:    product{},
+		Status:     http.StatusCreated,
+		Response:   product{},
+		Errors:     []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).UpdateProduct, openapi.Operation{
+		Summary:  "Changes the fields of the product given in the JSON Merge Patch",
+		Request:  product{},
+		Response: product{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).ReplaceProduct, openapi.Operation{
+		Summary:  "Replaces the whole product",
+		Request:  product{},
+		Response: product{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).DeleteProduct, openapi.Operation{
+		Summary:  "Deletes the product",
+		Response: "",
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).GetProductPrices, openapi.Operation{
+		Summary:  "Returns the applied and the scheduled prices of the product",
+		Response: []store.PriceChange{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).SchedulePriceChange, openapi.Operation{
+		Summary:  "Schedules a future price of the product, and the price coming back after Until",
+		Request:  priceSchedule{},
+		Status:   http.StatusCreated,
+		Response: []store.PriceChange{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).CancelPriceChange, openapi.Operation{
+		Summary:  "Cancels the scheduled price change",
+		Response: "",
+		Errors:   []int{http.StatusNotFound, http.StatusConflict},
+	})
+	return o
+}
@@ -0,0 +1,348 @@
+package products
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// defaultPriceStep is the width of the price buckets in major units when the request does not give price_step,
+// so it is 50.00 EUR, 50 JPY or 50.000 KWD
+const defaultPriceStep = 50
+
+// facets are the counts of the products matching the filters of the request, broken down to refine the filters further
+type facets struct {
+	// Total is the number of products matching all the filters
+	Total      int              `json:"Total"`
+	Attributes []attributeFacet `json:"Attributes"`
+	Prices     []priceBucket    `json:"Prices"`
+	Categories []categoryCount  `json:"Categories"`
+}
+
+// attributeFacet counts the products with every value of the attribute.
+// The filter of the attribute itself is not applied, so the other values can still be picked.
+type attributeFacet struct {
+	Name   string       `json:"Name"`
+	Type   string       `json:"Type,omitempty"`
+	Unit   string       `json:"Unit,omitempty"`
+	Values []valueCount `json:"Values"`
+}
+
+// valueCount is the number of products with the attribute value, given as store.AttributeText
+type valueCount struct {
+	Value string `json:"Value"`
+	Count int    `json:"Count"`
+}
+
+// priceBucket is the number of products priced from Min to Max, both ends included,
+// the price filters are not applied to it
+type priceBucket struct {
+	Min   money.Money `json:"Min"`
+	Max   money.Money `json:"Max"`
+	Count int         `json:"Count"`
+}
+
+// categoryCount is the number of products in the subcategory and all its own subcategories
+type categoryCount struct {
+	CategoryID   string `json:"CategoryID"`
+	CategoryName string `json:"CategoryName"`
+	Count        int    `json:"Count"`
+}
+
+// GetFacets returns the number of the products matching the filters of the request query for every value
+// of every attribute, for every price bucket of price_step minor units, 50 major units of the currency without it,
+// and for every subcategory.
+// A product with variants is counted once for every value and price bucket one of its variants has,
+// the variant having the attributes of the product and its own ones and the price of Variant.PriceOf.
+// The products are taken from the category given in the category parameter and all its subcategories,
+// or from the whole catalog without it, and filtered with name_contains, currency, price_min, price_max
+// and the attr.{name} parameters.
+func (h *Handler) GetFacets(w http.ResponseWriter, r *http.Request) {
+	//read the filters and the width of the price buckets
+	//or report an error
+	q, err := api.ParseListQuery(r, store.SortCreated, store.SortName, store.SortPrice)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	var step int64
+	if param := r.URL.Query().Get("price_step"); param != "" {
+		if step, err = strconv.ParseInt(param, 10, 64); err != nil || step < 1 {
+			api.WriteError(w, r, api.BadRequest("The price_step must be a positive number of minor currency units"))
+			return
+		}
+	}
+
+	//get the attribute schema of the category from the link
+	//or report an error
+	categoryID := r.URL.Query().Get("category")
+	var schema []store.Attribute
+	if categoryID != "" {
+		if schema, err = h.schema(categoryID); err != nil {
+			api.WriteError(w, r, err)
+			return
+		} else if schema == nil {
+			api.WriteError(w, r, api.NotFound("Category with ID %s not found", categoryID))
+			return
+		}
+	}
+
+	//get all the products of the category, the other filters are applied while counting
+	//or report an error
+	page, err := h.store.ListProducts(store.ListQuery{CategoryID: categoryID, IncludeDescendants: true})
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	ids := make([]string, 0, len(page.Items))
+	for _, p := range page.Items {
+		ids = append(ids, p.ProductID)
+	}
+	variants, err := h.store.VariantsOf(ids)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	tree, err := h.store.CategoryTree()
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	sold := make([]offers, 0, len(page.Items))
+	for _, p := range page.Items {
+		sold = append(sold, offersOf(p, variants[p.ProductID]))
+	}
+	result := facets{
+		Attributes: attributeFacets(sold, q, schema),
+		Prices:     priceBuckets(sold, q, step),
+		Categories: categoryCounts(sold, q, subcategories(tree, categoryID)),
+	}
+	for _, o := range sold {
+		if o.match(q) {
+			result.Total++
+		}
+	}
+
+	api.WriteJSON(w, http.StatusOK, result)
+}
+
+// offers are the ways a product is sold: every variant as the product with the attributes and the price
+// of the variant, or the product itself if it has no variants
+type offers []product
+
+// offersOf returns the offers of the product with the variants
+func offersOf(p product, variants []store.Variant) offers {
+	if len(variants) == 0 {
+		return offers{p}
+	}
+	result := make(offers, 0, len(variants))
+	for _, v := range variants {
+		offer := p
+		offer.Price = v.PriceOf(p)
+		offer.Attributes = make(map[string]interface{}, len(p.Attributes)+len(v.Attributes))
+		for name, value := range p.Attributes {
+			offer.Attributes[name] = value
+		}
+		for name, value := range v.Attributes {
+			offer.Attributes[name] = value
+		}
+		result = append(result, offer)
+	}
+	return result
+}
+
+// match reports whether one of the offers matches the query
+func (o offers) match(q store.ListQuery) bool {
+	for _, offer := range o {
+		if q.MatchesProduct(offer) {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeFacets counts the values of the attributes of the schema and of the other attributes the products have
+func attributeFacets(products []offers, q store.ListQuery, schema []store.Attribute) []attributeFacet {
+	//the attributes outside the schema are given by name, after the schema ones
+	attributes := append([]store.Attribute{}, schema...)
+	known := make(map[string]bool, len(schema))
+	for _, a := range schema {
+		known[a.Name] = true
+	}
+	var others []string
+	for _, o := range products {
+		for _, offer := range o {
+			for name := range offer.Attributes {
+				if !known[name] {
+					known[name] = true
+					others = append(others, name)
+				}
+			}
+		}
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		attributes = append(attributes, store.Attribute{Name: name})
+	}
+
+	result := make([]attributeFacet, 0, len(attributes))
+	for _, a := range attributes {
+		//the other values of the attribute stay countable while one of them is picked
+		others := q
+		others.Attributes = make(map[string]string, len(q.Attributes))
+		for name, text := range q.Attributes {
+			if name != a.Name {
+				others.Attributes[name] = text
+			}
+		}
+
+		//a product is counted once for every value its matching offers have
+		counts := make(map[string]int)
+		for _, o := range products {
+			values := make(map[string]bool)
+			for _, offer := range o {
+				if value, ok := offer.Attributes[a.Name]; ok && others.MatchesProduct(offer) {
+					values[store.AttributeText(value)] = true
+				}
+			}
+			for value := range values {
+				counts[value]++
+			}
+		}
+		result = append(result, attributeFacet{Name: a.Name, Type: a.Type, Unit: a.Unit, Values: valueCounts(a, counts)})
+	}
+	return result
+}
+
+// valueCounts orders the counted values: the values of an enum in the order of the schema, even the ones
+// no product has, then the numbers from the smallest and the other values alphabetically
+func valueCounts(a store.Attribute, counts map[string]int) []valueCount {
+	result := make([]valueCount, 0, len(counts)+len(a.Values))
+	listed := make(map[string]bool, len(a.Values))
+	if a.Type == store.AttributeEnum {
+		for _, value := range a.Values {
+			listed[value] = true
+			result = append(result, valueCount{Value: value, Count: counts[value]})
+		}
+	}
+
+	rest := make([]string, 0, len(counts))
+	for value := range counts {
+		if !listed[value] {
+			rest = append(rest, value)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		x, errX := strconv.ParseFloat(rest[i], 64)
+		y, errY := strconv.ParseFloat(rest[j], 64)
+		if a.Type == store.AttributeNumber && errX == nil && errY == nil {
+			return x < y
+		}
+		return rest[i] < rest[j]
+	})
+	for _, value := range rest {
+		result = append(result, valueCount{Value: value, Count: counts[value]})
+	}
+	return result
+}
+
+// priceBuckets counts the products in the price ranges of step minor units, or of defaultPriceStep
+// major units if step is 0, which have some, ordered by the currency and then from the cheapest
+func priceBuckets(products []offers, q store.ListQuery, step int64) []priceBucket {
+	//the other price ranges stay countable while one of them is picked
+	q.PriceMin, q.PriceMax = nil, nil
+
+	type bucketKey struct {
+		currency string
+		index    int64
+	}
+	//a product is counted once in every bucket the prices of its matching offers fall in
+	counts := make(map[bucketKey]int)
+	for _, o := range products {
+		buckets := make(map[bucketKey]bool)
+		for _, offer := range o {
+			if q.MatchesProduct(offer) {
+				buckets[bucketKey{offer.Price.Currency, offer.Price.Amount / bucketStep(offer.Price.Currency, step)}] = true
+			}
+		}
+		for key := range buckets {
+			counts[key]++
+		}
+	}
+
+	result := make([]priceBucket, 0, len(counts))
+	for key, count := range counts {
+		step := bucketStep(key.currency, step)
+		result = append(result, priceBucket{
+			Min:   money.New(key.index*step, key.currency),
+			Max:   money.New((key.index+1)*step-1, key.currency),
+			Count: count,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Min.Currency != result[j].Min.Currency {
+			return result[i].Min.Currency < result[j].Min.Currency
+		}
+		return result[i].Min.Amount < result[j].Min.Amount
+	})
+	return result
+}
+
+// categoryCounts counts the products matching the query in every subcategory at any depth under the given ones
+func categoryCounts(products []offers, q store.ListQuery, children []store.CategoryNode) []categoryCount {
+	result := make([]categoryCount, 0, len(children))
+	for _, child := range children {
+		count := 0
+		subtree := subtreeIDs(child, make(map[string]bool))
+		for _, o := range products {
+			if subtree[o[0].CategoryID] && o.match(q) {
+				count++
+			}
+		}
+		result = append(result, categoryCount{CategoryID: child.CategoryID, CategoryName: child.CategoryName, Count: count})
+	}
+	return result
+}
+
+// subcategories returns the categories directly under the category in the tree,
+// or the top-level categories if categoryID is empty
+func subcategories(tree []store.CategoryNode, categoryID string) []store.CategoryNode {
+	if categoryID == "" {
+		return tree
+	}
+	for _, node := range tree {
+		if node.CategoryID == categoryID {
+			return node.Children
+		}
+		if children := subcategories(node.Children, categoryID); children != nil {
+			return children
+		}
+	}
+	return nil
+}
+
+// subtreeIDs adds the ids of the category and all the categories under it to ids
+func subtreeIDs(node store.CategoryNode, ids map[string]bool) map[string]bool {
+	ids[node.CategoryID] = true
+	for _, child := range node.Children {
+		subtreeIDs(child, ids)
+	}
+	return ids
+}
+
+// bucketStep returns step, or defaultPriceStep major units of the currency in its minor units if step is 0
+func bucketStep(currency string, step int64) int64 {
+	if step > 0 {
+		return step
+	}
+	step = defaultPriceStep
+	exponent, _ := money.Exponent(currency)
+	for i := 0; i < exponent; i++ {
+		step *= 10
+	}
+	return step
+}
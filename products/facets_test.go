@@ -0,0 +1,202 @@
+//package products contains test for facets.go
+package products
+
+import (
+	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//TestGetFacets tests whether GetFacets func counts the products of the category by their attributes, prices and subcategories
+func TestGetFacets(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, c := range []store.Category{
+		{CategoryID: "footwear", CategoryName: "Footwear", Attributes: []store.Attribute{
+			{Name: "size", Type: store.AttributeEnum, Values: []string{"38", "39", "40"}},
+			{Name: "waterproof", Type: store.AttributeBool},
+		}},
+		{CategoryID: "running", CategoryName: "Running Shoes", ParentID: "footwear", Attributes: []store.Attribute{
+			{Name: "drop", Type: store.AttributeNumber, Unit: "mm"},
+		}},
+		{CategoryID: "trail", CategoryName: "Trail Shoes", ParentID: "footwear"},
+	} {
+		if err := catalog.CreateCategory(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []product{
+		{ProductID: "pegasus", ProductName: "Pegasus", Price: money.New(9000, "EUR"), CategoryID: "running", Attributes: map[string]interface{}{"size": "38", "drop": 10.0}},
+		{ProductID: "vomero", ProductName: "Vomero", Price: money.New(12000, "EUR"), CategoryID: "running", Attributes: map[string]interface{}{"size": "39", "drop": 8.0}},
+		{ProductID: "boot", ProductName: "Boot", Price: money.New(4000, "EUR"), CategoryID: "footwear", Attributes: map[string]interface{}{"size": "38", "waterproof": true}},
+		{ProductID: "terra", ProductName: "Terra", Price: money.New(12500, "EUR"), CategoryID: "trail", Attributes: map[string]interface{}{"size": "40"}},
+	} {
+		if err := catalog.CreateProduct(p, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []struct {
+		query        string
+		expected     string
+		expectedCode int
+	}{
+		{
+			"category=footwear&attr.size=38",
+			`{"Total":2,"Attributes":[` +
+				`{"Name":"size","Type":"enum","Values":[{"Value":"38","Count":2},{"Value":"39","Count":1},{"Value":"40","Count":1}]},` +
+				`{"Name":"waterproof","Type":"bool","Values":[{"Value":"true","Count":1}]},` +
+				`{"Name":"drop","Values":[{"Value":"10","Count":1}]}],` +
+				`"Prices":[{"Min":{"Amount":0,"Currency":"EUR","Formatted":"0.00 EUR"},"Max":{"Amount":4999,"Currency":"EUR","Formatted":"49.99 EUR"},"Count":1},` +
+				`{"Min":{"Amount":5000,"Currency":"EUR","Formatted":"50.00 EUR"},"Max":{"Amount":9999,"Currency":"EUR","Formatted":"99.99 EUR"},"Count":1}],` +
+				`"Categories":[{"CategoryID":"running","CategoryName":"Running Shoes","Count":1},{"CategoryID":"trail","CategoryName":"Trail Shoes","Count":0}]}`,
+			200,
+		},
+		{
+			"category=running&price_min=10000&price_step=10000",
+			`{"Total":1,"Attributes":[` +
+				`{"Name":"size","Type":"enum","Values":[{"Value":"38","Count":0},{"Value":"39","Count":1},{"Value":"40","Count":0}]},` +
+				`{"Name":"waterproof","Type":"bool","Values":[]},` +
+				`{"Name":"drop","Type":"number","Unit":"mm","Values":[{"Value":"8","Count":1}]}],` +
+				`"Prices":[{"Min":{"Amount":0,"Currency":"EUR","Formatted":"0.00 EUR"},"Max":{"Amount":9999,"Currency":"EUR","Formatted":"99.99 EUR"},"Count":1},` +
+				`{"Min":{"Amount":10000,"Currency":"EUR","Formatted":"100.00 EUR"},"Max":{"Amount":19999,"Currency":"EUR","Formatted":"199.99 EUR"},"Count":1}],` +
+				`"Categories":[]}`,
+			200,
+		},
+		{
+			"category=randomID",
+			`{"type":"/problems/not-found","title":"Not Found","status":404,"detail":"Category with ID randomID not found","instance":"/products/facets"}`,
+			404,
+		},
+		{
+			"price_step=0",
+			`{"type":"/problems/bad-request","title":"Bad Request","status":400,"detail":"The price_step must be a positive number of minor currency units","instance":"/products/facets"}`,
+			400,
+		},
+	} {
+		req, err := http.NewRequest("GET", "/products/facets?"+p.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(NewHandler(catalog).GetFacets).ServeHTTP(rr, req)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.query)
+		assert.JSONEq(t, p.expected, rr.Body.String(), "Expected other facets for %s", p.query)
+	}
+
+	//without a category the whole catalog is counted by the top-level categories
+	req, err := http.NewRequest("GET", "/products/facets", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).GetFacets).ServeHTTP(rr, req)
+	var all facets
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &all))
+	assert.Equal(t, 6, all.Total, "Expected the seeded products and the shoes")
+	counts := make(map[string]int)
+	for _, c := range all.Categories {
+		counts[c.CategoryID] = c.Count
+	}
+	assert.Equal(t, 4, counts["footwear"], "Expected the shoes of all the subcategories in footwear")
+}
+
+//TestGetFacetsVariants tests whether GetFacets func counts the attribute values and the prices of the variants,
+//every product once for every value and price bucket
+func TestGetFacetsVariants(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	higher := money.New(12000, "EUR")
+	for _, v := range []store.Variant{
+		{VariantID: "v38", ProductID: "bq4foj37jhfipc5nqri0", SKU: "SRG-38", Attributes: map[string]string{"size": "38"}},
+		{VariantID: "v40", ProductID: "bq4foj37jhfipc5nqri0", SKU: "SRG-40", Attributes: map[string]string{"size": "40"}, Price: &higher},
+		{VariantID: "v42", ProductID: "bq4foj37jhfipc5nqri0", SKU: "SRG-42", Attributes: map[string]string{"size": "42"}, Price: &higher},
+	} {
+		if err := catalog.CreateVariant(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []struct {
+		query      string
+		total      int
+		sizes      []valueCount
+		prices     map[int64]int
+	}{
+		{
+			query:  "category=bq4fasj7jhfi127rimlg&price_step=2000",
+			total:  2,
+			sizes:  []valueCount{{Value: "38", Count: 1}, {Value: "40", Count: 1}, {Value: "42", Count: 1}},
+			prices: map[int64]int{4000: 1, 10000: 1, 12000: 1},
+		},
+		{
+			query:  "category=bq4fasj7jhfi127rimlg&price_step=2000&attr.size=42",
+			total:  1,
+			sizes:  []valueCount{{Value: "38", Count: 1}, {Value: "40", Count: 1}, {Value: "42", Count: 1}},
+			prices: map[int64]int{12000: 1},
+		},
+		{
+			query:  "category=bq4fasj7jhfi127rimlg&price_step=2000&price_max=11000",
+			total:  2,
+			sizes:  []valueCount{{Value: "38", Count: 1}},
+			prices: map[int64]int{4000: 1, 10000: 1, 12000: 1},
+		},
+	} {
+		req, err := http.NewRequest("GET", "/products/facets?"+p.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(NewHandler(catalog).GetFacets).ServeHTTP(rr, req)
+		var result facets
+		assert.Equal(t, 200, rr.Code, "OK response is expected for %s", p.query)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+
+		assert.Equal(t, p.total, result.Total, "Expected the products with a matching variant for %s", p.query)
+		if assert.Len(t, result.Attributes, 1, "Expected the size of the variants for %s", p.query) {
+			assert.Equal(t, "size", result.Attributes[0].Name)
+			assert.Equal(t, p.sizes, result.Attributes[0].Values, "Expected the products counted by the sizes of the variants for %s", p.query)
+		}
+		prices := make(map[int64]int)
+		for _, bucket := range result.Prices {
+			prices[bucket.Min.Amount] = bucket.Count
+		}
+		assert.Equal(t, p.prices, prices, "Expected the products counted by the prices of the variants for %s", p.query)
+	}
+}
+
+//TestGetFacetsDefaultPriceStep tests whether GetFacets func buckets the prices by 50 major units of every currency without price_step
+func TestGetFacetsDefaultPriceStep(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	if err := catalog.CreateCategory(store.Category{CategoryID: "watches", CategoryName: "Watches"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []product{
+		{ProductID: "eur", ProductName: "Euro Watch", Price: money.New(7500, "EUR"), CategoryID: "watches"},
+		{ProductID: "jpy", ProductName: "Yen Watch", Price: money.New(7500, "JPY"), CategoryID: "watches"},
+		{ProductID: "kwd", ProductName: "Dinar Watch", Price: money.New(7500, "KWD"), CategoryID: "watches"},
+	} {
+		if err := catalog.CreateProduct(p, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/products/facets?category=watches", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).GetFacets).ServeHTTP(rr, req)
+	var result facets
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, []priceBucket{
+		{Min: money.New(5000, "EUR"), Max: money.New(9999, "EUR"), Count: 1},
+		{Min: money.New(7500, "JPY"), Max: money.New(7549, "JPY"), Count: 1},
+		{Min: money.New(0, "KWD"), Max: money.New(49999, "KWD"), Count: 1},
+	}, result.Prices, "Expected the buckets of 50.00 EUR, 50 JPY and 50.000 KWD")
+}
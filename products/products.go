@@ -2,237 +2,593 @@
 package products
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/KseniiaL/AdcashTestAssignment/categories"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/pricing"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
 	"github.com/gorilla/mux"
 	"github.com/rs/xid"
-	"io/ioutil"
-	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// defaultSearchLimit is the number of search results returned when the request does not give a limit
+const defaultSearchLimit = 20
+
 // product stores information about product fields.
-type product struct {
-	ProductID          string `json:"ProductID"`
-	ProductName        string `json:"ProductName"`
-	ProductDescription string `json:"ProductDescription"`
-	Price			   int	  `json:"Price"`
-	CategoryID 		   string `json:"CategoryID"`
-}
-
-// allProducts is the slice of product structs
-type allProducts []product
-
-// products is the simple imitation of the DB (products table)
-var products = allProducts {
-	{
-		ProductID: 			"bq4foj37jhfipc5nqri0",
-		ProductName: 		"Nike SuperRep Go",
-		ProductDescription: "Women's Training Shoe",
-		Price: 				100,
-		CategoryID: 		"bq4fasj7jhfi127rimlg",
-	},
-	{
-		ProductID: 			"bq5457j7jhfi2s58o030",
-		ProductName: 		"Nike Icon Clash",
-		ProductDescription: "Women's Seamless Light-Support Sports Bra",
-		Price: 				50,
-		CategoryID: 		"bq4fasj7jhfi127rimlg",
-	},
-}
-
-// GetAllProducts returns products slice in JSON format as a response
-func GetAllProducts(w http.ResponseWriter, r *http.Request) {
-	//define new encoder that writes to the w
-	if err := json.NewEncoder(w).Encode(products); err != nil {
-		log.Printf(err.Error())
-		w.WriteHeader(500)
-	}
-}
-
-// GetProductById gets a product id from the request link and looks for the corresponding item in []products
-func GetProductById(w http.ResponseWriter, r *http.Request) {
+type product = store.Product
+
+// pricedProduct is the product with its price resolved for the requested currency and price list
+type pricedProduct struct {
+	product
+	// BasePrice is the stored price of the product the resolved one comes from
+	BasePrice money.Money `json:"BasePrice"`
+	PriceList string      `json:"PriceList,omitempty"`
+}
+
+// listedProduct is the product on a product list with its availability
+type listedProduct struct {
+	product
+	// Available is the number of pieces of the product and its variants which can still be reserved in all the warehouses
+	Available  int64 `json:"Available"`
+	OutOfStock bool  `json:"OutOfStock"`
+	// PriceRange is the lowest and the highest price of the variants, it is not set for a product without variants
+	PriceRange *priceRange `json:"PriceRange,omitempty"`
+}
+
+// priceRange is the lowest and the highest price of the variants of a product
+type priceRange struct {
+	Min money.Money `json:"Min"`
+	Max money.Money `json:"Max"`
+}
+
+// priceSchedule is the request body of SchedulePriceChange
+type priceSchedule struct {
+	Price money.Money `json:"Price"`
+	// EffectiveAt is when the product gets the price
+	EffectiveAt time.Time `json:"EffectiveAt"`
+	// Until is when the product gets back the price it had before EffectiveAt, never if it is not set
+	Until *time.Time `json:"Until"`
+}
+
+// Handler contains all the product handlers, the store and the price book they work with
+type Handler struct {
+	store store.CatalogStore
+	book  *pricing.Book
+}
+
+// NewHandler returns a Handler which reads and writes products through the given store,
+// without price lists and exchange rates
+func NewHandler(s store.CatalogStore) *Handler {
+	return NewPricingHandler(s, pricing.NewBook())
+}
+
+// NewPricingHandler returns a Handler which reads and writes products through the given store
+// and resolves their prices with the exchange rates and price lists of the book
+func NewPricingHandler(s store.CatalogStore, b *pricing.Book) *Handler {
+	return &Handler{store: s, book: b}
+}
+
+// GetAllProducts returns the products in JSON format as a response, every product with the number
+// of its available pieces, flagged OutOfStock if there are none, and with the PriceRange of its variants.
+// The list can be paged, sorted and filtered with the query parameters read by api.ParseListQuery.
+func (h *Handler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
+	h.listProducts(w, r, "", false)
+}
+
+// GetProductById gets a product id from the request link and looks for the corresponding item in the store.
+// With the currency or pricelist query parameters the price is resolved in that currency and price list,
+// and the stored price is returned in BasePrice.
+func (h *Handler) GetProductById(w http.ResponseWriter, r *http.Request) {
 	//get product id from the link
 	productID := mux.Vars(r)["id"]
 
-	//find the product with the given id in the slice
-	var prod product
-	for _, singleProduct := range products {
-		if singleProduct.ProductID == productID {
-			prod = singleProduct
-			break
-		}
+	//find the product with the given id in the store
+	//or report an error
+	prod, err := h.store.Product(productID)
+	if err != nil {
+		api.WriteError(w, r, productError(err, product{ProductID: productID}))
+		return
 	}
 
-	//return the product information to ResponseWriter
-	//or log the encoding error
-	if prod.ProductID == productID {
-		if err := json.NewEncoder(w).Encode(prod); err != nil {
-			log.Printf(err.Error())
-			w.WriteHeader(500)
-			return
-		}
-	} else {
-		w.WriteHeader(412)
-		fmt.Fprintf(w, "Product with ID %s not found", productID)
+	//get the currency and price list from the link
+	currency, listName := r.URL.Query().Get("currency"), r.URL.Query().Get("pricelist")
+	if currency == "" && listName == "" {
+		//return the product information to ResponseWriter
+		api.WriteJSON(w, http.StatusOK, prod)
 		return
 	}
+	if _, ok := money.Exponent(currency); currency != "" && !ok {
+		api.WriteError(w, r, api.BadRequest("Kindly enter an ISO 4217 currency code in the currency parameter, e.g. EUR"))
+		return
+	}
+
+	//resolve the price
+	//or report an error
+	price, err := h.book.Resolve(prod.ProductID, prod.Price, currency, listName)
+	if err != nil {
+		api.WriteError(w, r, priceError(err, listName))
+		return
+	}
+
+	//return the product with the resolved price
+	api.WriteJSON(w, http.StatusOK, pricedProduct{product: withPrice(prod, price), BasePrice: prod.Price, PriceList: listName})
 }
 
-// GetProductsOfCategory gets a category id from the request link and returns all products of the given category in response
-func GetProductsOfCategory(w http.ResponseWriter, r *http.Request) {
+// GetProductsOfCategory gets a category id from the request link and returns the products of the given category in response.
+// With descendants=true in the query the products of all its subcategories are returned too.
+// The list can be paged, sorted and filtered in the same way as GetAllProducts.
+func (h *Handler) GetProductsOfCategory(w http.ResponseWriter, r *http.Request) {
 	//get category id from the link
 	categoryID := mux.Vars(r)["id"]
 
-	//define new slice and append it with products which have the same categoryID
-	productsOfCategory := make([]product, 0)
-	for _, singleProduct := range products {
-		if singleProduct.CategoryID == categoryID {
-			productsOfCategory = append(productsOfCategory, singleProduct)
+	//check whether the subcategories are included
+	//or report an error
+	includeDescendants := false
+	if param := r.URL.Query().Get("descendants"); param != "" {
+		var err error
+		if includeDescendants, err = strconv.ParseBool(param); err != nil {
+			api.WriteError(w, r, api.BadRequest("The descendants parameter must be true or false"))
+			return
 		}
 	}
 
-	//return the products list to ResponseWriter
-	//or log the encoding error
-	if err := json.NewEncoder(w).Encode(productsOfCategory); err != nil {
-		log.Printf(err.Error())
-		w.WriteHeader(500)
+	h.listProducts(w, r, categoryID, includeDescendants)
+}
+
+// listProducts returns the page of the products asked for in the request query,
+// only the products of the given category (and its subcategories if includeDescendants is set) if categoryID is not empty
+func (h *Handler) listProducts(w http.ResponseWriter, r *http.Request, categoryID string, includeDescendants bool) {
+	//read the paging, sorting and filtering parameters
+	//or report an error
+	q, err := api.ParseListQuery(r, store.SortCreated, store.SortName, store.SortPrice)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	q.CategoryID = categoryID
+	q.IncludeDescendants = includeDescendants
+
+	//get the page from the store
+	//or report an error
+	page, err := h.store.ListProducts(q)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//flag the products which can not be reserved
+	//or report an error
+	productIDs := make([]string, 0, len(page.Items))
+	for _, p := range page.Items {
+		productIDs = append(productIDs, p.ProductID)
 	}
+	summary, err := h.store.StockSummary(productIDs)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//summarize the prices of the variants
+	//or report an error
+	variants, err := h.store.VariantsOf(productIDs)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	items := make([]listedProduct, 0, len(page.Items))
+	for _, p := range page.Items {
+		available := summary[p.ProductID].Available
+		items = append(items, listedProduct{product: p, Available: available, OutOfStock: available == 0,
+			PriceRange: newPriceRange(p, variants[p.ProductID])})
+	}
+
+	api.WritePage(w, r, items, page.Total, page.Next)
+}
+
+// newPriceRange returns the lowest and the highest price of the variants of the product,
+// or nil if it has no variants priced in its currency
+func newPriceRange(p product, variants []store.Variant) *priceRange {
+	var prices *priceRange
+	for _, v := range variants {
+		price := v.PriceOf(p)
+		//the variants priced before the product changed its currency can not be compared
+		if price.Currency != p.Price.Currency {
+			continue
+		}
+		if prices == nil {
+			prices = &priceRange{Min: price, Max: price}
+		} else if price.Amount < prices.Min.Amount {
+			prices.Min = price
+		} else if price.Amount > prices.Max.Amount {
+			prices.Max = price
+		}
+	}
+	return prices
+}
+
+// SearchProducts returns the products matching the text in the q query parameter, the most relevant first.
+// The words of the text also match the words starting with them and the words with a typo,
+// the limit query parameter sets the number of results.
+func (h *Handler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	//get the search text and the number of results from the link
+	//or report an error
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		api.WriteError(w, r, api.BadRequest("Kindly enter the search text in the q parameter"))
+		return
+	}
+	limit, err := api.ParseLimit(r, defaultSearchLimit)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//search the store
+	//or report an error
+	found, err := h.store.SearchProducts(query, limit)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, found)
+}
+
+// GetProductPrices gets a product id from the request link and returns its price timeline:
+// the prices it has had and the scheduled ones, ordered by the time they take effect
+func (h *Handler) GetProductPrices(w http.ResponseWriter, r *http.Request) {
+	//get product id from the link
+	productID := mux.Vars(r)["id"]
+
+	//find the timeline of the product in the store
+	//or report an error
+	timeline, err := h.store.PriceHistory(productID)
+	if err != nil {
+		api.WriteError(w, r, productError(err, product{ProductID: productID}))
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, timeline)
 }
 
-// DeleteProduct gets a product id from the request link and removes corresponding item from the slice
-func DeleteProduct(w http.ResponseWriter, r *http.Request) {
+// SchedulePriceChange gets a product id from the request link and schedules the price in the request body
+// to take effect at EffectiveAt. With Until the price the product has before EffectiveAt is scheduled to come back,
+// e.g. at the end of a sale, as a change which Reverts the first one. Its price is the one expected now, the price
+// which comes back is resolved when it is applied, and canceling either change cancels both.
+// The scheduled changes are returned in response.
+func (h *Handler) SchedulePriceChange(w http.ResponseWriter, r *http.Request) {
 	//get product id from the link
 	productID := mux.Vars(r)["id"]
-	productsLength := len(products)
+	var schedule priceSchedule
+
+	//unmarshal the information from JSON into the schedule instance
+	//or report an error
+	if err := api.ReadJSON(r, &schedule); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
 
-	//find the product with the given id and remove from the slice
-	for i, singleProduct := range products {
-		if singleProduct.ProductID == productID {
-			products = append(products[:i], products[i+1:]...)
-			fmt.Fprintf(w, "The category with ID %v has been deleted successfully", productID)
+	//check the price and the times
+	now := time.Now()
+	if err := validateSchedule(schedule, now); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	changes := []store.PriceChange{newScheduledPrice(schedule.Price, schedule.EffectiveAt, r, now)}
+	if schedule.Until != nil {
+		//find the price the product is expected to have just before the change
+		//or report an error
+		previous, err := h.priceAt(productID, schedule.EffectiveAt)
+		if err != nil {
+			api.WriteError(w, r, productError(err, product{ProductID: productID}))
+			return
 		}
+		revert := newScheduledPrice(previous, *schedule.Until, r, now)
+		revert.Reverts = changes[0].ChangeID
+		changes = append(changes, revert)
 	}
 
-	//report product with the given id not exists
-	if productsLength == len(products) {
-		w.WriteHeader(412)
-		fmt.Fprintf(w, "Product with ID %s not found", productID)
+	//add the changes to the timeline of the product
+	//or report an error
+	if err := h.store.SchedulePriceChanges(productID, changes); err != nil {
+		api.WriteError(w, r, productError(err, product{ProductID: productID}))
 		return
 	}
+
+	//return the scheduled changes in response
+	for i := range changes {
+		changes[i].ProductID = productID
+		changes[i].Status = store.PriceScheduled
+	}
+	api.WriteJSON(w, http.StatusCreated, changes)
 }
 
-// CreateProduct creates a new sample of product, fills it with the information from the request body,
-// and appends to the []products slice
-func CreateProduct(w http.ResponseWriter, r *http.Request) {
-	var newProduct product
-	//get the information containing in request's body
+// CancelPriceChange gets a product id and a price change id from the request link
+// and removes the scheduled change from the timeline of the product, together with the change reverting it
+// or the change it reverts
+func (h *Handler) CancelPriceChange(w http.ResponseWriter, r *http.Request) {
+	//get product id and price change id from the link
+	productID, changeID := mux.Vars(r)["id"], mux.Vars(r)["changeID"]
+
+	//remove the change from the timeline
 	//or report an error
-	reqBody, err := ioutil.ReadAll(r.Body)
+	switch err := h.store.CancelPriceChange(productID, changeID); err {
+	case nil:
+	case store.ErrNotFound:
+		api.WriteError(w, r, api.NotFound("Scheduled price change with ID %s of product %s not found", changeID, productID))
+		return
+	case store.ErrPriceChangeApplied:
+		api.WriteError(w, r, api.Conflict("Price change with ID %s has already been applied", changeID))
+		return
+	default:
+		api.WriteError(w, r, err)
+		return
+	}
+	fmt.Fprintf(w, "The price change with ID %v has been canceled successfully", changeID)
+}
+
+// priceAt returns the price the product has at the given time according to its timeline
+func (h *Handler) priceAt(productID string, at time.Time) (money.Money, error) {
+	prod, err := h.store.Product(productID)
 	if err != nil {
-		fmt.Fprintf(w, "Kindly enter data with the product name and description only in order to create")
-		log.Fatal(err.Error())
+		return money.Money{}, err
 	}
+	timeline, err := h.store.PriceHistory(productID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	price := prod.Price
+	for _, c := range timeline {
+		if !c.EffectiveAt.Before(at) {
+			break
+		}
+		price = c.Price
+	}
+	return price, nil
+}
 
-	//unmarshal the information from JSON into the product instance
+// newScheduledPrice returns the change to the price at the given time, scheduled now by the author of the request
+func newScheduledPrice(price money.Money, effectiveAt time.Time, r *http.Request, now time.Time) store.PriceChange {
+	return store.PriceChange{
+		ChangeID:    xid.New().String(),
+		Price:       price,
+		EffectiveAt: effectiveAt.UTC(),
+		Author:      api.Author(r),
+		RecordedAt:  now.UTC(),
+	}
+}
+
+// validateSchedule returns a validation Problem if the price is invalid or the times are not in the future
+func validateSchedule(schedule priceSchedule, now time.Time) error {
+	fieldErrors := api.PriceErrors("Price", schedule.Price)
+	if schedule.EffectiveAt.IsZero() {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "EffectiveAt", Detail: "Kindly enter the time the price takes effect"})
+	} else if !schedule.EffectiveAt.After(now) {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "EffectiveAt", Detail: "The price change must take effect in the future"})
+	}
+	if schedule.Until != nil && !schedule.Until.After(schedule.EffectiveAt) {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "Until", Detail: "The previous price must come back after the change takes effect"})
+	}
+
+	if len(fieldErrors) != 0 {
+		return api.Validation(fieldErrors...)
+	}
+	return nil
+}
+
+// DeleteProduct gets a product id from the request link and removes corresponding item from the store
+func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	//get product id from the link
+	productID := mux.Vars(r)["id"]
+
+	//remove the product with the given id from the store
 	//or report an error
-	if err = json.Unmarshal(reqBody, &newProduct); err != nil {
-		log.Printf("Body parse error, %v", err.Error())
-		w.WriteHeader(400)
+	if err := h.store.DeleteProduct(productID); err != nil {
+		api.WriteError(w, r, productError(err, product{ProductID: productID}))
 		return
 	}
+	fmt.Fprintf(w, "The product with ID %v has been deleted successfully", productID)
+}
 
-	//ProductName is required field
-	if len(newProduct.ProductName) == 0 {
-		w.WriteHeader(422)
-		fmt.Fprintf(w, "Kindly enter data with the product name in order to create new category")
+// CreateProduct creates a new sample of product, fills it with the information from the request body,
+// and adds it to the store
+func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	var newProduct product
+
+	//unmarshal the information from JSON into the product instance
+	//or report an error
+	if err := api.ReadJSON(r, &newProduct); err != nil {
+		api.WriteError(w, r, err)
 		return
 	}
 
 	//generate unique productID
 	newProduct.ProductID = xid.New().String()
 
-	//check if the category given exists in categories
-	var usedCategory categories.Category
-	for _, singleCategory := range categories.Categories {
-		if singleCategory.CategoryID == newProduct.CategoryID {
-			usedCategory = singleCategory
-			break
+	//check the required fields and the attributes against the schema of the category and add the new product
+	//to the store in one transaction, so the schema can not change in between
+	//or report an error
+	err := h.store.Transaction(func(tx store.CatalogStore) error {
+		txHandler := &Handler{store: tx, book: h.book}
+		schema, err := txHandler.schema(newProduct.CategoryID)
+		if err != nil {
+			return err
 		}
-	}
-
-	//if exists append the new product to the slice
-	if len(newProduct.CategoryID) != 0 && usedCategory.CategoryID == newProduct.CategoryID {
-		products = append(products, newProduct)
-		w.WriteHeader(http.StatusCreated)
-
-		//return the product in response
-		//or report an error
-		if err = json.NewEncoder(w).Encode(newProduct); err != nil {
-			log.Printf(err.Error())
-			w.WriteHeader(500)
-			return
+		if err = validate(newProduct, schema); err != nil {
+			return err
 		}
-	} else {
-		w.WriteHeader(422)
-		fmt.Fprintf(w, "Category with ID \"%s\" not found. Kindly enter data with the category ID", newProduct.CategoryID)
+		return productError(tx.CreateProduct(newProduct, api.Author(r)), newProduct)
+	})
+	if err != nil {
+		api.WriteError(w, r, err)
 		return
 	}
+
+	//return the product in response
+	api.WriteJSON(w, http.StatusCreated, newProduct)
 }
 
-// UpdateProduct gets a product id from the request link and replaces the fields in the corresponding product
-// with the given ones in the request body
-func UpdateProduct(w http.ResponseWriter, r *http.Request) {
+// UpdateProduct gets a product id from the request link and applies the JSON Merge Patch (RFC 7386)
+// from the request body to the corresponding product, so only the fields given in the body are changed
+func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	//get product id from the link
 	productID := mux.Vars(r)["id"]
-	var updateProduct product
 
 	//get the information containing in request's body
 	//or report an error
-	reqBody, err := ioutil.ReadAll(r.Body)
+	reqBody, err := api.ReadBody(r)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//find the product, apply the patch to it and write it in one store transaction,
+	//so a change made by another request in between is not lost
+	//or report an error
+	var singleProduct product
+	err = h.store.Transaction(func(tx store.CatalogStore) error {
+		stored, err := tx.Product(productID)
+		if err != nil {
+			return productError(err, product{ProductID: productID})
+		}
+		if err = api.PatchResource(&stored, reqBody); err != nil {
+			return api.BadRequest("The request body is not a valid merge patch: %v", err)
+		}
+		//the id is taken from the link only
+		stored.ProductID = productID
+		singleProduct = stored
+		return (&Handler{store: tx, book: h.book}).writeProduct(stored, api.Author(r))
+	})
 	if err != nil {
-		fmt.Fprintf(w, "Kindly enter data with the product name and description only in order to update")
-		log.Fatal(err.Error())
+		api.WriteError(w, r, err)
+		return
 	}
 
+	//return the product in response
+	api.WriteJSON(w, http.StatusOK, singleProduct)
+}
+
+// ReplaceProduct gets a product id from the request link and replaces the whole corresponding product
+// with the one in the request body, the fields missing in the body are cleared
+func (h *Handler) ReplaceProduct(w http.ResponseWriter, r *http.Request) {
+	//get product id from the link
+	productID := mux.Vars(r)["id"]
+	var replaceProduct product
+
 	//unmarshal the information from JSON into the product instance
 	//or report an error
-	if err = json.Unmarshal(reqBody, &updateProduct); err != nil {
-		log.Printf("Body parse error, %v", err.Error())
-		w.WriteHeader(400)
-		return
-	}
-
-	//find the given product in the slice by id
-	for i, singleProduct := range products {
-		if singleProduct.ProductID == productID {
-			//change the fields
-			singleProduct.ProductName = updateProduct.ProductName
-			singleProduct.ProductDescription = updateProduct.ProductDescription
-			singleProduct.Price = updateProduct.Price
-
-			//check if a categoryID exists in the categories
-			var usedCategory categories.Category
-			for _, singleCategory := range categories.Categories {
-				if singleCategory.CategoryID == updateProduct.CategoryID {
-					usedCategory = singleCategory
-					break
-				}
-			}
-			//then replace the categoryID in the product
-			if len(usedCategory.CategoryID) != 0 {
-				singleProduct.CategoryID = updateProduct.CategoryID
-			}
-
-			products = append(products[:i], singleProduct)
-			//return the product in response
-			//or report an error
-			if err = json.NewEncoder(w).Encode(singleProduct); err != nil {
-				log.Printf(err.Error())
-				w.WriteHeader(500)
-				return
-			}
-		}
+	if err := api.ReadJSON(r, &replaceProduct); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	//the id is taken from the link only
+	replaceProduct.ProductID = productID
+
+	h.saveProduct(w, r, replaceProduct)
+}
+
+// saveProduct validates the updated product, writes it to the store in a transaction and returns it in response
+func (h *Handler) saveProduct(w http.ResponseWriter, r *http.Request, updatedProduct product) {
+	err := h.store.Transaction(func(tx store.CatalogStore) error {
+		return (&Handler{store: tx, book: h.book}).writeProduct(updatedProduct, api.Author(r))
+	})
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//return the product in response
+	api.WriteJSON(w, http.StatusOK, updatedProduct)
+}
+
+// writeProduct checks the required fields and the attributes of the updated product and writes it to the store,
+// or returns the Problem of the invalid fields or of the failed write. The variants are checked against the schema
+// after the write, as the product can be moved to another category. It is called with the Handler of a store
+// transaction, so the schema can not change in between and nothing is stored if the variants do not match.
+func (h *Handler) writeProduct(updatedProduct product, author string) error {
+	schema, err := h.schema(updatedProduct.CategoryID)
+	if err != nil {
+		return err
+	}
+	if err = validate(updatedProduct, schema); err != nil {
+		return err
+	}
+	if err = h.store.UpdateProduct(updatedProduct, author); err != nil {
+		return productError(err, updatedProduct)
+	}
+	return api.CheckProducts(h.store, []store.Product{updatedProduct})
+}
+
+// schema returns the attribute schema of the category with the attributes inherited from its parents.
+// It returns nil for an unknown category, which is reported when the product is stored,
+// and an empty schema for a category without attributes.
+func (h *Handler) schema(categoryID string) ([]store.Attribute, error) {
+	schema, err := store.CategorySchema(h.store, categoryID)
+	if err == store.ErrNotFound {
+		return nil, nil
+	}
+	return schema, err
+}
+
+// validate returns a validation Problem if some of the product fields are invalid
+// or its attributes do not match the schema of its category, the attributes are not checked with a nil schema
+func validate(p product, schema []store.Attribute) error {
+	var fieldErrors []api.FieldError
+	//ProductName is required field
+	if len(p.ProductName) == 0 {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "ProductName", Detail: "Kindly enter the product name"})
+	}
+	//every product belongs to some category
+	if len(p.CategoryID) == 0 {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "CategoryID", Detail: "Kindly enter the category ID"})
+	}
+	fieldErrors = append(fieldErrors, api.PriceErrors("Price", p.Price)...)
+	if schema != nil {
+		fieldErrors = append(fieldErrors, api.AttributeErrors(p.Attributes, schema)...)
 	}
-}
\ No newline at end of file
+
+	if len(fieldErrors) != 0 {
+		return api.Validation(fieldErrors...)
+	}
+	return nil
+}
+
+// withPrice returns the product with the given price
+func withPrice(p product, price money.Money) product {
+	p.Price = price
+	return p
+}
+
+// priceError turns the errors of resolving a price in the given price list into Problems
+func priceError(err error, listName string) error {
+	var noRate *pricing.RateNotFoundError
+	if errors.As(err, &noRate) {
+		return api.BadRequest("No exchange rate from %s to %s", noRate.From, noRate.To)
+	}
+	switch err {
+	case pricing.ErrPriceListNotFound:
+		return api.BadRequest("Price list %s given in pricelist not found", listName)
+	case pricing.ErrOutOfRange:
+		return api.BadRequest("The resolved price is out of range")
+	}
+	return err
+}
+
+// productError turns the store errors about the given product into Problems
+func productError(err error, p product) error {
+	switch err {
+	case store.ErrNotFound:
+		return api.NotFound("Product with ID %s not found", p.ProductID)
+	case store.ErrAlreadyExists:
+		return api.Conflict("Product with ID %s already exists", p.ProductID)
+	case store.ErrCategoryNotFound:
+		return api.Validation(api.FieldError{Field: "CategoryID", Detail: fmt.Sprintf("Category with ID %q not found", p.CategoryID)})
+	}
+	return err
+}
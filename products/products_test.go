@@ -4,12 +4,18 @@ package products
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/pricing"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 //prodByIdTest is a structure for testing GET /product/{id} requests
@@ -20,18 +26,18 @@ var prodByIdTest = []struct {
 }{
 	{
 		"bq4foj37jhfipc5nqri0",
-		`{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Nike SuperRep Go","ProductDescription":"Women's Training Shoe","Price":100,"CategoryID":"bq4fasj7jhfi127rimlg"}`,
+		`{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Nike SuperRep Go","ProductDescription":"Women's Training Shoe","Price":{"Amount":10000,"Currency":"EUR","Formatted":"100.00 EUR"},"CategoryID":"bq4fasj7jhfi127rimlg"}`,
 		200,
 	},
 	{
 		"bq5457j7jhfi2s58o030",
-		`{"ProductID":"bq5457j7jhfi2s58o030","ProductName":"Nike Icon Clash","ProductDescription":"Women's Seamless Light-Support Sports Bra","Price":50,"CategoryID":"bq4fasj7jhfi127rimlg"}`,
+		`{"ProductID":"bq5457j7jhfi2s58o030","ProductName":"Nike Icon Clash","ProductDescription":"Women's Seamless Light-Support Sports Bra","Price":{"Amount":5000,"Currency":"EUR","Formatted":"50.00 EUR"},"CategoryID":"bq4fasj7jhfi127rimlg"}`,
 		200,
 	},
 	{
 		"randomID",
-		`Product with ID randomID not found`,
-		412,
+		`{"type":"/problems/not-found","title":"Not Found","status":404,"detail":"Product with ID randomID not found","instance":"/products/randomID"}`,
+		404,
 	},
 }
 
@@ -43,7 +49,7 @@ var prodByCategoryTest = []struct {
 }{
 	{
 		"bq4fasj7jhfi127rimlg",
-		`[{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Nike SuperRep Go","ProductDescription":"Women's Training Shoe","Price":100,"CategoryID":"bq4fasj7jhfi127rimlg"},{"ProductID":"bq5457j7jhfi2s58o030","ProductName":"Nike Icon Clash","ProductDescription":"Women's Seamless Light-Support Sports Bra","Price":50,"CategoryID":"bq4fasj7jhfi127rimlg"}]`,
+		`[{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Nike SuperRep Go","ProductDescription":"Women's Training Shoe","Price":{"Amount":10000,"Currency":"EUR","Formatted":"100.00 EUR"},"CategoryID":"bq4fasj7jhfi127rimlg","Available":0,"OutOfStock":true},{"ProductID":"bq5457j7jhfi2s58o030","ProductName":"Nike Icon Clash","ProductDescription":"Women's Seamless Light-Support Sports Bra","Price":{"Amount":5000,"Currency":"EUR","Formatted":"50.00 EUR"},"CategoryID":"bq4fasj7jhfi127rimlg","Available":0,"OutOfStock":true}]`,
 		200,
 	},
 	{
@@ -53,8 +59,18 @@ var prodByCategoryTest = []struct {
 	},
 }
 
+//countProducts returns the number of products in the given store
+func countProducts(t *testing.T, catalog store.CatalogStore) int {
+	allProducts, err := catalog.Products()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(allProducts)
+}
+
 //TestGetAllProducts tests whether GetAllProducts func returns the right response body and status
 func TestGetAllProducts(t *testing.T) {
+	catalog := store.NewMemoryStore()
 	//Create a request to pass to the handler
 	req, err := http.NewRequest("GET", "/products", nil)
 	if err != nil {
@@ -62,7 +78,7 @@ func TestGetAllProducts(t *testing.T) {
 	}
 	//Create a ResponseRecorder to record the response
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(GetAllProducts)
+	handler := http.HandlerFunc(NewHandler(catalog).GetAllProducts)
 
 	handler.ServeHTTP(rr, req)
 	// Check the response status code is what we expect
@@ -72,13 +88,14 @@ func TestGetAllProducts(t *testing.T) {
 	}
 
 	// Check the response body is what we expect.
-	expected := `[{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Nike SuperRep Go","ProductDescription":"Women's Training Shoe","Price":100,"CategoryID":"bq4fasj7jhfi127rimlg"},{"ProductID":"bq5457j7jhfi2s58o030","ProductName":"Nike Icon Clash","ProductDescription":"Women's Seamless Light-Support Sports Bra","Price":50,"CategoryID":"bq4fasj7jhfi127rimlg"}]`
+	expected := `[{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Nike SuperRep Go","ProductDescription":"Women's Training Shoe","Price":{"Amount":10000,"Currency":"EUR","Formatted":"100.00 EUR"},"CategoryID":"bq4fasj7jhfi127rimlg","Available":0,"OutOfStock":true},{"ProductID":"bq5457j7jhfi2s58o030","ProductName":"Nike Icon Clash","ProductDescription":"Women's Seamless Light-Support Sports Bra","Price":{"Amount":5000,"Currency":"EUR","Formatted":"50.00 EUR"},"CategoryID":"bq4fasj7jhfi127rimlg","Available":0,"OutOfStock":true}]`
 	assert.JSONEq(t, expected, rr.Body.String(), "Expected response body to be the same")
 }
 
 //TestGetProductById tests whether GetProductById func returns the right response bodies and statuses
 //while iterating over prodByIdTest
 func TestGetProductById(t *testing.T) {
+	catalog := store.NewMemoryStore()
 	for _, p := range prodByIdTest {
 		//request url
 		path := "/products/" + p.id
@@ -90,18 +107,19 @@ func TestGetProductById(t *testing.T) {
 		}
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(GetProductById)
+		handler := http.HandlerFunc(NewHandler(catalog).GetProductById)
 
 		handler.ServeHTTP(rr, req)
 
 		assert.Equal(t, p.expectedCode, rr.Code, "OK response is expected")
-		assert.Equal(t, p.expected, strings.TrimSuffix(rr.Body.String(),"\n"), "Response body is expected to be equal to expected value")
+		assert.JSONEq(t, p.expected, rr.Body.String(), "Response body is expected to be equal to expected value")
 	}
 }
 
 //TestGetProductsOfCategory tests whether GetProductsOfCategory func returns the right response bodies and statuses
 //while iterating over prodByCategoryTest
 func TestGetProductsOfCategory(t *testing.T) {
+	catalog := store.NewMemoryStore()
 	for _, p := range prodByCategoryTest {
 		path := "/products/category/" + p.categoryId
 		req, err := http.NewRequest("GET", path, nil)
@@ -112,19 +130,20 @@ func TestGetProductsOfCategory(t *testing.T) {
 		}
 
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(GetProductsOfCategory)
+		handler := http.HandlerFunc(NewHandler(catalog).GetProductsOfCategory)
 
 		handler.ServeHTTP(rr, req)
 
 		assert.Equal(t, p.expectedCode, rr.Code, "Response code is expected to be different")
-		assert.Equal(t, p.expected, strings.TrimSuffix(rr.Body.String(),"\n"), "Response body is expected to be equal to expected value")
+		assert.JSONEq(t, p.expected, rr.Body.String(), "Response body is expected to be equal to expected value")
 	}
 }
 
-//TestDeleteProduct tests whether DeleteProduct func returns the right status and actually deletes a product from []products
+//TestDeleteProduct tests whether DeleteProduct func returns the right status and actually deletes a product from the store
 func TestDeleteProduct(t *testing.T) {
-	//initial length of []products
-	initialLen := len(products)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countProducts(t, catalog)
 
 	req, err := http.NewRequest("DELETE", "/products/bq4foj37jhfipc5nqri0", nil)
 	req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
@@ -134,20 +153,21 @@ func TestDeleteProduct(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(DeleteProduct)
+	handler := http.HandlerFunc(NewHandler(catalog).DeleteProduct)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 200, rr.Code, "OK response is expected")
-	//the length of []products should decrease after deleting product
-	assert.NotEqual(t, initialLen, len(products), "Expected length to decrease after creating new product")
+	//the length of the store should decrease after deleting product
+	assert.NotEqual(t, initialLen, countProducts(t, catalog), "Expected length to decrease after creating new product")
 }
 
-//TestDeleteProductWrongID tests whether DeleteProduct func returns the right status and does not delete a product from []products
+//TestDeleteProductWrongID tests whether DeleteProduct func returns the right status and does not delete a product from the store
 //because the ID is wrong
 func TestDeleteProductWrongID(t *testing.T) {
-	//initial length of []products
-	initialLen := len(products)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countProducts(t, catalog)
 
 	req, err := http.NewRequest("DELETE", "/products/randomID", nil)
 	req = mux.SetURLVars(req, map[string]string{"id": "randomID"})
@@ -155,24 +175,26 @@ func TestDeleteProductWrongID(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(DeleteProduct)
+	handler := http.HandlerFunc(NewHandler(catalog).DeleteProduct)
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, 412, rr.Code, "Precondition Failed response is expected")
-	//the length of []products should not change after trying to delete non-existing product
-	assert.Equal(t, initialLen, len(products), "Expected length to stay same after creating new product")
+	assert.Equal(t, 404, rr.Code, "Not Found response is expected")
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"), "Problem details are expected")
+	//the length of the store should not change after trying to delete non-existing product
+	assert.Equal(t, initialLen, countProducts(t, catalog), "Expected length to stay same after creating new product")
 }
 
-//TestCreateProduct tests whether CreateProduct func returns the right status and actually appends a product to []products
+//TestCreateProduct tests whether CreateProduct func returns the right status and actually appends a product to the store
 func TestCreateProduct(t *testing.T) {
-	//initial length of []products
-	initialLen := len(products)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countProducts(t, catalog)
 	//parameters passed to request body
 	requestBody := &product{
 		ProductName: 		"Super Cool Product",
 		ProductDescription: "Brand new cool product",
-		Price: 				1000,
+		Price: 				money.New(1000, "EUR"),
 		CategoryID: 		"bq4fasj7jhfi127rimlg",
 	}
 	jsonProduct, _ := json.Marshal(requestBody)
@@ -182,25 +204,26 @@ func TestCreateProduct(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateProduct)
+	handler := http.HandlerFunc(NewHandler(catalog).CreateProduct)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 201, rr.Code, "Created response is expected")
-	//the length of []products should increase after creating new product
-	assert.NotEqual(t, initialLen, len(products), "Expected length to increase after creating new product")
+	//the length of the store should increase after creating new product
+	assert.NotEqual(t, initialLen, countProducts(t, catalog), "Expected length to increase after creating new product")
 }
 
 //TestCreateProductNonExistingCategory tests whether CreateCategory func returns the right status and does not append
-//a product to []products because of the non-existing category to refer to
+//a product to the store because of the non-existing category to refer to
 func TestCreateProductNonExistingCategory(t *testing.T) {
-	//initial length of []products
-	initialLen := len(products)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countProducts(t, catalog)
 	//parameters passed to request body with wrong CategoryID
 	requestBody := &product{
 		ProductName: 		"Super Cool Product",
 		ProductDescription: "Brand new cool product",
-		Price: 				1000,
+		Price: 				money.New(1000, "EUR"),
 		CategoryID: 		"randomCategoryID",
 	}
 	jsonProduct, _ := json.Marshal(requestBody)
@@ -209,21 +232,22 @@ func TestCreateProductNonExistingCategory(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateProduct)
+	handler := http.HandlerFunc(NewHandler(catalog).CreateProduct)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected")
-	//the length of []products should not change after trying to create new product but passing wrong CategoryID
+	//the length of the store should not change after trying to create new product but passing wrong CategoryID
 	//product should be connected to the existing category
-	assert.Equal(t, initialLen, len(products), "Expected length to stay same after creating new product")
+	assert.Equal(t, initialLen, countProducts(t, catalog), "Expected length to stay same after creating new product")
 }
 
 //TestCreateProductEmptyBody tests whether CreateCategory func returns the right status and does not append
-//a product to []products because of the empty request body
+//a product to the store because of the empty request body
 func TestCreateProductEmptyBody(t *testing.T) {
-	//initial length of []products
-	initialLen := len(products)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countProducts(t, catalog)
 	//empty body
 	requestBody := &product{}
 	jsonProduct, _ := json.Marshal(requestBody)
@@ -232,20 +256,21 @@ func TestCreateProductEmptyBody(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateProduct)
+	handler := http.HandlerFunc(NewHandler(catalog).CreateProduct)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 422, rr.Code, "Created response is expected")
-	//the length of []products should not change after trying to create new empty product
-	assert.Equal(t, initialLen, len(products), "Expected length to increase after creating new product")
+	//the length of the store should not change after trying to create new empty product
+	assert.Equal(t, initialLen, countProducts(t, catalog), "Expected length to increase after creating new product")
 }
 
 //TestCreateProductEmptyBody tests whether CreateCategory func returns the right status and does not append
-//a product to []products because of the wrong syntax in JSON request body
+//a product to the store because of the wrong syntax in JSON request body
 func TestCreateProductWrongJSONSyntax(t *testing.T) {
-	//initial length of []Categories
-	initialLen := len(products)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countProducts(t, catalog)
 	//parameters passed to request body
 	requestBody := `{{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Name",,,}}`
 	req, err := http.NewRequest("POST", "/products/new", bytes.NewBufferString(requestBody))
@@ -253,24 +278,25 @@ func TestCreateProductWrongJSONSyntax(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateProduct)
+	handler := http.HandlerFunc(NewHandler(catalog).CreateProduct)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 400, rr.Code, "Bad request response is expected")
-	assert.Equal(t, initialLen, len(products), "Expected length to stay the same after wrong syntax json")
+	assert.Equal(t, initialLen, countProducts(t, catalog), "Expected length to stay the same after wrong syntax json")
 
 }
 
-//TestUpdateProduct tests whether UpdateProduct func returns the right status and does not change []products, but updates fields
+//TestUpdateProduct tests whether UpdateProduct func returns the right status and does not change the store, but updates fields
 func TestUpdateProduct(t *testing.T) {
-	//initial length of []products
-	initialLen := len(products)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countProducts(t, catalog)
 	//parameters passed to request body
 	requestBody := &product{
 		ProductName: 		"Super Cool Product",
 		ProductDescription: "Brand new cool product",
-		Price: 				1000,
+		Price: 				money.New(1000, "EUR"),
 		CategoryID: 		"bq4fasj7jhfi127rimlg",
 	}
 	jsonProduct, _ := json.Marshal(requestBody)
@@ -282,19 +308,20 @@ func TestUpdateProduct(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(UpdateProduct)
+	handler := http.HandlerFunc(NewHandler(catalog).UpdateProduct)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 200, rr.Code, "OK response is expected")
-	assert.Equal(t, initialLen, len(products), "Expected length to stay the same after creating new product")
+	assert.Equal(t, initialLen, countProducts(t, catalog), "Expected length to stay the same after creating new product")
 }
 
 //TestUpdateProductWrongJSONSyntax tests whether UpdateProduct func returns the right status and does update fields
 //because of the wrong syntax in JSON request body
 func TestUpdateProductWrongJSONSyntax(t *testing.T) {
-	//initial length of []Categories
-	initialLen := len(products)
+	catalog := store.NewMemoryStore()
+	//initial length of the store
+	initialLen := countProducts(t, catalog)
 	//parameters passed to request body
 	requestBody := `{{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Name",,,}}`
 	req, err := http.NewRequest("POST", "/products/bq4foj37jhfipc5nqri0", bytes.NewBufferString(requestBody))
@@ -303,11 +330,802 @@ func TestUpdateProductWrongJSONSyntax(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(UpdateProduct)
+	handler := http.HandlerFunc(NewHandler(catalog).UpdateProduct)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, 400, rr.Code, "Bad request response is expected")
-	assert.Equal(t, initialLen, len(products), "Expected length to stay the same after wrong syntax json")
+	assert.Equal(t, initialLen, countProducts(t, catalog), "Expected length to stay the same after wrong syntax json")
+
+}
+//TestUpdateProductPartial tests whether UpdateProduct func changes only the fields given in the merge patch
+//and keeps all the other products in the store
+func TestUpdateProductPartial(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	requestBody := `{"Price":{"Amount":12000},"ProductDescription":null}`
+	req, err := http.NewRequest("PATCH", "/products/bq4foj37jhfipc5nqri0", bytes.NewBufferString(requestBody))
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).UpdateProduct)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	expected := `{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Nike SuperRep Go","ProductDescription":"","Price":{"Amount":12000,"Currency":"EUR","Formatted":"120.00 EUR"},"CategoryID":"bq4fasj7jhfi127rimlg"}`
+	assert.JSONEq(t, expected, rr.Body.String(), "Expected only the price and the removed description to change")
+	//the product after the updated one should still be there
+	_, err = catalog.Product("bq5457j7jhfi2s58o030")
+	assert.NoError(t, err, "Expected the other products to stay in the store")
+}
+
+//racingStore makes a change of another request right after a product is read the first time, before the patch is written
+type racingStore struct {
+	store.CatalogStore
+	race *func()
+}
+
+//Product reads the product and makes the change of the other request
+func (s *racingStore) Product(id string) (product, error) {
+	p, err := s.CatalogStore.Product(id)
+	if race := *s.race; race != nil {
+		*s.race = nil
+		race()
+	}
+	return p, err
+}
+
+//Category reads the category and makes the change of the other request
+func (s *racingStore) Category(id string) (store.Category, error) {
+	c, err := s.CatalogStore.Category(id)
+	if race := *s.race; race != nil {
+		*s.race = nil
+		race()
+	}
+	return c, err
+}
+
+//Transaction reads the products of the transaction through racingStore too
+func (s *racingStore) Transaction(fn func(tx store.CatalogStore) error) error {
+	return s.CatalogStore.Transaction(func(tx store.CatalogStore) error {
+		return fn(&racingStore{CatalogStore: tx, race: s.race})
+	})
+}
+
+//TestUpdateProductConcurrent tests whether UpdateProduct func keeps the change another request makes
+//while the merge patch is applied
+func TestUpdateProductConcurrent(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	otherDone := make(chan error, 1)
+	race := func() {
+		go func() {
+			p, err := catalog.Product("bq4foj37jhfipc5nqri0")
+			if err == nil {
+				p.ProductDescription = "Changed by another request"
+				err = catalog.UpdateProduct(p, "test")
+			}
+			otherDone <- err
+		}()
+		//the other request is done unless it waits for the patch
+		select {
+		case <-otherDone:
+			otherDone <- nil
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	req := httptest.NewRequest("PATCH", "/products/bq4foj37jhfipc5nqri0", strings.NewReader(`{"ProductName":"Nike SuperRep Go 2"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(&racingStore{CatalogStore: catalog, race: &race}).UpdateProduct).ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.NoError(t, <-otherDone)
+
+	stored, err := catalog.Product("bq4foj37jhfipc5nqri0")
+	assert.NoError(t, err)
+	assert.Equal(t, "Nike SuperRep Go 2", stored.ProductName, "Expected the patch to be applied")
+	assert.Equal(t, "Changed by another request", stored.ProductDescription, "Expected the change of the other request to be kept")
+}
+
+//TestCreateProductSchemaConcurrent tests whether CreateProduct func does not store a product against the schema
+//another request changes while the product is checked
+func TestCreateProductSchemaConcurrent(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	if err := catalog.CreateCategory(store.Category{CategoryID: "caps", CategoryName: "Caps"}); err != nil {
+		t.Fatal(err)
+	}
+	otherDone := make(chan error, 1)
+	race := func() {
+		//the other request adds a required attribute unless a product of the category does not have it
+		go func() {
+			otherDone <- catalog.Transaction(func(tx store.CatalogStore) error {
+				err := tx.UpdateCategory(store.Category{CategoryID: "caps", CategoryName: "Caps",
+					Attributes: []store.Attribute{{Name: "size", Type: store.AttributeString, Required: true}}})
+				if err != nil {
+					return err
+				}
+				products, err := tx.ProductsOfCategory("caps")
+				if err != nil {
+					return err
+				}
+				return api.CheckProducts(tx, products)
+			})
+		}()
+		//the other request is done unless it waits for the product
+		select {
+		case err := <-otherDone:
+			otherDone <- err
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/products/new", strings.NewReader(`{"ProductName":"Cap","Price":{"Amount":1000,"Currency":"EUR"},"CategoryID":"caps"}`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(&racingStore{CatalogStore: catalog, race: &race}).CreateProduct).ServeHTTP(rr, req)
+	<-otherDone
+
+	all, err := catalog.Products()
+	assert.NoError(t, err)
+	assert.NoError(t, api.CheckProducts(catalog, all), "Expected every product to match the schema of its category")
+}
+
+//TestUpdateProductVariantsSchema tests whether UpdateProduct, ReplaceProduct and ImportProducts funcs
+//do not move a product to a category whose schema its variants do not match
+func TestUpdateProductVariantsSchema(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	if err := catalog.CreateCategory(store.Category{CategoryID: "shoes", CategoryName: "Shoes", Attributes: []store.Attribute{{Name: "size", Type: store.AttributeString}}}); err != nil {
+		t.Fatal(err)
+	}
+	shoe := product{ProductID: "shoe", ProductName: "Shoe", Price: money.New(1000, "EUR"), CategoryID: "shoes"}
+	if err := catalog.CreateProduct(shoe, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.CreateVariant(store.Variant{VariantID: "v38", ProductID: "shoe", SKU: "SHOE-38", Attributes: map[string]string{"size": "38"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"ProductName":"Shoe","Price":{"Amount":1000,"Currency":"EUR"},"CategoryID":"bq4fb3b7jhfi7v7uo39g"}`
+	for method, handler := range map[string]http.HandlerFunc{"PATCH": NewHandler(catalog).UpdateProduct, "PUT": NewHandler(catalog).ReplaceProduct} {
+		req := httptest.NewRequest(method, "/products/shoe", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "shoe"})
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, 409, rr.Code, "Conflict response is expected for %s, body: %s", method, rr.Body.String())
+		assert.Contains(t, rr.Body.String(), `"mismatched_products":["shoe"]`, "Expected the product to be listed for %s", method)
+	}
+
+	rr := importProducts(catalog, "", "", "product_id,category_id\nshoe,bq4fb3b7jhfi7v7uo39g\n")
+	var result api.ImportResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.Failed, "Expected the row to fail")
+
+	stored, _ := catalog.Product("shoe")
+	assert.Equal(t, "shoes", stored.CategoryID, "Expected the product to stay in its category")
+}
+
+//TestUpdateProductNonExistingCategory tests whether UpdateProduct func does not move a product to a missing category
+func TestUpdateProductNonExistingCategory(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	requestBody := `{"CategoryID":"randomCategoryID"}`
+	req, err := http.NewRequest("PATCH", "/products/bq4foj37jhfipc5nqri0", bytes.NewBufferString(requestBody))
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).UpdateProduct)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected")
+	stored, _ := catalog.Product("bq4foj37jhfipc5nqri0")
+	assert.Equal(t, "bq4fasj7jhfi127rimlg", stored.CategoryID, "Expected the category to stay the same")
+}
+
+//TestUpdateProductWrongID tests whether UpdateProduct and ReplaceProduct funcs report an unknown id
+func TestUpdateProductWrongID(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, method := range []string{"PATCH", "PUT"} {
+		requestBody := `{"ProductName":"Name","Price":{"Amount":1000,"Currency":"EUR"},"CategoryID":"bq4fasj7jhfi127rimlg"}`
+		req, err := http.NewRequest(method, "/products/randomID", bytes.NewBufferString(requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": "randomID"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).UpdateProduct)
+		if method == "PUT" {
+			handler = NewHandler(catalog).ReplaceProduct
+		}
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, 404, rr.Code, "Not Found response is expected for %s", method)
+		assert.Equal(t, 2, countProducts(t, catalog), "Expected length to stay the same after updating unknown product")
+	}
+}
+
+//TestReplaceProduct tests whether ReplaceProduct func replaces the whole product and clears the missing fields
+func TestReplaceProduct(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	requestBody := `{"ProductName":"Replaced Product","Price":{"Amount":1000,"Currency":"JPY"},"CategoryID":"bq4fb3b7jhfi7v7uo39g"}`
+	req, err := http.NewRequest("PUT", "/products/bq4foj37jhfipc5nqri0", bytes.NewBufferString(requestBody))
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).ReplaceProduct)
+
+	handler.ServeHTTP(rr, req)
 
-}
\ No newline at end of file
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	expected := product{ProductID: "bq4foj37jhfipc5nqri0", ProductName: "Replaced Product", Price: money.New(1000, "JPY"), CategoryID: "bq4fb3b7jhfi7v7uo39g"}
+	stored, _ := catalog.Product("bq4foj37jhfipc5nqri0")
+	assert.Equal(t, expected, stored, "Expected the description to be cleared and the price to be replaced")
+	assert.Equal(t, 2, countProducts(t, catalog), "Expected length to stay the same after replacing product")
+}
+
+//TestReplaceProductEmptyName tests whether ReplaceProduct func requires the product name
+func TestReplaceProductEmptyName(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	requestBody := `{"CategoryID":"bq4fasj7jhfi127rimlg"}`
+	req, err := http.NewRequest("PUT", "/products/bq4foj37jhfipc5nqri0", bytes.NewBufferString(requestBody))
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).ReplaceProduct)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected")
+	stored, _ := catalog.Product("bq4foj37jhfipc5nqri0")
+	assert.Equal(t, "Nike SuperRep Go", stored.ProductName, "Expected the product to stay the same")
+}
+
+//TestCreateProductFieldErrors tests whether CreateProduct func lists every invalid field in the problem details
+func TestCreateProductFieldErrors(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, p := range []struct {
+		requestBody string
+		expected    string
+	}{
+		{
+			`{}`,
+			`[{"field":"ProductName","detail":"Kindly enter the product name"},{"field":"CategoryID","detail":"Kindly enter the category ID"},` +
+				`{"field":"Price.Currency","detail":"Kindly enter an ISO 4217 currency code, e.g. EUR"}]`,
+		},
+		{
+			`{"ProductName":"Name","Price":{"Amount":-100,"Currency":"EUR"},"CategoryID":"bq4fasj7jhfi127rimlg"}`,
+			`[{"field":"Price.Amount","detail":"The price can not be negative"}]`,
+		},
+		{
+			`{"ProductName":"Name","Price":{"Amount":100,"Currency":"XYZ"},"CategoryID":"bq4fasj7jhfi127rimlg"}`,
+			`[{"field":"Price.Currency","detail":"Kindly enter an ISO 4217 currency code, e.g. EUR"}]`,
+		},
+		{
+			`{"ProductName":"Name","Price":{"Amount":100,"Currency":"EUR"},"CategoryID":"randomCategoryID"}`,
+			`[{"field":"CategoryID","detail":"Category with ID \"randomCategoryID\" not found"}]`,
+		},
+	} {
+		req, err := http.NewRequest("POST", "/products/new", bytes.NewBufferString(p.requestBody))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).CreateProduct)
+
+		handler.ServeHTTP(rr, req)
+
+		var problem struct {
+			Status int             `json:"status"`
+			Errors json.RawMessage `json:"errors"`
+		}
+		assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected")
+		assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"), "Problem details are expected")
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, 422, problem.Status)
+		assert.JSONEq(t, p.expected, string(problem.Errors), "Expected field errors for %s", p.requestBody)
+	}
+}
+
+//TestGetAllProductsPaged tests whether GetAllProducts func follows the limit and sort parameters
+//and links the next page which continues the list
+func TestGetAllProductsPaged(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	handler := http.HandlerFunc(NewHandler(catalog).GetAllProducts)
+
+	req, err := http.NewRequest("GET", "/products?sort=-price&limit=1&currency=EUR", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Equal(t, "2", rr.Header().Get("X-Total-Count"), "Expected the total number of products")
+	assert.JSONEq(t, `[{"ProductID":"bq4foj37jhfipc5nqri0","ProductName":"Nike SuperRep Go","ProductDescription":"Women's Training Shoe","Price":{"Amount":10000,"Currency":"EUR","Formatted":"100.00 EUR"},"CategoryID":"bq4fasj7jhfi127rimlg","Available":0,"OutOfStock":true}]`, rr.Body.String(), "Expected the most expensive product")
+
+	//follow the link to the next page
+	link := rr.Header().Get("Link")
+	assert.Regexp(t, `^</products\?currency=EUR&cursor=[\w-]+&limit=1&sort=-price>; rel="next"$`, link, "Expected the link to the next page")
+	req, err = http.NewRequest("GET", strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.Empty(t, rr.Header().Get("Link"), "Expected no link after the last page")
+	assert.JSONEq(t, `[{"ProductID":"bq5457j7jhfi2s58o030","ProductName":"Nike Icon Clash","ProductDescription":"Women's Seamless Light-Support Sports Bra","Price":{"Amount":5000,"Currency":"EUR","Formatted":"50.00 EUR"},"CategoryID":"bq4fasj7jhfi127rimlg","Available":0,"OutOfStock":true}]`, rr.Body.String(), "Expected the cheaper product on the next page")
+
+	//the prices in different currencies can not be sorted
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/products?sort=price", nil))
+	assert.Equal(t, 400, rr.Code, "Bad Request response is expected for the price order without a currency")
+}
+
+//TestGetAllProductsFiltered tests whether GetAllProducts func filters by price, currency and name
+func TestGetAllProductsFiltered(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, p := range []struct {
+		query    string
+		expected string
+	}{
+		{"price_min=6000", `["bq4foj37jhfipc5nqri0"]`},
+		{"price_max=5000", `["bq5457j7jhfi2s58o030"]`},
+		{"currency=EUR&price_max=5000", `["bq5457j7jhfi2s58o030"]`},
+		{"currency=JPY", `[]`},
+		{"name_contains=clash", `["bq5457j7jhfi2s58o030"]`},
+		{"name_contains=nike&price_min=20000", `[]`},
+	} {
+		req, err := http.NewRequest("GET", "/products?"+p.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).GetAllProducts)
+
+		handler.ServeHTTP(rr, req)
+
+		var found []product
+		assert.Equal(t, 200, rr.Code, "OK response is expected")
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &found))
+		ids := make([]string, 0, len(found))
+		for _, prod := range found {
+			ids = append(ids, prod.ProductID)
+		}
+		idsJSON, _ := json.Marshal(ids)
+		assert.JSONEq(t, p.expected, string(idsJSON), "Expected other products for %s", p.query)
+	}
+}
+
+//TestGetAllProductsWrongQuery tests whether GetAllProducts func reports invalid list parameters
+func TestGetAllProductsWrongQuery(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, query := range []string{"limit=0", "limit=1001", "limit=many", "sort=color", "price_min=cheap", "currency=XYZ", "cursor=random"} {
+		req, err := http.NewRequest("GET", "/products?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).GetAllProducts)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, 400, rr.Code, "Bad Request response is expected for %s", query)
+		assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"), "Problem details are expected")
+	}
+}
+
+//TestSearchProducts tests whether SearchProducts func returns the matching products, the most relevant first
+func TestSearchProducts(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	for _, p := range []struct {
+		query        string
+		expected     []string // ids of the products found
+		expectedCode int
+	}{
+		{"q=sports+bra", []string{"bq5457j7jhfi2s58o030"}, 200},
+		{"q=nike", []string{"bq4foj37jhfipc5nqri0", "bq5457j7jhfi2s58o030"}, 200},
+		{"q=nike&limit=1", []string{"bq4foj37jhfipc5nqri0"}, 200},
+		{"q=trainin", []string{"bq4foj37jhfipc5nqri0"}, 200},
+		{"q=seamles", []string{"bq5457j7jhfi2s58o030"}, 200},
+		{"q=sprots", []string{"bq5457j7jhfi2s58o030"}, 200},
+		{"q=umbrella", []string{}, 200},
+		{"q=+", nil, 400},
+		{"q=nike&limit=0", nil, 400},
+	} {
+		req, err := http.NewRequest("GET", "/products/search?"+p.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).SearchProducts)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.query)
+		if p.expectedCode != 200 {
+			continue
+		}
+		var found []store.ProductMatch
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &found))
+		ids := make([]string, 0, len(found))
+		for _, match := range found {
+			ids = append(ids, match.ProductID)
+		}
+		assert.Equal(t, p.expected, ids, "Expected other products for %s", p.query)
+	}
+}
+
+//TestGetProductsOfCategoryDescendants tests whether GetProductsOfCategory func includes the products
+//of the subcategories when asked to
+func TestGetProductsOfCategoryDescendants(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	assert.NoError(t, catalog.CreateCategory(store.Category{CategoryID: "women", CategoryName: "Women", ParentID: "bq4fb3b7jhfi7v7uo39g"}))
+	assert.NoError(t, catalog.CreateProduct(product{ProductID: "dress", ProductName: "Dress", Price: money.New(2500, "EUR"), CategoryID: "women"}, "test"))
+	for _, p := range []struct {
+		query        string
+		expected     string
+		expectedCode int
+	}{
+		{"", `[]`, 200},
+		{"?descendants=false", `[]`, 200},
+		{"?descendants=true", `[{"ProductID":"dress","ProductName":"Dress","ProductDescription":"","Price":{"Amount":2500,"Currency":"EUR","Formatted":"25.00 EUR"},"CategoryID":"women","Available":0,"OutOfStock":true}]`, 200},
+		{"?descendants=maybe", ``, 400},
+	} {
+		req, err := http.NewRequest("GET", "/products/category/bq4fb3b7jhfi7v7uo39g"+p.query, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "bq4fb3b7jhfi7v7uo39g"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).GetProductsOfCategory)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.query)
+		if p.expectedCode == 200 {
+			assert.JSONEq(t, p.expected, rr.Body.String(), "Expected other products for %s", p.query)
+		}
+	}
+}
+
+//TestGetProductByIdResolvedPrice tests whether GetProductById func resolves the price
+//in the currency and price list given in the link
+func TestGetProductByIdResolvedPrice(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	book, err := pricing.NewBookFromConfig(pricing.Config{
+		Rates:      pricing.Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.1, "JPY": 160}},
+		PriceLists: []pricing.PriceList{{Name: "wholesale", Adjustment: -20}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []struct {
+		query        string
+		expected     string
+		expectedCode int
+	}{
+		{"?currency=USD", `{"Amount":11000,"Currency":"USD","Formatted":"110.00 USD"}`, 200},
+		{"?pricelist=wholesale", `{"Amount":8000,"Currency":"EUR","Formatted":"80.00 EUR"}`, 200},
+		{"?currency=JPY&pricelist=wholesale", `{"Amount":12800,"Currency":"JPY","Formatted":"12800 JPY"}`, 200},
+		{"?currency=EURO", ``, 400},
+		{"?currency=GBP", ``, 400},
+		{"?pricelist=retail", ``, 400},
+	} {
+		req, err := http.NewRequest("GET", "/products/bq4foj37jhfipc5nqri0"+p.query, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewPricingHandler(catalog, book).GetProductById)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.query)
+		if p.expectedCode != 200 {
+			continue
+		}
+		var resolved struct {
+			Price     json.RawMessage
+			BasePrice json.RawMessage
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resolved))
+		assert.JSONEq(t, p.expected, string(resolved.Price), "Expected another price for %s", p.query)
+		assert.JSONEq(t, `{"Amount":10000,"Currency":"EUR","Formatted":"100.00 EUR"}`, string(resolved.BasePrice),
+			"Expected the stored price in BasePrice for %s", p.query)
+	}
+}
+
+//TestSchedulePriceChange tests whether SchedulePriceChange func schedules the sale price and the price after the sale,
+//and GetProductPrices func returns them in the timeline
+func TestSchedulePriceChange(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	requestBody := fmt.Sprintf(`{"Price":{"Amount":7500,"Currency":"EUR"},"EffectiveAt":%q,"Until":%q}`,
+		start.Format(time.RFC3339), start.Add(72*time.Hour).Format(time.RFC3339))
+	req, err := http.NewRequest("POST", "/products/bq4foj37jhfipc5nqri0/prices", bytes.NewBufferString(requestBody))
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(api.AuthorHeader, "sales team")
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(NewHandler(catalog).SchedulePriceChange)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 201, rr.Code, "Created response is expected")
+	var scheduled []store.PriceChange
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &scheduled))
+	if assert.Len(t, scheduled, 2, "Expected the sale and its end to be scheduled") {
+		assert.Equal(t, money.New(7500, "EUR"), scheduled[0].Price)
+		assert.Equal(t, money.New(10000, "EUR"), scheduled[1].Price, "Expected the price before the sale to come back")
+		assert.Equal(t, "sales team", scheduled[1].Author)
+		assert.Equal(t, store.PriceScheduled, scheduled[1].Status)
+	}
+
+	req, err = http.NewRequest("GET", "/products/bq4foj37jhfipc5nqri0/prices", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).GetProductPrices).ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	var timeline []store.PriceChange
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &timeline))
+	statuses := make([]string, 0, len(timeline))
+	for _, c := range timeline {
+		statuses = append(statuses, c.Price.String()+" "+c.Status)
+	}
+	assert.Equal(t, []string{"100.00 EUR applied", "75.00 EUR scheduled", "100.00 EUR scheduled"}, statuses)
+}
+
+//TestSchedulePriceChangeFieldErrors tests whether SchedulePriceChange func reports the invalid price and times
+func TestSchedulePriceChangeFieldErrors(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	for _, p := range []struct {
+		requestBody string
+		expected    string
+	}{
+		{
+			`{}`,
+			`[{"field":"Price.Currency","detail":"Kindly enter an ISO 4217 currency code, e.g. EUR"},{"field":"EffectiveAt","detail":"Kindly enter the time the price takes effect"}]`,
+		},
+		{
+			fmt.Sprintf(`{"Price":{"Amount":-1,"Currency":"EUR"},"EffectiveAt":%q}`, past),
+			`[{"field":"Price.Amount","detail":"The price can not be negative"},{"field":"EffectiveAt","detail":"The price change must take effect in the future"}]`,
+		},
+		{
+			fmt.Sprintf(`{"Price":{"Amount":1,"Currency":"EUR"},"EffectiveAt":%q,"Until":%q}`, future, past),
+			`[{"field":"Until","detail":"The previous price must come back after the change takes effect"}]`,
+		},
+	} {
+		req, err := http.NewRequest("POST", "/products/bq4foj37jhfipc5nqri0/prices", bytes.NewBufferString(p.requestBody))
+		req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).SchedulePriceChange)
+
+		handler.ServeHTTP(rr, req)
+
+		var problem struct {
+			Errors json.RawMessage `json:"errors"`
+		}
+		assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected for %s", p.requestBody)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.JSONEq(t, p.expected, string(problem.Errors), "Expected field errors for %s", p.requestBody)
+	}
+	timeline, _ := catalog.PriceHistory("bq4foj37jhfipc5nqri0")
+	assert.Len(t, timeline, 1, "Expected nothing to be scheduled")
+}
+
+//TestCancelPriceChange tests whether CancelPriceChange func removes a scheduled change
+//and reports the applied and unknown ones
+func TestCancelPriceChange(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	err := catalog.SchedulePriceChanges("bq4foj37jhfipc5nqri0", []store.PriceChange{
+		{ChangeID: "sale", Price: money.New(7500, "EUR"), EffectiveAt: time.Now().Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeline, _ := catalog.PriceHistory("bq4foj37jhfipc5nqri0")
+	for _, p := range []struct {
+		changeID     string
+		expectedCode int
+	}{
+		{"sale", 200},
+		{"sale", 404},
+		{timeline[0].ChangeID, 409},
+	} {
+		req, err := http.NewRequest("DELETE", "/products/bq4foj37jhfipc5nqri0/prices/"+p.changeID, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "bq4foj37jhfipc5nqri0", "changeID": p.changeID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(NewHandler(catalog).CancelPriceChange)
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.changeID)
+	}
+}
+
+//TestGetAllProductsStock tests whether GetAllProducts func flags the products without available pieces
+func TestGetAllProductsStock(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	_, err := catalog.AdjustStock(store.StockAdjustment{AdjustmentID: "a1", ProductID: "bq4foj37jhfipc5nqri0", WarehouseID: "tallinn", Delta: 3, Reason: store.ReasonReceived})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = catalog.AdjustStock(store.StockAdjustment{AdjustmentID: "a2", ProductID: "bq5457j7jhfi2s58o030", WarehouseID: "tallinn", Delta: 1, Reason: store.ReasonReceived})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the only piece of the second product is reserved
+	_, err = catalog.ReserveStock(store.Reservation{ReservationID: "r1", ProductID: "bq5457j7jhfi2s58o030", Quantity: 1, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", "/products", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).GetAllProducts).ServeHTTP(rr, req)
+
+	var listed []struct {
+		ProductID  string
+		Available  int64
+		OutOfStock bool
+	}
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	if assert.Len(t, listed, 2) {
+		assert.Equal(t, int64(3), listed[0].Available, "Expected the received pieces to be available")
+		assert.False(t, listed[0].OutOfStock)
+		assert.Equal(t, int64(0), listed[1].Available, "Expected the reserved piece not to be available")
+		assert.True(t, listed[1].OutOfStock)
+	}
+}
+
+//TestGetAllProductsPriceRange tests whether GetAllProducts func summarizes the prices of the variants
+func TestGetAllProductsPriceRange(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	cheap, dear := money.New(8000, "EUR"), money.New(12000, "EUR")
+	for _, v := range []store.Variant{
+		{VariantID: "v1", ProductID: "bq4foj37jhfipc5nqri0", SKU: "SRG-38", Price: &cheap},
+		{VariantID: "v2", ProductID: "bq4foj37jhfipc5nqri0", SKU: "SRG-40"},
+		{VariantID: "v3", ProductID: "bq4foj37jhfipc5nqri0", SKU: "SRG-42", Price: &dear},
+	} {
+		if err := catalog.CreateVariant(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/products", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).GetAllProducts).ServeHTTP(rr, req)
+
+	var listed []struct {
+		PriceRange json.RawMessage
+	}
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	if assert.Len(t, listed, 2) {
+		assert.JSONEq(t, `{"Min":{"Amount":8000,"Currency":"EUR","Formatted":"80.00 EUR"},"Max":{"Amount":12000,"Currency":"EUR","Formatted":"120.00 EUR"}}`,
+			string(listed[0].PriceRange), "Expected the lowest and the highest variant price")
+		assert.Nil(t, listed[1].PriceRange, "Expected no price range without variants")
+	}
+}
+
+//TestProductAttributes tests whether CreateProduct and UpdateProduct funcs check the attributes of the product
+//against the schema of its category and of the parent categories
+func TestProductAttributes(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	err := catalog.CreateCategory(store.Category{CategoryID: "footwear", CategoryName: "Footwear", Attributes: []store.Attribute{
+		{Name: "size", Type: store.AttributeEnum, Required: true, Values: []string{"38", "39", "40"}},
+		{Name: "waterproof", Type: store.AttributeBool},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = catalog.CreateCategory(store.Category{CategoryID: "running", CategoryName: "Running Shoes", ParentID: "footwear", Attributes: []store.Attribute{
+		{Name: "drop", Type: store.AttributeNumber, Unit: "mm"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []struct {
+		requestBody string
+		expected    string
+	}{
+		{
+			`{"ProductName":"Pegasus","Price":{"Amount":100,"Currency":"EUR"},"CategoryID":"running","Attributes":{"waterproof":"yes","drop":"10"}}`,
+			`[{"field":"Attributes.size","detail":"Kindly enter the size"},{"field":"Attributes.waterproof","detail":"The waterproof must be true or false"},` +
+				`{"field":"Attributes.drop","detail":"The drop must be a number of mm"}]`,
+		},
+		{
+			`{"ProductName":"Pegasus","Price":{"Amount":100,"Currency":"EUR"},"CategoryID":"running","Attributes":{"size":"45","wattage":60}}`,
+			`[{"field":"Attributes.size","detail":"The size must be one of 38, 39, 40"},{"field":"Attributes.wattage","detail":"The category of the product has no attribute wattage"}]`,
+		},
+		{
+			`{"ProductName":"Pegasus","Price":{"Amount":100,"Currency":"EUR"},"CategoryID":"bq4fasj7jhfi127rimlg","Attributes":{"size":"38"}}`,
+			`[{"field":"Attributes.size","detail":"The category of the product has no attribute size"}]`,
+		},
+	} {
+		req, err := http.NewRequest("POST", "/products/new", bytes.NewBufferString(p.requestBody))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(NewHandler(catalog).CreateProduct).ServeHTTP(rr, req)
+
+		var problem struct {
+			Errors json.RawMessage `json:"errors"`
+		}
+		assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected for %s", p.requestBody)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.JSONEq(t, p.expected, string(problem.Errors), "Expected field errors for %s", p.requestBody)
+	}
+
+	var created store.Product
+	body := `{"ProductName":"Pegasus","Price":{"Amount":100,"Currency":"EUR"},"CategoryID":"running","Attributes":{"size":"39","drop":10,"waterproof":false}}`
+	req, err := http.NewRequest("POST", "/products/new", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).CreateProduct).ServeHTTP(rr, req)
+	assert.Equal(t, 201, rr.Code, "Created response is expected")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+
+	//the required attribute can not be removed
+	req, err = http.NewRequest("PATCH", "/products/"+created.ProductID, bytes.NewBufferString(`{"Attributes":{"size":null}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": created.ProductID})
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).UpdateProduct).ServeHTTP(rr, req)
+	assert.Equal(t, 422, rr.Code, "Unprocessable Entity response is expected")
+
+	req, err = http.NewRequest("PATCH", "/products/"+created.ProductID, bytes.NewBufferString(`{"Attributes":{"waterproof":null,"size":"40"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": created.ProductID})
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(NewHandler(catalog).UpdateProduct).ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	stored, _ := catalog.Product(created.ProductID)
+	assert.Equal(t, map[string]interface{}{"size": "40", "drop": float64(10)}, stored.Attributes, "Expected the patched attributes")
+}
@@ -0,0 +1,89 @@
+//package scheduler applies the scheduled price changes of the catalog and releases the expired stock reservations
+//in the background
+package scheduler
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"log"
+	"sync"
+	"time"
+)
+
+// Scheduler checks the store for due price changes and expired reservations at a fixed interval,
+// applies the changes and releases the reservations
+type Scheduler struct {
+	store    store.CatalogStore
+	interval time.Duration
+	//now returns the current time, replaced in the tests
+	now func() time.Time
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New returns a Scheduler which applies the due price changes and releases the expired reservations
+// of the store every interval
+func New(s store.CatalogStore, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		store:    s,
+		interval: interval,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start applies the changes which became due while the scheduler was not running
+// and then keeps applying them in the background until Stop is called
+func (sc *Scheduler) Start() {
+	go func() {
+		defer close(sc.done)
+		ticker := time.NewTicker(sc.interval)
+		defer ticker.Stop()
+		for {
+			sc.runOnce()
+			select {
+			case <-ticker.C:
+			case <-sc.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background checks started by Start and waits for the running one to finish
+func (sc *Scheduler) Stop() {
+	sc.stopOnce.Do(func() {
+		close(sc.stop)
+	})
+	<-sc.done
+}
+
+// RunOnce applies the price changes which are due now and returns them
+func (sc *Scheduler) RunOnce() ([]store.PriceChange, error) {
+	return sc.store.ApplyDuePriceChanges(sc.now())
+}
+
+// ReleaseExpired releases the reservations which have expired by now and returns how many were released
+func (sc *Scheduler) ReleaseExpired() (int, error) {
+	return sc.store.ReleaseExpiredReservations(sc.now())
+}
+
+// runOnce applies the due price changes, releases the expired reservations and logs them or the errors
+func (sc *Scheduler) runOnce() {
+	applied, err := sc.RunOnce()
+	if err != nil {
+		log.Printf("price scheduler: %v", err)
+	}
+	for _, c := range applied {
+		log.Printf("price scheduler: product %s costs %s since %s", c.ProductID, c.Price, c.EffectiveAt.Format(time.RFC3339))
+	}
+
+	released, err := sc.ReleaseExpired()
+	if err != nil {
+		log.Printf("reservation scheduler: %v", err)
+	} else if released > 0 {
+		log.Printf("reservation scheduler: %d expired reservations released", released)
+	}
+}
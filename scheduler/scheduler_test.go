@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+//TestRunOnce tests whether RunOnce applies only the price changes which are due
+func TestRunOnce(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	start := time.Now().Add(time.Hour)
+	err := catalog.SchedulePriceChanges("bq4foj37jhfipc5nqri0", []store.PriceChange{
+		{ChangeID: "sale", Price: money.New(7500, "EUR"), EffectiveAt: start},
+		{ChangeID: "end", Price: money.New(10000, "EUR"), EffectiveAt: start.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := New(catalog, time.Minute)
+
+	applied, err := sc.RunOnce()
+	assert.NoError(t, err)
+	assert.Empty(t, applied, "Expected nothing to be due yet")
+
+	sc.now = func() time.Time { return start.Add(time.Minute) }
+	applied, err = sc.RunOnce()
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1, "Expected the sale to be applied")
+	p, _ := catalog.Product("bq4foj37jhfipc5nqri0")
+	assert.Equal(t, money.New(7500, "EUR"), p.Price)
+}
+
+//TestStart tests whether the started scheduler applies the changes which are already due and stops
+func TestStart(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	err := catalog.SchedulePriceChanges("bq4foj37jhfipc5nqri0", []store.PriceChange{
+		{ChangeID: "sale", Price: money.New(7500, "EUR"), EffectiveAt: time.Now().Add(10 * time.Millisecond)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := New(catalog, 5*time.Millisecond)
+	sc.Start()
+	assert.Eventually(t, func() bool {
+		p, _ := catalog.Product("bq4foj37jhfipc5nqri0")
+		return p.Price == money.New(7500, "EUR")
+	}, time.Second, 5*time.Millisecond, "Expected the sale to be applied in the background")
+	sc.Stop()
+	sc.Stop()
+}
+
+//TestReleaseExpired tests whether ReleaseExpired releases only the reservations which have expired
+func TestReleaseExpired(t *testing.T) {
+	catalog := store.NewMemoryStore()
+	_, err := catalog.AdjustStock(store.StockAdjustment{AdjustmentID: "a1", ProductID: "bq4foj37jhfipc5nqri0", WarehouseID: "tallinn", Delta: 2, Reason: store.ReasonReceived})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(time.Hour)
+	_, err = catalog.ReserveStock(store.Reservation{ReservationID: "r1", ProductID: "bq4foj37jhfipc5nqri0", Quantity: 2, ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := New(catalog, time.Minute)
+
+	released, err := sc.ReleaseExpired()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, released, "Expected the reservation to be kept")
+
+	sc.now = func() time.Time { return expiresAt.Add(time.Minute) }
+	released, err = sc.ReleaseExpired()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, released, "Expected the reservation to be released")
+	assert.Equal(t, store.ErrNotFound, catalog.ReleaseReservation("bq4foj37jhfipc5nqri0", "r1"), "Expected the reservation to be gone")
+}
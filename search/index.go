@@ -0,0 +1,203 @@
+//package search contains the in-process full-text index used to search the catalog
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// the BM25 parameters: k1 limits how much repeating a term raises the score,
+// b sets how much longer documents are penalized
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// the share of the score a document gets for a term matched by prefix or with typos instead of exactly
+const (
+	prefixWeight = 0.8
+	typoWeight   = 0.6
+)
+
+// Field is a piece of the document text. The terms of a field with a bigger Boost count as if they were repeated.
+type Field struct {
+	Text  string
+	Boost float64
+}
+
+// Match is a document found by Search with its relevance score
+type Match struct {
+	ID    string
+	Score float64
+}
+
+// document is the indexed text of a document: the weighted frequency of every term and the weighted length
+type document struct {
+	terms  map[string]float64
+	length float64
+}
+
+// Index is an inverted index of documents ranked with BM25.
+// Query terms also match the indexed terms starting with them and the terms with a few typos after the first letter.
+// It is safe for concurrent use.
+type Index struct {
+	mu sync.RWMutex
+	//postings maps every term to the documents containing it and its weighted frequency there
+	postings map[string]map[string]float64
+	//terms are all the indexed terms, for prefix and typo lookups
+	terms *termSet
+	docs  map[string]document
+	//totalLength is the sum of the document lengths, for the average length used by BM25
+	totalLength float64
+}
+
+// NewIndex returns an empty Index
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string]float64),
+		docs:     make(map[string]document),
+		terms:    newTermSet(),
+	}
+}
+
+// Len returns the number of indexed documents
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// Put indexes the document with the given id, replacing its previous text
+func (ix *Index) Put(id string, fields ...Field) {
+	doc := document{terms: make(map[string]float64)}
+	for _, field := range fields {
+		for _, term := range tokenize(field.Text) {
+			doc.terms[term] += field.Boost
+			doc.length += field.Boost
+		}
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+	ix.docs[id] = doc
+	ix.totalLength += doc.length
+	for term, frequency := range doc.terms {
+		docs, ok := ix.postings[term]
+		if !ok {
+			docs = make(map[string]float64)
+			ix.postings[term] = docs
+			ix.terms.add(term)
+		}
+		docs[id] = frequency
+	}
+}
+
+// Remove takes the document with the given id out of the index
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// remove takes the document out of the index while the caller holds the lock
+func (ix *Index) remove(id string) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	delete(ix.docs, id)
+	ix.totalLength -= doc.length
+	for term := range doc.terms {
+		docs := ix.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(ix.postings, term)
+			ix.terms.remove(term)
+		}
+	}
+}
+
+// Search returns up to limit documents matching any of the query terms, the most relevant first.
+// Documents with the same score are ordered by id. A limit of 0 returns all the matches.
+func (ix *Index) Search(query string, limit int) []Match {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if len(ix.docs) == 0 {
+		return []Match{}
+	}
+	averageLength := ix.totalLength / float64(len(ix.docs))
+
+	scores := make(map[string]float64)
+	seen := make(map[string]bool)
+	for _, queryTerm := range tokenize(query) {
+		if seen[queryTerm] {
+			continue
+		}
+		seen[queryTerm] = true
+
+		//a document gets the score of the best way it matches the query term,
+		//so "shoe" matching both "shoe" and "shoes" does not count twice
+		best := make(map[string]float64)
+		for term, weight := range ix.expand(queryTerm) {
+			docs := ix.postings[term]
+			idf := math.Log(1 + (float64(len(ix.docs))-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+			for id, frequency := range docs {
+				length := ix.docs[id].length
+				score := weight * idf * frequency * (k1 + 1) / (frequency + k1*(1-b+b*length/averageLength))
+				if score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+		}
+	}
+
+	matches := make([]Match, 0, len(scores))
+	for id, score := range scores {
+		matches = append(matches, Match{ID: id, Score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// expand returns the indexed terms the query term matches with the weight of the match:
+// the term itself, the terms starting with it and the terms within the tolerated number of typos
+func (ix *Index) expand(queryTerm string) map[string]float64 {
+	weights := make(map[string]float64)
+	set := func(term string, weight float64) {
+		if weight > weights[term] {
+			weights[term] = weight
+		}
+	}
+
+	if _, ok := ix.postings[queryTerm]; ok {
+		set(queryTerm, 1)
+	}
+	//a single letter would match too much
+	if len([]rune(queryTerm)) > 1 {
+		ix.terms.withPrefix(queryTerm, func(term string) {
+			set(term, prefixWeight)
+		})
+	}
+	//the typos are only looked for in the terms which can be close enough
+	if edits := maxEdits(queryTerm); edits > 0 {
+		ix.terms.candidates(queryTerm, edits, func(term string) {
+			if distance := editDistance(queryTerm, term, edits); distance > 0 && distance <= edits {
+				set(term, typoWeight/float64(distance))
+			}
+		})
+	}
+	return weights
+}
@@ -0,0 +1,158 @@
+package search
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//newTestIndex returns an index of a few products with the name boosted over the description
+func newTestIndex() *Index {
+	ix := NewIndex()
+	for id, text := range map[string][2]string{
+		"shoe":    {"Nike SuperRep Go", "Women's Training Shoe"},
+		"bra":     {"Nike Icon Clash", "Women's Seamless Light-Support Sports Bra"},
+		"jacket":  {"Sports Jacket", "Windproof running jacket"},
+		"trainer": {"Trail Trainer", "Running shoe for rough trails"},
+	} {
+		ix.Put(id, Field{Text: text[0], Boost: 2}, Field{Text: text[1], Boost: 1})
+	}
+	return ix
+}
+
+//ids returns the ids of the matches in their order
+func ids(matches []Match) []string {
+	result := make([]string, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.ID)
+	}
+	return result
+}
+
+//TestTokenize tests whether the text is split into lowercase words without punctuation
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"womens", "seamless", "light", "support", "sports", "bra"}, tokenize("Women's Seamless Light-Support Sports Bra"))
+	assert.Equal(t, []string{"nike", "air", "max", "90"}, tokenize("  NIKE Air-Max 90!"))
+	assert.Empty(t, tokenize(" - "))
+}
+
+//TestEditDistance tests whether the typos are counted and the counting stops after the maximum
+func TestEditDistance(t *testing.T) {
+	for _, p := range []struct {
+		a, b     string
+		max      int
+		expected int
+	}{
+		{"sports", "sports", 2, 0},
+		{"sprots", "sports", 2, 1}, //transposition
+		{"sport", "sports", 2, 1},  //insertion
+		{"spots", "sports", 2, 1},  //deletion
+		{"shoe", "shoes", 1, 1},
+		{"jacket", "jackets", 1, 1},
+		{"bra", "training", 2, 3},
+		{"ab", "ba", 1, 1},
+	} {
+		assert.Equal(t, p.expected, editDistance(p.a, p.b, p.max), "Expected another distance between %s and %s", p.a, p.b)
+	}
+}
+
+//TestSearchRanking tests whether the documents matching more of the query terms and in the name go first
+func TestSearchRanking(t *testing.T) {
+	ix := newTestIndex()
+	assert.Equal(t, []string{"bra", "jacket"}, ids(ix.Search("sports bra", 0)), "Expected the bra before the other sports product")
+	assert.Equal(t, []string{"jacket", "trainer"}, ids(ix.Search("running", 0)), "Expected the match in the shorter text first")
+	assert.Equal(t, []string{"trainer", "shoe"}, ids(ix.Search("trainer shoe", 0)), "Expected the name match first")
+	assert.Equal(t, []string{"bra"}, ids(ix.Search("sports bra", 1)), "Expected the limit to be applied")
+	assert.Empty(t, ids(ix.Search("umbrella", 0)), "Expected no matches")
+	assert.Empty(t, ids(ix.Search("", 0)), "Expected no matches for an empty query")
+}
+
+//TestSearchPrefixAndTypos tests whether the query terms match the beginnings of the words and the words with typos
+func TestSearchPrefixAndTypos(t *testing.T) {
+	ix := newTestIndex()
+	assert.Equal(t, []string{"trainer", "shoe"}, ids(ix.Search("trai", 0)), "Expected the words starting with trai")
+	assert.Equal(t, []string{"jacket", "bra"}, ids(ix.Search("sprots", 0)), "Expected sports despite the typo, in the name first")
+	assert.Equal(t, []string{"jacket"}, ids(ix.Search("jakcet", 0)), "Expected jacket despite the typo")
+	assert.Empty(t, ids(ix.Search("brq", 0)), "Expected no typos tolerated in short words")
+
+	exact := ix.Search("sports", 0)
+	typo := ix.Search("sprots", 0)
+	assert.Greater(t, exact[0].Score, typo[0].Score, "Expected the exact match to score higher than the typo")
+}
+
+//TestPutAndRemove tests whether the index follows the changed and removed documents
+func TestPutAndRemove(t *testing.T) {
+	ix := newTestIndex()
+
+	ix.Put("bra", Field{Text: "Nike Swoosh", Boost: 2})
+	assert.Equal(t, []string{"jacket"}, ids(ix.Search("sports", 0)), "Expected the old text to be forgotten")
+	assert.Equal(t, []string{"bra"}, ids(ix.Search("swoosh", 0)), "Expected the new text to be found")
+
+	ix.Remove("jacket")
+	ix.Remove("unknown")
+	assert.Empty(t, ids(ix.Search("sports", 0)), "Expected the removed document not to be found")
+	assert.Equal(t, 3, ix.Len())
+	_, ok := ix.postings["windproof"]
+	assert.False(t, ok, "Expected the terms of the removed document to be dropped")
+	ix.terms.withPrefix("windproof", func(term string) {
+		t.Errorf("Expected the term %s of the removed document to be dropped from the lookups", term)
+	})
+}
+
+//TestTermSet tests whether the terms are found by prefix and as typo candidates after they are added and removed
+func TestTermSet(t *testing.T) {
+	terms := newTermSet()
+	for _, term := range []string{"train", "trainer", "trail", "trails", "jacket", "shoe"} {
+		terms.add(term)
+	}
+	terms.remove("trainer")
+	terms.remove("unknown")
+
+	var prefixed, candidates []string
+	terms.withPrefix("trai", func(term string) {
+		prefixed = append(prefixed, term)
+	})
+	terms.candidates("trian", 1, func(term string) {
+		candidates = append(candidates, term)
+	})
+	assert.ElementsMatch(t, []string{"train", "trail", "trails"}, prefixed, "Expected the terms starting with trai")
+	assert.ElementsMatch(t, []string{"train", "trail", "trails"}, candidates, "Expected the terms with the first letter and a close length")
+	assert.Len(t, terms.root.children['t'].children['r'].children['a'].children['i'].children['n'].children, 0, "Expected the nodes of the removed term to be dropped")
+}
+
+//word returns a distinct made-up word of six letters for every number
+func word(n int) string {
+	letters := make([]byte, 6)
+	for i := range letters {
+		letters[i] = byte('a' + n%26)
+		n /= 26
+	}
+	return string(letters)
+}
+
+//benchmarkIndex returns an index of n products with distinct words, like the one built at startup
+func benchmarkIndex(n int) *Index {
+	ix := NewIndex()
+	for i := 0; i < n; i++ {
+		ix.Put(fmt.Sprint(i), Field{Text: "Product " + word(i), Boost: 2}, Field{Text: word(i*7 + 1), Boost: 1})
+	}
+	return ix
+}
+
+//BenchmarkPut measures building an index of many products with distinct terms
+func BenchmarkPut(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchmarkIndex(100000)
+	}
+}
+
+//BenchmarkSearchTypo measures looking for a query term with a typo in an index of many distinct terms
+func BenchmarkSearchTypo(b *testing.B) {
+	ix := benchmarkIndex(100000)
+	query := []rune(word(12345))
+	query[2], query[3] = query[3], query[2]
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix.Search(string(query), 10)
+	}
+}
@@ -0,0 +1,117 @@
+package search
+
+// termSet keeps the indexed terms for the prefix and typo lookups.
+// Adding and removing a term takes time proportional to its length, not to the number of terms.
+type termSet struct {
+	//root is the trie of the terms, for the terms starting with a prefix
+	root trieNode
+	//the terms by their first letter and length, for the terms with a few typos
+	buckets map[bucket]map[string]bool
+}
+
+// trieNode is a node of the trie of the terms, the path from the root to the node spells a prefix of the terms under it
+type trieNode struct {
+	children map[rune]*trieNode
+	//term is the term ending at the node, or empty
+	term string
+}
+
+// bucket groups the terms with the same first letter and the same number of letters
+type bucket struct {
+	first  rune
+	length int
+}
+
+// newTermSet returns an empty termSet
+func newTermSet() *termSet {
+	return &termSet{buckets: make(map[bucket]map[string]bool)}
+}
+
+// bucketOf returns the bucket of the term
+func bucketOf(runes []rune) bucket {
+	return bucket{first: runes[0], length: len(runes)}
+}
+
+// add adds the term to the set
+func (s *termSet) add(term string) {
+	runes := []rune(term)
+	node := &s.root
+	for _, r := range runes {
+		next, ok := node.children[r]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[rune]*trieNode)
+			}
+			next = &trieNode{}
+			node.children[r] = next
+		}
+		node = next
+	}
+	node.term = term
+
+	terms, ok := s.buckets[bucketOf(runes)]
+	if !ok {
+		terms = make(map[string]bool)
+		s.buckets[bucketOf(runes)] = terms
+	}
+	terms[term] = true
+}
+
+// remove takes the term out of the set
+func (s *termSet) remove(term string) {
+	runes := []rune(term)
+	path := []*trieNode{&s.root}
+	for _, r := range runes {
+		next, ok := path[len(path)-1].children[r]
+		if !ok {
+			return
+		}
+		path = append(path, next)
+	}
+	path[len(runes)].term = ""
+	//drop the nodes which are left without terms
+	for i := len(runes); i > 0 && path[i].term == "" && len(path[i].children) == 0; i-- {
+		delete(path[i-1].children, runes[i-1])
+	}
+
+	terms := s.buckets[bucketOf(runes)]
+	delete(terms, term)
+	if len(terms) == 0 {
+		delete(s.buckets, bucketOf(runes))
+	}
+}
+
+// withPrefix calls fn for every term starting with the prefix, the prefix itself included
+func (s *termSet) withPrefix(prefix string, fn func(term string)) {
+	node := &s.root
+	for _, r := range prefix {
+		next, ok := node.children[r]
+		if !ok {
+			return
+		}
+		node = next
+	}
+	node.each(fn)
+}
+
+// each calls fn for the term of the node and all the terms under it
+func (n *trieNode) each(fn func(term string)) {
+	if n.term != "" {
+		fn(n.term)
+	}
+	for _, child := range n.children {
+		child.each(fn)
+	}
+}
+
+// candidates calls fn for every term which can be within the number of typos of the given term:
+// the terms starting with the same letter whose length differs by at most that number.
+// Like the prefix length of other search engines, the first letter has to be right, so not all the terms are compared.
+func (s *termSet) candidates(term string, edits int, fn func(term string)) {
+	runes := []rune(term)
+	for length := len(runes) - edits; length <= len(runes)+edits; length++ {
+		for candidate := range s.buckets[bucket{first: runes[0], length: length}] {
+			fn(candidate)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// apostrophes drops the apostrophes from the text, the replacer is built once
+var apostrophes = strings.NewReplacer("'", "", "’", "")
+
+// tokenize splits the text into lowercase terms made of letters and digits.
+// Apostrophes are dropped, so "Women's" becomes the single term "womens".
+func tokenize(text string) []string {
+	text = apostrophes.Replace(strings.ToLower(text))
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// maxEdits returns the number of typos tolerated in a query term of the given length:
+// none for short terms where one typo already makes another word
+func maxEdits(term string) int {
+	switch length := len([]rune(term)); {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	}
+	return 2
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and transpositions of adjacent letters
+// turning a into b (optimal string alignment distance), or maxDistance+1 as soon as it is known to be bigger than maxDistance
+func editDistance(a string, b string, maxDistance int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > maxDistance {
+		return maxDistance + 1
+	}
+	//the last three rows of the distance matrix
+	previous2 := make([]int, len(rb)+1)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && previous2[j-2]+1 < current[j] {
+				current[j] = previous2[j-2] + 1
+			}
+			if current[j] < rowMin {
+				rowMin = current[j]
+			}
+		}
+		//the distance never gets smaller than the minimum of a row
+		if rowMin > maxDistance {
+			return maxDistance + 1
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(rb)]
+}
+
+// min3 returns the smallest of the three numbers
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// abs returns the absolute value of the number
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
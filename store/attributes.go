@@ -0,0 +1,152 @@
+package store
+
+import "strconv"
+
+// the types of the attributes in the schema of a category
+const (
+	// AttributeString is any text
+	AttributeString = "string"
+	// AttributeNumber is any number, e.g. the wattage of a lamp
+	AttributeNumber = "number"
+	// AttributeEnum is one of the Values of the attribute, e.g. the size of a shoe
+	AttributeEnum = "enum"
+	// AttributeBool is true or false
+	AttributeBool = "bool"
+)
+
+// AttributeTypes are all the types an attribute can have
+var AttributeTypes = []string{AttributeString, AttributeNumber, AttributeEnum, AttributeBool}
+
+// Attribute is one field of the attribute schema of a category, the products of the category
+// and of its subcategories describe themselves with it
+type Attribute struct {
+	Name string `json:"Name"`
+	// Type is one of AttributeTypes
+	Type string `json:"Type"`
+	// Required attributes must be given by every product of the category
+	Required bool `json:"Required"`
+	// Unit is what the number is measured in, e.g. "W"
+	Unit string `json:"Unit,omitempty"`
+	// Values are the allowed values of an enum attribute
+	Values []string `json:"Values,omitempty"`
+}
+
+// Accepts reports whether the value decoded from JSON has the type of the attribute
+func (a Attribute) Accepts(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		if a.Type == AttributeEnum {
+			for _, allowed := range a.Values {
+				if v == allowed {
+					return true
+				}
+			}
+		}
+		return a.Type == AttributeString
+	case float64, int, int64:
+		return a.Type == AttributeNumber
+	case bool:
+		return a.Type == AttributeBool
+	}
+	return false
+}
+
+// AcceptsText reports whether the text, e.g. a variant attribute, is a value of the attribute:
+// one of the Values of an enum, a number or true or false
+func (a Attribute) AcceptsText(value string) bool {
+	switch a.Type {
+	case AttributeEnum:
+		for _, allowed := range a.Values {
+			if value == allowed {
+				return true
+			}
+		}
+		return false
+	case AttributeNumber:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case AttributeBool:
+		_, err := strconv.ParseBool(value)
+		return err == nil
+	}
+	return a.Type == AttributeString
+}
+
+// AttributeText returns the attribute value as it is shown in the facets and compared by the list filters:
+// the text itself, the number without trailing zeros, e.g. "60" or "10.5", or "true" and "false"
+func AttributeText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// Schema returns the attributes the products of the category are described with: the attributes of the category
+// and of all its ancestors, given from the top-level one down. An attribute of a subcategory replaces
+// the attribute of its parent with the same name.
+func Schema(ancestors []Category, c Category) []Attribute {
+	var schema []Attribute
+	position := make(map[string]int)
+	for _, category := range append(ancestors[:len(ancestors):len(ancestors)], c) {
+		for _, a := range category.Attributes {
+			if i, ok := position[a.Name]; ok {
+				schema[i] = a
+				continue
+			}
+			position[a.Name] = len(schema)
+			schema = append(schema, a)
+		}
+	}
+	return schema
+}
+
+// CategorySchema returns the Schema of the category with the given id, or ErrNotFound if there is no such category
+func CategorySchema(catalog CatalogStore, categoryID string) ([]Attribute, error) {
+	c, err := catalog.Category(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := catalog.CategoryAncestors(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]Attribute{}, Schema(ancestors, c)...), nil
+}
+
+// copyCategory returns the category with its own copy of the attribute schema,
+// so changing the copy does not change the stored category
+func copyCategory(c Category) Category {
+	if c.Attributes == nil {
+		return c
+	}
+	attributes := make([]Attribute, 0, len(c.Attributes))
+	for _, a := range c.Attributes {
+		a.Values = append([]string(nil), a.Values...)
+		attributes = append(attributes, a)
+	}
+	c.Attributes = attributes
+	return c
+}
+
+// copyProduct returns the product with its own copy of the attributes,
+// so changing the copy does not change the stored product
+func copyProduct(p Product) Product {
+	if p.Attributes == nil {
+		return p
+	}
+	attributes := make(map[string]interface{}, len(p.Attributes))
+	for name, value := range p.Attributes {
+		attributes[name] = value
+	}
+	p.Attributes = attributes
+	return p
+}
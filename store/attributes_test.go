@@ -0,0 +1,109 @@
+package store
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//TestAttributeAccepts tests whether the attributes accept only the values of their type
+func TestAttributeAccepts(t *testing.T) {
+	size := Attribute{Name: "size", Type: AttributeEnum, Values: []string{"38", "39"}}
+	assert.True(t, size.Accepts("38"))
+	assert.False(t, size.Accepts("45"), "Expected only the enum values")
+	assert.False(t, size.Accepts(38.0))
+	wattage := Attribute{Name: "wattage", Type: AttributeNumber, Unit: "W"}
+	assert.True(t, wattage.Accepts(60.0))
+	assert.True(t, wattage.Accepts(60))
+	assert.False(t, wattage.Accepts("60"))
+	assert.True(t, Attribute{Type: AttributeBool}.Accepts(false))
+	assert.True(t, Attribute{Type: AttributeString}.Accepts("black"))
+	assert.False(t, Attribute{Type: AttributeString}.Accepts(nil), "Expected null not to be a text")
+}
+
+//TestSchema tests whether the subcategories inherit the attributes of their parents and can replace them
+func TestSchema(t *testing.T) {
+	footwear := Category{CategoryID: "footwear", Attributes: []Attribute{
+		{Name: "size", Type: AttributeEnum, Values: []string{"38", "39"}},
+		{Name: "colour", Type: AttributeString},
+	}}
+	kids := Category{CategoryID: "kids", ParentID: "footwear", Attributes: []Attribute{
+		{Name: "size", Type: AttributeEnum, Values: []string{"28", "29"}, Required: true},
+		{Name: "lights", Type: AttributeBool},
+	}}
+	assert.Equal(t, []Attribute{
+		{Name: "size", Type: AttributeEnum, Values: []string{"28", "29"}, Required: true},
+		{Name: "colour", Type: AttributeString},
+		{Name: "lights", Type: AttributeBool},
+	}, Schema([]Category{footwear}, kids))
+	assert.Nil(t, Schema(nil, Category{}), "Expected no attributes without a schema")
+}
+
+//TestStoreAttributes tests whether the schemas of the categories and the attributes of the products are kept
+func TestStoreAttributes(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		c := Category{CategoryID: "lamps", CategoryName: "Lamps", Attributes: []Attribute{
+			{Name: "wattage", Type: AttributeNumber, Required: true, Unit: "W"},
+			{Name: "socket", Type: AttributeEnum, Values: []string{"E14", "E27"}},
+		}}
+		assert.NoError(t, catalog.CreateCategory(c))
+		p := Product{ProductID: "lamp", ProductName: "Lamp", Price: money.New(2500, "EUR"), CategoryID: "lamps",
+			Attributes: map[string]interface{}{"wattage": 60.0, "socket": "E27"}}
+		assert.NoError(t, catalog.CreateProduct(p, "test"))
+
+		stored, err := catalog.Category("lamps")
+		assert.NoError(t, err)
+		assert.Equal(t, c, stored, "%s: expected the schema to be kept", name)
+		storedProduct, err := catalog.Product("lamp")
+		assert.NoError(t, err)
+		assert.Equal(t, p, storedProduct, "%s: expected the attributes to be kept", name)
+
+		//the returned values can be changed without changing the stored ones
+		stored.Attributes[0].Values = append(stored.Attributes[0].Values, "x")
+		storedProduct.Attributes["socket"] = "E14"
+		stored, _ = catalog.Category("lamps")
+		storedProduct, _ = catalog.Product("lamp")
+		assert.Equal(t, c, stored, "%s: expected the stored schema to stay the same", name)
+		assert.Equal(t, p, storedProduct, "%s: expected the stored attributes to stay the same", name)
+
+		p.Attributes = nil
+		assert.NoError(t, catalog.UpdateProduct(p, "test"))
+		storedProduct, _ = catalog.Product("lamp")
+		assert.Nil(t, storedProduct.Attributes, "%s: expected the attributes to be cleared", name)
+	}
+}
+
+//TestListProductsByAttributes tests whether both stores filter the products by the text of their attributes
+func TestListProductsByAttributes(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		assert.NoError(t, catalog.CreateCategory(Category{CategoryID: "lamps", CategoryName: "Lamps"}))
+		for id, attributes := range map[string]map[string]interface{}{
+			"bulb":   {"wattage": 60.0, "socket": "E27", "dimmable": true},
+			"spot":   {"wattage": 7.5, "socket": "E14", "dimmable": false},
+			"candle": {"wattage": 60.0, "socket": "E14"},
+		} {
+			p := Product{ProductID: id, ProductName: id, Price: money.New(500, "EUR"), CategoryID: "lamps", Attributes: attributes}
+			assert.NoError(t, catalog.CreateProduct(p, "test"))
+		}
+
+		for _, filter := range []struct {
+			attributes map[string]string
+			expected   []string
+		}{
+			{map[string]string{"wattage": "60"}, []string{"bulb", "candle"}},
+			{map[string]string{"wattage": "7.5"}, []string{"spot"}},
+			{map[string]string{"wattage": "60", "socket": "E14"}, []string{"candle"}},
+			{map[string]string{"dimmable": "false"}, []string{"spot"}},
+			{map[string]string{"dimmable": "true"}, []string{"bulb"}},
+			{map[string]string{"colour": "white"}, []string{}},
+		} {
+			page, err := catalog.ListProducts(ListQuery{Sort: SortName, CategoryID: "lamps", Attributes: filter.attributes})
+			assert.NoError(t, err)
+			ids := make([]string, 0, len(page.Items))
+			for _, p := range page.Items {
+				ids = append(ids, p.ProductID)
+			}
+			assert.Equal(t, filter.expected, ids, "%s: expected other products for %v", name, filter.attributes)
+		}
+	}
+}
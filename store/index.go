@@ -0,0 +1,94 @@
+package store
+
+// idList keeps ids in the order they were added.
+// Adding, removing and checking an id take O(1), listing takes O(n).
+type idList struct {
+	//ids in insertion order, removed ones are left as holes until the next compaction
+	ids []idSlot
+	//position of every id in ids
+	pos map[string]int
+	//number of holes in ids
+	removed int
+}
+
+// idSlot is one position in idList
+type idSlot struct {
+	id      string
+	removed bool
+}
+
+// newIDList returns an empty idList
+func newIDList() *idList {
+	return &idList{pos: make(map[string]int)}
+}
+
+// add appends the id to the end of the list unless it is already there,
+// and returns the func which takes it out again while the list is as add left it
+func (l *idList) add(id string) (undo func()) {
+	if _, ok := l.pos[id]; ok {
+		return func() {}
+	}
+	l.pos[id] = len(l.ids)
+	l.ids = append(l.ids, idSlot{id: id})
+	return func() {
+		delete(l.pos, id)
+		l.ids = l.ids[:len(l.ids)-1]
+	}
+}
+
+// remove takes the id out of the list, keeping the order of the others,
+// and returns the func which puts it back in its place while the list is as remove left it
+func (l *idList) remove(id string) (undo func()) {
+	i, ok := l.pos[id]
+	if !ok {
+		return func() {}
+	}
+	delete(l.pos, id)
+	l.ids[i].removed = true
+	l.removed++
+	undo = func() {
+		l.ids[i].removed = false
+		l.pos[id] = i
+		l.removed--
+	}
+
+	//compact the slice once the holes take more than half of it
+	if l.removed > len(l.ids)/2 {
+		//the compacted ids are a new slice, so the old one is kept as it was for undo
+		ids, removed := l.ids, l.removed
+		compacted := make([]idSlot, 0, len(l.pos))
+		for _, slot := range l.ids {
+			if !slot.removed {
+				l.pos[slot.id] = len(compacted)
+				compacted = append(compacted, slot)
+			}
+		}
+		l.ids = compacted
+		l.removed = 0
+		undo = func() {
+			l.ids, l.removed = ids, removed-1
+			for j, slot := range ids {
+				if !slot.removed {
+					l.pos[slot.id] = j
+				}
+			}
+			ids[i].removed = false
+			l.pos[id] = i
+		}
+	}
+	return undo
+}
+
+// len returns the number of ids in the list
+func (l *idList) len() int {
+	return len(l.pos)
+}
+
+// each calls fn for every id in insertion order
+func (l *idList) each(fn func(id string)) {
+	for _, slot := range l.ids {
+		if !slot.removed {
+			fn(slot.id)
+		}
+	}
+}
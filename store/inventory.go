@@ -0,0 +1,160 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rs/xid"
+	"sort"
+	"time"
+)
+
+// the reason codes of the stock adjustments
+const (
+	// ReasonReceived is a delivery to the warehouse, the quantity goes up
+	ReasonReceived = "received"
+	// ReasonReturned is a product returned by a customer, the quantity goes up
+	ReasonReturned = "returned"
+	// ReasonSold is a product sent to a customer, the quantity goes down
+	ReasonSold = "sold"
+	// ReasonDamaged is a product which can not be sold anymore, the quantity goes down
+	ReasonDamaged = "damaged"
+	// ReasonLost is a product missing from the warehouse, the quantity goes down
+	ReasonLost = "lost"
+	// ReasonCount is the correction after counting the stock, the quantity goes either way
+	ReasonCount = "count"
+)
+
+// ReasonDirections maps every reason code to the sign of its adjustments: 1 for up, -1 for down and 0 for either way
+var ReasonDirections = map[string]int{
+	ReasonReceived: 1,
+	ReasonReturned: 1,
+	ReasonSold:     -1,
+	ReasonDamaged:  -1,
+	ReasonLost:     -1,
+	ReasonCount:    0,
+}
+
+// ErrInsufficientStock is matched by the *InsufficientStockError returned when there is not enough stock
+// for a reservation or an adjustment
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// InsufficientStockError tells how many pieces are available in the warehouse, or in the best one
+// if the warehouse was not given
+type InsufficientStockError struct {
+	WarehouseID string
+	Available   int64
+}
+
+// Error tells how many pieces are available
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock: %d available in warehouse %q", e.Available, e.WarehouseID)
+}
+
+// Is makes errors.Is(err, ErrInsufficientStock) true for an *InsufficientStockError
+func (e *InsufficientStockError) Is(target error) bool {
+	return target == ErrInsufficientStock
+}
+
+// StockLevel is the stock of a product in a warehouse, or in all of them if WarehouseID is empty
+type StockLevel struct {
+	WarehouseID string `json:"WarehouseID,omitempty"`
+	// OnHand is the number of pieces in the warehouse
+	OnHand int64 `json:"OnHand"`
+	// Reserved is the number of pieces held by the reservations which have not expired
+	Reserved int64 `json:"Reserved"`
+	// Available is the number of pieces which can still be reserved
+	Available int64 `json:"Available"`
+}
+
+// StockAdjustment is a change of the quantity of a product or of one of its variants in a warehouse
+type StockAdjustment struct {
+	AdjustmentID string `json:"AdjustmentID"`
+	ProductID    string `json:"ProductID"`
+	// VariantID is empty for the pieces of the product itself
+	VariantID   string `json:"VariantID,omitempty"`
+	WarehouseID string `json:"WarehouseID"`
+	// Delta is the number of pieces added, negative for the removed ones
+	Delta int64 `json:"Delta"`
+	// Reason is one of the reason codes in ReasonDirections
+	Reason string    `json:"Reason"`
+	Note   string    `json:"Note,omitempty"`
+	Author string    `json:"Author"`
+	At     time.Time `json:"At"`
+}
+
+// Reservation holds some pieces of a product or of one of its variants in a warehouse
+// until it expires, is released or is committed
+type Reservation struct {
+	ReservationID string `json:"ReservationID"`
+	ProductID     string `json:"ProductID"`
+	// VariantID is empty for the pieces of the product itself
+	VariantID   string    `json:"VariantID,omitempty"`
+	WarehouseID string    `json:"WarehouseID"`
+	Quantity    int64     `json:"Quantity"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	ExpiresAt   time.Time `json:"ExpiresAt"`
+}
+
+// active reports whether the reservation still holds its pieces at the given time
+func (r Reservation) active(now time.Time) bool {
+	return r.ExpiresAt.After(now)
+}
+
+// newStockLevel returns the stock level with the available pieces, never below zero
+func newStockLevel(warehouseID string, onHand int64, reserved int64) StockLevel {
+	available := onHand - reserved
+	if available < 0 {
+		available = 0
+	}
+	return StockLevel{WarehouseID: warehouseID, OnHand: onHand, Reserved: reserved, Available: available}
+}
+
+// totalStock sums the stock levels of all the warehouses
+func totalStock(levels []StockLevel) StockLevel {
+	var onHand, reserved int64
+	for _, level := range levels {
+		onHand += level.OnHand
+		reserved += level.Reserved
+	}
+	return newStockLevel("", onHand, reserved)
+}
+
+// sortStockLevels orders the stock levels by warehouse id
+func sortStockLevels(levels []StockLevel) {
+	sort.Slice(levels, func(i, j int) bool { return levels[i].WarehouseID < levels[j].WarehouseID })
+}
+
+// pickWarehouse returns the stock level of the warehouse the reservation takes its pieces from:
+// the given one, or the first warehouse by id with enough available pieces.
+// It returns an *InsufficientStockError if there are not enough pieces.
+func pickWarehouse(levels []StockLevel, warehouseID string, quantity int64) (StockLevel, error) {
+	sortStockLevels(levels)
+	best := StockLevel{WarehouseID: warehouseID}
+	for _, level := range levels {
+		if warehouseID != "" && level.WarehouseID != warehouseID {
+			continue
+		}
+		if level.Available >= quantity {
+			return level, nil
+		}
+		if level.Available > best.Available {
+			best = level
+		}
+	}
+	return StockLevel{}, &InsufficientStockError{WarehouseID: best.WarehouseID, Available: best.Available}
+}
+
+// newCommitAdjustment returns the adjustment selling the pieces of the reservation, made now by the author
+func newCommitAdjustment(r Reservation, author string) StockAdjustment {
+	return StockAdjustment{
+		AdjustmentID: xid.New().String(),
+		ProductID:    r.ProductID,
+		VariantID:    r.VariantID,
+		WarehouseID:  r.WarehouseID,
+		Delta:        -r.Quantity,
+		Reason:       ReasonSold,
+		Note:         "reservation " + r.ReservationID,
+		Author:       author,
+		At:           time.Now().UTC(),
+	}
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+//adjustment returns the adjustment of the first seed product in the warehouse
+func adjustment(id string, warehouseID string, delta int64, reason string) StockAdjustment {
+	return StockAdjustment{AdjustmentID: id, ProductID: "bq4foj37jhfipc5nqri0", WarehouseID: warehouseID, Delta: delta, Reason: reason, Author: "test", At: time.Now().UTC()}
+}
+
+//reservation returns the reservation of the first seed product which expires after the ttl
+func reservation(id string, warehouseID string, quantity int64, ttl time.Duration) Reservation {
+	now := time.Now().UTC()
+	return Reservation{ReservationID: id, ProductID: "bq4foj37jhfipc5nqri0", WarehouseID: warehouseID, Quantity: quantity, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+}
+
+//TestAdjustStock tests whether the adjustments change the quantities of the warehouses and are recorded,
+//and the quantity can not go below the reserved pieces
+func TestAdjustStock(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		level, err := catalog.AdjustStock(adjustment("a1", "tallinn", 10, ReasonReceived))
+		assert.NoError(t, err)
+		assert.Equal(t, StockLevel{WarehouseID: "tallinn", OnHand: 10, Available: 10}, level, "%s: expected the received pieces", name)
+		_, err = catalog.AdjustStock(adjustment("a2", "riga", 4, ReasonReceived))
+		assert.NoError(t, err)
+		level, err = catalog.AdjustStock(adjustment("a3", "tallinn", -3, ReasonSold))
+		assert.NoError(t, err)
+		assert.Equal(t, StockLevel{WarehouseID: "tallinn", OnHand: 7, Available: 7}, level, "%s: expected the sold pieces to be gone", name)
+
+		_, err = catalog.AdjustStock(adjustment("a4", "riga", -5, ReasonLost))
+		assert.Equal(t, &InsufficientStockError{WarehouseID: "riga", Available: 4}, err, "%s: expected the missing pieces to be reported", name)
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+		_, err = catalog.AdjustStock(StockAdjustment{AdjustmentID: "a5", ProductID: "randomID", WarehouseID: "riga", Delta: 1, Reason: ReasonReceived})
+		assert.Equal(t, ErrNotFound, err, "%s: expected the unknown product to be reported", name)
+
+		levels, err := catalog.Stock("bq4foj37jhfipc5nqri0", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []StockLevel{{WarehouseID: "riga", OnHand: 4, Available: 4}, {WarehouseID: "tallinn", OnHand: 7, Available: 7}}, levels,
+			"%s: expected the warehouses ordered by id", name)
+		adjustments, err := catalog.StockAdjustments("bq4foj37jhfipc5nqri0", "")
+		assert.NoError(t, err)
+		if assert.Len(t, adjustments, 3, "%s: expected only the applied adjustments", name) {
+			assert.Equal(t, "a3", adjustments[2].AdjustmentID)
+			assert.Equal(t, ReasonSold, adjustments[2].Reason)
+		}
+
+		//the reserved pieces can not be taken out
+		_, err = catalog.ReserveStock(reservation("r1", "tallinn", 5, time.Hour))
+		assert.NoError(t, err)
+		_, err = catalog.AdjustStock(adjustment("a6", "tallinn", -3, ReasonDamaged))
+		assert.Equal(t, &InsufficientStockError{WarehouseID: "tallinn", Available: 2}, err, "%s: expected the reserved pieces to be kept", name)
+
+		summary, err := catalog.StockSummary([]string{"bq4foj37jhfipc5nqri0", "bq5457j7jhfi2s58o030"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]StockLevel{
+			"bq4foj37jhfipc5nqri0": {OnHand: 11, Reserved: 5, Available: 6},
+			"bq5457j7jhfi2s58o030": {},
+		}, summary, "%s: expected the warehouses to be summed", name)
+	}
+}
+
+//TestReserveStock tests whether the reservations hold the available pieces until they are released,
+//committed or expire
+func TestReserveStock(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		for _, a := range []StockAdjustment{adjustment("a1", "tallinn", 2, ReasonReceived), adjustment("a2", "riga", 5, ReasonReceived)} {
+			if _, err := catalog.AdjustStock(a); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		//without a warehouse the first one with enough pieces is taken
+		r, err := catalog.ReserveStock(reservation("r1", "", 3, time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, "riga", r.WarehouseID, "%s: expected the warehouse with enough pieces", name)
+		_, err = catalog.ReserveStock(reservation("r2", "", 3, time.Hour))
+		assert.Equal(t, &InsufficientStockError{WarehouseID: "riga", Available: 2}, err, "%s: expected the best warehouse to be reported", name)
+		_, err = catalog.ReserveStock(reservation("r3", "tallinn", 2, time.Hour))
+		assert.NoError(t, err)
+		_, err = catalog.ReserveStock(reservation("r4", "vilnius", 1, time.Hour))
+		assert.Equal(t, &InsufficientStockError{WarehouseID: "vilnius"}, err, "%s: expected the unknown warehouse to be empty", name)
+		_, err = catalog.ReserveStock(Reservation{ReservationID: "r5", ProductID: "randomID", Quantity: 1})
+		assert.Equal(t, ErrNotFound, err, "%s: expected the unknown product to be reported", name)
+
+		//the released pieces can be reserved again
+		assert.NoError(t, catalog.ReleaseReservation("bq4foj37jhfipc5nqri0", "r3"))
+		assert.Equal(t, ErrNotFound, catalog.ReleaseReservation("bq4foj37jhfipc5nqri0", "r3"), "%s: expected the reservation to be gone", name)
+		assert.Equal(t, ErrNotFound, catalog.ReleaseReservation("bq5457j7jhfi2s58o030", "r1"), "%s: expected the reservation of another product to be kept", name)
+
+		//the committed pieces are sold
+		sale, err := catalog.CommitReservation("bq4foj37jhfipc5nqri0", "r1", "checkout")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-3), sale.Delta)
+		assert.Equal(t, ReasonSold, sale.Reason)
+		assert.Equal(t, "checkout", sale.Author)
+		_, err = catalog.CommitReservation("bq4foj37jhfipc5nqri0", "r1", "checkout")
+		assert.Equal(t, ErrNotFound, err, "%s: expected the reservation to be committed once", name)
+		levels, err := catalog.Stock("bq4foj37jhfipc5nqri0", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []StockLevel{{WarehouseID: "riga", OnHand: 2, Available: 2}, {WarehouseID: "tallinn", OnHand: 2, Available: 2}}, levels,
+			"%s: expected the sold pieces to be gone", name)
+
+		//the expired reservations do not hold their pieces and can not be committed
+		_, err = catalog.ReserveStock(reservation("expired", "riga", 2, -time.Minute))
+		assert.NoError(t, err)
+		_, err = catalog.ReserveStock(reservation("active", "riga", 2, time.Hour))
+		assert.NoError(t, err)
+		_, err = catalog.CommitReservation("bq4foj37jhfipc5nqri0", "expired", "checkout")
+		assert.Equal(t, ErrNotFound, err, "%s: expected the expired reservation to be reported", name)
+		released, err := catalog.ReleaseExpiredReservations(time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, released, "%s: expected only the expired reservation to be released", name)
+		assert.NoError(t, catalog.ReleaseReservation("bq4foj37jhfipc5nqri0", "active"))
+
+		//the stock is deleted with the product
+		assert.NoError(t, catalog.DeleteProduct("bq4foj37jhfipc5nqri0"))
+		summary, err := catalog.StockSummary([]string{"bq4foj37jhfipc5nqri0"})
+		assert.NoError(t, err)
+		assert.Equal(t, StockLevel{}, summary["bq4foj37jhfipc5nqri0"], "%s: expected no stock", name)
+	}
+}
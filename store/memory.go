@@ -0,0 +1,1051 @@
+package store
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/search"
+	"sync"
+	"time"
+)
+
+// MemoryStore is the simple imitation of the DB which keeps categories and products in maps indexed by id,
+// plus the indexes of subcategories by parent and of products by category, the full-text index of products,
+// the price timelines, the variants and the stock of the products.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu sync.RWMutex
+	memoryData
+	//journal records how to undo the changes made in a transaction, it is nil outside of transactions
+	journal *journal
+}
+
+// memoryData is what MemoryStore keeps, a transaction works on the same maps and indexes as the store
+type memoryData struct {
+	categories map[string]Category
+	products   map[string]Product
+	//ids in the order the categories and products were created
+	categoryOrder *idList
+	productOrder  *idList
+	//ids of the products of every category, in the order the products were created
+	productsByCategory map[string]*idList
+	//ids of the subcategories of every category, in the order they were put under it
+	childrenByParent map[string]*idList
+	//the names and descriptions of the products
+	searchIndex *search.Index
+	//the applied and scheduled price changes of every product, in the order they were recorded
+	priceChanges map[string][]PriceChange
+	//the variants of every product in the order they were created, the ids of their products and of the SKUs
+	variants      map[string][]Variant
+	variantOwners map[string]string
+	skus          map[string]string
+	//the pieces of every product and its variants on hand in every warehouse
+	stock map[string]map[stockSlot]int64
+	//the stock adjustments and the reservations of every product and its variants, in the order they were made
+	stockAdjustments map[string][]StockAdjustment
+	reservations     map[string][]Reservation
+}
+
+// journal is the undo journal of a transaction of MemoryStore
+type journal struct {
+	//undo has a func for every change which puts back what the change replaced, in the order of the changes
+	undo []func()
+	//the products whose price changes, variants and stock are already saved in undo
+	saved map[string]bool
+}
+
+// stockSlot is where the pieces of a product are kept: the variant, empty for the product itself, and the warehouse
+type stockSlot struct {
+	variantID   string
+	warehouseID string
+}
+
+var _ CatalogStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a MemoryStore filled with the seed categories and products
+func NewMemoryStore() *MemoryStore {
+	s := newEmptyMemoryStore()
+	for _, c := range []Category{
+		{
+			CategoryID:          "bq4fasj7jhfi127rimlg",
+			CategoryName:        "Shopping Products",
+			CategoryDescription: "Products consumers purchase and consume on a less frequent schedule compared to convenience products.",
+		},
+		{
+			CategoryID:          "bq4fb3b7jhfi7v7uo39g",
+			CategoryName:        "Specialty Products",
+			CategoryDescription: "Products that are more expensive relative to convenience and shopping products.",
+		},
+	} {
+		s.putCategory(c)
+	}
+	for _, p := range []Product{
+		{
+			ProductID:          "bq4foj37jhfipc5nqri0",
+			ProductName:        "Nike SuperRep Go",
+			ProductDescription: "Women's Training Shoe",
+			Price:              money.New(10000, "EUR"),
+			CategoryID:         "bq4fasj7jhfi127rimlg",
+		},
+		{
+			ProductID:          "bq5457j7jhfi2s58o030",
+			ProductName:        "Nike Icon Clash",
+			ProductDescription: "Women's Seamless Light-Support Sports Bra",
+			Price:              money.New(5000, "EUR"),
+			CategoryID:         "bq4fasj7jhfi127rimlg",
+		},
+	} {
+		s.putProduct(p)
+		s.recordPrice(p, SystemAuthor)
+	}
+	return s
+}
+
+// newEmptyMemoryStore returns a MemoryStore without any categories and products
+func newEmptyMemoryStore() *MemoryStore {
+	return &MemoryStore{memoryData: memoryData{
+		categories:         make(map[string]Category),
+		products:           make(map[string]Product),
+		categoryOrder:      newIDList(),
+		productOrder:       newIDList(),
+		productsByCategory: make(map[string]*idList),
+		childrenByParent:   make(map[string]*idList),
+		searchIndex:        search.NewIndex(),
+		priceChanges:       make(map[string][]PriceChange),
+		variants:           make(map[string][]Variant),
+		variantOwners:      make(map[string]string),
+		skus:               make(map[string]string),
+		stock:              make(map[string]map[stockSlot]int64),
+		stockAdjustments:   make(map[string][]StockAdjustment),
+		reservations:       make(map[string][]Reservation),
+	}}
+}
+
+// Categories returns all the categories in the order they were created
+func (s *MemoryStore) Categories() ([]Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	allCategories := make([]Category, 0, s.categoryOrder.len())
+	s.categoryOrder.each(func(id string) {
+		allCategories = append(allCategories, copyCategory(s.categories[id]))
+	})
+	return allCategories, nil
+}
+
+// Category looks for the category with the given id in the index
+func (s *MemoryStore) Category(id string) (Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.categories[id]
+	if !ok {
+		return Category{}, ErrNotFound
+	}
+	return copyCategory(c), nil
+}
+
+// CreateCategory adds the category to the index if its parent exists
+func (s *MemoryStore) CreateCategory(c Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.categories[c.CategoryID]; ok {
+		return ErrAlreadyExists
+	}
+	if err := s.checkParent(c); err != nil {
+		return err
+	}
+	s.putCategory(c)
+	return nil
+}
+
+// UpdateCategory replaces the category with the same id and moves it under its new parent in the index
+func (s *MemoryStore) UpdateCategory(c Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.categories[c.CategoryID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.ParentID != c.ParentID {
+		if err := s.checkParent(c); err != nil {
+			return err
+		}
+		s.removeFromParent(stored)
+	}
+	s.putCategory(c)
+	return nil
+}
+
+// DeleteCategory removes the category with the given id unless it has subcategories,
+// and removes or moves its products as the policy says while holding the lock, so nobody sees a half-done delete
+func (s *MemoryStore) DeleteCategory(id string, policy DeletePolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := policy.check(id); err != nil {
+		return err
+	}
+	stored, ok := s.categories[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	//everything is checked before anything is changed
+	inUse := CategoryInUseError{}
+	if children, ok := s.childrenByParent[id]; ok {
+		inUse.Subcategories = children.len()
+	}
+	var productIDs []string
+	if ids, ok := s.productsByCategory[id]; ok {
+		ids.each(func(productID string) {
+			productIDs = append(productIDs, productID)
+		})
+	}
+	if inUse.Subcategories > 0 || (len(productIDs) > 0 && (policy.Mode == "" || policy.Mode == DeleteRestrict)) {
+		inUse.Products = len(productIDs)
+		return &inUse
+	}
+	if _, ok := s.categories[policy.TargetID]; policy.Mode == DeleteReassign && !ok {
+		return ErrCategoryNotFound
+	}
+
+	for _, productID := range productIDs {
+		p := s.products[productID]
+		if policy.Mode == DeleteCascade {
+			s.removeProduct(p)
+		} else {
+			s.removeFromCategory(p)
+			p.CategoryID = policy.TargetID
+			s.putProduct(p)
+		}
+	}
+	s.saveCategory(id)
+	delete(s.categories, id)
+	s.record(s.categoryOrder.remove(id))
+	s.removeFromParent(stored)
+	return nil
+}
+
+// ChildCategories returns the subcategories of the category using the parent index
+func (s *MemoryStore) ChildCategories(id string) ([]Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.categories[id]; !ok {
+		return nil, ErrNotFound
+	}
+	children := make([]Category, 0)
+	if ids, ok := s.childrenByParent[id]; ok {
+		ids.each(func(childID string) {
+			children = append(children, copyCategory(s.categories[childID]))
+		})
+	}
+	return children, nil
+}
+
+// CategoryAncestors follows the parents of the category up to the top-level one
+func (s *MemoryStore) CategoryAncestors(id string) ([]Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	ancestors := make([]Category, 0)
+	for parentID := c.ParentID; parentID != ""; parentID = s.categories[parentID].ParentID {
+		ancestors = append([]Category{copyCategory(s.categories[parentID])}, ancestors...)
+	}
+	return ancestors, nil
+}
+
+// CategoryTree nests all the categories under their parents
+func (s *MemoryStore) CategoryTree() ([]CategoryNode, error) {
+	allCategories, err := s.Categories()
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(allCategories), nil
+}
+
+// Products returns all the products in the order they were created
+func (s *MemoryStore) Products() ([]Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	allProducts := make([]Product, 0, s.productOrder.len())
+	s.productOrder.each(func(id string) {
+		allProducts = append(allProducts, copyProduct(s.products[id]))
+	})
+	return allProducts, nil
+}
+
+// Product looks for the product with the given id in the index
+func (s *MemoryStore) Product(id string) (Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.products[id]
+	if !ok {
+		return Product{}, ErrNotFound
+	}
+	return copyProduct(p), nil
+}
+
+// ProductsOfCategory returns the products of the given category using the category index
+func (s *MemoryStore) ProductsOfCategory(categoryID string) ([]Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	productsOfCategory := make([]Product, 0)
+	if ids, ok := s.productsByCategory[categoryID]; ok {
+		ids.each(func(id string) {
+			productsOfCategory = append(productsOfCategory, copyProduct(s.products[id]))
+		})
+	}
+	return productsOfCategory, nil
+}
+
+// CreateProduct adds the product to the indexes if its category exists and starts its price timeline
+func (s *MemoryStore) CreateProduct(p Product, author string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.categories[p.CategoryID]; !ok {
+		return ErrCategoryNotFound
+	}
+	if _, ok := s.products[p.ProductID]; ok {
+		return ErrAlreadyExists
+	}
+	s.putProduct(p)
+	s.recordPrice(p, author)
+	return nil
+}
+
+// UpdateProduct replaces the product with the same id and moves it to its new category in the index,
+// a new price is added to the price timeline
+func (s *MemoryStore) UpdateProduct(p Product, author string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.products[p.ProductID]
+	if !ok {
+		return ErrNotFound
+	}
+	//the category only has to be checked when the product moves to another one
+	if stored.CategoryID != p.CategoryID {
+		if _, ok := s.categories[p.CategoryID]; !ok {
+			return ErrCategoryNotFound
+		}
+		s.removeFromCategory(stored)
+	}
+	s.putProduct(p)
+	if stored.Price != p.Price {
+		s.recordPrice(p, author)
+	}
+	return nil
+}
+
+// DeleteProduct removes the product with the given id from the indexes
+func (s *MemoryStore) DeleteProduct(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.products[id]
+	if !ok {
+		return ErrNotFound
+	}
+	s.removeProduct(stored)
+	return nil
+}
+
+// putCategory stores the category and adds it to the parent index while the caller holds the lock
+func (s *MemoryStore) putCategory(c Category) {
+	s.saveCategory(c.CategoryID)
+	s.categories[c.CategoryID] = copyCategory(c)
+	s.record(s.categoryOrder.add(c.CategoryID))
+	if c.ParentID == "" {
+		return
+	}
+	s.record(s.listOf(s.childrenByParent, c.ParentID).add(c.CategoryID))
+}
+
+// removeFromParent takes the category out of the index of its parent while the caller holds the lock
+func (s *MemoryStore) removeFromParent(c Category) {
+	s.removeListed(s.childrenByParent, c.ParentID, c.CategoryID)
+}
+
+// checkParent returns ErrParentNotFound if the parent of the category does not exist
+// and ErrCategoryCycle if the category would become its own ancestor, while the caller holds the lock
+func (s *MemoryStore) checkParent(c Category) error {
+	for parentID := c.ParentID; parentID != ""; parentID = s.categories[parentID].ParentID {
+		if parentID == c.CategoryID {
+			return ErrCategoryCycle
+		}
+		if _, ok := s.categories[parentID]; !ok {
+			return ErrParentNotFound
+		}
+	}
+	return nil
+}
+
+// descendants returns the id of the category and the ids of all its subcategories at any depth
+// while the caller holds the lock
+func (s *MemoryStore) descendants(id string) []string {
+	ids := []string{id}
+	for i := 0; i < len(ids); i++ {
+		if children, ok := s.childrenByParent[ids[i]]; ok {
+			children.each(func(childID string) {
+				ids = append(ids, childID)
+			})
+		}
+	}
+	return ids
+}
+
+// putProduct stores the product and adds it to the category and search indexes while the caller holds the lock
+func (s *MemoryStore) putProduct(p Product) {
+	s.saveProduct(p.ProductID)
+	s.products[p.ProductID] = copyProduct(p)
+	s.record(s.productOrder.add(p.ProductID))
+	s.record(s.listOf(s.productsByCategory, p.CategoryID).add(p.ProductID))
+	indexProduct(s.searchIndex, p)
+}
+
+// removeProduct takes the product out of the store and all the indexes while the caller holds the lock
+func (s *MemoryStore) removeProduct(p Product) {
+	s.saveProduct(p.ProductID)
+	s.saveRecords(p.ProductID)
+	delete(s.products, p.ProductID)
+	s.record(s.productOrder.remove(p.ProductID))
+	s.removeFromCategory(p)
+	s.searchIndex.Remove(p.ProductID)
+	delete(s.priceChanges, p.ProductID)
+	for _, v := range s.variants[p.ProductID] {
+		delete(s.variantOwners, v.VariantID)
+		delete(s.skus, v.SKU)
+	}
+	delete(s.variants, p.ProductID)
+	delete(s.stock, p.ProductID)
+	delete(s.stockAdjustments, p.ProductID)
+	delete(s.reservations, p.ProductID)
+}
+
+// recordPrice adds the current price of the product to its timeline while the caller holds the lock
+func (s *MemoryStore) recordPrice(p Product, author string) {
+	s.saveRecords(p.ProductID)
+	s.priceChanges[p.ProductID] = append(s.priceChanges[p.ProductID], newPriceChange(p, author))
+}
+
+// PriceHistory returns the applied and scheduled prices of the product ordered by the time they take effect
+func (s *MemoryStore) PriceHistory(productID string) ([]PriceChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.products[productID]; !ok {
+		return nil, ErrNotFound
+	}
+	timeline := append([]PriceChange{}, s.priceChanges[productID]...)
+	sortTimeline(timeline)
+	return timeline, nil
+}
+
+// SchedulePriceChanges adds the changes to the timeline of the product as scheduled ones
+func (s *MemoryStore) SchedulePriceChanges(productID string, changes []PriceChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.products[productID]; !ok {
+		return ErrNotFound
+	}
+	s.saveRecords(productID)
+	for _, c := range changes {
+		c.ProductID = productID
+		c.Status = PriceScheduled
+		s.priceChanges[productID] = append(s.priceChanges[productID], c)
+	}
+	return nil
+}
+
+// CancelPriceChange removes the scheduled change from the timeline of the product together with its other half,
+// the change reverting it or the change it reverts if that is still scheduled
+func (s *MemoryStore) CancelPriceChange(productID string, changeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timeline := s.priceChanges[productID]
+	for _, c := range timeline {
+		if c.ChangeID != changeID {
+			continue
+		}
+		if c.Status == PriceApplied {
+			return ErrPriceChangeApplied
+		}
+		s.saveRecords(productID)
+		kept := make([]PriceChange, 0, len(timeline)-1)
+		for _, other := range timeline {
+			if !cancels(changeID, c, other) {
+				kept = append(kept, other)
+			}
+		}
+		s.priceChanges[productID] = kept
+		return nil
+	}
+	return ErrNotFound
+}
+
+// ApplyDuePriceChanges sets the prices of the scheduled changes which are due at the given time
+func (s *MemoryStore) ApplyDuePriceChanges(now time.Time) ([]PriceChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := make([]PriceChange, 0)
+	for _, timeline := range s.priceChanges {
+		for _, c := range timeline {
+			if c.Status == PriceScheduled && !c.EffectiveAt.After(now) {
+				due = append(due, c)
+			}
+		}
+	}
+	//the later changes of a product win over the earlier ones
+	sortTimeline(due)
+	for i := range due {
+		p := s.products[due[i].ProductID]
+		s.saveProduct(p.ProductID)
+		s.saveRecords(p.ProductID)
+		timeline := s.priceChanges[p.ProductID]
+		if due[i].Reverts != "" {
+			sorted := append([]PriceChange(nil), timeline...)
+			sortTimeline(sorted)
+			due[i].Price = revertPrice(sorted, due[i], p.Price)
+		}
+		p.Price = due[i].Price
+		s.products[p.ProductID] = p
+		due[i].Status = PriceApplied
+		for j := range timeline {
+			if timeline[j].ChangeID == due[i].ChangeID {
+				timeline[j] = due[i]
+			}
+		}
+	}
+	return due, nil
+}
+
+// Variants returns the variants of the product in the order they were created
+func (s *MemoryStore) Variants(productID string) ([]Variant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.products[productID]; !ok {
+		return nil, ErrNotFound
+	}
+	return s.copyVariants(productID), nil
+}
+
+// VariantsOf returns the variants of every given product which has some
+func (s *MemoryStore) VariantsOf(productIDs []string) (map[string][]Variant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	variants := make(map[string][]Variant)
+	for _, productID := range productIDs {
+		if len(s.variants[productID]) > 0 {
+			variants[productID] = s.copyVariants(productID)
+		}
+	}
+	return variants, nil
+}
+
+// Variant looks for the variant among the variants of the product
+func (s *MemoryStore) Variant(productID string, variantID string) (Variant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.checkStockItem(productID, variantID); err != nil {
+		return Variant{}, err
+	}
+	i := s.variantIndex(productID, variantID)
+	return copyVariant(s.variants[productID][i]), nil
+}
+
+// CreateVariant adds the variant to the variants of its product and to the SKU index
+func (s *MemoryStore) CreateVariant(v Variant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.products[v.ProductID]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := s.variantOwners[v.VariantID]; ok {
+		return ErrAlreadyExists
+	}
+	if _, ok := s.skus[v.SKU]; ok {
+		return ErrDuplicateSKU
+	}
+	s.saveRecords(v.ProductID)
+	s.variants[v.ProductID] = append(s.variants[v.ProductID], copyVariant(v))
+	s.variantOwners[v.VariantID] = v.ProductID
+	s.skus[v.SKU] = v.VariantID
+	return nil
+}
+
+// UpdateVariant replaces the variant with the same id and moves it to its new SKU in the index
+func (s *MemoryStore) UpdateVariant(v Variant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkStockItem(v.ProductID, v.VariantID); err != nil {
+		return err
+	}
+	if owner, ok := s.skus[v.SKU]; ok && owner != v.VariantID {
+		return ErrDuplicateSKU
+	}
+	s.saveRecords(v.ProductID)
+	i := s.variantIndex(v.ProductID, v.VariantID)
+	delete(s.skus, s.variants[v.ProductID][i].SKU)
+	s.variants[v.ProductID][i] = copyVariant(v)
+	s.skus[v.SKU] = v.VariantID
+	return nil
+}
+
+// DeleteVariant removes the variant from the variants of its product and the SKU index
+// together with its stock, adjustments and reservations
+func (s *MemoryStore) DeleteVariant(productID string, variantID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkStockItem(productID, variantID); err != nil {
+		return err
+	}
+	s.saveRecords(productID)
+	variants := s.variants[productID]
+	i := s.variantIndex(productID, variantID)
+	delete(s.skus, variants[i].SKU)
+	delete(s.variantOwners, variantID)
+	s.variants[productID] = append(variants[:i:i], variants[i+1:]...)
+
+	for slot := range s.stock[productID] {
+		if slot.variantID == variantID {
+			delete(s.stock[productID], slot)
+		}
+	}
+	adjustments := s.stockAdjustments[productID][:0]
+	for _, a := range s.stockAdjustments[productID] {
+		if a.VariantID != variantID {
+			adjustments = append(adjustments, a)
+		}
+	}
+	s.stockAdjustments[productID] = adjustments
+	reservations := s.reservations[productID][:0]
+	for _, r := range s.reservations[productID] {
+		if r.VariantID != variantID {
+			reservations = append(reservations, r)
+		}
+	}
+	s.reservations[productID] = reservations
+	return nil
+}
+
+// Stock returns the stock levels of the product or the variant, the reservations which have not expired count as reserved
+func (s *MemoryStore) Stock(productID string, variantID string) ([]StockLevel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.checkStockItem(productID, variantID); err != nil {
+		return nil, err
+	}
+	return s.itemStock(productID, variantID, time.Now()), nil
+}
+
+// StockSummary sums the stock levels of every product and its variants over the warehouses
+func (s *MemoryStore) StockSummary(productIDs []string) (map[string]StockLevel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	summary := make(map[string]StockLevel, len(productIDs))
+	for _, productID := range productIDs {
+		levels := make([]StockLevel, 0)
+		for _, level := range s.stockLevels(productID, now) {
+			levels = append(levels, level)
+		}
+		summary[productID] = totalStock(levels)
+	}
+	return summary, nil
+}
+
+// AdjustStock changes the quantity of the product or the variant in the warehouse
+// unless fewer pieces than reserved would be left
+func (s *MemoryStore) AdjustStock(a StockAdjustment) (StockLevel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkStockItem(a.ProductID, a.VariantID); err != nil {
+		return StockLevel{}, err
+	}
+	level, ok := s.stockLevels(a.ProductID, time.Now())[stockSlot{variantID: a.VariantID, warehouseID: a.WarehouseID}]
+	if !ok {
+		level = newStockLevel(a.WarehouseID, 0, 0)
+	}
+	if level.OnHand+a.Delta < level.Reserved {
+		return StockLevel{}, &InsufficientStockError{WarehouseID: a.WarehouseID, Available: level.Available}
+	}
+	s.putStock(a)
+	return newStockLevel(a.WarehouseID, level.OnHand+a.Delta, level.Reserved), nil
+}
+
+// StockAdjustments returns the adjustments of the product or the variant in the order they were made
+func (s *MemoryStore) StockAdjustments(productID string, variantID string) ([]StockAdjustment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.checkStockItem(productID, variantID); err != nil {
+		return nil, err
+	}
+	adjustments := make([]StockAdjustment, 0)
+	for _, a := range s.stockAdjustments[productID] {
+		if a.VariantID == variantID {
+			adjustments = append(adjustments, a)
+		}
+	}
+	return adjustments, nil
+}
+
+// ReserveStock adds the reservation of the product or the variant if the warehouse has enough available pieces
+func (s *MemoryStore) ReserveStock(r Reservation) (Reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkStockItem(r.ProductID, r.VariantID); err != nil {
+		return Reservation{}, err
+	}
+	level, err := pickWarehouse(s.itemStock(r.ProductID, r.VariantID, time.Now()), r.WarehouseID, r.Quantity)
+	if err != nil {
+		return Reservation{}, err
+	}
+	r.WarehouseID = level.WarehouseID
+	s.saveRecords(r.ProductID)
+	s.reservations[r.ProductID] = append(s.reservations[r.ProductID], r)
+	return r, nil
+}
+
+// ReleaseReservation removes the reservation of the product
+func (s *MemoryStore) ReleaseReservation(productID string, reservationID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.takeReservation(productID, reservationID); !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CommitReservation removes the reservation of the product and records the sale of its pieces
+func (s *MemoryStore) CommitReservation(productID string, reservationID string, author string) (StockAdjustment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.reservations[productID] {
+		if r.ReservationID == reservationID && !r.active(time.Now()) {
+			//the pieces are not held anymore, the reservation is left for ReleaseExpiredReservations
+			return StockAdjustment{}, ErrNotFound
+		}
+	}
+	r, ok := s.takeReservation(productID, reservationID)
+	if !ok {
+		return StockAdjustment{}, ErrNotFound
+	}
+	a := newCommitAdjustment(r, author)
+	s.putStock(a)
+	return a, nil
+}
+
+// ReleaseExpiredReservations removes the reservations of all the products which have expired at the given time
+func (s *MemoryStore) ReleaseExpiredReservations(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	released := 0
+	for productID, reservations := range s.reservations {
+		s.saveRecords(productID)
+		active := reservations[:0]
+		for _, r := range reservations {
+			if r.active(now) {
+				active = append(active, r)
+			} else {
+				released++
+			}
+		}
+		if len(active) == 0 {
+			delete(s.reservations, productID)
+		} else {
+			s.reservations[productID] = active
+		}
+	}
+	return released, nil
+}
+
+// checkStockItem returns ErrNotFound if the product does not exist
+// and ErrVariantNotFound if the variant is given but the product does not have it, while the caller holds the lock
+func (s *MemoryStore) checkStockItem(productID string, variantID string) error {
+	if _, ok := s.products[productID]; !ok {
+		return ErrNotFound
+	}
+	if variantID != "" && s.variantOwners[variantID] != productID {
+		return ErrVariantNotFound
+	}
+	return nil
+}
+
+// variantIndex returns the position of the variant among the variants of the product, or -1,
+// while the caller holds the lock
+func (s *MemoryStore) variantIndex(productID string, variantID string) int {
+	for i, v := range s.variants[productID] {
+		if v.VariantID == variantID {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyVariants returns the copies of the variants of the product while the caller holds the lock
+func (s *MemoryStore) copyVariants(productID string) []Variant {
+	variants := make([]Variant, 0, len(s.variants[productID]))
+	for _, v := range s.variants[productID] {
+		variants = append(variants, copyVariant(v))
+	}
+	return variants
+}
+
+// stockLevels returns the stock levels of the product and its variants in every warehouse, counting the reservations
+// which are active at the given time, while the caller holds the lock
+func (s *MemoryStore) stockLevels(productID string, now time.Time) map[stockSlot]StockLevel {
+	reserved := make(map[stockSlot]int64)
+	for _, r := range s.reservations[productID] {
+		if r.active(now) {
+			reserved[stockSlot{variantID: r.VariantID, warehouseID: r.WarehouseID}] += r.Quantity
+		}
+	}
+	levels := make(map[stockSlot]StockLevel, len(s.stock[productID]))
+	for slot, onHand := range s.stock[productID] {
+		levels[slot] = newStockLevel(slot.warehouseID, onHand, reserved[slot])
+	}
+	return levels
+}
+
+// itemStock returns the stock levels of the product, or of the variant if variantID is not empty,
+// ordered by warehouse id while the caller holds the lock
+func (s *MemoryStore) itemStock(productID string, variantID string, now time.Time) []StockLevel {
+	levels := make([]StockLevel, 0)
+	for slot, level := range s.stockLevels(productID, now) {
+		if slot.variantID == variantID {
+			levels = append(levels, level)
+		}
+	}
+	sortStockLevels(levels)
+	return levels
+}
+
+// putStock applies the adjustment to the quantity of the product or the variant and records it
+// while the caller holds the lock
+func (s *MemoryStore) putStock(a StockAdjustment) {
+	s.saveRecords(a.ProductID)
+	slots, ok := s.stock[a.ProductID]
+	if !ok {
+		slots = make(map[stockSlot]int64)
+		s.stock[a.ProductID] = slots
+	}
+	slots[stockSlot{variantID: a.VariantID, warehouseID: a.WarehouseID}] += a.Delta
+	s.stockAdjustments[a.ProductID] = append(s.stockAdjustments[a.ProductID], a)
+}
+
+// takeReservation removes the reservation of the product and returns it while the caller holds the lock
+func (s *MemoryStore) takeReservation(productID string, reservationID string) (Reservation, bool) {
+	reservations := s.reservations[productID]
+	for i, r := range reservations {
+		if r.ReservationID == reservationID {
+			s.saveRecords(productID)
+			s.reservations[productID] = append(reservations[:i:i], reservations[i+1:]...)
+			return r, true
+		}
+	}
+	return Reservation{}, false
+}
+
+// removeFromCategory takes the product out of the index of its category while the caller holds the lock
+func (s *MemoryStore) removeFromCategory(p Product) {
+	s.removeListed(s.productsByCategory, p.CategoryID, p.ProductID)
+}
+
+// listOf returns the list of the key in the index, adding an empty one if there is none, while the caller holds the lock
+func (s *MemoryStore) listOf(index map[string]*idList, key string) *idList {
+	ids, ok := index[key]
+	if !ok {
+		ids = newIDList()
+		index[key] = ids
+		s.record(func() { delete(index, key) })
+	}
+	return ids
+}
+
+// removeListed takes the id out of the list of the key in the index and drops the list once it is empty,
+// while the caller holds the lock
+func (s *MemoryStore) removeListed(index map[string]*idList, key string, id string) {
+	ids, ok := index[key]
+	if !ok {
+		return
+	}
+	s.record(ids.remove(id))
+	if ids.len() == 0 {
+		delete(index, key)
+		s.record(func() { index[key] = ids })
+	}
+}
+
+// Transaction calls fn with a store working on the same data while the lock is held, so nobody sees the changes
+// before all of them are made. Every change made by fn is recorded in an undo journal which is replayed backwards
+// if fn fails or panics, so a transaction costs as much as its changes, not as much as the whole store.
+func (s *MemoryStore) Transaction(fn func(tx CatalogStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx := &MemoryStore{memoryData: s.memoryData, journal: &journal{saved: make(map[string]bool)}}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.journal.rollback()
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+
+	//the changes of a nested transaction are undone if the outer one fails
+	if s.journal != nil {
+		s.journal.undo = append(s.journal.undo, tx.journal.undo...)
+		for productID := range tx.journal.saved {
+			s.journal.saved[productID] = true
+		}
+	}
+	return nil
+}
+
+// rollback undoes the changes recorded in the journal, the last one first
+func (j *journal) rollback() {
+	for i := len(j.undo) - 1; i >= 0; i-- {
+		j.undo[i]()
+	}
+	j.undo = nil
+}
+
+// record adds the func undoing a change to the journal while the caller holds the lock,
+// outside of transactions the change is not recorded
+func (s *MemoryStore) record(undo func()) {
+	if s.journal != nil {
+		s.journal.undo = append(s.journal.undo, undo)
+	}
+}
+
+// saveCategory records how to put back the stored category with the id, or to remove it if there is none,
+// before it is changed while the caller holds the lock
+func (s *MemoryStore) saveCategory(id string) {
+	if s.journal == nil {
+		return
+	}
+	stored, ok := s.categories[id]
+	s.record(func() {
+		if ok {
+			s.categories[id] = stored
+		} else {
+			delete(s.categories, id)
+		}
+	})
+}
+
+// saveProduct records how to put back the stored product with the id and its text in the search index,
+// or to remove them if there is none, before it is changed while the caller holds the lock
+func (s *MemoryStore) saveProduct(id string) {
+	if s.journal == nil {
+		return
+	}
+	stored, ok := s.products[id]
+	s.record(func() {
+		if ok {
+			s.products[id] = stored
+			indexProduct(s.searchIndex, stored)
+		} else {
+			delete(s.products, id)
+			s.searchIndex.Remove(id)
+		}
+	})
+}
+
+// saveRecords records how to put back the price changes, the variants and the stock of the product
+// before they are changed for the first time in the transaction, while the caller holds the lock.
+// Some of them are changed in place, so they are copied.
+func (s *MemoryStore) saveRecords(productID string) {
+	if s.journal == nil || s.journal.saved[productID] {
+		return
+	}
+	s.journal.saved[productID] = true
+	priceChanges := append([]PriceChange(nil), s.priceChanges[productID]...)
+	variants := append([]Variant(nil), s.variants[productID]...)
+	stock := make(map[stockSlot]int64, len(s.stock[productID]))
+	for slot, onHand := range s.stock[productID] {
+		stock[slot] = onHand
+	}
+	adjustments := append([]StockAdjustment(nil), s.stockAdjustments[productID]...)
+	reservations := append([]Reservation(nil), s.reservations[productID]...)
+
+	s.record(func() {
+		for _, v := range s.variants[productID] {
+			delete(s.variantOwners, v.VariantID)
+			//the SKU may be back with the variant of another product which had it before
+			if s.skus[v.SKU] == v.VariantID {
+				delete(s.skus, v.SKU)
+			}
+		}
+		for _, v := range variants {
+			s.variantOwners[v.VariantID] = productID
+			s.skus[v.SKU] = v.VariantID
+		}
+		delete(s.priceChanges, productID)
+		delete(s.variants, productID)
+		delete(s.stock, productID)
+		delete(s.stockAdjustments, productID)
+		delete(s.reservations, productID)
+		if len(priceChanges) > 0 {
+			s.priceChanges[productID] = priceChanges
+		}
+		if len(variants) > 0 {
+			s.variants[productID] = variants
+		}
+		if len(stock) > 0 {
+			s.stock[productID] = stock
+		}
+		if len(adjustments) > 0 {
+			s.stockAdjustments[productID] = adjustments
+		}
+		if len(reservations) > 0 {
+			s.reservations[productID] = reservations
+		}
+	})
+}
+
+// ListCategories filters, sorts and pages the categories
+func (s *MemoryStore) ListCategories(q ListQuery) (CategoryPage, error) {
+	allCategories, err := s.Categories()
+	if err != nil {
+		return CategoryPage{}, err
+	}
+	return pageCategories(allCategories, q), nil
+}
+
+// ListProducts filters, sorts and pages the products, using the category index for the category filter
+func (s *MemoryStore) ListProducts(q ListQuery) (ProductPage, error) {
+	if q.CategoryID == "" {
+		allProducts, err := s.Products()
+		if err != nil {
+			return ProductPage{}, err
+		}
+		return pageProducts(allProducts, q), nil
+	}
+
+	s.mu.RLock()
+	categoryIDs := []string{q.CategoryID}
+	if q.IncludeDescendants {
+		categoryIDs = s.descendants(q.CategoryID)
+	}
+	ofCategories := make([]Product, 0)
+	for _, categoryID := range categoryIDs {
+		if ids, ok := s.productsByCategory[categoryID]; ok {
+			ids.each(func(id string) {
+				ofCategories = append(ofCategories, copyProduct(s.products[id]))
+			})
+		}
+	}
+	s.mu.RUnlock()
+	return pageProducts(ofCategories, q), nil
+}
+
+// SearchProducts looks for the products in the full-text index
+func (s *MemoryStore) SearchProducts(query string, limit int) ([]ProductMatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matches := s.searchIndex.Search(query, limit)
+	found := make([]ProductMatch, 0, len(matches))
+	for _, m := range matches {
+		found = append(found, ProductMatch{Product: copyProduct(s.products[m.ID]), Score: m.Score})
+	}
+	return found, nil
+}
@@ -0,0 +1,279 @@
+//package store contains tests and benchmarks for memory.go
+package store
+
+import (
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//TestMemoryStoresAreIsolated tests whether changing one MemoryStore does not affect another one
+func TestMemoryStoresAreIsolated(t *testing.T) {
+	first := NewMemoryStore()
+	second := NewMemoryStore()
+
+	assert.NoError(t, first.DeleteCategory("bq4fb3b7jhfi7v7uo39g", DeletePolicy{}))
+
+	firstCategories, _ := first.Categories()
+	secondCategories, _ := second.Categories()
+	assert.Len(t, firstCategories, 1, "Expected the category to be deleted from the first store")
+	assert.Len(t, secondCategories, 2, "Expected the second store to keep the seed categories")
+}
+
+//TestMemoryStoreReturnsCopies tests whether changing a returned slice does not change the stored products
+func TestMemoryStoreReturnsCopies(t *testing.T) {
+	catalog := NewMemoryStore()
+
+	allProducts, _ := catalog.Products()
+	allProducts[0].ProductName = "Changed"
+
+	stored, err := catalog.Product(allProducts[0].ProductID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Nike SuperRep Go", stored.ProductName, "Expected the stored product to stay the same")
+}
+
+//TestMemoryStoreNotFound tests whether lookups, updates and deletes of unknown ids return ErrNotFound
+func TestMemoryStoreNotFound(t *testing.T) {
+	catalog := NewMemoryStore()
+
+	_, err := catalog.Category("randomID")
+	assert.Equal(t, ErrNotFound, err)
+	_, err = catalog.Product("randomID")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, ErrNotFound, catalog.UpdateCategory(Category{CategoryID: "randomID"}))
+	assert.Equal(t, ErrNotFound, catalog.UpdateProduct(Product{ProductID: "randomID", CategoryID: "bq4fasj7jhfi127rimlg"}, "test"))
+	assert.Equal(t, ErrNotFound, catalog.DeleteCategory("randomID", DeletePolicy{}))
+	assert.Equal(t, ErrNotFound, catalog.DeleteProduct("randomID"))
+}
+
+//TestMemoryStoreProductCategory tests whether a product can not refer to a category which does not exist
+func TestMemoryStoreProductCategory(t *testing.T) {
+	catalog := NewMemoryStore()
+
+	err := catalog.CreateProduct(Product{ProductID: "newID", ProductName: "Name", CategoryID: "randomID"}, "test")
+	assert.Equal(t, ErrCategoryNotFound, err)
+
+	err = catalog.UpdateProduct(Product{ProductID: "bq4foj37jhfipc5nqri0", ProductName: "Name", CategoryID: "randomID"}, "test")
+	assert.Equal(t, ErrCategoryNotFound, err)
+
+	ofCategory, _ := catalog.ProductsOfCategory("bq4fasj7jhfi127rimlg")
+	assert.Len(t, ofCategory, 2, "Expected the products of the category to stay the same")
+}
+
+//TestMemoryStoreCategoryIndex tests whether the category index follows the products when they are moved and deleted
+func TestMemoryStoreCategoryIndex(t *testing.T) {
+	catalog := NewMemoryStore()
+	moved := Product{ProductID: "bq4foj37jhfipc5nqri0", ProductName: "Nike SuperRep Go", CategoryID: "bq4fb3b7jhfi7v7uo39g"}
+
+	assert.NoError(t, catalog.UpdateProduct(moved, "test"))
+	shopping, _ := catalog.ProductsOfCategory("bq4fasj7jhfi127rimlg")
+	specialty, _ := catalog.ProductsOfCategory("bq4fb3b7jhfi7v7uo39g")
+	assert.Len(t, shopping, 1, "Expected the moved product to leave its old category")
+	assert.Equal(t, []Product{moved}, specialty, "Expected the moved product in its new category")
+
+	assert.NoError(t, catalog.DeleteProduct(moved.ProductID))
+	specialty, _ = catalog.ProductsOfCategory("bq4fb3b7jhfi7v7uo39g")
+	assert.Empty(t, specialty, "Expected the deleted product to leave the category index")
+}
+
+//TestMemoryStoreKeepsOrder tests whether the products are listed in creation order after many deletions
+func TestMemoryStoreKeepsOrder(t *testing.T) {
+	catalog := newEmptyMemoryStore()
+	assert.NoError(t, catalog.CreateCategory(Category{CategoryID: "category"}))
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, catalog.CreateProduct(Product{ProductID: fmt.Sprint(i), CategoryID: "category"}, "test"))
+	}
+	//delete every product except the multiples of 10, which makes the id lists compact
+	for i := 0; i < 100; i++ {
+		if i%10 != 0 {
+			assert.NoError(t, catalog.DeleteProduct(fmt.Sprint(i)))
+		}
+	}
+
+	var expected []Product
+	for i := 0; i < 100; i += 10 {
+		expected = append(expected, Product{ProductID: fmt.Sprint(i), CategoryID: "category"})
+	}
+	allProducts, _ := catalog.Products()
+	ofCategory, _ := catalog.ProductsOfCategory("category")
+	assert.Equal(t, expected, allProducts)
+	assert.Equal(t, expected, ofCategory)
+}
+
+//TestMemoryStoreRollbackKeepsOrder tests whether a rolled back transaction which makes the id lists compact
+//leaves the products in creation order
+func TestMemoryStoreRollbackKeepsOrder(t *testing.T) {
+	catalog := newEmptyMemoryStore()
+	assert.NoError(t, catalog.CreateCategory(Category{CategoryID: "category"}))
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, catalog.CreateProduct(Product{ProductID: fmt.Sprint(i), CategoryID: "category"}, "test"))
+	}
+	//holes left by deletions before the transaction
+	for i := 0; i < 100; i += 7 {
+		assert.NoError(t, catalog.DeleteProduct(fmt.Sprint(i)))
+	}
+	before, _ := catalog.Products()
+
+	err := catalog.Transaction(func(tx CatalogStore) error {
+		for i := 0; i < 100; i += 2 {
+			tx.DeleteProduct(fmt.Sprint(i))
+		}
+		assert.Equal(t, ErrCategoryNotFound, tx.CreateProduct(Product{ProductID: "1", CategoryID: "other"}, "test"))
+		assert.NoError(t, tx.CreateProduct(Product{ProductID: "0", CategoryID: "category"}, "test"))
+		return errStop
+	})
+	assert.Equal(t, errStop, err)
+
+	allProducts, _ := catalog.Products()
+	ofCategory, _ := catalog.ProductsOfCategory("category")
+	assert.Equal(t, before, allProducts)
+	assert.Equal(t, before, ofCategory)
+}
+
+//benchmarkSizes are the numbers of products the benchmarks run with
+var benchmarkSizes = []int{1000, 100000}
+
+//benchmarkCatalog returns a MemoryStore and the same data in slices, the way it was stored before the index,
+//with 100 categories and n products spread evenly over them
+func benchmarkCatalog(b *testing.B, n int) (*MemoryStore, []Category, []Product) {
+	catalog := newEmptyMemoryStore()
+	var categories []Category
+	var products []Product
+	for i := 0; i < 100; i++ {
+		c := Category{CategoryID: fmt.Sprintf("category-%d", i)}
+		categories = append(categories, c)
+		if err := catalog.CreateCategory(c); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		p := Product{ProductID: fmt.Sprintf("product-%d", i), CategoryID: categories[i%100].CategoryID}
+		products = append(products, p)
+		if err := catalog.CreateProduct(p, "test"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return catalog, categories, products
+}
+
+//BenchmarkProductByID compares looking a product up with the linear scan GetProductById used to do and with the index
+func BenchmarkProductByID(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		catalog, _, products := benchmarkCatalog(b, n)
+		//the last product is the worst case for the scan
+		id := products[n-1].ProductID
+
+		b.Run(fmt.Sprintf("slice-scan/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, singleProduct := range products {
+					if singleProduct.ProductID == id {
+						break
+					}
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("index/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := catalog.Product(id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+//BenchmarkCategoryExists compares the category check of CreateProduct and UpdateProduct
+//done with the linear scan and with the index
+func BenchmarkCategoryExists(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		catalog, categories, _ := benchmarkCatalog(b, n)
+		id := categories[len(categories)-1].CategoryID
+
+		b.Run(fmt.Sprintf("slice-scan/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, singleCategory := range categories {
+					if singleCategory.CategoryID == id {
+						break
+					}
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("index/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := catalog.Category(id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+//BenchmarkProductsOfCategory compares collecting the products of one category by scanning all the products
+//and with the category index
+func BenchmarkProductsOfCategory(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		catalog, categories, products := benchmarkCatalog(b, n)
+		id := categories[0].CategoryID
+
+		b.Run(fmt.Sprintf("slice-scan/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				productsOfCategory := make([]Product, 0)
+				for _, singleProduct := range products {
+					if singleProduct.CategoryID == id {
+						productsOfCategory = append(productsOfCategory, singleProduct)
+					}
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("index/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := catalog.ProductsOfCategory(id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+//BenchmarkTransaction measures a batch of a few changes which is committed and one which is rolled back
+//in a catalog of a realistic size, the cost should not grow with the number of products
+func BenchmarkTransaction(b *testing.B) {
+	for _, n := range []int{1000, 200000} {
+		catalog, categories, products := benchmarkCatalog(b, n)
+		change := func(tx CatalogStore, i int) {
+			p := products[i%n]
+			p.ProductName = fmt.Sprintf("Renamed %d", i)
+			p.Price = money.New(int64(i), "EUR")
+			if err := tx.UpdateProduct(p, "test"); err != nil {
+				b.Fatal(err)
+			}
+			created := Product{ProductID: fmt.Sprintf("new-%d", i), ProductName: "New", CategoryID: categories[i%100].CategoryID}
+			if err := tx.CreateProduct(created, "test"); err != nil {
+				b.Fatal(err)
+			}
+			if err := tx.DeleteProduct(created.ProductID); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		b.Run(fmt.Sprintf("commit/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := catalog.Transaction(func(tx CatalogStore) error {
+					change(tx, i)
+					return nil
+				}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("rollback/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				catalog.Transaction(func(tx CatalogStore) error {
+					change(tx, i)
+					return errStop
+				})
+			}
+		})
+	}
+}
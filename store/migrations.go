@@ -0,0 +1,269 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migration is one versioned step of the SQLite schema
+type migration struct {
+	version     int
+	description string
+	statements  []string
+	// fill runs after the statements, for the values SQLite can not compute itself
+	fill func(tx *sql.Tx) error
+}
+
+// migrations are applied in order on startup, every version exactly once.
+// Never change an already released migration, append a new one instead.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create categories and products tables",
+		statements: []string{
+			`CREATE TABLE categories (
+				CategoryID          TEXT PRIMARY KEY,
+				CategoryName        TEXT NOT NULL,
+				CategoryDescription TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE TABLE products (
+				ProductID          TEXT PRIMARY KEY,
+				ProductName        TEXT NOT NULL,
+				ProductDescription TEXT NOT NULL DEFAULT '',
+				Price              INTEGER NOT NULL DEFAULT 0,
+				CategoryID         TEXT NOT NULL REFERENCES categories (CategoryID)
+			)`,
+			`CREATE INDEX products_category ON products (CategoryID)`,
+		},
+	},
+	{
+		version:     2,
+		description: "insert seed categories and products",
+		statements: []string{
+			`INSERT INTO categories (CategoryID, CategoryName, CategoryDescription) VALUES
+				('bq4fasj7jhfi127rimlg', 'Shopping Products', 'Products consumers purchase and consume on a less frequent schedule compared to convenience products.'),
+				('bq4fb3b7jhfi7v7uo39g', 'Specialty Products', 'Products that are more expensive relative to convenience and shopping products.')`,
+			`INSERT INTO products (ProductID, ProductName, ProductDescription, Price, CategoryID) VALUES
+				('bq4foj37jhfipc5nqri0', 'Nike SuperRep Go', 'Women''s Training Shoe', 100, 'bq4fasj7jhfi127rimlg'),
+				('bq5457j7jhfi2s58o030', 'Nike Icon Clash', 'Women''s Seamless Light-Support Sports Bra', 50, 'bq4fasj7jhfi127rimlg')`,
+		},
+	},
+	{
+		version:     3,
+		description: "add parent categories",
+		statements: []string{
+			//NULL for the top-level categories
+			`ALTER TABLE categories ADD COLUMN ParentID TEXT REFERENCES categories (CategoryID)`,
+			`CREATE INDEX categories_parent ON categories (ParentID)`,
+		},
+	},
+	{
+		version:     4,
+		description: "add product currency, prices in minor units",
+		statements: []string{
+			//the prices used to be whole euros
+			`ALTER TABLE products ADD COLUMN Currency TEXT NOT NULL DEFAULT 'EUR'`,
+			`UPDATE products SET Price = Price * 100`,
+		},
+	},
+	{
+		version:     5,
+		description: "add price history",
+		statements: []string{
+			//the times are stored in the fixed-width UTC layout of formatTime
+			`CREATE TABLE price_changes (
+				ChangeID    TEXT PRIMARY KEY,
+				ProductID   TEXT NOT NULL REFERENCES products (ProductID) ON DELETE CASCADE,
+				Price       INTEGER NOT NULL,
+				Currency    TEXT NOT NULL,
+				EffectiveAt TEXT NOT NULL,
+				Author      TEXT NOT NULL DEFAULT '',
+				RecordedAt  TEXT NOT NULL,
+				Status      TEXT NOT NULL
+			)`,
+			`CREATE INDEX price_changes_product ON price_changes (ProductID, EffectiveAt)`,
+			`CREATE INDEX price_changes_due ON price_changes (Status, EffectiveAt)`,
+			//the timelines start with the prices the products have now
+			`INSERT INTO price_changes (ChangeID, ProductID, Price, Currency, EffectiveAt, Author, RecordedAt, Status)
+				SELECT 'initial-' || ProductID, ProductID, Price, Currency,
+					strftime('%Y-%m-%dT%H:%M:%f000000Z', 'now'), 'system', strftime('%Y-%m-%dT%H:%M:%f000000Z', 'now'), 'applied'
+				FROM products`,
+		},
+	},
+	{
+		version:     6,
+		description: "add stock, stock adjustments and reservations",
+		statements: []string{
+			`CREATE TABLE stock (
+				ProductID   TEXT NOT NULL REFERENCES products (ProductID) ON DELETE CASCADE,
+				WarehouseID TEXT NOT NULL,
+				OnHand      INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (ProductID, WarehouseID)
+			)`,
+			//the times are stored in the fixed-width UTC layout of formatTime
+			`CREATE TABLE stock_adjustments (
+				AdjustmentID TEXT PRIMARY KEY,
+				ProductID    TEXT NOT NULL REFERENCES products (ProductID) ON DELETE CASCADE,
+				WarehouseID  TEXT NOT NULL,
+				Delta        INTEGER NOT NULL,
+				Reason       TEXT NOT NULL,
+				Note         TEXT NOT NULL DEFAULT '',
+				Author       TEXT NOT NULL DEFAULT '',
+				At           TEXT NOT NULL
+			)`,
+			`CREATE INDEX stock_adjustments_product ON stock_adjustments (ProductID)`,
+			`CREATE TABLE reservations (
+				ReservationID TEXT PRIMARY KEY,
+				ProductID     TEXT NOT NULL REFERENCES products (ProductID) ON DELETE CASCADE,
+				WarehouseID   TEXT NOT NULL,
+				Quantity      INTEGER NOT NULL,
+				CreatedAt     TEXT NOT NULL,
+				ExpiresAt     TEXT NOT NULL
+			)`,
+			`CREATE INDEX reservations_product ON reservations (ProductID, WarehouseID)`,
+			`CREATE INDEX reservations_expiry ON reservations (ExpiresAt)`,
+		},
+	},
+	{
+		version:     7,
+		description: "add product variants and their stock",
+		statements: []string{
+			//Attributes is a JSON object, Price and Currency are NULL when the variant costs as much as the product
+			`CREATE TABLE variants (
+				VariantID  TEXT PRIMARY KEY,
+				ProductID  TEXT NOT NULL REFERENCES products (ProductID) ON DELETE CASCADE,
+				SKU        TEXT NOT NULL UNIQUE,
+				Attributes TEXT NOT NULL DEFAULT '{}',
+				Price      INTEGER,
+				Currency   TEXT
+			)`,
+			`CREATE INDEX variants_product ON variants (ProductID)`,
+			//the stock of the product itself keeps the empty VariantID, the primary key can only be changed
+			//by copying the table
+			`CREATE TABLE variant_stock (
+				ProductID   TEXT NOT NULL REFERENCES products (ProductID) ON DELETE CASCADE,
+				VariantID   TEXT NOT NULL DEFAULT '',
+				WarehouseID TEXT NOT NULL,
+				OnHand      INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (ProductID, VariantID, WarehouseID)
+			)`,
+			`INSERT INTO variant_stock (ProductID, WarehouseID, OnHand) SELECT ProductID, WarehouseID, OnHand FROM stock`,
+			`DROP TABLE stock`,
+			`ALTER TABLE variant_stock RENAME TO stock`,
+			`ALTER TABLE stock_adjustments ADD COLUMN VariantID TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE reservations ADD COLUMN VariantID TEXT NOT NULL DEFAULT ''`,
+			`DROP INDEX reservations_product`,
+			`CREATE INDEX reservations_product ON reservations (ProductID, VariantID, WarehouseID)`,
+		},
+	},
+	{
+		version:     8,
+		description: "add category attribute schemas and product attributes",
+		statements: []string{
+			//the schemas are JSON arrays and the attributes of the products JSON objects
+			`ALTER TABLE categories ADD COLUMN Attributes TEXT NOT NULL DEFAULT '[]'`,
+			`ALTER TABLE products ADD COLUMN Attributes TEXT NOT NULL DEFAULT '{}'`,
+		},
+	},
+	{
+		version:     9,
+		description: "link the price changes to the changes they revert",
+		statements: []string{
+			`ALTER TABLE price_changes ADD COLUMN Reverts TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     10,
+		description: "store the names folded by foldName for name_contains",
+		statements: []string{
+			//lower() of SQLite folds only the ASCII letters, the names are folded in Go like in MemoryStore
+			`ALTER TABLE categories ADD COLUMN FoldedName TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE products ADD COLUMN FoldedName TEXT NOT NULL DEFAULT ''`,
+		},
+		fill: func(tx *sql.Tx) error {
+			if err := foldNames(tx, "categories", "CategoryID", "CategoryName"); err != nil {
+				return err
+			}
+			return foldNames(tx, "products", "ProductID", "ProductName")
+		},
+	},
+}
+
+// foldNames sets the FoldedName column of every row of the table to the folded name
+func foldNames(tx *sql.Tx, table string, idColumn string, nameColumn string) error {
+	rows, err := tx.Query(`SELECT ` + idColumn + `, ` + nameColumn + ` FROM ` + table)
+	if err != nil {
+		return err
+	}
+	//the rows are read before they are updated, the transaction has a single connection
+	names := make(map[string]string)
+	for rows.Next() {
+		var id, name string
+		if err = rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		names[id] = name
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for id, name := range names {
+		if _, err = tx.Exec(`UPDATE `+table+` SET FoldedName = ? WHERE `+idColumn+` = ?`, foldName(name), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrate creates the schema_migrations table if needed and applies every migration
+// which has not been applied to the database yet
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %v", m.version, m.description, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs all the statements and the fill of the migration in one transaction
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range m.statements {
+		if _, err = tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if m.fill != nil {
+		if err = m.fill(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err = tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, m.version); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
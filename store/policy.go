@@ -0,0 +1,60 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// the ways DeleteCategory treats the products of the deleted category
+const (
+	// DeleteRestrict refuses to delete a category which still has products
+	DeleteRestrict = "restrict"
+	// DeleteCascade deletes the products together with the category
+	DeleteCascade = "cascade"
+	// DeleteReassign moves the products to the target category before deleting the category
+	DeleteReassign = "reassign"
+)
+
+// ErrInvalidPolicy is returned for an unknown delete mode, a reassign without a target
+// or a reassign to the deleted category itself
+var ErrInvalidPolicy = errors.New("invalid delete policy")
+
+// DeletePolicy says what DeleteCategory does with the products of the category.
+// The zero value is DeleteRestrict.
+type DeletePolicy struct {
+	// Mode is DeleteRestrict, DeleteCascade or DeleteReassign, empty means DeleteRestrict
+	Mode string
+	// TargetID is the category the products are moved to by DeleteReassign
+	TargetID string
+}
+
+// check returns ErrInvalidPolicy if the policy can not be applied to the deletion of the category with the given id
+func (p DeletePolicy) check(id string) error {
+	switch p.Mode {
+	case "", DeleteRestrict, DeleteCascade:
+		return nil
+	case DeleteReassign:
+		if p.TargetID == "" || p.TargetID == id {
+			return ErrInvalidPolicy
+		}
+		return nil
+	}
+	return ErrInvalidPolicy
+}
+
+// CategoryInUseError is returned by DeleteCategory when the category can not be deleted
+// because of its subcategories, or its products with DeleteRestrict. errors.Is matches it with ErrCategoryInUse.
+type CategoryInUseError struct {
+	Products      int
+	Subcategories int
+}
+
+// Error describes what still belongs to the category
+func (e *CategoryInUseError) Error() string {
+	return fmt.Sprintf("%v: %d products and %d subcategories", ErrCategoryInUse, e.Products, e.Subcategories)
+}
+
+// Is makes errors.Is(err, ErrCategoryInUse) true
+func (e *CategoryInUseError) Is(target error) bool {
+	return target == ErrCategoryInUse
+}
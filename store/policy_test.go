@@ -0,0 +1,73 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//policyStores returns a new MemoryStore and SQLiteStore, where the seed category "bq4fasj7jhfi127rimlg" has two products
+func policyStores(t *testing.T) map[string]CatalogStore {
+	sqlite, _ := openTestSQLite(t)
+	return map[string]CatalogStore{"memory": NewMemoryStore(), "sqlite": sqlite}
+}
+
+//TestDeleteCategoryRestrict tests whether a category with products is kept and the products are counted
+func TestDeleteCategoryRestrict(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		for _, policy := range []DeletePolicy{{}, {Mode: DeleteRestrict}} {
+			err := catalog.DeleteCategory("bq4fasj7jhfi127rimlg", policy)
+			assert.Equal(t, &CategoryInUseError{Products: 2}, err, "%s: expected the products to be counted", name)
+			assert.ErrorIs(t, err, ErrCategoryInUse)
+		}
+		_, err := catalog.Category("bq4fasj7jhfi127rimlg")
+		assert.NoError(t, err, "%s: expected the category to stay", name)
+		assert.NoError(t, catalog.DeleteCategory("bq4fb3b7jhfi7v7uo39g", DeletePolicy{Mode: DeleteRestrict}), "%s: expected an empty category to be deleted", name)
+	}
+}
+
+//TestDeleteCategoryCascade tests whether the products are deleted together with the category
+func TestDeleteCategoryCascade(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		assert.NoError(t, catalog.DeleteCategory("bq4fasj7jhfi127rimlg", DeletePolicy{Mode: DeleteCascade}))
+
+		_, err := catalog.Category("bq4fasj7jhfi127rimlg")
+		assert.Equal(t, ErrNotFound, err, "%s: expected the category to be deleted", name)
+		allProducts, _ := catalog.Products()
+		assert.Empty(t, allProducts, "%s: expected the products to be deleted", name)
+		found, _ := catalog.SearchProducts("nike", 0)
+		assert.Empty(t, found, "%s: expected the products to leave the search index", name)
+	}
+}
+
+//TestDeleteCategoryReassign tests whether the products are moved to the target category
+//and nothing changes if the target does not exist
+func TestDeleteCategoryReassign(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		err := catalog.DeleteCategory("bq4fasj7jhfi127rimlg", DeletePolicy{Mode: DeleteReassign, TargetID: "randomID"})
+		assert.Equal(t, ErrCategoryNotFound, err, "%s: expected the unknown target to be reported", name)
+		ofCategory, _ := catalog.ProductsOfCategory("bq4fasj7jhfi127rimlg")
+		assert.Len(t, ofCategory, 2, "%s: expected the products to stay after the failed delete", name)
+
+		assert.NoError(t, catalog.DeleteCategory("bq4fasj7jhfi127rimlg", DeletePolicy{Mode: DeleteReassign, TargetID: "bq4fb3b7jhfi7v7uo39g"}))
+		_, err = catalog.Category("bq4fasj7jhfi127rimlg")
+		assert.Equal(t, ErrNotFound, err, "%s: expected the category to be deleted", name)
+		moved, _ := catalog.ProductsOfCategory("bq4fb3b7jhfi7v7uo39g")
+		assert.Equal(t, []string{"bq4foj37jhfipc5nqri0", "bq5457j7jhfi2s58o030"}, []string{moved[0].ProductID, moved[1].ProductID},
+			"%s: expected the products in the target category", name)
+	}
+}
+
+//TestDeleteCategoryInvalidPolicy tests whether the policies which can not be applied are rejected before anything changes
+func TestDeleteCategoryInvalidPolicy(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		for _, policy := range []DeletePolicy{
+			{Mode: "orphan"},
+			{Mode: DeleteReassign},
+			{Mode: DeleteReassign, TargetID: "bq4fasj7jhfi127rimlg"},
+		} {
+			assert.Equal(t, ErrInvalidPolicy, catalog.DeleteCategory("bq4fasj7jhfi127rimlg", policy), "%s: expected %+v to be rejected", name, policy)
+		}
+		allProducts, _ := catalog.Products()
+		assert.Len(t, allProducts, 2, "%s: expected the products to stay", name)
+	}
+}
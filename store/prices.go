@@ -0,0 +1,99 @@
+package store
+
+import (
+	"errors"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/rs/xid"
+	"sort"
+	"time"
+)
+
+// the states of a price change in the timeline of a product
+const (
+	// PriceApplied is a price the product has or has had
+	PriceApplied = "applied"
+	// PriceScheduled is a price the product gets when the change is due
+	PriceScheduled = "scheduled"
+)
+
+// SystemAuthor is the author of the seed prices and of the prices recorded by the migrations
+const SystemAuthor = "system"
+
+// ErrPriceChangeApplied is returned when an already applied price change is canceled
+var ErrPriceChangeApplied = errors.New("price change already applied")
+
+// PriceChange is an entry in the price timeline of a product
+type PriceChange struct {
+	ChangeID  string      `json:"ChangeID"`
+	ProductID string      `json:"ProductID"`
+	Price     money.Money `json:"Price"`
+	// EffectiveAt is when the product got or gets the price
+	EffectiveAt time.Time `json:"EffectiveAt"`
+	// Author is who changed or scheduled the price
+	Author string `json:"Author"`
+	// RecordedAt is when the change was made or scheduled
+	RecordedAt time.Time `json:"RecordedAt"`
+	// Status is PriceApplied or PriceScheduled
+	Status string `json:"Status"`
+	// Reverts is the ChangeID of the scheduled change whose price this change takes back, e.g. the end of a sale.
+	// Its Price is only the expected one until it is applied, see revertPrice.
+	Reverts string `json:"Reverts,omitempty"`
+}
+
+// newPriceChange returns the applied change of the product to its price, made now by the author
+func newPriceChange(p Product, author string) PriceChange {
+	now := time.Now().UTC()
+	return PriceChange{
+		ChangeID:    xid.New().String(),
+		ProductID:   p.ProductID,
+		Price:       p.Price,
+		EffectiveAt: now,
+		Author:      author,
+		RecordedAt:  now,
+		Status:      PriceApplied,
+	}
+}
+
+// revertPrice returns the price the due change reverting another one gives the product, resolved when it is applied
+// with the timeline of the product ordered by sortTimeline: the price the product had before the reverted change
+// took effect. If the price has been changed since the reverted change, or the reverted change has not been applied,
+// there is nothing to take back and the current price is kept.
+func revertPrice(timeline []PriceChange, revert PriceChange, current money.Money) money.Money {
+	reverted := -1
+	for i, c := range timeline {
+		if c.ChangeID == revert.Reverts {
+			reverted = i
+		}
+	}
+	if reverted < 0 || timeline[reverted].Status != PriceApplied {
+		return current
+	}
+	for _, c := range timeline[reverted+1:] {
+		if c.Status == PriceApplied {
+			return current
+		}
+	}
+	for i := reverted - 1; i >= 0; i-- {
+		if timeline[i].Status == PriceApplied {
+			return timeline[i].Price
+		}
+	}
+	return current
+}
+
+// cancels reports whether canceling the change with the id also cancels the scheduled change c:
+// the change itself and the other half of a change and its revert are canceled together
+func cancels(changeID string, canceled PriceChange, c PriceChange) bool {
+	return c.Status == PriceScheduled &&
+		(c.ChangeID == changeID || c.Reverts == changeID || (canceled.Reverts != "" && c.ChangeID == canceled.Reverts))
+}
+
+// sortTimeline orders the price changes by the time they take effect, the changes recorded earlier first
+func sortTimeline(changes []PriceChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].EffectiveAt.Equal(changes[j].EffectiveAt) {
+			return changes[i].EffectiveAt.Before(changes[j].EffectiveAt)
+		}
+		return changes[i].RecordedAt.Before(changes[j].RecordedAt)
+	})
+}
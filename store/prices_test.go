@@ -0,0 +1,143 @@
+package store
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+//timelinePrices returns the amounts, authors and states of the price timeline of the product
+func timelinePrices(t *testing.T, catalog CatalogStore, productID string) []string {
+	timeline, err := catalog.PriceHistory(productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prices := make([]string, 0, len(timeline))
+	for _, c := range timeline {
+		prices = append(prices, c.Price.String()+" "+c.Author+" "+c.Status)
+	}
+	return prices
+}
+
+//TestPriceHistory tests whether the created and changed prices are recorded with their author
+func TestPriceHistory(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		p := Product{ProductID: "newID", ProductName: "Cap", Price: money.New(1000, "EUR"), CategoryID: "bq4fasj7jhfi127rimlg"}
+		assert.NoError(t, catalog.CreateProduct(p, "alice"))
+		p.ProductName = "Blue Cap"
+		assert.NoError(t, catalog.UpdateProduct(p, "bob"))
+		p.Price = money.New(1200, "EUR")
+		assert.NoError(t, catalog.UpdateProduct(p, "carol"))
+
+		assert.Equal(t, []string{"10.00 EUR alice applied", "12.00 EUR carol applied"}, timelinePrices(t, catalog, "newID"),
+			"%s: expected only the price changes", name)
+		assert.Equal(t, []string{"100.00 EUR system applied"}, timelinePrices(t, catalog, "bq4foj37jhfipc5nqri0"),
+			"%s: expected the seed price", name)
+		_, err := catalog.PriceHistory("randomID")
+		assert.Equal(t, ErrNotFound, err, "%s: expected the unknown product to be reported", name)
+
+		//the timeline is deleted with the product
+		assert.NoError(t, catalog.DeleteProduct("newID"))
+		assert.NoError(t, catalog.CreateProduct(Product{ProductID: "newID", ProductName: "Cap", Price: money.New(900, "EUR"), CategoryID: "bq4fasj7jhfi127rimlg"}, "dave"))
+		assert.Equal(t, []string{"9.00 EUR dave applied"}, timelinePrices(t, catalog, "newID"), "%s: expected a new timeline", name)
+	}
+}
+
+//TestScheduledPriceChanges tests whether the scheduled prices are applied in order when they are due
+//and only the scheduled ones can be canceled
+func TestScheduledPriceChanges(t *testing.T) {
+	start := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	for name, catalog := range policyStores(t) {
+		changes := []PriceChange{
+			{ChangeID: "end", Price: money.New(10000, "EUR"), EffectiveAt: start.Add(2 * time.Hour), Author: "sales", RecordedAt: start},
+			{ChangeID: "sale", Price: money.New(7500, "EUR"), EffectiveAt: start, Author: "sales", RecordedAt: start},
+			{ChangeID: "canceled", Price: money.New(1, "EUR"), EffectiveAt: start.Add(time.Hour), Author: "sales", RecordedAt: start},
+		}
+		assert.Equal(t, ErrNotFound, catalog.SchedulePriceChanges("randomID", changes), "%s: expected the unknown product to be reported", name)
+		assert.NoError(t, catalog.SchedulePriceChanges("bq4foj37jhfipc5nqri0", changes))
+		assert.NoError(t, catalog.CancelPriceChange("bq4foj37jhfipc5nqri0", "canceled"))
+		assert.Equal(t, ErrNotFound, catalog.CancelPriceChange("bq4foj37jhfipc5nqri0", "canceled"), "%s: expected the change to be gone", name)
+		assert.Equal(t, ErrNotFound, catalog.CancelPriceChange("bq5457j7jhfi2s58o030", "sale"), "%s: expected the change of another product to be kept", name)
+		assert.Equal(t, []string{"100.00 EUR system applied", "75.00 EUR sales scheduled", "100.00 EUR sales scheduled"},
+			timelinePrices(t, catalog, "bq4foj37jhfipc5nqri0"), "%s: expected the timeline ordered by the time the prices take effect", name)
+
+		//nothing is due yet
+		applied, err := catalog.ApplyDuePriceChanges(time.Now())
+		assert.NoError(t, err)
+		assert.Empty(t, applied, "%s: expected no due changes", name)
+
+		//the sale has started
+		applied, err = catalog.ApplyDuePriceChanges(start.Add(time.Minute))
+		assert.NoError(t, err)
+		if assert.Len(t, applied, 1, "%s: expected the sale to be applied", name) {
+			assert.Equal(t, "sale", applied[0].ChangeID)
+			assert.Equal(t, PriceApplied, applied[0].Status)
+			assert.Equal(t, start, applied[0].EffectiveAt.UTC())
+		}
+		p, _ := catalog.Product("bq4foj37jhfipc5nqri0")
+		assert.Equal(t, money.New(7500, "EUR"), p.Price, "%s: expected the sale price", name)
+		assert.Equal(t, ErrPriceChangeApplied, catalog.CancelPriceChange("bq4foj37jhfipc5nqri0", "sale"))
+
+		//the sale has ended long ago
+		applied, err = catalog.ApplyDuePriceChanges(start.Add(24 * time.Hour))
+		assert.NoError(t, err)
+		assert.Len(t, applied, 1, "%s: expected the end of the sale to be applied", name)
+		p, _ = catalog.Product("bq4foj37jhfipc5nqri0")
+		assert.Equal(t, money.New(10000, "EUR"), p.Price, "%s: expected the price before the sale", name)
+		assert.Equal(t, []string{"100.00 EUR system applied", "75.00 EUR sales applied", "100.00 EUR sales applied"},
+			timelinePrices(t, catalog, "bq4foj37jhfipc5nqri0"), "%s: expected all the changes to be applied", name)
+	}
+}
+
+//TestRevertedPriceChanges tests whether the end of a sale restores the price the product had when the sale started,
+//keeps the prices changed during the sale and is canceled together with the sale
+func TestRevertedPriceChanges(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		p, _ := catalog.Product("bq4foj37jhfipc5nqri0")
+
+		//the price is changed after the sale was scheduled but before it starts
+		start := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		assert.NoError(t, catalog.SchedulePriceChanges(p.ProductID, []PriceChange{
+			{ChangeID: "sale", Price: money.New(7500, "EUR"), EffectiveAt: start, Author: "sales", RecordedAt: start},
+			{ChangeID: "end", Price: money.New(10000, "EUR"), EffectiveAt: start.Add(time.Hour), Author: "sales", RecordedAt: start, Reverts: "sale"},
+		}))
+		p.Price = money.New(11000, "EUR")
+		assert.NoError(t, catalog.UpdateProduct(p, "bob"))
+		_, err := catalog.ApplyDuePriceChanges(start.Add(2 * time.Hour))
+		assert.NoError(t, err)
+		p, _ = catalog.Product(p.ProductID)
+		assert.Equal(t, money.New(11000, "EUR"), p.Price, "%s: expected the price the sale started from", name)
+		assert.Equal(t, []string{"100.00 EUR system applied", "110.00 EUR bob applied", "75.00 EUR sales applied", "110.00 EUR sales applied"},
+			timelinePrices(t, catalog, p.ProductID), "%s: expected the restored price to be recorded", name)
+
+		//the price is changed during the sale
+		started := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		assert.NoError(t, catalog.SchedulePriceChanges(p.ProductID, []PriceChange{
+			{ChangeID: "sale2", Price: money.New(8000, "EUR"), EffectiveAt: started, Author: "sales", RecordedAt: started},
+			{ChangeID: "end2", Price: money.New(11000, "EUR"), EffectiveAt: started.Add(2 * time.Hour), Author: "sales", RecordedAt: started, Reverts: "sale2"},
+		}))
+		_, err = catalog.ApplyDuePriceChanges(time.Now())
+		assert.NoError(t, err)
+		p, _ = catalog.Product(p.ProductID)
+		p.Price = money.New(9000, "EUR")
+		assert.NoError(t, catalog.UpdateProduct(p, "carol"))
+		_, err = catalog.ApplyDuePriceChanges(started.Add(3 * time.Hour))
+		assert.NoError(t, err)
+		p, _ = catalog.Product(p.ProductID)
+		assert.Equal(t, money.New(9000, "EUR"), p.Price, "%s: expected the price changed during the sale to be kept", name)
+
+		//canceling either half cancels both
+		for _, canceled := range []string{"sale3", "end3"} {
+			assert.NoError(t, catalog.SchedulePriceChanges(p.ProductID, []PriceChange{
+				{ChangeID: "sale3", Price: money.New(5000, "EUR"), EffectiveAt: start.Add(48 * time.Hour), Author: "sales", RecordedAt: start},
+				{ChangeID: "end3", Price: money.New(9000, "EUR"), EffectiveAt: start.Add(49 * time.Hour), Author: "sales", RecordedAt: start, Reverts: "sale3"},
+			}))
+			assert.NoError(t, catalog.CancelPriceChange(p.ProductID, canceled))
+			timeline, _ := catalog.PriceHistory(p.ProductID)
+			for _, c := range timeline {
+				assert.Equal(t, PriceApplied, c.Status, "%s: expected %s to be canceled with %s", name, c.ChangeID, canceled)
+			}
+		}
+	}
+}
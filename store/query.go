@@ -0,0 +1,238 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"sort"
+	"strings"
+)
+
+// the orders lists can be sorted in
+const (
+	// SortCreated orders by creation time. The ids are xids, which sort in the order they were generated.
+	SortCreated = "created"
+	SortName    = "name"
+	SortPrice   = "price"
+)
+
+// ErrInvalidCursor is returned for a cursor which is malformed or was issued for another sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListQuery filters, orders and pages a list of categories or products
+type ListQuery struct {
+	// Sort is one of SortCreated, SortName and SortPrice, Desc reverses the order
+	Sort string
+	Desc bool
+	// Limit is the maximum number of items on the page, 0 means no limit
+	Limit int
+	// After is the cursor of the last item of the previous page, nil for the first page
+	After *Cursor
+
+	// CategoryID keeps only the products of the category,
+	// and of all its subcategories at any depth if IncludeDescendants is set
+	CategoryID         string
+	IncludeDescendants bool
+	// NameContains keeps only the items which have it in their name, ignoring case
+	NameContains string
+	// Currency keeps only the products priced in the currency
+	Currency string
+	// PriceMin and PriceMax keep only the products with the price amount in the range, both ends included.
+	// The amounts are in minor units, so they are meant to be used together with Currency.
+	PriceMin *int64
+	PriceMax *int64
+	// Attributes keeps only the products which have all the attributes with the values, compared as AttributeText
+	Attributes map[string]string
+}
+
+// Cursor is the position after the last item of a page: its sort key and id.
+// Paging with the key instead of an offset stays stable when items are created or deleted between the pages.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Name  string `json:"n,omitempty"`
+	Price int64  `json:"p,omitempty"`
+	ID    string `json:"id"`
+}
+
+// String encodes the cursor into an opaque URL-safe token
+func (c Cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes the token made by Cursor.String and checks that it belongs to the query order
+func ParseCursor(token string, q ListQuery) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err = json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != q.Sort || c.Desc != q.Desc {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// CategoryPage is one page of a category list
+type CategoryPage struct {
+	Items []Category
+	// Total is the number of categories matching the filters on all the pages
+	Total int
+	// Next is the cursor of the next page, nil on the last page
+	Next *Cursor
+}
+
+// ProductPage is one page of a product list
+type ProductPage struct {
+	Items []Product
+	// Total is the number of products matching the filters on all the pages
+	Total int
+	// Next is the cursor of the next page, nil on the last page
+	Next *Cursor
+}
+
+// categoryCursor returns the cursor pointing after the category
+func categoryCursor(c Category, q ListQuery) *Cursor {
+	cursor := &Cursor{Sort: q.Sort, Desc: q.Desc, ID: c.CategoryID}
+	if q.Sort == SortName {
+		cursor.Name = c.CategoryName
+	}
+	return cursor
+}
+
+// productCursor returns the cursor pointing after the product
+func productCursor(p Product, q ListQuery) *Cursor {
+	cursor := &Cursor{Sort: q.Sort, Desc: q.Desc, ID: p.ProductID}
+	switch q.Sort {
+	case SortName:
+		cursor.Name = p.ProductName
+	case SortPrice:
+		cursor.Price = p.Price.Amount
+	}
+	return cursor
+}
+
+// compareKeys compares two items by the sort key of the query and then by id,
+// it returns a negative number if a goes first
+func compareKeys(a Cursor, b Cursor, q ListQuery) int {
+	result := 0
+	switch q.Sort {
+	case SortName:
+		result = strings.Compare(a.Name, b.Name)
+	case SortPrice:
+		if a.Price < b.Price {
+			result = -1
+		} else if a.Price > b.Price {
+			result = 1
+		}
+	}
+	if result == 0 {
+		result = strings.Compare(a.ID, b.ID)
+	}
+	if q.Desc {
+		return -result
+	}
+	return result
+}
+
+// matchesName reports whether the name passes the NameContains filter
+func (q ListQuery) matchesName(name string) bool {
+	return q.NameContains == "" || strings.Contains(foldName(name), foldName(q.NameContains))
+}
+
+// foldName returns the name in lower case, as the NameContains filter compares it.
+// SQLiteStore stores the folded names, so both stores ignore the case of all the letters, not only of the ASCII ones.
+func foldName(name string) string {
+	return strings.ToLower(name)
+}
+
+// MatchesProduct reports whether the product passes the NameContains, price and Attributes filters,
+// the category of the product is not checked
+func (q ListQuery) MatchesProduct(p Product) bool {
+	return q.matchesName(p.ProductName) && q.matchesPrice(p.Price) && q.matchesAttributes(p.Attributes)
+}
+
+// matchesAttributes reports whether the attributes pass the Attributes filter
+func (q ListQuery) matchesAttributes(attributes map[string]interface{}) bool {
+	for name, text := range q.Attributes {
+		value, ok := attributes[name]
+		if !ok || AttributeText(value) != text {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesPrice reports whether the price passes the Currency, PriceMin and PriceMax filters
+func (q ListQuery) matchesPrice(price money.Money) bool {
+	return (q.Currency == "" || price.Currency == q.Currency) &&
+		(q.PriceMin == nil || price.Amount >= *q.PriceMin) && (q.PriceMax == nil || price.Amount <= *q.PriceMax)
+}
+
+// pageCategories filters, sorts and pages the categories in memory
+func pageCategories(all []Category, q ListQuery) CategoryPage {
+	matching := make([]Category, 0, len(all))
+	for _, c := range all {
+		if q.matchesName(c.CategoryName) {
+			matching = append(matching, c)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return compareKeys(*categoryCursor(matching[i], q), *categoryCursor(matching[j], q), q) < 0
+	})
+
+	//skip everything up to the cursor
+	start := 0
+	if q.After != nil {
+		start = sort.Search(len(matching), func(i int) bool {
+			return compareKeys(*categoryCursor(matching[i], q), *q.After, q) > 0
+		})
+	}
+	end := len(matching)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+
+	page := CategoryPage{Items: append([]Category{}, matching[start:end]...), Total: len(matching)}
+	if end < len(matching) {
+		page.Next = categoryCursor(matching[end-1], q)
+	}
+	return page
+}
+
+// pageProducts filters, sorts and pages the products in memory.
+// The products of the query category have already been selected by the caller.
+func pageProducts(all []Product, q ListQuery) ProductPage {
+	matching := make([]Product, 0, len(all))
+	for _, p := range all {
+		if q.MatchesProduct(p) {
+			matching = append(matching, p)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return compareKeys(*productCursor(matching[i], q), *productCursor(matching[j], q), q) < 0
+	})
+
+	//skip everything up to the cursor
+	start := 0
+	if q.After != nil {
+		start = sort.Search(len(matching), func(i int) bool {
+			return compareKeys(*productCursor(matching[i], q), *q.After, q) > 0
+		})
+	}
+	end := len(matching)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+
+	page := ProductPage{Items: append([]Product{}, matching[start:end]...), Total: len(matching)}
+	if end < len(matching) {
+		page.Next = productCursor(matching[end-1], q)
+	}
+	return page
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//listQueries are the queries run against both stores in TestListProductsStoresAgree
+var listQueries = []ListQuery{
+	{Sort: SortCreated},
+	{Sort: SortCreated, Desc: true, Limit: 3},
+	{Sort: SortName, Limit: 4},
+	{Sort: SortPrice, Desc: true, Limit: 2},
+	{Sort: SortPrice, Limit: 5, PriceMin: int64Pointer(30), PriceMax: int64Pointer(70)},
+	{Sort: SortName, Desc: true, Limit: 3, NameContains: "SHOE"},
+	{Sort: SortPrice, Limit: 2, CategoryID: "bq4fasj7jhfi127rimlg"},
+}
+
+//int64Pointer returns a pointer to the given number
+func int64Pointer(n int64) *int64 {
+	return &n
+}
+
+//fillProducts adds the products with repeating names and prices, so the id has to break the ties
+func fillProducts(t *testing.T, catalog CatalogStore) {
+	for i := 0; i < 12; i++ {
+		p := Product{
+			ProductID:   fmt.Sprintf("p%02d", i),
+			ProductName: []string{"Shoe", "Sports Bra", "Running Shoe"}[i%3],
+			Price:       money.New(int64(10*(i%5)), "EUR"),
+			CategoryID:  "bq4fasj7jhfi127rimlg",
+		}
+		if i%2 == 0 {
+			p.CategoryID = "bq4fb3b7jhfi7v7uo39g"
+		}
+		if err := catalog.CreateProduct(p, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//allPages follows the cursors through every page of the query
+func allPages(t *testing.T, catalog CatalogStore, q ListQuery) []ProductPage {
+	var pages []ProductPage
+	for {
+		page, err := catalog.ListProducts(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+		if page.Next == nil {
+			return pages
+		}
+		q.After = page.Next
+	}
+}
+
+//TestListProductsStoresAgree tests whether MemoryStore and SQLiteStore return the same pages for the same queries
+func TestListProductsStoresAgree(t *testing.T) {
+	memory := NewMemoryStore()
+	sqlite, _ := openTestSQLite(t)
+	fillProducts(t, memory)
+	fillProducts(t, sqlite)
+
+	for _, q := range listQueries {
+		memoryPages := allPages(t, memory, q)
+		assert.Equal(t, memoryPages, allPages(t, sqlite, q), "Expected the same pages for %+v", q)
+
+		//every matching product is on exactly one page
+		seen := make(map[string]bool)
+		for _, page := range memoryPages {
+			if q.Limit > 0 {
+				assert.LessOrEqual(t, len(page.Items), q.Limit, "Expected no more than the limit on a page")
+			}
+			for _, p := range page.Items {
+				assert.False(t, seen[p.ProductID], "Expected product %s on one page only", p.ProductID)
+				seen[p.ProductID] = true
+			}
+		}
+		assert.Equal(t, memoryPages[0].Total, len(seen), "Expected the total to count all the pages")
+	}
+}
+
+//TestListNameContainsFolding tests whether both stores ignore the case of the letters outside ASCII in name_contains,
+//also for the seed names the migrations have folded
+func TestListNameContainsFolding(t *testing.T) {
+	sqlite, _ := openTestSQLite(t)
+	for name, catalog := range map[string]CatalogStore{"memory": NewMemoryStore(), "sqlite": sqlite} {
+		if err := catalog.CreateCategory(Category{CategoryID: "shoes", CategoryName: "ÄSTHETIK Schuhe"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := catalog.CreateProduct(Product{ProductID: "eclair", ProductName: "ÉCLAIR Sneaker", Price: money.New(100, "EUR"), CategoryID: "shoes"}, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, contains := range []string{"éclair", "Éclair"} {
+			page, err := catalog.ListProducts(ListQuery{Sort: SortCreated, NameContains: contains})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, page.Total, "%s: expected the product for %q", name, contains)
+		}
+		categories, err := catalog.ListCategories(ListQuery{Sort: SortCreated, NameContains: "ästhetik"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, categories.Total, "%s: expected the category", name)
+		page, err := catalog.ListProducts(ListQuery{Sort: SortCreated, NameContains: "nike SUPERREP"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, page.Total, "%s: expected the seed product", name)
+	}
+}
+
+//TestListProductsSortedByPrice tests whether the products are sorted by price with the id breaking the ties
+func TestListProductsSortedByPrice(t *testing.T) {
+	catalog := NewMemoryStore()
+	fillProducts(t, catalog)
+
+	page, err := catalog.ListProducts(ListQuery{Sort: SortPrice, Desc: true, Limit: 3})
+	assert.NoError(t, err)
+	var ids []string
+	for _, p := range page.Items {
+		ids = append(ids, p.ProductID)
+	}
+	assert.Equal(t, []string{"bq4foj37jhfipc5nqri0", "bq5457j7jhfi2s58o030", "p09"}, ids, "Expected the most expensive products first")
+	assert.Equal(t, 14, page.Total, "Expected all the products to be counted")
+	assert.Equal(t, &Cursor{Sort: SortPrice, Desc: true, Price: 40, ID: "p09"}, page.Next, "Expected the cursor of the last product")
+}
+
+//TestListProductsCursorIsStable tests whether the next page neither repeats nor skips products
+//when products are created and deleted between the pages
+func TestListProductsCursorIsStable(t *testing.T) {
+	sqlite, _ := openTestSQLite(t)
+	for name, catalog := range map[string]CatalogStore{"memory": NewMemoryStore(), "sqlite": sqlite} {
+		fillProducts(t, catalog)
+		q := ListQuery{Sort: SortName, Limit: 5}
+		first, err := catalog.ListProducts(q)
+		assert.NoError(t, err)
+
+		//a product sorted before the cursor is created and one from the first page is deleted
+		assert.NoError(t, catalog.CreateProduct(Product{ProductID: "p99", ProductName: "A Cap", CategoryID: "bq4fasj7jhfi127rimlg"}, "test"))
+		assert.NoError(t, catalog.DeleteProduct(first.Items[0].ProductID))
+
+		q.After = first.Next
+		second, err := catalog.ListProducts(q)
+		assert.NoError(t, err)
+
+		all, err := catalog.ListProducts(ListQuery{Sort: SortName})
+		assert.NoError(t, err)
+		//the second page starts right after the last product of the first one
+		for i, p := range all.Items {
+			if p.ProductID == first.Items[4].ProductID {
+				assert.Equal(t, all.Items[i+1:i+6], second.Items, "%s: expected the page after the cursor", name)
+			}
+		}
+	}
+}
+
+//TestParseCursor tests whether a cursor is accepted only by the query of its sort order
+func TestParseCursor(t *testing.T) {
+	cursor := Cursor{Sort: SortPrice, Desc: true, Price: 40, ID: "p04"}
+
+	parsed, err := ParseCursor(cursor.String(), ListQuery{Sort: SortPrice, Desc: true})
+	assert.NoError(t, err)
+	assert.Equal(t, &cursor, parsed, "Expected the same cursor back")
+
+	_, err = ParseCursor(cursor.String(), ListQuery{Sort: SortPrice})
+	assert.Equal(t, ErrInvalidCursor, err, "Expected the cursor of another order to be rejected")
+	_, err = ParseCursor("not a cursor", ListQuery{Sort: SortPrice, Desc: true})
+	assert.Equal(t, ErrInvalidCursor, err, "Expected a malformed cursor to be rejected")
+}
@@ -0,0 +1,20 @@
+package store
+
+import "github.com/KseniiaL/AdcashTestAssignment/search"
+
+// nameBoost makes a term in the product name count as much as that many terms in the description
+const nameBoost = 2
+
+// ProductMatch is a product found by SearchProducts with its relevance score
+type ProductMatch struct {
+	Product
+	Score float64 `json:"Score"`
+}
+
+// indexProduct puts the searchable text of the product into the search index
+func indexProduct(index *search.Index, p Product) {
+	index.Put(p.ProductID,
+		search.Field{Text: p.ProductName, Boost: nameBoost},
+		search.Field{Text: p.ProductDescription, Boost: 1},
+	)
+}
@@ -0,0 +1,1314 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/KseniiaL/AdcashTestAssignment/search"
+	"github.com/mattn/go-sqlite3"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQLiteStore keeps categories and products in an SQLite database file.
+// It is safe for concurrent use.
+type SQLiteStore struct {
+	//db is the database file, closed by Close
+	db *sql.DB
+	//conn runs the queries: the database, or the transaction of the store given to the func of Transaction
+	conn database
+	//the full-text index of the products is kept in memory and built when the database is opened
+	searchIndex *search.Index
+	//productWrites makes the product changes reach the search index in the order they were made in the database
+	productWrites sync.Mutex
+	//the changes of the search index made in a transaction wait in pendingIndex until it is committed
+	inTransaction bool
+	pendingIndex  []func(index *search.Index)
+}
+
+var _ CatalogStore = (*SQLiteStore)(nil)
+
+// OpenSQLite opens (or creates) the database file at the given path and migrates it to the latest schema
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	//foreign keys are disabled in SQLite by default,
+	//concurrent requests wait for the write lock instead of failing with "database is locked"
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	if err = migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &SQLiteStore{db: db, conn: sqlDB{db}, searchIndex: search.NewIndex()}
+	allProducts, err := s.Products()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, p := range allProducts {
+		indexProduct(s.searchIndex, p)
+	}
+	return s, nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Categories returns all the categories in the order they were created
+func (s *SQLiteStore) Categories() ([]Category, error) {
+	return s.queryCategories(`SELECT ` + categoryColumns + ` FROM categories ORDER BY rowid`)
+}
+
+// Category looks for the category with the given id in the categories table
+func (s *SQLiteStore) Category(id string) (Category, error) {
+	found, err := s.queryCategories(`SELECT `+categoryColumns+` FROM categories WHERE CategoryID = ?`, id)
+	if err != nil {
+		return Category{}, err
+	}
+	if len(found) == 0 {
+		return Category{}, ErrNotFound
+	}
+	return found[0], nil
+}
+
+// CreateCategory inserts the category into the categories table
+func (s *SQLiteStore) CreateCategory(c Category) error {
+	//the foreign key does not stop a category from referring to itself
+	if c.ParentID == c.CategoryID {
+		return ErrCategoryCycle
+	}
+	attributes, err := json.Marshal(schemaColumn(c.Attributes))
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.Exec(`INSERT INTO categories (CategoryID, CategoryName, CategoryDescription, ParentID, Attributes, FoldedName) VALUES (?, ?, ?, ?, ?, ?)`,
+		c.CategoryID, c.CategoryName, c.CategoryDescription, nullString(c.ParentID), string(attributes), foldName(c.CategoryName))
+	if isForeignKeyError(err) {
+		return ErrParentNotFound
+	} else if isPrimaryKeyError(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// UpdateCategory replaces the row of the category with the same id.
+// The new parent is checked for a cycle in the same transaction, so concurrent moves can not make one.
+func (s *SQLiteStore) UpdateCategory(c Category) error {
+	attributes, err := json.Marshal(schemaColumn(c.Attributes))
+	if err != nil {
+		return err
+	}
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	//the category can not be put under itself or any of its descendants,
+	//so it must not be among the ancestors of the new parent
+	if c.ParentID != "" {
+		var cycle bool
+		err = tx.QueryRow(`WITH RECURSIVE ancestors(id) AS (
+				SELECT ?
+				UNION
+				SELECT ParentID FROM categories JOIN ancestors ON CategoryID = ancestors.id WHERE ParentID IS NOT NULL
+			)
+			SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = ?)`, c.ParentID, c.CategoryID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCategoryCycle
+		}
+	}
+
+	result, err := tx.Exec(`UPDATE categories SET CategoryName = ?, CategoryDescription = ?, ParentID = ?, Attributes = ?, FoldedName = ? WHERE CategoryID = ?`,
+		c.CategoryName, c.CategoryDescription, nullString(c.ParentID), string(attributes), foldName(c.CategoryName), c.CategoryID)
+	if isForeignKeyError(err) {
+		return ErrParentNotFound
+	}
+	if err = affectedOne(result, err); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteCategory removes the category with the given id from the categories table
+// and deletes or moves its products as the policy says, all in one transaction
+func (s *SQLiteStore) DeleteCategory(id string, policy DeletePolicy) error {
+	if err := policy.check(id); err != nil {
+		return err
+	}
+	//cascaded products leave the search index in the same order as they leave the database
+	s.productWrites.Lock()
+	defer s.productWrites.Unlock()
+
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE CategoryID = ?)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	//everything is checked before anything is changed
+	inUse := CategoryInUseError{}
+	err = tx.QueryRow(`SELECT (SELECT COUNT(*) FROM products WHERE CategoryID = ?), (SELECT COUNT(*) FROM categories WHERE ParentID = ?)`, id, id).
+		Scan(&inUse.Products, &inUse.Subcategories)
+	if err != nil {
+		return err
+	}
+	if inUse.Subcategories > 0 || (inUse.Products > 0 && (policy.Mode == "" || policy.Mode == DeleteRestrict)) {
+		return &inUse
+	}
+
+	var cascaded []string
+	switch policy.Mode {
+	case DeleteCascade:
+		rows, err := tx.Query(`SELECT ProductID FROM products WHERE CategoryID = ?`, id)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var productID string
+			if err = rows.Scan(&productID); err != nil {
+				rows.Close()
+				return err
+			}
+			cascaded = append(cascaded, productID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(`DELETE FROM products WHERE CategoryID = ?`, id); err != nil {
+			return err
+		}
+	case DeleteReassign:
+		if err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE CategoryID = ?)`, policy.TargetID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrCategoryNotFound
+		}
+		if _, err = tx.Exec(`UPDATE products SET CategoryID = ? WHERE CategoryID = ?`, policy.TargetID, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec(`DELETE FROM categories WHERE CategoryID = ?`, id); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	s.reindex(func(index *search.Index) {
+		for _, productID := range cascaded {
+			index.Remove(productID)
+		}
+	})
+	return nil
+}
+
+// ChildCategories returns the categories which have the given ParentID
+func (s *SQLiteStore) ChildCategories(id string) ([]Category, error) {
+	if _, err := s.Category(id); err != nil {
+		return nil, err
+	}
+	return s.queryCategories(`SELECT `+categoryColumns+` FROM categories WHERE ParentID = ? ORDER BY rowid`, id)
+}
+
+// CategoryAncestors follows the ParentID of the category up to the top-level one
+func (s *SQLiteStore) CategoryAncestors(id string) ([]Category, error) {
+	if _, err := s.Category(id); err != nil {
+		return nil, err
+	}
+	return s.queryCategories(`WITH RECURSIVE ancestors(id, depth) AS (
+			SELECT ParentID, 1 FROM categories WHERE CategoryID = ?
+			UNION ALL
+			SELECT ParentID, depth + 1 FROM categories JOIN ancestors ON CategoryID = ancestors.id
+		)
+		SELECT `+categoryColumns+` FROM categories JOIN ancestors ON CategoryID = ancestors.id ORDER BY depth DESC`, id)
+}
+
+// CategoryTree nests all the categories under their parents
+func (s *SQLiteStore) CategoryTree() ([]CategoryNode, error) {
+	allCategories, err := s.Categories()
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(allCategories), nil
+}
+
+// Products returns all the products in the order they were created
+func (s *SQLiteStore) Products() ([]Product, error) {
+	return s.queryProducts(`SELECT ` + productColumns + ` FROM products ORDER BY rowid`)
+}
+
+// Product looks for the product with the given id in the products table
+func (s *SQLiteStore) Product(id string) (Product, error) {
+	found, err := s.queryProducts(`SELECT `+productColumns+` FROM products WHERE ProductID = ?`, id)
+	if err != nil {
+		return Product{}, err
+	}
+	if len(found) == 0 {
+		return Product{}, ErrNotFound
+	}
+	return found[0], nil
+}
+
+// ProductsOfCategory returns the products which have the given categoryID
+func (s *SQLiteStore) ProductsOfCategory(categoryID string) ([]Product, error) {
+	return s.queryProducts(`SELECT `+productColumns+` FROM products
+		WHERE CategoryID = ? ORDER BY rowid`, categoryID)
+}
+
+// CreateProduct inserts the product into the products table and the search index
+// and its price into the price_changes table
+func (s *SQLiteStore) CreateProduct(p Product, author string) error {
+	attributes, err := json.Marshal(attributesColumn(p.Attributes))
+	if err != nil {
+		return err
+	}
+	s.productWrites.Lock()
+	defer s.productWrites.Unlock()
+
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO products (`+productColumns+`, FoldedName) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ProductID, p.ProductName, p.ProductDescription, p.Price.Amount, p.Price.Currency, p.CategoryID, string(attributes), foldName(p.ProductName))
+	if isForeignKeyError(err) {
+		return ErrCategoryNotFound
+	} else if isPrimaryKeyError(err) {
+		return ErrAlreadyExists
+	} else if err != nil {
+		return err
+	}
+	if err = insertPriceChange(tx, newPriceChange(p, author)); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	s.reindex(func(index *search.Index) {
+		indexProduct(index, p)
+	})
+	return nil
+}
+
+// UpdateProduct replaces the row of the product with the same id and its text in the search index,
+// a new price is also inserted into the price_changes table
+func (s *SQLiteStore) UpdateProduct(p Product, author string) error {
+	attributes, err := json.Marshal(attributesColumn(p.Attributes))
+	if err != nil {
+		return err
+	}
+	s.productWrites.Lock()
+	defer s.productWrites.Unlock()
+
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var stored money.Money
+	err = tx.QueryRow(`SELECT Price, Currency FROM products WHERE ProductID = ?`, p.ProductID).Scan(&stored.Amount, &stored.Currency)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE products SET ProductName = ?, ProductDescription = ?, Price = ?, Currency = ?, CategoryID = ?, Attributes = ?, FoldedName = ? WHERE ProductID = ?`,
+		p.ProductName, p.ProductDescription, p.Price.Amount, p.Price.Currency, p.CategoryID, string(attributes), foldName(p.ProductName), p.ProductID)
+	if isForeignKeyError(err) {
+		return ErrCategoryNotFound
+	} else if err != nil {
+		return err
+	}
+	if stored != p.Price {
+		if err = insertPriceChange(tx, newPriceChange(p, author)); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	s.reindex(func(index *search.Index) {
+		indexProduct(index, p)
+	})
+	return nil
+}
+
+// DeleteProduct removes the product with the given id from the products table and the search index
+func (s *SQLiteStore) DeleteProduct(id string) error {
+	s.productWrites.Lock()
+	defer s.productWrites.Unlock()
+	result, err := s.conn.Exec(`DELETE FROM products WHERE ProductID = ?`, id)
+	if err = affectedOne(result, err); err != nil {
+		return err
+	}
+	s.reindex(func(index *search.Index) {
+		index.Remove(id)
+	})
+	return nil
+}
+
+// PriceHistory returns the rows of the product in the price_changes table ordered by the time they take effect
+func (s *SQLiteStore) PriceHistory(productID string) ([]PriceChange, error) {
+	if _, err := s.Product(productID); err != nil {
+		return nil, err
+	}
+	return s.queryPriceChanges(`SELECT `+priceChangeColumns+` FROM price_changes
+		WHERE ProductID = ? ORDER BY EffectiveAt, RecordedAt, rowid`, productID)
+}
+
+// SchedulePriceChanges inserts the scheduled changes of the product into the price_changes table
+func (s *SQLiteStore) SchedulePriceChanges(productID string, changes []PriceChange) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = productExists(tx, productID); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		c.ProductID = productID
+		c.Status = PriceScheduled
+		if err = insertPriceChange(tx, c); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CancelPriceChange deletes the scheduled change of the product from the price_changes table
+// together with its other half, the change reverting it or the change it reverts if that is still scheduled
+func (s *SQLiteStore) CancelPriceChange(productID string, changeID string) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status, reverts string
+	err = tx.QueryRow(`SELECT Status, Reverts FROM price_changes WHERE ChangeID = ? AND ProductID = ?`, changeID, productID).Scan(&status, &reverts)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if status == PriceApplied {
+		return ErrPriceChangeApplied
+	}
+	_, err = tx.Exec(`DELETE FROM price_changes WHERE ProductID = ? AND Status = ? AND (ChangeID = ? OR Reverts = ? OR ChangeID = ?)`,
+		productID, PriceScheduled, changeID, changeID, reverts)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ApplyDuePriceChanges copies the prices of the due scheduled changes to the products table, all in one transaction
+func (s *SQLiteStore) ApplyDuePriceChanges(now time.Time) ([]PriceChange, error) {
+	s.productWrites.Lock()
+	defer s.productWrites.Unlock()
+
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	due, err := queryPriceChanges(tx, `SELECT `+priceChangeColumns+` FROM price_changes
+		WHERE Status = ? AND EffectiveAt <= ? ORDER BY EffectiveAt, RecordedAt, rowid`, PriceScheduled, formatTime(now))
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range due {
+		if c.Reverts != "" {
+			//the price which comes back is resolved with the timeline as it is now
+			var current money.Money
+			err = tx.QueryRow(`SELECT Price, Currency FROM products WHERE ProductID = ?`, c.ProductID).Scan(&current.Amount, &current.Currency)
+			if err != nil {
+				return nil, err
+			}
+			timeline, err := queryPriceChanges(tx, `SELECT `+priceChangeColumns+` FROM price_changes
+				WHERE ProductID = ? ORDER BY EffectiveAt, RecordedAt, rowid`, c.ProductID)
+			if err != nil {
+				return nil, err
+			}
+			c.Price = revertPrice(timeline, c, current)
+		}
+		if _, err = tx.Exec(`UPDATE products SET Price = ?, Currency = ? WHERE ProductID = ?`, c.Price.Amount, c.Price.Currency, c.ProductID); err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`UPDATE price_changes SET Status = ?, Price = ?, Currency = ? WHERE ChangeID = ?`,
+			PriceApplied, c.Price.Amount, c.Price.Currency, c.ChangeID)
+		if err != nil {
+			return nil, err
+		}
+		c.Status = PriceApplied
+		due[i] = c
+	}
+	return due, tx.Commit()
+}
+
+// Variants returns the rows of the product in the variants table in the order they were inserted
+func (s *SQLiteStore) Variants(productID string) ([]Variant, error) {
+	if _, err := s.Product(productID); err != nil {
+		return nil, err
+	}
+	return queryVariants(s.conn, `SELECT `+variantColumns+` FROM variants WHERE ProductID = ? ORDER BY rowid`, productID)
+}
+
+// VariantsOf selects the variants of all the products in one query and groups them by product
+func (s *SQLiteStore) VariantsOf(productIDs []string) (map[string][]Variant, error) {
+	grouped := make(map[string][]Variant)
+	if len(productIDs) == 0 {
+		return grouped, nil
+	}
+	args := make([]interface{}, 0, len(productIDs))
+	for _, productID := range productIDs {
+		args = append(args, productID)
+	}
+	variants, err := queryVariants(s.conn, `SELECT `+variantColumns+` FROM variants
+		WHERE ProductID IN (?`+strings.Repeat(", ?", len(productIDs)-1)+`) ORDER BY rowid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range variants {
+		grouped[v.ProductID] = append(grouped[v.ProductID], v)
+	}
+	return grouped, nil
+}
+
+// Variant returns the row of the variants table with the product and variant ids
+func (s *SQLiteStore) Variant(productID string, variantID string) (Variant, error) {
+	if _, err := s.Product(productID); err != nil {
+		return Variant{}, err
+	}
+	variants, err := queryVariants(s.conn, `SELECT `+variantColumns+` FROM variants WHERE ProductID = ? AND VariantID = ?`, productID, variantID)
+	if err != nil {
+		return Variant{}, err
+	}
+	if len(variants) == 0 {
+		return Variant{}, ErrVariantNotFound
+	}
+	return variants[0], nil
+}
+
+// CreateVariant inserts the variant into the variants table
+func (s *SQLiteStore) CreateVariant(v Variant) error {
+	attributes, err := json.Marshal(v.Attributes)
+	if err != nil {
+		return err
+	}
+	amount, currency := variantPrice(v)
+	_, err = s.conn.Exec(`INSERT INTO variants (`+variantColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		v.VariantID, v.ProductID, v.SKU, string(attributes), amount, currency)
+	if isForeignKeyError(err) {
+		return ErrNotFound
+	} else if isPrimaryKeyError(err) {
+		return ErrAlreadyExists
+	} else if isUniqueError(err) {
+		return ErrDuplicateSKU
+	}
+	return err
+}
+
+// UpdateVariant replaces the row of the variants table with the same product and variant ids
+func (s *SQLiteStore) UpdateVariant(v Variant) error {
+	attributes, err := json.Marshal(v.Attributes)
+	if err != nil {
+		return err
+	}
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = stockItemExists(tx, v.ProductID, v.VariantID); err != nil {
+		return err
+	}
+	amount, currency := variantPrice(v)
+	_, err = tx.Exec(`UPDATE variants SET SKU = ?, Attributes = ?, Price = ?, Currency = ? WHERE VariantID = ?`,
+		v.SKU, string(attributes), amount, currency, v.VariantID)
+	if isUniqueError(err) {
+		return ErrDuplicateSKU
+	} else if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteVariant deletes the row of the variant from the variants table and its rows from the stock,
+// stock_adjustments and reservations tables, all in one transaction
+func (s *SQLiteStore) DeleteVariant(productID string, variantID string) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = stockItemExists(tx, productID, variantID); err != nil {
+		return err
+	}
+	for _, table := range []string{"variants", "stock", "stock_adjustments", "reservations"} {
+		if _, err = tx.Exec(`DELETE FROM `+table+` WHERE ProductID = ? AND VariantID = ?`, productID, variantID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Stock returns the rows of the product or the variant in the stock table with the quantities of their active reservations
+func (s *SQLiteStore) Stock(productID string, variantID string) ([]StockLevel, error) {
+	if err := stockItemExists(s.conn, productID, variantID); err != nil {
+		return nil, err
+	}
+	return queryStockLevels(s.conn, productID, variantID, time.Now())
+}
+
+// StockSummary sums the stock rows of the products and their variants and the active reservations in SQL
+func (s *SQLiteStore) StockSummary(productIDs []string) (map[string]StockLevel, error) {
+	summary := make(map[string]StockLevel, len(productIDs))
+	if len(productIDs) == 0 {
+		return summary, nil
+	}
+	args := []interface{}{formatTime(time.Now())}
+	for _, productID := range productIDs {
+		summary[productID] = newStockLevel("", 0, 0)
+		args = append(args, productID)
+	}
+	rows, err := s.conn.Query(`SELECT ProductID, SUM(OnHand), SUM(`+reservedColumn+`) FROM stock
+		WHERE ProductID IN (?`+strings.Repeat(", ?", len(productIDs)-1)+`) GROUP BY ProductID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID string
+		var onHand, reserved int64
+		if err = rows.Scan(&productID, &onHand, &reserved); err != nil {
+			return nil, err
+		}
+		summary[productID] = newStockLevel("", onHand, reserved)
+	}
+	return summary, rows.Err()
+}
+
+// AdjustStock changes the OnHand of the row of the product or the variant and the warehouse in the stock table
+// and inserts the adjustment into the stock_adjustments table, all in one transaction
+func (s *SQLiteStore) AdjustStock(a StockAdjustment) (StockLevel, error) {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return StockLevel{}, err
+	}
+	defer tx.Rollback()
+
+	if err = stockItemExists(tx, a.ProductID, a.VariantID); err != nil {
+		return StockLevel{}, err
+	}
+	levels, err := queryStockLevels(tx, a.ProductID, a.VariantID, time.Now())
+	if err != nil {
+		return StockLevel{}, err
+	}
+	level := newStockLevel(a.WarehouseID, 0, 0)
+	for _, l := range levels {
+		if l.WarehouseID == a.WarehouseID {
+			level = l
+		}
+	}
+	if level.OnHand+a.Delta < level.Reserved {
+		return StockLevel{}, &InsufficientStockError{WarehouseID: a.WarehouseID, Available: level.Available}
+	}
+	if err = insertStockAdjustment(tx, a); err != nil {
+		return StockLevel{}, err
+	}
+	return newStockLevel(a.WarehouseID, level.OnHand+a.Delta, level.Reserved), tx.Commit()
+}
+
+// StockAdjustments returns the rows of the product or the variant in the stock_adjustments table
+// in the order they were inserted
+func (s *SQLiteStore) StockAdjustments(productID string, variantID string) ([]StockAdjustment, error) {
+	if err := stockItemExists(s.conn, productID, variantID); err != nil {
+		return nil, err
+	}
+	rows, err := s.conn.Query(`SELECT `+stockAdjustmentColumns+` FROM stock_adjustments
+		WHERE ProductID = ? AND VariantID = ? ORDER BY rowid`, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	adjustments := make([]StockAdjustment, 0)
+	for rows.Next() {
+		var a StockAdjustment
+		var at string
+		if err = rows.Scan(&a.AdjustmentID, &a.ProductID, &a.VariantID, &a.WarehouseID, &a.Delta, &a.Reason, &a.Note, &a.Author, &at); err != nil {
+			return nil, err
+		}
+		if a.At, err = time.Parse(timeLayout, at); err != nil {
+			return nil, err
+		}
+		adjustments = append(adjustments, a)
+	}
+	return adjustments, rows.Err()
+}
+
+// ReserveStock inserts the reservation into the reservations table if the warehouse has enough available pieces
+// of the product or the variant, the check and the insert are done in one transaction
+func (s *SQLiteStore) ReserveStock(r Reservation) (Reservation, error) {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return Reservation{}, err
+	}
+	defer tx.Rollback()
+
+	if err = stockItemExists(tx, r.ProductID, r.VariantID); err != nil {
+		return Reservation{}, err
+	}
+	levels, err := queryStockLevels(tx, r.ProductID, r.VariantID, time.Now())
+	if err != nil {
+		return Reservation{}, err
+	}
+	level, err := pickWarehouse(levels, r.WarehouseID, r.Quantity)
+	if err != nil {
+		return Reservation{}, err
+	}
+	r.WarehouseID = level.WarehouseID
+	_, err = tx.Exec(`INSERT INTO reservations (ReservationID, ProductID, VariantID, WarehouseID, Quantity, CreatedAt, ExpiresAt) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.ReservationID, r.ProductID, r.VariantID, r.WarehouseID, r.Quantity, formatTime(r.CreatedAt), formatTime(r.ExpiresAt))
+	if err != nil {
+		return Reservation{}, err
+	}
+	return r, tx.Commit()
+}
+
+// ReleaseReservation deletes the reservation of the product from the reservations table
+func (s *SQLiteStore) ReleaseReservation(productID string, reservationID string) error {
+	result, err := s.conn.Exec(`DELETE FROM reservations WHERE ReservationID = ? AND ProductID = ?`, reservationID, productID)
+	return affectedOne(result, err)
+}
+
+// CommitReservation deletes the active reservation of the product from the reservations table
+// and inserts the sale of its pieces into the stock_adjustments table, all in one transaction
+func (s *SQLiteStore) CommitReservation(productID string, reservationID string, author string) (StockAdjustment, error) {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return StockAdjustment{}, err
+	}
+	defer tx.Rollback()
+
+	r := Reservation{ReservationID: reservationID, ProductID: productID}
+	err = tx.QueryRow(`SELECT VariantID, WarehouseID, Quantity FROM reservations WHERE ReservationID = ? AND ProductID = ? AND ExpiresAt > ?`,
+		reservationID, productID, formatTime(time.Now())).Scan(&r.VariantID, &r.WarehouseID, &r.Quantity)
+	if err == sql.ErrNoRows {
+		return StockAdjustment{}, ErrNotFound
+	} else if err != nil {
+		return StockAdjustment{}, err
+	}
+	if _, err = tx.Exec(`DELETE FROM reservations WHERE ReservationID = ?`, reservationID); err != nil {
+		return StockAdjustment{}, err
+	}
+	a := newCommitAdjustment(r, author)
+	if err = insertStockAdjustment(tx, a); err != nil {
+		return StockAdjustment{}, err
+	}
+	return a, tx.Commit()
+}
+
+// ReleaseExpiredReservations deletes the rows of the reservations table which have expired at the given time
+func (s *SQLiteStore) ReleaseExpiredReservations(now time.Time) (int, error) {
+	result, err := s.conn.Exec(`DELETE FROM reservations WHERE ExpiresAt <= ?`, formatTime(now))
+	if err != nil {
+		return 0, err
+	}
+	released, err := result.RowsAffected()
+	return int(released), err
+}
+
+// variantColumns are the columns scanned by queryVariants, Attributes is a JSON object
+// and Price and Currency are NULL if the variant has no price of its own
+const variantColumns = `VariantID, ProductID, SKU, Attributes, Price, Currency`
+
+// queryVariants runs the query selecting variantColumns and scans all the resulting rows into variants
+func queryVariants(q querier, query string, args ...interface{}) ([]Variant, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := make([]Variant, 0)
+	for rows.Next() {
+		var v Variant
+		var attributes string
+		var amount sql.NullInt64
+		var currency sql.NullString
+		if err = rows.Scan(&v.VariantID, &v.ProductID, &v.SKU, &attributes, &amount, &currency); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(attributes), &v.Attributes); err != nil {
+			return nil, err
+		}
+		if amount.Valid {
+			price := money.New(amount.Int64, currency.String)
+			v.Price = &price
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+// variantPrice returns the Price and Currency columns of the variant, NULL if it has no price of its own
+func variantPrice(v Variant) (interface{}, interface{}) {
+	if v.Price == nil {
+		return nil, nil
+	}
+	return v.Price.Amount, v.Price.Currency
+}
+
+// reservedColumn sums the quantities of the reservations of a row of the stock table which are active
+// at the time given as the first argument of the query
+const reservedColumn = `(SELECT COALESCE(SUM(Quantity), 0) FROM reservations
+	WHERE reservations.ProductID = stock.ProductID AND reservations.VariantID = stock.VariantID
+	AND reservations.WarehouseID = stock.WarehouseID AND ExpiresAt > ?1)`
+
+// stockAdjustmentColumns are the columns of the stock_adjustments table, At is stored by formatTime
+const stockAdjustmentColumns = `AdjustmentID, ProductID, VariantID, WarehouseID, Delta, Reason, Note, Author, At`
+
+// queryStockLevels returns the stock levels of the product, or of the variant if variantID is not empty,
+// in the database or transaction ordered by warehouse id, counting the reservations which are active at the given time
+func queryStockLevels(q querier, productID string, variantID string, now time.Time) ([]StockLevel, error) {
+	rows, err := q.Query(`SELECT WarehouseID, OnHand, `+reservedColumn+` FROM stock
+		WHERE ProductID = ?2 AND VariantID = ?3 ORDER BY WarehouseID`, formatTime(now), productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := make([]StockLevel, 0)
+	for rows.Next() {
+		var warehouseID string
+		var onHand, reserved int64
+		if err = rows.Scan(&warehouseID, &onHand, &reserved); err != nil {
+			return nil, err
+		}
+		levels = append(levels, newStockLevel(warehouseID, onHand, reserved))
+	}
+	return levels, rows.Err()
+}
+
+// insertStockAdjustment adds the delta of the adjustment to the stock table and inserts the adjustment
+// into the stock_adjustments table
+func insertStockAdjustment(tx transaction, a StockAdjustment) error {
+	_, err := tx.Exec(`INSERT INTO stock (ProductID, VariantID, WarehouseID, OnHand) VALUES (?, ?, ?, ?)
+		ON CONFLICT (ProductID, VariantID, WarehouseID) DO UPDATE SET OnHand = OnHand + excluded.OnHand`,
+		a.ProductID, a.VariantID, a.WarehouseID, a.Delta)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO stock_adjustments (`+stockAdjustmentColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.AdjustmentID, a.ProductID, a.VariantID, a.WarehouseID, a.Delta, a.Reason, a.Note, a.Author, formatTime(a.At))
+	return err
+}
+
+// productExists returns ErrNotFound if the product with the given id is not in the products table
+func productExists(q querier, productID string) error {
+	var exists bool
+	if err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM products WHERE ProductID = ?)`, productID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// stockItemExists returns ErrNotFound if the product is not in the products table
+// and ErrVariantNotFound if the variant is given but it is not a row of the product in the variants table
+func stockItemExists(q querier, productID string, variantID string) error {
+	if err := productExists(q, productID); err != nil || variantID == "" {
+		return err
+	}
+	var exists bool
+	err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM variants WHERE ProductID = ? AND VariantID = ?)`, productID, variantID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrVariantNotFound
+	}
+	return nil
+}
+
+// priceChangeColumns are the columns scanned by queryPriceChanges, the times are stored by formatTime
+const priceChangeColumns = `ChangeID, ProductID, Price, Currency, EffectiveAt, Author, RecordedAt, Status, Reverts`
+
+// timeLayout keeps the times in UTC with a fixed number of digits, so they are sorted and compared as text
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// formatTime returns the time as it is stored in the database
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// querier is the part of *sql.DB and *sql.Tx the queries need
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// database is the part of *sql.DB the store works with
+type database interface {
+	querier
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Begin() (transaction, error)
+}
+
+// transaction is the part of *sql.Tx the store works with
+type transaction interface {
+	querier
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Commit() error
+	Rollback() error
+}
+
+// sqlDB is the database of the store opened by OpenSQLite
+type sqlDB struct {
+	*sql.DB
+}
+
+// Begin starts a transaction of the database
+func (db sqlDB) Begin() (transaction, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// savepoints is the database of the store given to the func of Transaction,
+// the methods of the store begin savepoints in the transaction instead of transactions of their own
+type savepoints struct {
+	transaction
+}
+
+// Begin starts a savepoint. SQLite lets nested savepoints have the same name, the innermost one is released
+// or rolled back, so every savepoint is ended before the one it is nested in.
+func (db savepoints) Begin() (transaction, error) {
+	if _, err := db.Exec(`SAVEPOINT nested`); err != nil {
+		return nil, err
+	}
+	return &savepoint{transaction: db.transaction}, nil
+}
+
+// savepoint is a part of a transaction which is committed or rolled back on its own, like a transaction
+type savepoint struct {
+	transaction
+	done bool
+}
+
+// Commit releases the savepoint, its changes are kept until the transaction ends
+func (sp *savepoint) Commit() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	_, err := sp.Exec(`RELEASE nested`)
+	return err
+}
+
+// Rollback undoes the changes made since the savepoint and releases it
+func (sp *savepoint) Rollback() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	if _, err := sp.Exec(`ROLLBACK TO nested`); err != nil {
+		return err
+	}
+	_, err := sp.Exec(`RELEASE nested`)
+	return err
+}
+
+// queryPriceChanges runs the query selecting priceChangeColumns and scans all the resulting rows into price changes
+func (s *SQLiteStore) queryPriceChanges(query string, args ...interface{}) ([]PriceChange, error) {
+	return queryPriceChanges(s.conn, query, args...)
+}
+
+// queryPriceChanges runs the query selecting priceChangeColumns in the database or transaction
+// and scans all the resulting rows into price changes
+func queryPriceChanges(q querier, query string, args ...interface{}) ([]PriceChange, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]PriceChange, 0)
+	for rows.Next() {
+		var c PriceChange
+		var effectiveAt, recordedAt string
+		err = rows.Scan(&c.ChangeID, &c.ProductID, &c.Price.Amount, &c.Price.Currency, &effectiveAt, &c.Author, &recordedAt, &c.Status, &c.Reverts)
+		if err != nil {
+			return nil, err
+		}
+		if c.EffectiveAt, err = time.Parse(timeLayout, effectiveAt); err != nil {
+			return nil, err
+		}
+		if c.RecordedAt, err = time.Parse(timeLayout, recordedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// insertPriceChange inserts the change into the price_changes table
+func insertPriceChange(tx transaction, c PriceChange) error {
+	_, err := tx.Exec(`INSERT INTO price_changes (`+priceChangeColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.ChangeID, c.ProductID, c.Price.Amount, c.Price.Currency, formatTime(c.EffectiveAt), c.Author, formatTime(c.RecordedAt), c.Status, c.Reverts)
+	return err
+}
+
+// categoryColumns are the columns scanned by queryCategories, ParentID is NULL for the top-level categories
+// and Attributes is the JSON array of the schema
+const categoryColumns = `CategoryID, CategoryName, CategoryDescription, COALESCE(ParentID, ''), Attributes`
+
+// queryCategories runs the query selecting categoryColumns and scans all the resulting rows into categories
+func (s *SQLiteStore) queryCategories(query string, args ...interface{}) ([]Category, error) {
+	rows, err := s.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allCategories := make([]Category, 0)
+	for rows.Next() {
+		var c Category
+		var attributes string
+		if err = rows.Scan(&c.CategoryID, &c.CategoryName, &c.CategoryDescription, &c.ParentID, &attributes); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(attributes), &c.Attributes); err != nil {
+			return nil, err
+		}
+		//the categories without a schema are stored with an empty array
+		if len(c.Attributes) == 0 {
+			c.Attributes = nil
+		}
+		allCategories = append(allCategories, c)
+	}
+	return allCategories, rows.Err()
+}
+
+// productColumns are the columns scanned by queryProducts, Price is the amount in the minor units of Currency
+// and Attributes is a JSON object
+const productColumns = `ProductID, ProductName, ProductDescription, Price, Currency, CategoryID, Attributes`
+
+// queryProducts runs the query selecting productColumns and scans all the resulting rows into products
+func (s *SQLiteStore) queryProducts(query string, args ...interface{}) ([]Product, error) {
+	rows, err := s.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allProducts := make([]Product, 0)
+	for rows.Next() {
+		var p Product
+		var attributes string
+		if err = rows.Scan(&p.ProductID, &p.ProductName, &p.ProductDescription, &p.Price.Amount, &p.Price.Currency, &p.CategoryID, &attributes); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(attributes), &p.Attributes); err != nil {
+			return nil, err
+		}
+		//the products without attributes are stored with an empty object
+		if len(p.Attributes) == 0 {
+			p.Attributes = nil
+		}
+		allProducts = append(allProducts, p)
+	}
+	return allProducts, rows.Err()
+}
+
+// affectedOne turns the result of an UPDATE or DELETE which has not changed any row into ErrNotFound
+func affectedOne(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// schemaColumn stores a missing schema as an empty JSON array
+func schemaColumn(schema []Attribute) []Attribute {
+	if schema == nil {
+		return []Attribute{}
+	}
+	return schema
+}
+
+// attributesColumn stores missing attributes as an empty JSON object
+func attributesColumn(attributes map[string]interface{}) map[string]interface{} {
+	if attributes == nil {
+		return map[string]interface{}{}
+	}
+	return attributes
+}
+
+// nullString stores an empty string as NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// isForeignKeyError reports whether the statement failed because of a foreign key constraint
+func isForeignKeyError(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
+
+// isPrimaryKeyError reports whether the statement failed because the primary key is already taken
+func isPrimaryKeyError(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
+
+// isUniqueError reports whether the statement failed because a unique column already has the value
+func isUniqueError(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// ListCategories filters, sorts and pages the categories in SQL
+func (s *SQLiteStore) ListCategories(q ListQuery) (CategoryPage, error) {
+	columns := map[string]string{SortCreated: "CategoryID", SortName: "CategoryName"}
+	if columns[q.Sort] == "" {
+		q.Sort = SortCreated
+	}
+	var where sqlConditions
+	if q.NameContains != "" {
+		where.add("instr(FoldedName, ?) > 0", foldName(q.NameContains))
+	}
+
+	var page CategoryPage
+	if err := s.conn.QueryRow(`SELECT COUNT(*) FROM categories`+where.sql(), where.args...).Scan(&page.Total); err != nil {
+		return CategoryPage{}, err
+	}
+
+	if q.After != nil {
+		where.addKeyset(columns[q.Sort], "CategoryID", q.After.Name, q.After, q.Desc)
+	}
+	items, err := s.queryCategories(`SELECT `+categoryColumns+` FROM categories`+
+		where.sql()+orderBy(columns[q.Sort], "CategoryID", q), where.args...)
+	if err != nil {
+		return CategoryPage{}, err
+	}
+	page.Items = items
+
+	//one more row than the limit is selected to know if there is the next page
+	if q.Limit > 0 && len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.Next = categoryCursor(page.Items[q.Limit-1], q)
+	}
+	return page, nil
+}
+
+// ListProducts filters, sorts and pages the products in SQL
+func (s *SQLiteStore) ListProducts(q ListQuery) (ProductPage, error) {
+	columns := map[string]string{SortCreated: "ProductID", SortName: "ProductName", SortPrice: "Price"}
+	if columns[q.Sort] == "" {
+		q.Sort = SortCreated
+	}
+	var where sqlConditions
+	if q.CategoryID != "" && q.IncludeDescendants {
+		where.add(`CategoryID IN (
+			WITH RECURSIVE subtree(id) AS (
+				SELECT ?
+				UNION
+				SELECT CategoryID FROM categories JOIN subtree ON ParentID = subtree.id
+			)
+			SELECT id FROM subtree)`, q.CategoryID)
+	} else if q.CategoryID != "" {
+		where.add("CategoryID = ?", q.CategoryID)
+	}
+	if q.NameContains != "" {
+		where.add("instr(FoldedName, ?) > 0", foldName(q.NameContains))
+	}
+	if q.Currency != "" {
+		where.add("Currency = ?", q.Currency)
+	}
+	if q.PriceMin != nil {
+		where.add("Price >= ?", *q.PriceMin)
+	}
+	if q.PriceMax != nil {
+		where.add("Price <= ?", *q.PriceMax)
+	}
+	//the JSON booleans are read as 1 and 0, so they are turned into the text of AttributeText first
+	for name, text := range q.Attributes {
+		where.add(`EXISTS (SELECT 1 FROM json_each(products.Attributes) WHERE key = ?
+			AND CASE type WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE CAST(value AS TEXT) END = ?)`, name, text)
+	}
+
+	var page ProductPage
+	if err := s.conn.QueryRow(`SELECT COUNT(*) FROM products`+where.sql(), where.args...).Scan(&page.Total); err != nil {
+		return ProductPage{}, err
+	}
+
+	if q.After != nil {
+		var key interface{} = q.After.Name
+		if q.Sort == SortPrice {
+			key = q.After.Price
+		}
+		where.addKeyset(columns[q.Sort], "ProductID", key, q.After, q.Desc)
+	}
+	items, err := s.queryProducts(`SELECT `+productColumns+` FROM products`+
+		where.sql()+orderBy(columns[q.Sort], "ProductID", q), where.args...)
+	if err != nil {
+		return ProductPage{}, err
+	}
+	page.Items = items
+
+	//one more row than the limit is selected to know if there is the next page
+	if q.Limit > 0 && len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.Next = productCursor(page.Items[q.Limit-1], q)
+	}
+	return page, nil
+}
+
+// Transaction calls fn with a store whose methods work in one database transaction, which is committed if fn succeeds.
+// The search index is changed once the transaction is committed, so the searches made by fn do not find its changes.
+func (s *SQLiteStore) Transaction(fn func(tx CatalogStore) error) error {
+	//the products changed by fn reach the search index in the same order as they reach the database
+	s.productWrites.Lock()
+	defer s.productWrites.Unlock()
+
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txStore := &SQLiteStore{conn: savepoints{tx}, searchIndex: s.searchIndex, inTransaction: true}
+	if err = fn(txStore); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	for _, change := range txStore.pendingIndex {
+		s.reindex(change)
+	}
+	return nil
+}
+
+// reindex makes the change in the search index after the products are changed in the database,
+// in a transaction it waits until the transaction is committed
+func (s *SQLiteStore) reindex(change func(index *search.Index)) {
+	if s.inTransaction {
+		s.pendingIndex = append(s.pendingIndex, change)
+		return
+	}
+	change(s.searchIndex)
+}
+
+// SearchProducts looks for the products in the full-text index and reads the found ones from the database
+func (s *SQLiteStore) SearchProducts(query string, limit int) ([]ProductMatch, error) {
+	matches := s.searchIndex.Search(query, limit)
+	found := make([]ProductMatch, 0, len(matches))
+	for _, m := range matches {
+		p, err := s.Product(m.ID)
+		if err == ErrNotFound {
+			//deleted after the search
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		found = append(found, ProductMatch{Product: p, Score: m.Score})
+	}
+	return found, nil
+}
+
+// sqlConditions collects the WHERE conditions of a query with their arguments
+type sqlConditions struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add appends the condition with its arguments
+func (c *sqlConditions) add(condition string, args ...interface{}) {
+	c.conditions = append(c.conditions, condition)
+	c.args = append(c.args, args...)
+}
+
+// addKeyset appends the condition which selects the rows after the cursor in the sort order
+func (c *sqlConditions) addKeyset(keyColumn string, idColumn string, key interface{}, after *Cursor, desc bool) {
+	operator := ">"
+	if desc {
+		operator = "<"
+	}
+	if keyColumn == idColumn {
+		c.add(idColumn+" "+operator+" ?", after.ID)
+		return
+	}
+	c.add("("+keyColumn+" "+operator+" ? OR ("+keyColumn+" = ? AND "+idColumn+" "+operator+" ?))", key, key, after.ID)
+}
+
+// sql returns the WHERE clause
+func (c *sqlConditions) sql() string {
+	if len(c.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.conditions, " AND ")
+}
+
+// orderBy returns the ORDER BY and LIMIT clauses, the limit is one row more to detect the next page
+func orderBy(keyColumn string, idColumn string, q ListQuery) string {
+	direction := " ASC"
+	if q.Desc {
+		direction = " DESC"
+	}
+	clause := " ORDER BY " + keyColumn + direction
+	if keyColumn != idColumn {
+		clause += ", " + idColumn + direction
+	}
+	if q.Limit > 0 {
+		clause += " LIMIT " + strconv.Itoa(q.Limit+1)
+	}
+	return clause
+}
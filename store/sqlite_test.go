@@ -0,0 +1,125 @@
+package store
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+//openTestSQLite opens a new database in a temporary directory which is removed after the test
+func openTestSQLite(t *testing.T) (*SQLiteStore, string) {
+	path := filepath.Join(t.TempDir(), "catalog.db")
+	catalog, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { catalog.Close() })
+	return catalog, path
+}
+
+//TestSQLiteSeed tests whether a new database is migrated and filled with the seed data
+func TestSQLiteSeed(t *testing.T) {
+	catalog, _ := openTestSQLite(t)
+
+	seedCategories, _ := NewMemoryStore().Categories()
+	allCategories, err := catalog.Categories()
+	assert.NoError(t, err)
+	assert.Equal(t, seedCategories, allCategories, "Expected the same seed categories as in MemoryStore")
+
+	seedProducts, _ := NewMemoryStore().Products()
+	allProducts, err := catalog.Products()
+	assert.NoError(t, err)
+	assert.Equal(t, seedProducts, allProducts, "Expected the same seed products as in MemoryStore")
+}
+
+//TestSQLitePersistence tests whether the data is still there after reopening the database
+//and the migrations are not applied twice
+func TestSQLitePersistence(t *testing.T) {
+	catalog, path := openTestSQLite(t)
+	newCategory := Category{CategoryID: "newCategoryID", CategoryName: "Super Cool Category"}
+	newProduct := Product{ProductID: "newProductID", ProductName: "Super Cool Product", Price: money.New(1000, "EUR"), CategoryID: "newCategoryID"}
+	assert.NoError(t, catalog.CreateCategory(newCategory))
+	assert.NoError(t, catalog.CreateProduct(newProduct, "test"))
+	assert.NoError(t, catalog.Close())
+
+	reopened, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	storedCategory, err := reopened.Category("newCategoryID")
+	assert.NoError(t, err)
+	assert.Equal(t, newCategory, storedCategory)
+	storedProduct, err := reopened.Product("newProductID")
+	assert.NoError(t, err)
+	assert.Equal(t, newProduct, storedProduct)
+
+	allCategories, _ := reopened.Categories()
+	assert.Len(t, allCategories, 3, "Expected the seed migration to run only once")
+}
+
+//TestSQLiteForeignKey tests whether products can not refer to missing categories
+//and categories with products can not be deleted
+func TestSQLiteForeignKey(t *testing.T) {
+	catalog, _ := openTestSQLite(t)
+
+	err := catalog.CreateProduct(Product{ProductID: "newID", ProductName: "Name", CategoryID: "randomID"}, "test")
+	assert.Equal(t, ErrCategoryNotFound, err)
+	err = catalog.UpdateProduct(Product{ProductID: "bq4foj37jhfipc5nqri0", ProductName: "Name", CategoryID: "randomID"}, "test")
+	assert.Equal(t, ErrCategoryNotFound, err)
+
+	assert.ErrorIs(t, catalog.DeleteCategory("bq4fasj7jhfi127rimlg", DeletePolicy{}), ErrCategoryInUse)
+	assert.NoError(t, catalog.DeleteCategory("bq4fb3b7jhfi7v7uo39g", DeletePolicy{}))
+}
+
+//TestSQLiteNotFound tests whether lookups, updates and deletes of unknown ids return ErrNotFound
+func TestSQLiteNotFound(t *testing.T) {
+	catalog, _ := openTestSQLite(t)
+
+	_, err := catalog.Category("randomID")
+	assert.Equal(t, ErrNotFound, err)
+	_, err = catalog.Product("randomID")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, ErrNotFound, catalog.UpdateCategory(Category{CategoryID: "randomID"}))
+	assert.Equal(t, ErrNotFound, catalog.UpdateProduct(Product{ProductID: "randomID", CategoryID: "bq4fasj7jhfi127rimlg"}, "test"))
+	assert.Equal(t, ErrNotFound, catalog.DeleteCategory("randomID", DeletePolicy{}))
+	assert.Equal(t, ErrNotFound, catalog.DeleteProduct("randomID"))
+}
+
+//TestSearchProducts tests whether the search index of both stores follows the product changes
+//and the SQLite index is built again when the database is reopened
+func TestSearchProducts(t *testing.T) {
+	sqlite, path := openTestSQLite(t)
+	for name, catalog := range map[string]CatalogStore{"memory": NewMemoryStore(), "sqlite": sqlite} {
+		found, err := catalog.SearchProducts("sports bra", 0)
+		assert.NoError(t, err)
+		if assert.Len(t, found, 1, "%s: expected the seed bra", name) {
+			assert.Equal(t, "Nike Icon Clash", found[0].ProductName)
+			assert.Greater(t, found[0].Score, 0.0)
+		}
+
+		assert.NoError(t, catalog.CreateProduct(Product{ProductID: "newID", ProductName: "Sports Jacket", CategoryID: "bq4fasj7jhfi127rimlg"}, "test"))
+		assert.NoError(t, catalog.UpdateProduct(Product{ProductID: "bq5457j7jhfi2s58o030", ProductName: "Nike Icon Clash", CategoryID: "bq4fasj7jhfi127rimlg"}, "test"))
+		assert.NoError(t, catalog.DeleteProduct("bq4foj37jhfipc5nqri0"))
+
+		found, err = catalog.SearchProducts("sports", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []ProductMatch{{Product: Product{ProductID: "newID", ProductName: "Sports Jacket", CategoryID: "bq4fasj7jhfi127rimlg"}, Score: found[0].Score}},
+			found, "%s: expected only the new product after the bra description was cleared", name)
+		found, err = catalog.SearchProducts("training shoe", 0)
+		assert.NoError(t, err)
+		assert.Empty(t, found, "%s: expected the deleted product not to be found", name)
+	}
+
+	assert.NoError(t, sqlite.Close())
+	reopened, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	found, err := reopened.SearchProducts("jacket", 0)
+	assert.NoError(t, err)
+	assert.Len(t, found, 1, "Expected the index to be built from the database")
+}
@@ -0,0 +1,166 @@
+//package store describes the catalog storage and contains its in-memory and SQLite implementations
+package store
+
+import (
+	"errors"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"time"
+)
+
+// ErrNotFound is returned when the requested category or product does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrCategoryNotFound is returned when a product refers to a category that does not exist
+var ErrCategoryNotFound = errors.New("category not found")
+
+// ErrAlreadyExists is returned when a category or product with the same id is already stored
+var ErrAlreadyExists = errors.New("already exists")
+
+// ErrCategoryInUse is matched by the *CategoryInUseError returned when a category can not be deleted
+// because products or subcategories still belong to it
+var ErrCategoryInUse = errors.New("category is in use")
+
+// ErrParentNotFound is returned when a category refers to a parent category that does not exist
+var ErrParentNotFound = errors.New("parent category not found")
+
+// ErrCategoryCycle is returned when a category would become its own parent or the parent of one of its ancestors
+var ErrCategoryCycle = errors.New("category cycle")
+
+// Category stores information about category fields
+type Category struct {
+	CategoryID          string `json:"CategoryID"`
+	CategoryName        string `json:"CategoryName"`
+	CategoryDescription string `json:"CategoryDescription"`
+	// ParentID is the id of the category this one is nested in, empty for a top-level category
+	ParentID string `json:"ParentID,omitempty"`
+	// Attributes is the schema of the attributes of the products, the subcategories inherit it
+	Attributes []Attribute `json:"Attributes,omitempty"`
+}
+
+// Product stores information about product fields
+type Product struct {
+	ProductID          string      `json:"ProductID"`
+	ProductName        string      `json:"ProductName"`
+	ProductDescription string      `json:"ProductDescription"`
+	Price              money.Money `json:"Price"`
+	CategoryID         string      `json:"CategoryID"`
+	// Attributes are the values of the attributes in the schema of the category, e.g. {"size":"38","waterproof":true}
+	Attributes map[string]interface{} `json:"Attributes,omitempty"`
+}
+
+// CatalogStore is the storage the category and product handlers work with.
+// Every method returns copies, so changing a returned value does not change the stored one.
+type CatalogStore interface {
+	// Categories returns all the categories
+	Categories() ([]Category, error)
+	// Category returns the category with the given id or ErrNotFound
+	Category(id string) (Category, error)
+	// CreateCategory stores a new category or returns ErrAlreadyExists if its id is taken.
+	// It returns ErrParentNotFound if its parent does not exist and ErrCategoryCycle if it is its own parent.
+	CreateCategory(c Category) error
+	// UpdateCategory replaces the stored category with the same CategoryID or returns ErrNotFound.
+	// It returns ErrParentNotFound for an unknown parent and ErrCategoryCycle if the new parent is the category
+	// itself or one of its descendants.
+	UpdateCategory(c Category) error
+	// DeleteCategory removes the category with the given id or returns ErrNotFound.
+	// Its products are kept, deleted or moved as the policy says, all in one atomic step.
+	// It returns a *CategoryInUseError while subcategories belong to it, or products with DeleteRestrict,
+	// ErrCategoryNotFound for an unknown reassign target and ErrInvalidPolicy for a policy which can not be applied.
+	DeleteCategory(id string, policy DeletePolicy) error
+	// ChildCategories returns the categories directly under the category with the given id or ErrNotFound
+	ChildCategories(id string) ([]Category, error)
+	// CategoryAncestors returns the parent, grandparent and so on of the category with the given id,
+	// starting from the top-level category, or ErrNotFound
+	CategoryAncestors(id string) ([]Category, error)
+	// CategoryTree returns all the categories nested under their parents
+	CategoryTree() ([]CategoryNode, error)
+
+	// Products returns all the products
+	Products() ([]Product, error)
+	// Product returns the product with the given id or ErrNotFound
+	Product(id string) (Product, error)
+	// ProductsOfCategory returns all the products which belong to the given category
+	ProductsOfCategory(categoryID string) ([]Product, error)
+	// CreateProduct stores a new product and records its price in the price timeline with the author.
+	// It returns ErrCategoryNotFound if its category does not exist and ErrAlreadyExists if its id is taken.
+	CreateProduct(p Product, author string) error
+	// UpdateProduct replaces the stored product with the same ProductID, a changed price is recorded
+	// in the price timeline with the author.
+	// It returns ErrNotFound for an unknown product and ErrCategoryNotFound for an unknown category.
+	UpdateProduct(p Product, author string) error
+	// DeleteProduct removes the product with the given id or returns ErrNotFound
+	DeleteProduct(id string) error
+
+	// PriceHistory returns the price timeline of the product with the given id: the applied and the scheduled prices
+	// ordered by the time they take effect, or ErrNotFound
+	PriceHistory(productID string) ([]PriceChange, error)
+	// SchedulePriceChanges adds the future prices to the timeline of the product with the given id
+	// or returns ErrNotFound
+	SchedulePriceChanges(productID string, changes []PriceChange) error
+	// CancelPriceChange removes the scheduled change from the timeline of the product or returns ErrNotFound,
+	// ErrPriceChangeApplied if the product already has or has had the price
+	CancelPriceChange(productID string, changeID string) error
+	// ApplyDuePriceChanges gives the products the scheduled prices which are due at the given time,
+	// in the order they take effect, and returns the applied changes
+	ApplyDuePriceChanges(now time.Time) ([]PriceChange, error)
+
+	// Variants returns the variants of the product with the given id in the order they were created, or ErrNotFound
+	Variants(productID string) ([]Variant, error)
+	// VariantsOf returns the variants of the given products, the products without variants are left out
+	VariantsOf(productIDs []string) (map[string][]Variant, error)
+	// Variant returns the variant of the product or ErrNotFound for an unknown product
+	// and ErrVariantNotFound for an unknown variant
+	Variant(productID string, variantID string) (Variant, error)
+	// CreateVariant stores a new variant of its product or returns ErrNotFound for an unknown product,
+	// ErrAlreadyExists if its id is taken and ErrDuplicateSKU if another variant has its SKU
+	CreateVariant(v Variant) error
+	// UpdateVariant replaces the stored variant with the same ProductID and VariantID or returns ErrNotFound,
+	// ErrVariantNotFound and ErrDuplicateSKU in the same way as CreateVariant
+	UpdateVariant(v Variant) error
+	// DeleteVariant removes the variant of the product together with its stock, adjustments and reservations,
+	// or returns ErrNotFound or ErrVariantNotFound
+	DeleteVariant(productID string, variantID string) error
+
+	// Stock returns the stock levels of the product with the given id, or of its variant if variantID is not empty,
+	// in every warehouse ordered by warehouse id. It returns ErrNotFound or ErrVariantNotFound.
+	Stock(productID string, variantID string) ([]StockLevel, error)
+	// StockSummary returns the stock levels of the given products and all their variants summed over all the warehouses,
+	// a product without stock has a zero level
+	StockSummary(productIDs []string) (map[string]StockLevel, error)
+	// AdjustStock changes the quantity of the product or the variant in the warehouse of the adjustment,
+	// records the adjustment and returns the new stock level of the warehouse. It returns ErrNotFound
+	// or ErrVariantNotFound and an *InsufficientStockError if fewer pieces than reserved would be left.
+	AdjustStock(a StockAdjustment) (StockLevel, error)
+	// StockAdjustments returns the adjustments of the product with the given id, or of its variant
+	// if variantID is not empty, in the order they were made. It returns ErrNotFound or ErrVariantNotFound.
+	StockAdjustments(productID string, variantID string) ([]StockAdjustment, error)
+	// ReserveStock holds the pieces of the reservation until it expires and returns it. Without a WarehouseID
+	// the pieces are taken from the first warehouse by id which has enough of them. It returns ErrNotFound
+	// or ErrVariantNotFound and an *InsufficientStockError if there are not enough pieces.
+	ReserveStock(r Reservation) (Reservation, error)
+	// ReleaseReservation removes the reservation of the product or of one of its variants, so its pieces
+	// can be reserved again, or returns ErrNotFound
+	ReleaseReservation(productID string, reservationID string) error
+	// CommitReservation removes the reservation of the product or of one of its variants and takes its pieces
+	// out of the warehouse with a ReasonSold adjustment made by the author, all in one atomic step.
+	// It returns ErrNotFound for an unknown or expired reservation.
+	CommitReservation(productID string, reservationID string, author string) (StockAdjustment, error)
+	// ReleaseExpiredReservations removes the reservations which have expired at the given time
+	// and returns how many were removed
+	ReleaseExpiredReservations(now time.Time) (int, error)
+
+	// ListCategories returns the page of the categories selected by the query.
+	// The price filters and the CategoryID of the query are not used for categories.
+	ListCategories(q ListQuery) (CategoryPage, error)
+	// ListProducts returns the page of the products selected by the query
+	ListProducts(q ListQuery) (ProductPage, error)
+
+	// SearchProducts returns up to limit products whose name or description matches the query text,
+	// the most relevant first. A limit of 0 returns all the matches.
+	SearchProducts(query string, limit int) ([]ProductMatch, error)
+
+	// Transaction calls fn with a store which makes the changes of fn all or none: they are kept if fn returns nil
+	// and undone if it returns an error, which Transaction returns. Nobody else sees the changes before fn returns,
+	// so fn must only use the given store, the one Transaction is called on may wait until fn returns.
+	Transaction(fn func(tx CatalogStore) error) error
+}
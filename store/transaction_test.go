@@ -0,0 +1,142 @@
+package store
+
+import (
+	"errors"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+//errStop is returned by the funcs of the transactions which are rolled back
+var errStop = errors.New("stop")
+
+//changeInTransaction creates the "boots" category with a product and deletes the seed product "bq4foj37jhfipc5nqri0",
+//checking that the store of the transaction sees the changes
+func changeInTransaction(t *testing.T, name string, tx CatalogStore) {
+	assert.NoError(t, tx.CreateCategory(Category{CategoryID: "boots", CategoryName: "Boots"}), name)
+	assert.NoError(t, tx.CreateProduct(Product{ProductID: "hiker", ProductName: "Hiking Boot", Price: money.New(12000, "EUR"), CategoryID: "boots"}, "erp"), name)
+	assert.NoError(t, tx.DeleteProduct("bq4foj37jhfipc5nqri0"), name)
+
+	inBoots, err := tx.ProductsOfCategory("boots")
+	assert.NoError(t, err, name)
+	assert.Len(t, inBoots, 1, "%s: expected the transaction to see its product", name)
+	_, err = tx.Product("bq4foj37jhfipc5nqri0")
+	assert.Equal(t, ErrNotFound, err, "%s: expected the transaction to see the deletion", name)
+}
+
+//TestTransactionCommit tests whether all the changes of a transaction are kept when its func succeeds
+func TestTransactionCommit(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		err := catalog.Transaction(func(tx CatalogStore) error {
+			changeInTransaction(t, name, tx)
+			return nil
+		})
+		assert.NoError(t, err, name)
+
+		_, err = catalog.Category("boots")
+		assert.NoError(t, err, "%s: expected the category to be stored", name)
+		history, err := catalog.PriceHistory("hiker")
+		assert.NoError(t, err, "%s: expected the product to be stored", name)
+		assert.Len(t, history, 1, "%s: expected the price of the product to be recorded", name)
+		_, err = catalog.Product("bq4foj37jhfipc5nqri0")
+		assert.Equal(t, ErrNotFound, err, "%s: expected the product to be deleted", name)
+		found, _ := catalog.SearchProducts("boot", 0)
+		assert.Len(t, found, 1, "%s: expected the product in the search index", name)
+		found, _ = catalog.SearchProducts("superrep", 0)
+		assert.Empty(t, found, "%s: expected the deleted product to leave the search index", name)
+	}
+}
+
+//TestTransactionRollback tests whether none of the changes of a transaction are kept when its func fails,
+//also the ones of a nested transaction, and a failed method does not break the transaction
+func TestTransactionRollback(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		before, _ := catalog.Products()
+		err := catalog.Transaction(func(tx CatalogStore) error {
+			changeInTransaction(t, name, tx)
+			return errStop
+		})
+		assert.Equal(t, errStop, err, "%s: expected the error of the func", name)
+
+		_, err = catalog.Category("boots")
+		assert.Equal(t, ErrNotFound, err, "%s: expected the category to be rolled back", name)
+		after, _ := catalog.Products()
+		assert.Equal(t, before, after, "%s: expected the products to be rolled back", name)
+		found, _ := catalog.SearchProducts("boot", 0)
+		assert.Empty(t, found, "%s: expected the search index to be left as it was", name)
+		found, _ = catalog.SearchProducts("superrep", 0)
+		assert.Len(t, found, 1, "%s: expected the search index to be left as it was", name)
+
+		//the changes of the rolled back nested transaction and of the failed method are the only ones left out
+		err = catalog.Transaction(func(tx CatalogStore) error {
+			assert.NoError(t, tx.CreateCategory(Category{CategoryID: "sandals", CategoryName: "Sandals"}), name)
+			assert.Equal(t, ErrCategoryNotFound, tx.CreateProduct(Product{ProductID: "flip", ProductName: "Flip-flop", Price: money.New(900, "EUR"), CategoryID: "beach"}, "erp"), name)
+			assert.Equal(t, errStop, tx.Transaction(func(nested CatalogStore) error {
+				changeInTransaction(t, name, nested)
+				return errStop
+			}), name)
+			return nil
+		})
+		assert.NoError(t, err, name)
+		_, err = catalog.Category("sandals")
+		assert.NoError(t, err, "%s: expected the category of the outer transaction to be stored", name)
+		_, err = catalog.Category("boots")
+		assert.Equal(t, ErrNotFound, err, "%s: expected the nested transaction to be rolled back", name)
+		after, _ = catalog.Products()
+		assert.Equal(t, before, after, "%s: expected no products to be stored", name)
+	}
+}
+
+//TestTransactionRollbackRecords tests whether the price changes, the variants, the SKUs and the stock
+//changed in a transaction which fails are left as they were
+func TestTransactionRollbackRecords(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		assert.NoError(t, catalog.CreateVariant(shoeVariant("v1", "SRG-38", "38")), name)
+		_, err := catalog.AdjustStock(adjustment("a1", "tallinn", 10, ReasonReceived))
+		assert.NoError(t, err, name)
+		_, err = catalog.ReserveStock(reservation("r1", "tallinn", 4, time.Hour))
+		assert.NoError(t, err, name)
+		historyBefore, _ := catalog.PriceHistory("bq4foj37jhfipc5nqri0")
+		variantsBefore, _ := catalog.Variants("bq4foj37jhfipc5nqri0")
+		stockBefore, _ := catalog.Stock("bq4foj37jhfipc5nqri0", "")
+		adjustmentsBefore, _ := catalog.StockAdjustments("bq4foj37jhfipc5nqri0", "")
+
+		err = catalog.Transaction(func(tx CatalogStore) error {
+			//the SKU of the variant is given to a variant of the other product
+			assert.NoError(t, tx.UpdateVariant(shoeVariant("v1", "SRG-40", "40")), name)
+			taken := shoeVariant("v2", "SRG-38", "38")
+			taken.ProductID = "bq5457j7jhfi2s58o030"
+			assert.NoError(t, tx.CreateVariant(taken), name)
+			_, err := tx.AdjustStock(adjustment("a2", "tallinn", -2, ReasonSold))
+			assert.NoError(t, err, name)
+			assert.NoError(t, tx.ReleaseReservation("bq4foj37jhfipc5nqri0", "r1"), name)
+			assert.NoError(t, tx.SchedulePriceChanges("bq4foj37jhfipc5nqri0", []PriceChange{
+				{ChangeID: "sale", Price: money.New(8000, "EUR"), EffectiveAt: time.Now().Add(-time.Minute)},
+			}), name)
+			_, err = tx.ApplyDuePriceChanges(time.Now())
+			assert.NoError(t, err, name)
+			assert.NoError(t, tx.DeleteCategory("bq4fasj7jhfi127rimlg", DeletePolicy{Mode: DeleteCascade}), name)
+			return errStop
+		})
+		assert.Equal(t, errStop, err, name)
+
+		stored, err := catalog.Product("bq4foj37jhfipc5nqri0")
+		assert.NoError(t, err, "%s: expected the product to be rolled back", name)
+		assert.Equal(t, int64(10000), stored.Price.Amount, "%s: expected the price to be rolled back", name)
+		history, _ := catalog.PriceHistory("bq4foj37jhfipc5nqri0")
+		assert.Equal(t, historyBefore, history, "%s: expected the price timeline to be rolled back", name)
+		variants, _ := catalog.Variants("bq4foj37jhfipc5nqri0")
+		assert.Equal(t, variantsBefore, variants, "%s: expected the variants to be rolled back", name)
+		variants, _ = catalog.Variants("bq5457j7jhfi2s58o030")
+		assert.Empty(t, variants, "%s: expected the variant of the other product to be rolled back", name)
+		assert.Equal(t, ErrDuplicateSKU, catalog.CreateVariant(shoeVariant("v3", "SRG-38", "38")), "%s: expected the SKU to stay taken", name)
+		assert.NoError(t, catalog.CreateVariant(shoeVariant("v4", "SRG-40", "40")), "%s: expected the SKU to be free again", name)
+		stock, _ := catalog.Stock("bq4foj37jhfipc5nqri0", "")
+		assert.Equal(t, stockBefore, stock, "%s: expected the stock and the reservation to be rolled back", name)
+		adjustments, _ := catalog.StockAdjustments("bq4foj37jhfipc5nqri0", "")
+		assert.Equal(t, adjustmentsBefore, adjustments, "%s: expected the adjustments to be rolled back", name)
+		inCategory, _ := catalog.ProductsOfCategory("bq4fasj7jhfi127rimlg")
+		assert.Len(t, inCategory, 2, "%s: expected the category to keep its products", name)
+	}
+}
@@ -0,0 +1,76 @@
+package store
+
+import "strings"
+
+// CategoryNode is a category with its subcategories
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"Children"`
+}
+
+// buildTree nests the categories under their parents, keeping their order among the siblings.
+// Categories whose parent is not in the list become top-level nodes.
+func buildTree(all []Category) []CategoryNode {
+	known := make(map[string]bool, len(all))
+	for _, c := range all {
+		known[c.CategoryID] = true
+	}
+	children := make(map[string][]Category)
+	for _, c := range all {
+		parentID := c.ParentID
+		if !known[parentID] {
+			parentID = ""
+		}
+		children[parentID] = append(children[parentID], c)
+	}
+
+	var build func(parentID string) []CategoryNode
+	build = func(parentID string) []CategoryNode {
+		nodes := make([]CategoryNode, 0, len(children[parentID]))
+		for _, c := range children[parentID] {
+			nodes = append(nodes, CategoryNode{Category: c, Children: build(c.CategoryID)})
+		}
+		return nodes
+	}
+	return build("")
+}
+
+// CategoryNames finds the ids of the categories by their names, ignoring case, e.g. for a category named in a CSV file
+type CategoryNames map[string][]string
+
+// NewCategoryNames returns the ids of the categories by their names
+func NewCategoryNames(categories []Category) CategoryNames {
+	names := make(CategoryNames)
+	for _, c := range categories {
+		names.Add(c)
+	}
+	return names
+}
+
+// Add adds the id of the category under its name
+func (names CategoryNames) Add(c Category) {
+	key := strings.ToLower(c.CategoryName)
+	names[key] = append(names[key], c.CategoryID)
+}
+
+// Rename moves the id of the category from its old name to its current one
+func (names CategoryNames) Rename(c Category) {
+	for key, ids := range names {
+		for i, id := range ids {
+			if id == c.CategoryID {
+				names[key] = append(ids[:i:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(names[key]) == 0 {
+			delete(names, key)
+		}
+	}
+	names.Add(c)
+}
+
+// IDs returns the ids of the categories with the name, more than one if the categories in different places
+// of the tree have the same name
+func (names CategoryNames) IDs(name string) []string {
+	return names[strings.ToLower(name)]
+}
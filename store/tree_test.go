@@ -0,0 +1,153 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//apparel, women and shoes are nested into each other by fillTree
+var (
+	apparel = Category{CategoryID: "apparel", CategoryName: "Apparel"}
+	women   = Category{CategoryID: "women", CategoryName: "Women", ParentID: "apparel"}
+	shoes   = Category{CategoryID: "shoes", CategoryName: "Shoes", ParentID: "women"}
+)
+
+//fillTree adds the Apparel → Women → Shoes categories with a product in Women and in Shoes
+func fillTree(t *testing.T, catalog CatalogStore) {
+	for _, c := range []Category{apparel, women, shoes} {
+		if err := catalog.CreateCategory(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []Product{
+		{ProductID: "dress", ProductName: "Dress", CategoryID: "women"},
+		{ProductID: "sneaker", ProductName: "Sneaker", CategoryID: "shoes"},
+	} {
+		if err := catalog.CreateProduct(p, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//treeStores returns a new MemoryStore and SQLiteStore filled by fillTree
+func treeStores(t *testing.T) map[string]CatalogStore {
+	sqlite, _ := openTestSQLite(t)
+	stores := map[string]CatalogStore{"memory": NewMemoryStore(), "sqlite": sqlite}
+	for _, catalog := range stores {
+		fillTree(t, catalog)
+	}
+	return stores
+}
+
+//TestCategoryTree tests whether both stores return the same children, ancestors and tree
+func TestCategoryTree(t *testing.T) {
+	for name, catalog := range treeStores(t) {
+		children, err := catalog.ChildCategories("apparel")
+		assert.NoError(t, err)
+		assert.Equal(t, []Category{women}, children, "%s: expected the direct children only", name)
+		children, err = catalog.ChildCategories("shoes")
+		assert.NoError(t, err)
+		assert.Empty(t, children, "%s: expected no children of a leaf", name)
+
+		ancestors, err := catalog.CategoryAncestors("shoes")
+		assert.NoError(t, err)
+		assert.Equal(t, []Category{apparel, women}, ancestors, "%s: expected the breadcrumb from the top", name)
+		ancestors, err = catalog.CategoryAncestors("apparel")
+		assert.NoError(t, err)
+		assert.Empty(t, ancestors, "%s: expected no ancestors of a top-level category", name)
+
+		_, err = catalog.ChildCategories("randomID")
+		assert.Equal(t, ErrNotFound, err)
+		_, err = catalog.CategoryAncestors("randomID")
+		assert.Equal(t, ErrNotFound, err)
+
+		tree, err := catalog.CategoryTree()
+		assert.NoError(t, err)
+		if assert.Len(t, tree, 3, "%s: expected the seed categories and Apparel at the top", name) {
+			assert.Equal(t, CategoryNode{Category: apparel, Children: []CategoryNode{
+				{Category: women, Children: []CategoryNode{{Category: shoes, Children: []CategoryNode{}}}},
+			}}, tree[2])
+		}
+	}
+}
+
+//TestCategoryCycle tests whether a category can not be put under itself or its descendants
+func TestCategoryCycle(t *testing.T) {
+	for name, catalog := range treeStores(t) {
+		err := catalog.CreateCategory(Category{CategoryID: "self", CategoryName: "Self", ParentID: "self"})
+		assert.Equal(t, ErrCategoryCycle, err, "%s: expected a category not to be its own parent", name)
+
+		moved := apparel
+		moved.ParentID = "shoes"
+		assert.Equal(t, ErrCategoryCycle, catalog.UpdateCategory(moved), "%s: expected a cycle through the descendants", name)
+		moved.ParentID = "apparel"
+		assert.Equal(t, ErrCategoryCycle, catalog.UpdateCategory(moved), "%s: expected a cycle to itself", name)
+
+		//shoes can be moved up to the top-level category
+		moved = shoes
+		moved.ParentID = "apparel"
+		assert.NoError(t, catalog.UpdateCategory(moved))
+		children, _ := catalog.ChildCategories("apparel")
+		assert.Equal(t, []Category{women, moved}, children, "%s: expected shoes under apparel", name)
+		children, _ = catalog.ChildCategories("women")
+		assert.Empty(t, children, "%s: expected shoes to leave women", name)
+	}
+}
+
+//TestCategoryParent tests whether the parent has to exist and a category with subcategories can not be deleted
+func TestCategoryParent(t *testing.T) {
+	for name, catalog := range treeStores(t) {
+		err := catalog.CreateCategory(Category{CategoryID: "new", CategoryName: "New", ParentID: "randomID"})
+		assert.Equal(t, ErrParentNotFound, err, "%s: expected an unknown parent to be rejected", name)
+		moved := women
+		moved.ParentID = "randomID"
+		assert.Equal(t, ErrParentNotFound, catalog.UpdateCategory(moved), "%s: expected an unknown parent to be rejected", name)
+
+		assert.Equal(t, &CategoryInUseError{Subcategories: 1}, catalog.DeleteCategory("apparel", DeletePolicy{Mode: DeleteCascade}),
+			"%s: expected the subcategories to stay attached", name)
+		assert.NoError(t, catalog.DeleteProduct("sneaker"))
+		assert.NoError(t, catalog.DeleteCategory("shoes", DeletePolicy{}))
+		children, _ := catalog.ChildCategories("women")
+		assert.Empty(t, children, "%s: expected the deleted category to leave its parent", name)
+	}
+}
+
+//TestListProductsOfDescendants tests whether the products of the subcategories are listed with IncludeDescendants
+func TestListProductsOfDescendants(t *testing.T) {
+	for name, catalog := range treeStores(t) {
+		for _, p := range []struct {
+			q        ListQuery
+			expected []string
+		}{
+			{ListQuery{Sort: SortName, CategoryID: "apparel"}, []string{}},
+			{ListQuery{Sort: SortName, CategoryID: "apparel", IncludeDescendants: true}, []string{"dress", "sneaker"}},
+			{ListQuery{Sort: SortName, CategoryID: "women", IncludeDescendants: true, Limit: 1}, []string{"dress"}},
+			{ListQuery{Sort: SortName, CategoryID: "shoes", IncludeDescendants: true}, []string{"sneaker"}},
+		} {
+			page, err := catalog.ListProducts(p.q)
+			assert.NoError(t, err)
+			ids := make([]string, 0, len(page.Items))
+			for _, product := range page.Items {
+				ids = append(ids, product.ProductID)
+			}
+			assert.Equal(t, p.expected, ids, "%s: expected other products for %+v", name, p.q)
+		}
+	}
+}
+
+//TestCategoryNames tests whether the categories are found by their names, ignoring case, also after a rename
+func TestCategoryNames(t *testing.T) {
+	names := NewCategoryNames([]Category{
+		{CategoryID: "trail-shoes", CategoryName: "Trail"},
+		{CategoryID: "trail-bikes", CategoryName: "trail"},
+		{CategoryID: "boots", CategoryName: "Boots"},
+	})
+	assert.Equal(t, []string{"trail-shoes", "trail-bikes"}, names.IDs("TRAIL"))
+	assert.Empty(t, names.IDs("Sandals"))
+
+	names.Rename(Category{CategoryID: "trail-bikes", CategoryName: "Mountain Bikes"})
+	assert.Equal(t, []string{"trail-shoes"}, names.IDs("Trail"))
+	assert.Equal(t, []string{"trail-bikes"}, names.IDs("mountain bikes"))
+	names.Rename(Category{CategoryID: "boots", CategoryName: "Hiking Boots"})
+	assert.Empty(t, names.IDs("Boots"), "Expected the old name to be forgotten")
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"errors"
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+)
+
+// ErrVariantNotFound is returned when the product exists but the requested variant does not
+var ErrVariantNotFound = errors.New("variant not found")
+
+// ErrDuplicateSKU is returned when another variant already has the SKU
+var ErrDuplicateSKU = errors.New("duplicate SKU")
+
+// Variant is one sellable item of a product, e.g. a size and colour of a shoe
+type Variant struct {
+	VariantID string `json:"VariantID"`
+	ProductID string `json:"ProductID"`
+	// SKU is the stock keeping unit, unique in the whole catalog
+	SKU string `json:"SKU"`
+	// Attributes tell the variants of the product apart, e.g. {"size":"38","colour":"black"}
+	Attributes map[string]string `json:"Attributes"`
+	// Price overrides the price of the product, the variant costs as much as the product if it is not set
+	Price *money.Money `json:"Price,omitempty"`
+}
+
+// PriceOf returns the price of the variant of the given product
+func (v Variant) PriceOf(p Product) money.Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return p.Price
+}
+
+// copyVariant returns the variant with its own copies of the attributes and the price,
+// so changing the copy does not change the stored variant
+func copyVariant(v Variant) Variant {
+	attributes := make(map[string]string, len(v.Attributes))
+	for name, value := range v.Attributes {
+		attributes[name] = value
+	}
+	v.Attributes = attributes
+	if v.Price != nil {
+		price := *v.Price
+		v.Price = &price
+	}
+	return v
+}
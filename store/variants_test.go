@@ -0,0 +1,112 @@
+package store
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+//shoeVariant returns the variant of the first seed product with the size
+func shoeVariant(id string, sku string, size string) Variant {
+	return Variant{VariantID: id, ProductID: "bq4foj37jhfipc5nqri0", SKU: sku, Attributes: map[string]string{"size": size}}
+}
+
+//TestVariants tests whether the variants are created, updated and deleted and their SKUs stay unique
+func TestVariants(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		small, large := shoeVariant("v1", "SRG-38", "38"), shoeVariant("v2", "SRG-42", "42")
+		price := money.New(12000, "EUR")
+		large.Price = &price
+		assert.NoError(t, catalog.CreateVariant(small))
+		assert.NoError(t, catalog.CreateVariant(large))
+		assert.Equal(t, ErrAlreadyExists, catalog.CreateVariant(shoeVariant("v1", "SRG-40", "40")), "%s: expected the taken id to be reported", name)
+		assert.Equal(t, ErrDuplicateSKU, catalog.CreateVariant(shoeVariant("v3", "SRG-38", "40")), "%s: expected the taken SKU to be reported", name)
+		unknown := shoeVariant("v4", "SRG-44", "44")
+		unknown.ProductID = "randomID"
+		assert.Equal(t, ErrNotFound, catalog.CreateVariant(unknown), "%s: expected the unknown product to be reported", name)
+
+		variants, err := catalog.Variants("bq4foj37jhfipc5nqri0")
+		assert.NoError(t, err)
+		assert.Equal(t, []Variant{small, large}, variants, "%s: expected the variants in the order they were created", name)
+		grouped, err := catalog.VariantsOf([]string{"bq4foj37jhfipc5nqri0", "bq5457j7jhfi2s58o030"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]Variant{"bq4foj37jhfipc5nqri0": {small, large}}, grouped, "%s: expected only the products with variants", name)
+		_, err = catalog.Variant("bq5457j7jhfi2s58o030", "v1")
+		assert.Equal(t, ErrVariantNotFound, err, "%s: expected the variant of another product to be hidden", name)
+
+		//the SKU moves to the updated variant
+		small.SKU = "SRG-38W"
+		assert.NoError(t, catalog.UpdateVariant(small))
+		assert.NoError(t, catalog.CreateVariant(shoeVariant("v3", "SRG-38", "38")), "%s: expected the old SKU to be free", name)
+		small.SKU = "SRG-42"
+		assert.Equal(t, ErrDuplicateSKU, catalog.UpdateVariant(small), "%s: expected the taken SKU to be reported", name)
+		assert.Equal(t, ErrVariantNotFound, catalog.UpdateVariant(shoeVariant("v9", "SRG-45", "45")), "%s: expected the unknown variant to be reported", name)
+		stored, err := catalog.Variant("bq4foj37jhfipc5nqri0", "v1")
+		assert.NoError(t, err)
+		assert.Equal(t, "SRG-38W", stored.SKU, "%s: expected the SKU of the last successful update", name)
+
+		assert.NoError(t, catalog.DeleteVariant("bq4foj37jhfipc5nqri0", "v1"))
+		assert.Equal(t, ErrVariantNotFound, catalog.DeleteVariant("bq4foj37jhfipc5nqri0", "v1"), "%s: expected the variant to be deleted once", name)
+		assert.NoError(t, catalog.CreateVariant(shoeVariant("v5", "SRG-38W", "38")), "%s: expected the SKU of the deleted variant to be free", name)
+
+		//the variants are deleted together with the product
+		assert.NoError(t, catalog.DeleteProduct("bq4foj37jhfipc5nqri0"))
+		assert.NoError(t, catalog.CreateProduct(Product{ProductID: "newID", ProductName: "Cap", Price: money.New(900, "EUR"), CategoryID: "bq4fasj7jhfi127rimlg"}, "test"))
+		v := shoeVariant("v6", "SRG-42", "M")
+		v.ProductID = "newID"
+		assert.NoError(t, catalog.CreateVariant(v), "%s: expected the SKUs of the deleted product to be free", name)
+	}
+}
+
+//TestVariantStock tests whether the variants keep their own stock and the summary adds it to the product
+func TestVariantStock(t *testing.T) {
+	for name, catalog := range policyStores(t) {
+		assert.NoError(t, catalog.CreateVariant(shoeVariant("v1", "SRG-38", "38")))
+		received := adjustment("a1", "tallinn", 4, ReasonReceived)
+		received.VariantID = "v1"
+		_, err := catalog.AdjustStock(received)
+		assert.NoError(t, err)
+		_, err = catalog.AdjustStock(adjustment("a2", "tallinn", 1, ReasonReceived))
+		assert.NoError(t, err)
+		unknown := adjustment("a3", "tallinn", 1, ReasonReceived)
+		unknown.VariantID = "v9"
+		_, err = catalog.AdjustStock(unknown)
+		assert.Equal(t, ErrVariantNotFound, err, "%s: expected the unknown variant to be reported", name)
+
+		//the variant pieces can not be reserved for the product
+		_, err = catalog.ReserveStock(reservation("r1", "tallinn", 2, time.Hour))
+		assert.Equal(t, &InsufficientStockError{WarehouseID: "tallinn", Available: 1}, err, "%s: expected only the product pieces", name)
+		r := reservation("r2", "", 3, time.Hour)
+		r.VariantID = "v1"
+		r, err = catalog.ReserveStock(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "tallinn", r.WarehouseID)
+
+		levels, err := catalog.Stock("bq4foj37jhfipc5nqri0", "v1")
+		assert.NoError(t, err)
+		assert.Equal(t, []StockLevel{{WarehouseID: "tallinn", OnHand: 4, Reserved: 3, Available: 1}}, levels, "%s: expected the variant stock", name)
+		levels, err = catalog.Stock("bq4foj37jhfipc5nqri0", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []StockLevel{{WarehouseID: "tallinn", OnHand: 1, Available: 1}}, levels, "%s: expected the product stock", name)
+		summary, err := catalog.StockSummary([]string{"bq4foj37jhfipc5nqri0"})
+		assert.NoError(t, err)
+		assert.Equal(t, StockLevel{OnHand: 5, Reserved: 3, Available: 2}, summary["bq4foj37jhfipc5nqri0"], "%s: expected the product and the variant summed", name)
+
+		sold, err := catalog.CommitReservation("bq4foj37jhfipc5nqri0", "r2", "test")
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", sold.VariantID, "%s: expected the variant pieces to be sold", name)
+		adjustments, err := catalog.StockAdjustments("bq4foj37jhfipc5nqri0", "v1")
+		assert.NoError(t, err)
+		assert.Len(t, adjustments, 2, "%s: expected the received and the sold pieces of the variant", name)
+
+		//the stock is deleted together with the variant
+		assert.NoError(t, catalog.DeleteVariant("bq4foj37jhfipc5nqri0", "v1"))
+		summary, err = catalog.StockSummary([]string{"bq4foj37jhfipc5nqri0"})
+		assert.NoError(t, err)
+		assert.Equal(t, StockLevel{OnHand: 1, Available: 1}, summary["bq4foj37jhfipc5nqri0"], "%s: expected only the product pieces", name)
+		adjustments, err = catalog.StockAdjustments("bq4foj37jhfipc5nqri0", "")
+		assert.NoError(t, err)
+		assert.Len(t, adjustments, 1, "%s: expected only the product adjustment", name)
+	}
+}
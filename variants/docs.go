@@ -0,0 +1,46 @@
+package variants
+
+import (
+	"github.com/KseniiaL/AdcashTestAssignment/openapi"
+	"net/http"
+)
+
+// Operations describes the variant handlers for the OpenAPI document
+func Operations() openapi.Operations {
+	o := openapi.Operations{}
+	o.Add((*Handler).GetVariants, openapi.Operation{
+		Summary:  "Returns the variants of the product",
+		Response: []variant{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).GetVariant, openapi.Operation{
+		Summary:  "Returns the variant",
+		Response: variant{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	o.Add((*Handler).CreateVariant, openapi.Operation{
+		Summary:  "Creates the variant of the product",
+		Request:  variant{},
+		Status:   http.StatusCreated,
+		Response: variant{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).UpdateVariant, openapi.Operation{
+		Summary:  "Changes the fields of the variant given in the JSON Merge Patch",
+		Request:  variant{},
+		Response: variant{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).ReplaceVariant, openapi.Operation{
+		Summary:  "Replaces the whole variant",
+		Request:  variant{},
+		Response: variant{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	o.Add((*Handler).DeleteVariant, openapi.Operation{
+		Summary:  "Deletes the variant together with its stock",
+		Response: "",
+		Errors:   []int{http.StatusNotFound},
+	})
+	return o
+}
@@ -0,0 +1,225 @@
+//package variants contains the methods for working with the variants of the products, e.g. their sizes and colours
+package variants
+
+import (
+	"fmt"
+	"github.com/KseniiaL/AdcashTestAssignment/api"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/gorilla/mux"
+	"github.com/rs/xid"
+	"net/http"
+	"strings"
+)
+
+// variant stores information about variant fields
+type variant = store.Variant
+
+// Handler contains all the variant handlers and the store they work with
+type Handler struct {
+	store store.CatalogStore
+}
+
+// NewHandler returns a Handler which reads and writes the variants through the given store
+func NewHandler(s store.CatalogStore) *Handler {
+	return &Handler{store: s}
+}
+
+// GetVariants gets a product id from the request link and returns its variants in the order they were created
+func (h *Handler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	//get product id from the link
+	productID := mux.Vars(r)["id"]
+
+	//find the variants of the product in the store
+	//or report an error
+	variants, err := h.store.Variants(productID)
+	if err != nil {
+		api.WriteError(w, r, variantError(err, variant{ProductID: productID}))
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, variants)
+}
+
+// GetVariant gets a product id and a variant id from the request link and returns the variant
+func (h *Handler) GetVariant(w http.ResponseWriter, r *http.Request) {
+	//get product id and variant id from the link
+	productID, variantID := mux.Vars(r)["id"], mux.Vars(r)["variantID"]
+
+	//find the variant in the store
+	//or report an error
+	singleVariant, err := h.store.Variant(productID, variantID)
+	if err != nil {
+		api.WriteError(w, r, variantError(err, variant{ProductID: productID, VariantID: variantID}))
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, singleVariant)
+}
+
+// CreateVariant gets a product id from the request link and adds the variant in the request body to the product.
+// The SKU must not be taken by any other variant, the Attributes must be values of the attributes in the schema
+// of the category of the product and the Price, if given, must be in the currency of the product.
+// The new variant is returned in response.
+func (h *Handler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	//get product id from the link
+	productID := mux.Vars(r)["id"]
+	var newVariant variant
+
+	//unmarshal the information from JSON into the variant instance
+	//or report an error
+	if err := api.ReadJSON(r, &newVariant); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//generate unique variantID, the product id is taken from the link only
+	newVariant.VariantID = xid.New().String()
+	newVariant.ProductID = productID
+
+	h.saveVariant(w, r, newVariant, http.StatusCreated, store.CatalogStore.CreateVariant)
+}
+
+// UpdateVariant gets a product id and a variant id from the request link and applies the JSON Merge Patch
+// in the request body to the variant, so only the fields sent are changed
+func (h *Handler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	//get product id and variant id from the link
+	productID, variantID := mux.Vars(r)["id"], mux.Vars(r)["variantID"]
+
+	//get the information containing in request's body
+	//or report an error
+	reqBody, err := api.ReadBody(r)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//find the given variant in the store
+	//or report an error
+	singleVariant, err := h.store.Variant(productID, variantID)
+	if err != nil {
+		api.WriteError(w, r, variantError(err, variant{ProductID: productID, VariantID: variantID}))
+		return
+	}
+
+	//apply the patch to the variant
+	//or report an error
+	if err = api.PatchResource(&singleVariant, reqBody); err != nil {
+		api.WriteError(w, r, api.BadRequest("The request body is not a valid merge patch: %v", err))
+		return
+	}
+	//the ids are taken from the link only
+	singleVariant.ProductID, singleVariant.VariantID = productID, variantID
+
+	h.saveVariant(w, r, singleVariant, http.StatusOK, store.CatalogStore.UpdateVariant)
+}
+
+// ReplaceVariant gets a product id and a variant id from the request link and replaces the variant
+// with the one in the request body
+func (h *Handler) ReplaceVariant(w http.ResponseWriter, r *http.Request) {
+	//get product id and variant id from the link
+	productID, variantID := mux.Vars(r)["id"], mux.Vars(r)["variantID"]
+	var replaceVariant variant
+
+	//unmarshal the information from JSON into the variant instance
+	//or report an error
+	if err := api.ReadJSON(r, &replaceVariant); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	//the ids are taken from the link only
+	replaceVariant.ProductID, replaceVariant.VariantID = productID, variantID
+
+	h.saveVariant(w, r, replaceVariant, http.StatusOK, store.CatalogStore.UpdateVariant)
+}
+
+// DeleteVariant gets a product id and a variant id from the request link and removes the variant
+// together with its stock
+func (h *Handler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	//get product id and variant id from the link
+	productID, variantID := mux.Vars(r)["id"], mux.Vars(r)["variantID"]
+
+	//remove the variant from the store
+	//or report an error
+	if err := h.store.DeleteVariant(productID, variantID); err != nil {
+		api.WriteError(w, r, variantError(err, variant{ProductID: productID, VariantID: variantID}))
+		return
+	}
+	fmt.Fprintf(w, "The variant with ID %v has been deleted successfully", variantID)
+}
+
+// saveVariant validates the variant against its product and the attribute schema of its category, writes it
+// to the store with the given func and returns it in response with the given status. The product, the schema and
+// the write are read and made in one store transaction, so the schema can not change in between.
+func (h *Handler) saveVariant(w http.ResponseWriter, r *http.Request, v variant, status int, write func(tx store.CatalogStore, v store.Variant) error) {
+	if v.Attributes == nil {
+		v.Attributes = map[string]string{}
+	}
+	err := h.store.Transaction(func(tx store.CatalogStore) error {
+		//find the product the variant belongs to and the attribute schema of its category
+		parent, err := tx.Product(v.ProductID)
+		if err != nil {
+			return variantError(err, v)
+		}
+		schema, err := store.CategorySchema(tx, parent.CategoryID)
+		if err != nil {
+			return err
+		}
+
+		//check the required fields
+		if err = validate(v, parent, schema); err != nil {
+			return err
+		}
+		return variantError(write(tx, v), v)
+	})
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+
+	//return the variant in response
+	api.WriteJSON(w, status, v)
+}
+
+// validate returns a validation Problem if some of the variant fields are invalid
+// or its attributes are not in the schema of the category of the product
+func validate(v variant, parent store.Product, schema []store.Attribute) error {
+	var fieldErrors []api.FieldError
+	//SKU is required field
+	if strings.TrimSpace(v.SKU) == "" {
+		fieldErrors = append(fieldErrors, api.FieldError{Field: "SKU", Detail: "Kindly enter the SKU"})
+	}
+	for name := range v.Attributes {
+		if strings.TrimSpace(name) == "" {
+			fieldErrors = append(fieldErrors, api.FieldError{Field: "Attributes", Detail: "The attribute names can not be empty"})
+		}
+	}
+	fieldErrors = append(fieldErrors, api.VariantAttributeErrors(v.Attributes, schema)...)
+	//the prices of the variants are compared with each other on the product list
+	if v.Price != nil {
+		priceErrors := api.PriceErrors("Price", *v.Price)
+		if len(priceErrors) == 0 && v.Price.Currency != parent.Price.Currency {
+			priceErrors = append(priceErrors, api.FieldError{Field: "Price.Currency", Detail: "The variant must be priced in the product currency " + parent.Price.Currency})
+		}
+		fieldErrors = append(fieldErrors, priceErrors...)
+	}
+
+	if len(fieldErrors) != 0 {
+		return api.Validation(fieldErrors...)
+	}
+	return nil
+}
+
+// variantError turns the store errors about the given variant into Problems
+func variantError(err error, v variant) error {
+	switch err {
+	case store.ErrNotFound:
+		return api.NotFound("Product with ID %s not found", v.ProductID)
+	case store.ErrVariantNotFound:
+		return api.NotFound("Variant with ID %s of product %s not found", v.VariantID, v.ProductID)
+	case store.ErrAlreadyExists:
+		return api.Conflict("Variant with ID %s already exists", v.VariantID)
+	case store.ErrDuplicateSKU:
+		return api.Conflict("Another variant already has SKU %s", v.SKU)
+	}
+	return err
+}
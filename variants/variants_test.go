@@ -0,0 +1,110 @@
+//package variants contains test for variants.go
+package variants
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/KseniiaL/AdcashTestAssignment/store"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//serve sends the request with the product id and the variant id in the link vars to the handler func
+func serve(t *testing.T, handler http.HandlerFunc, method string, vars map[string]string, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, "/products/"+vars["id"]+"/variants", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, vars)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+//shoeCatalog returns a store whose seed category has the size and colour attributes of the variants
+func shoeCatalog(t *testing.T) store.CatalogStore {
+	catalog := store.NewMemoryStore()
+	c, _ := catalog.Category("bq4fasj7jhfi127rimlg")
+	c.Attributes = []store.Attribute{
+		{Name: "size", Type: store.AttributeEnum, Values: []string{"38", "39", "40"}},
+		{Name: "colour", Type: store.AttributeString},
+		{Name: "waterproof", Type: store.AttributeBool},
+	}
+	if err := catalog.UpdateCategory(c); err != nil {
+		t.Fatal(err)
+	}
+	return catalog
+}
+
+//TestCreateVariant tests whether CreateVariant func adds the variant to the product and reports the invalid ones
+func TestCreateVariant(t *testing.T) {
+	catalog := shoeCatalog(t)
+	create := NewHandler(catalog).CreateVariant
+	product := map[string]string{"id": "bq4foj37jhfipc5nqri0"}
+
+	var created store.Variant
+	rr := serve(t, create, "POST", product, `{"SKU":"SRG-38-BLK","Attributes":{"size":"38","colour":"black"},"Price":{"Amount":9000,"Currency":"EUR"}}`)
+	assert.Equal(t, 201, rr.Code, "Created response is expected")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "bq4foj37jhfipc5nqri0", created.ProductID, "Expected the product from the link")
+	assert.NotEmpty(t, created.VariantID, "Expected a generated variant id")
+
+	for _, p := range []struct {
+		productID    string
+		requestBody  string
+		expected     string
+		expectedCode int
+	}{
+		{"bq4foj37jhfipc5nqri0", `{"SKU":"SRG-38-BLK"}`,
+			`{"type":"/problems/conflict","title":"Conflict","status":409,"detail":"Another variant already has SKU SRG-38-BLK","instance":"/products/bq4foj37jhfipc5nqri0/variants"}`, 409},
+		{"bq4foj37jhfipc5nqri0", `{"Attributes":{"size":"40"}}`,
+			`{"type":"/problems/validation","title":"Unprocessable Entity","status":422,"detail":"The request body contains invalid fields","instance":"/products/bq4foj37jhfipc5nqri0/variants","errors":[{"field":"SKU","detail":"Kindly enter the SKU"}]}`, 422},
+		{"bq4foj37jhfipc5nqri0", `{"SKU":"SRG-40","Price":{"Amount":9000,"Currency":"USD"}}`,
+			`{"type":"/problems/validation","title":"Unprocessable Entity","status":422,"detail":"The request body contains invalid fields","instance":"/products/bq4foj37jhfipc5nqri0/variants","errors":[{"field":"Price.Currency","detail":"The variant must be priced in the product currency EUR"}]}`, 422},
+		{"bq4foj37jhfipc5nqri0", `{"SKU":"SRG-41","Attributes":{"size":"41","waterproof":"maybe","width":"wide"}}`,
+			`{"type":"/problems/validation","title":"Unprocessable Entity","status":422,"detail":"The request body contains invalid fields","instance":"/products/bq4foj37jhfipc5nqri0/variants","errors":[{"field":"Attributes.size","detail":"The size must be one of 38, 39, 40"},{"field":"Attributes.waterproof","detail":"The waterproof must be true or false"},{"field":"Attributes.width","detail":"The category of the product has no attribute width"}]}`, 422},
+		{"randomID", `{"SKU":"SRG-40"}`,
+			`{"type":"/problems/not-found","title":"Not Found","status":404,"detail":"Product with ID randomID not found","instance":"/products/randomID/variants"}`, 404},
+	} {
+		rr := serve(t, create, "POST", map[string]string{"id": p.productID}, p.requestBody)
+
+		assert.Equal(t, p.expectedCode, rr.Code, "Expected another status for %s", p.requestBody)
+		assert.JSONEq(t, p.expected, rr.Body.String(), "Expected another body for %s", p.requestBody)
+	}
+
+	variants, _ := catalog.Variants("bq4foj37jhfipc5nqri0")
+	assert.Equal(t, []store.Variant{created}, variants, "Expected only the valid variant to be stored")
+}
+
+//TestUpdateVariant tests whether UpdateVariant, ReplaceVariant and DeleteVariant funcs change and remove the variant
+func TestUpdateVariant(t *testing.T) {
+	catalog := shoeCatalog(t)
+	err := catalog.CreateVariant(store.Variant{VariantID: "v1", ProductID: "bq4foj37jhfipc5nqri0", SKU: "SRG-38", Attributes: map[string]string{"size": "38", "colour": "black"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	variant := map[string]string{"id": "bq4foj37jhfipc5nqri0", "variantID": "v1"}
+
+	rr := serve(t, NewHandler(catalog).UpdateVariant, "PATCH", variant, `{"Attributes":{"colour":null},"Price":{"Amount":9500,"Currency":"EUR"}}`)
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.JSONEq(t, `{"VariantID":"v1","ProductID":"bq4foj37jhfipc5nqri0","SKU":"SRG-38","Attributes":{"size":"38"},"Price":{"Amount":9500,"Currency":"EUR","Formatted":"95.00 EUR"}}`,
+		rr.Body.String(), "Expected only the patched fields to change")
+
+	rr = serve(t, NewHandler(catalog).ReplaceVariant, "PUT", variant, `{"SKU":"SRG-39","Attributes":{"size":"39"}}`)
+	assert.Equal(t, 200, rr.Code, "OK response is expected")
+	assert.JSONEq(t, `{"VariantID":"v1","ProductID":"bq4foj37jhfipc5nqri0","SKU":"SRG-39","Attributes":{"size":"39"}}`,
+		rr.Body.String(), "Expected the price override to be gone")
+
+	rr = serve(t, NewHandler(catalog).GetVariant, "GET", map[string]string{"id": "bq5457j7jhfi2s58o030", "variantID": "v1"}, "")
+	assert.Equal(t, 404, rr.Code, "Expected the variant of another product to be hidden")
+
+	for _, expectedCode := range []int{200, 404} {
+		rr = serve(t, NewHandler(catalog).DeleteVariant, "DELETE", variant, "")
+		assert.Equal(t, expectedCode, rr.Code, "Expected another status")
+	}
+	rr = serve(t, NewHandler(catalog).GetVariants, "GET", variant, "")
+	assert.JSONEq(t, `[]`, rr.Body.String(), "Expected no variants to be left")
+}